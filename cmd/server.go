@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/hootsuite/atlantis/server"
+	"github.com/hootsuite/atlantis/server/events"
 	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -17,29 +18,119 @@ import (
 // 2. Add a new field to server.Config and set the mapstructure tag equal to the flag name.
 // 3. Add your flag's description etc. to the stringFlags, intFlags, or boolFlags slices.
 const (
-	AtlantisURLFlag     = "atlantis-url"
-	ConfigFlag          = "config"
-	DataDirFlag         = "data-dir"
-	GHHostnameFlag      = "gh-hostname"
-	GHTokenFlag         = "gh-token"
-	GHUserFlag          = "gh-user"
-	GHWebHookSecret     = "gh-webhook-secret" // nolint: gas
-	GitlabHostnameFlag  = "gitlab-hostname"
-	GitlabTokenFlag     = "gitlab-token"
-	GitlabUserFlag      = "gitlab-user"
-	GitlabWebHookSecret = "gitlab-webhook-secret"
-	LogLevelFlag        = "log-level"
-	PortFlag            = "port"
-	RequireApprovalFlag = "require-approval"
-	SSLCertFileFlag     = "ssl-cert-file"
-	SSLKeyFileFlag      = "ssl-key-file"
+	AllowedCommentFlagsFlag        = "allowed-comment-flags"
+	APITokensFlag                  = "api-tokens" // nolint: gas
+	ApplyTimeoutFlag               = "apply-timeout"
+	AtlantisURLFlag                = "atlantis-url"
+	AutoplanReposFlag              = "autoplan-repos"
+	AzureDevopsTokenFlag           = "azuredevops-token"
+	AzureDevopsUserFlag            = "azuredevops-user"
+	AzureDevopsWebhookPasswordFlag = "azuredevops-webhook-password" // nolint: gas
+	AzureDevopsWebhookUserFlag     = "azuredevops-webhook-user"
+	BitbucketBaseURLFlag           = "bitbucket-base-url"
+	BitbucketTokenFlag             = "bitbucket-token"
+	BitbucketUserFlag              = "bitbucket-user"
+	BitbucketWebHookSecret         = "bitbucket-webhook-secret" // nolint: gas
+	ConfigFlag                     = "config"
+	DataDirFlag                    = "data-dir"
+	DeniedCommentFlagsFlag         = "denied-comment-flags"
+	DisableAutoplanFlag            = "disable-autoplan"
+	DrainTimeoutFlag               = "drain-timeout"
+	GHAppIDFlag                    = "gh-app-id"
+	GHAppKeyFileFlag               = "gh-app-key-file"
+	GHChecksFlag                   = "gh-checks"
+	GHHostnameFlag                 = "gh-hostname"
+	GHTokenFlag                    = "gh-token"
+	GHUserFlag                     = "gh-user"
+	GHWebHookSecret                = "gh-webhook-secret" // nolint: gas
+	GiteaHostnameFlag              = "gitea-hostname"
+	GiteaTokenFlag                 = "gitea-token"
+	GiteaUserFlag                  = "gitea-user"
+	GiteaWebHookSecret             = "gitea-webhook-secret" // nolint: gas
+	GitlabHostnameFlag             = "gitlab-hostname"
+	GitlabTokenFlag                = "gitlab-token"
+	GitlabUserFlag                 = "gitlab-user"
+	GitlabWebHookSecret            = "gitlab-webhook-secret"
+	InitTimeoutFlag                = "init-timeout"
+	JobRetentionDaysFlag           = "job-retention-days"
+	LogLevelFlag                   = "log-level"
+	MaxRunningCommandsFlag         = "max-running-commands"
+	MaxRunningCommandsPerRepoFlag  = "max-running-commands-per-repo"
+	ParallelPoolSizeFlag           = "parallel-pool-size"
+	PlanTimeoutFlag                = "plan-timeout"
+	PortFlag                       = "port"
+	RepoConfigFlag                 = "repo-config"
+	RequireApprovalFlag            = "require-approval"
+	RunTimeoutFlag                 = "run-timeout"
+	SSLCertFileFlag                = "ssl-cert-file"
+	SSLKeyFileFlag                 = "ssl-key-file"
 )
 
 var stringFlags = []stringFlag{
+	{
+		name: AllowedCommentFlagsFlag,
+		description: "Comma-separated list of Terraform flags that can be passed to plan and apply in pull request comments, ex. -target,-var." +
+			" If not set, all flags that aren't denied by --" + DeniedCommentFlagsFlag + " are allowed.",
+	},
+	{
+		name: APITokensFlag,
+		description: "Comma-separated list of tokens that authenticate requests to the JSON API under /api/v1 with an \"Authorization: Bearer <token>\" header." +
+			" If not set, the API is disabled. Can also be specified via the ATLANTIS_API_TOKENS environment variable.",
+		env: "ATLANTIS_API_TOKENS",
+	},
 	{
 		name:        AtlantisURLFlag,
 		description: "URL that Atlantis can be reached at. Defaults to http://$(hostname):$port where $port is from --" + PortFlag + ".",
 	},
+	{
+		name: AutoplanReposFlag,
+		description: "Comma-separated list of repos that plan should be run on automatically when a pull request is opened or updated." +
+			" Repos are specified by their full name, ex. hootsuite/atlantis. A trailing * matches any repos with that prefix, ex. hootsuite/*.",
+		value: "*",
+	},
+	{
+		name:        AzureDevopsUserFlag,
+		description: "Azure DevOps username of API user.",
+	},
+	{
+		name:        AzureDevopsTokenFlag,
+		description: "Azure DevOps personal access token of API user. Can also be specified via the ATLANTIS_AZUREDEVOPS_TOKEN environment variable.",
+		env:         "ATLANTIS_AZUREDEVOPS_TOKEN",
+	},
+	{
+		name: AzureDevopsWebhookUserFlag,
+		description: "Optional username that Azure DevOps service hooks use for basic auth." +
+			" If not specified, Atlantis won't be able to validate that the incoming service hook call came from Azure DevOps.",
+	},
+	{
+		name: AzureDevopsWebhookPasswordFlag,
+		description: "Password that Azure DevOps service hooks use for basic auth with --" + AzureDevopsWebhookUserFlag + "." +
+			" Can also be specified via the ATLANTIS_AZUREDEVOPS_WEBHOOK_PASSWORD environment variable.",
+		env: "ATLANTIS_AZUREDEVOPS_WEBHOOK_PASSWORD",
+	},
+	{
+		name: BitbucketBaseURLFlag,
+		description: "Base URL of your Bitbucket Server installation, ex. https://bitbucket.example.com." +
+			" If using Bitbucket Cloud (bitbucket.org), no need to set.",
+		value: server.DefaultBitbucketBaseURL,
+	},
+	{
+		name:        BitbucketUserFlag,
+		description: "Bitbucket username of API user.",
+	},
+	{
+		name: BitbucketTokenFlag,
+		description: "Bitbucket app password of API user for Bitbucket Cloud or password or personal access token for Bitbucket Server." +
+			" Can also be specified via the ATLANTIS_BITBUCKET_TOKEN environment variable.",
+		env: "ATLANTIS_BITBUCKET_TOKEN",
+	},
+	{
+		name: BitbucketWebHookSecret,
+		description: "Optional secret used to validate Bitbucket Server webhooks. Bitbucket Cloud doesn't support webhook secrets." +
+			" If not specified, Atlantis won't be able to validate that the incoming webhook call came from Bitbucket Server. " +
+			"Can also be specified via the ATLANTIS_BITBUCKET_WEBHOOK_SECRET environment variable.",
+		env: "ATLANTIS_BITBUCKET_WEBHOOK_SECRET",
+	},
 	{
 		name:        ConfigFlag,
 		description: "Path to config file.",
@@ -49,6 +140,14 @@ var stringFlags = []stringFlag{
 		description: "Path to directory to store Atlantis data.",
 		value:       "~/.atlantis",
 	},
+	{
+		name:        DeniedCommentFlagsFlag,
+		description: "Comma-separated list of Terraform flags that can't be passed to plan and apply in pull request comments, ex. -state,-var-file.",
+	},
+	{
+		name:        GHAppKeyFileFlag,
+		description: "Path to the private key PEM file of the GitHub App to authenticate as. Requires --" + GHAppIDFlag + ".",
+	},
 	{
 		name:        GHHostnameFlag,
 		description: "Hostname of your Github Enterprise installation. If using github.com, no need to set.",
@@ -56,7 +155,7 @@ var stringFlags = []stringFlag{
 	},
 	{
 		name:        GHUserFlag,
-		description: "GitHub username of API user.",
+		description: "GitHub username of API user. If authenticating as a GitHub App, the name of the app so comments can @mention it.",
 	},
 	{
 		name:        GHTokenFlag,
@@ -70,6 +169,26 @@ var stringFlags = []stringFlag{
 			"Can also be specified via the ATLANTIS_GH_WEBHOOK_SECRET environment variable.",
 		env: "ATLANTIS_GH_WEBHOOK_SECRET",
 	},
+	{
+		name:        GiteaHostnameFlag,
+		description: "Hostname of your Gitea installation, ex. gitea.example.com. Required if --" + GiteaUserFlag + " is set.",
+	},
+	{
+		name:        GiteaUserFlag,
+		description: "Gitea username of API user.",
+	},
+	{
+		name:        GiteaTokenFlag,
+		description: "Gitea token of API user. Can also be specified via the ATLANTIS_GITEA_TOKEN environment variable.",
+		env:         "ATLANTIS_GITEA_TOKEN",
+	},
+	{
+		name: GiteaWebHookSecret,
+		description: "Optional secret used to validate Gitea webhooks." +
+			" If not specified, Atlantis won't be able to validate that the incoming webhook call came from Gitea. " +
+			"Can also be specified via the ATLANTIS_GITEA_WEBHOOK_SECRET environment variable.",
+		env: "ATLANTIS_GITEA_WEBHOOK_SECRET",
+	},
 	{
 		name:        GitlabHostnameFlag,
 		description: "Hostname of your GitLab Enterprise installation. If using gitlab.com, no need to set.",
@@ -96,6 +215,11 @@ var stringFlags = []stringFlag{
 		description: "Log level. Either debug, info, warn, or error.",
 		value:       "info",
 	},
+	{
+		name: RepoConfigFlag,
+		description: "Path to a YAML file with the server-side repo config. It sets defaults for repos, ex. their workflow and apply requirements," +
+			" and lists which settings each repo's atlantis.yaml can override. If not set, repos can override everything.",
+	},
 	{
 		name:        SSLCertFileFlag,
 		description: "File containing x509 Certificate used for serving HTTPS. If the cert is signed by a CA, the file should be the concatenation of the server's certificate, any intermediates, and the CA's certificate.",
@@ -106,6 +230,16 @@ var stringFlags = []stringFlag{
 	},
 }
 var boolFlags = []boolFlag{
+	{
+		name:        DisableAutoplanFlag,
+		description: "Disable running plan automatically when a pull request is opened or updated.",
+		value:       false,
+	},
+	{
+		name:        GHChecksFlag,
+		description: "Publish plan and apply results on GitHub as a check run per project and workspace instead of a commit status. Requires --" + GHAppIDFlag + ".",
+		value:       false,
+	},
 	{
 		name:        RequireApprovalFlag,
 		description: "Require pull requests to be \"Approved\" before allowing the apply command to be run.",
@@ -113,11 +247,54 @@ var boolFlags = []boolFlag{
 	},
 }
 var intFlags = []intFlag{
+	{
+		name:        ApplyTimeoutFlag,
+		description: "Seconds terraform apply can run for before it's interrupted. Can be overridden in atlantis.yaml. Defaults to no timeout.",
+	},
+	{
+		name:        DrainTimeoutFlag,
+		description: "Seconds to wait for running commands to finish when shutting down. Commands still running afterwards are cancelled. Commands received in the meantime aren't run.",
+		value:       300,
+	},
+	{
+		name:        GHAppIDFlag,
+		description: "ID of the GitHub App to authenticate as instead of using --" + GHTokenFlag + ". Requires --" + GHAppKeyFileFlag + ".",
+	},
+	{
+		name:        InitTimeoutFlag,
+		description: "Seconds terraform init can run for before it's interrupted. Can be overridden in atlantis.yaml. Defaults to no timeout.",
+	},
+	{
+		name:        JobRetentionDaysFlag,
+		description: "Days to keep the output of plans and applies for. If 0, it's kept forever.",
+		value:       7,
+	},
+	{
+		name:        MaxRunningCommandsFlag,
+		description: "Max number of commands that can run at once. Further commands are queued. Defaults to no limit.",
+	},
+	{
+		name:        MaxRunningCommandsPerRepoFlag,
+		description: "Max number of commands for the same repo that can run at once. Further commands for that repo are queued without holding up other repos. Defaults to no limit.",
+	},
+	{
+		name:        ParallelPoolSizeFlag,
+		description: "Max number of projects to plan or apply at once for a pull request. Can be set per repo with parallel_pool_size in --" + RepoConfigFlag + ".",
+		value:       1,
+	},
+	{
+		name:        PlanTimeoutFlag,
+		description: "Seconds terraform plan can run for before it's interrupted. Can be overridden in atlantis.yaml. Defaults to no timeout.",
+	},
 	{
 		name:        PortFlag,
 		description: "Port to bind to.",
 		value:       4141,
 	},
+	{
+		name:        RunTimeoutFlag,
+		description: "Seconds run steps and hooks can run for before they're interrupted. Can be overridden in atlantis.yaml. Defaults to no timeout.",
+	},
 }
 
 type stringFlag struct {
@@ -242,6 +419,9 @@ func (s *ServerCmd) run() error {
 	if err := s.setDataDir(&config); err != nil {
 		return err
 	}
+	if err := s.setServerRepoConfig(&config); err != nil {
+		return err
+	}
 	s.trimAtSymbolFromUsers(&config)
 
 	// Config looks good. Start the server.
@@ -259,23 +439,82 @@ func (s *ServerCmd) validate(config server.Config) error {
 		return errors.New("invalid log level: not one of debug, info, warn, error")
 	}
 
+	if config.DrainTimeout < 0 {
+		return fmt.Errorf("--%s can't be negative", DrainTimeoutFlag)
+	}
+	timeouts := []struct {
+		flag  string
+		value int
+	}{
+		{InitTimeoutFlag, config.InitTimeout},
+		{PlanTimeoutFlag, config.PlanTimeout},
+		{ApplyTimeoutFlag, config.ApplyTimeout},
+		{RunTimeoutFlag, config.RunTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value < 0 {
+			return fmt.Errorf("--%s can't be negative", timeout.flag)
+		}
+	}
+	if config.JobRetentionDays < 0 {
+		return fmt.Errorf("--%s can't be negative", JobRetentionDaysFlag)
+	}
+	if config.MaxRunningCommands < 0 {
+		return fmt.Errorf("--%s can't be negative", MaxRunningCommandsFlag)
+	}
+	if config.MaxRunningCommandsPerRepo < 0 {
+		return fmt.Errorf("--%s can't be negative", MaxRunningCommandsPerRepoFlag)
+	}
+
+	if config.ParallelPoolSize < 1 {
+		return fmt.Errorf("--%s must be at least 1", ParallelPoolSizeFlag)
+	}
+
 	if (config.SSLKeyFile == "") != (config.SSLCertFile == "") {
 		return fmt.Errorf("--%s and --%s are both required for ssl", SSLKeyFileFlag, SSLCertFileFlag)
 	}
 
+	if !strings.HasPrefix(config.BitbucketBaseURL, "http://") && !strings.HasPrefix(config.BitbucketBaseURL, "https://") {
+		return fmt.Errorf("--%s must have http:// or https://, got %q", BitbucketBaseURLFlag, config.BitbucketBaseURL)
+	}
+
+	if (config.GithubAppID == 0) != (config.GithubAppKeyFile == "") {
+		return fmt.Errorf("--%s and --%s must both be set", GHAppIDFlag, GHAppKeyFileFlag)
+	}
+	if config.GithubAppID != 0 && config.GithubToken != "" {
+		return fmt.Errorf("--%s and --%s can't both be set", GHAppIDFlag, GHTokenFlag)
+	}
+	// Only GitHub Apps can create check runs.
+	if config.GithubChecks && config.GithubAppID == 0 {
+		return fmt.Errorf("--%s requires --%s", GHChecksFlag, GHAppIDFlag)
+	}
+
 	// The following combinations are valid.
-	// 1. github user and token set
+	// 1. github user and token or app set
 	// 2. gitlab user and token set
-	// 3. all 4 set
-	vcsErr := fmt.Errorf("--%s/--%s or --%s/--%s must be set", GHUserFlag, GHTokenFlag, GitlabUserFlag, GitlabTokenFlag)
-	if ((config.GithubUser == "") != (config.GithubToken == "")) || ((config.GitlabUser == "") != (config.GitlabToken == "")) {
+	// 3. bitbucket user and token set
+	// 4. azure devops user and token set
+	// 5. gitea user and token set
+	// 6. any combination of the above
+	vcsErr := fmt.Errorf("--%s/--%s, --%s/--%s, --%s/--%s, --%s/--%s or --%s/--%s must be set", GHUserFlag, GHTokenFlag, GitlabUserFlag, GitlabTokenFlag, BitbucketUserFlag, BitbucketTokenFlag, AzureDevopsUserFlag, AzureDevopsTokenFlag, GiteaUserFlag, GiteaTokenFlag)
+	if ((config.GithubUser == "") != (config.GithubToken == "" && config.GithubAppID == 0)) ||
+		((config.GitlabUser == "") != (config.GitlabToken == "")) ||
+		((config.BitbucketUser == "") != (config.BitbucketToken == "")) ||
+		((config.AzureDevopsUser == "") != (config.AzureDevopsToken == "")) ||
+		((config.GiteaUser == "") != (config.GiteaToken == "")) {
 		return vcsErr
 	}
 	// At this point, we know that there can't be a single user/token without
 	// its partner, but we haven't checked if any user/token is set at all.
-	if config.GithubUser == "" && config.GitlabUser == "" {
+	if config.GithubUser == "" && config.GitlabUser == "" && config.BitbucketUser == "" && config.AzureDevopsUser == "" && config.GiteaUser == "" {
 		return vcsErr
 	}
+	if config.GiteaUser != "" && config.GiteaHostname == "" {
+		return fmt.Errorf("--%s must be set to use Gitea", GiteaHostnameFlag)
+	}
+	if (config.AzureDevopsWebhookUser == "") != (config.AzureDevopsWebhookPassword == "") {
+		return fmt.Errorf("--%s and --%s must both be set", AzureDevopsWebhookUserFlag, AzureDevopsWebhookPasswordFlag)
+	}
 	return nil
 }
 
@@ -305,10 +544,28 @@ func (s *ServerCmd) setDataDir(config *server.Config) error {
 	return nil
 }
 
-// trimAtSymbolFromUsers trims @ from the front of the github and gitlab usernames
+// setServerRepoConfig reads the server-side repo config if it was set so that
+// mistakes in it are caught on startup.
+func (s *ServerCmd) setServerRepoConfig(config *server.Config) error {
+	if config.RepoConfig == "" {
+		return nil
+	}
+	repoConfig, err := events.ReadServerRepoConfig(config.RepoConfig)
+	if err != nil {
+		return errors.Wrap(err, "invalid repo config")
+	}
+	config.ServerRepoConfig = repoConfig
+	return nil
+}
+
+// trimAtSymbolFromUsers trims @ from the front of the github, gitlab,
+// bitbucket, azure devops and gitea usernames
 func (s *ServerCmd) trimAtSymbolFromUsers(config *server.Config) {
 	config.GithubUser = strings.TrimPrefix(config.GithubUser, "@")
 	config.GitlabUser = strings.TrimPrefix(config.GitlabUser, "@")
+	config.BitbucketUser = strings.TrimPrefix(config.BitbucketUser, "@")
+	config.AzureDevopsUser = strings.TrimPrefix(config.AzureDevopsUser, "@")
+	config.GiteaUser = strings.TrimPrefix(config.GiteaUser, "@")
 }
 
 // withErrPrint prints out any errors to a terminal in red.
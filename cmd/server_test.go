@@ -155,7 +155,7 @@ func TestExecute_ValidateSSLConfig(t *testing.T) {
 }
 
 func TestExecute_ValidateVCSConfig(t *testing.T) {
-	expErr := "--gh-user/--gh-token or --gitlab-user/--gitlab-token must be set"
+	expErr := "--gh-user/--gh-token, --gitlab-user/--gitlab-token, --bitbucket-user/--bitbucket-token, --azuredevops-user/--azuredevops-token or --gitea-user/--gitea-token must be set"
 	cases := []struct {
 		description string
 		flags       map[string]interface{}
@@ -210,6 +210,21 @@ func TestExecute_ValidateVCSConfig(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"just bitbucket user set",
+			map[string]interface{}{
+				cmd.BitbucketUserFlag: "user",
+			},
+			true,
+		},
+		{
+			"bitbucket user and github token set",
+			map[string]interface{}{
+				cmd.BitbucketUserFlag: "user",
+				cmd.GHTokenFlag:       "token",
+			},
+			true,
+		},
 		{
 			"github user and github token set and should be successful",
 			map[string]interface{}{
@@ -218,6 +233,23 @@ func TestExecute_ValidateVCSConfig(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"github user and github app set and should be successful",
+			map[string]interface{}{
+				cmd.GHUserFlag:       "app",
+				cmd.GHAppIDFlag:      1,
+				cmd.GHAppKeyFileFlag: "key.pem",
+			},
+			false,
+		},
+		{
+			"just github app set",
+			map[string]interface{}{
+				cmd.GHAppIDFlag:      1,
+				cmd.GHAppKeyFileFlag: "key.pem",
+			},
+			true,
+		},
 		{
 			"gitlab user and gitlab token set and should be successful",
 			map[string]interface{}{
@@ -226,6 +258,45 @@ func TestExecute_ValidateVCSConfig(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"just azure devops token set",
+			map[string]interface{}{
+				cmd.AzureDevopsTokenFlag: "token",
+			},
+			true,
+		},
+		{
+			"azure devops user and azure devops token set and should be successful",
+			map[string]interface{}{
+				cmd.AzureDevopsUserFlag:  "user",
+				cmd.AzureDevopsTokenFlag: "token",
+			},
+			false,
+		},
+		{
+			"just gitea user set",
+			map[string]interface{}{
+				cmd.GiteaUserFlag: "user",
+			},
+			true,
+		},
+		{
+			"gitea user and gitea token set and should be successful",
+			map[string]interface{}{
+				cmd.GiteaHostnameFlag: "gitea.example.com",
+				cmd.GiteaUserFlag:     "user",
+				cmd.GiteaTokenFlag:    "token",
+			},
+			false,
+		},
+		{
+			"bitbucket user and bitbucket token set and should be successful",
+			map[string]interface{}{
+				cmd.BitbucketUserFlag:  "user",
+				cmd.BitbucketTokenFlag: "token",
+			},
+			false,
+		},
 		{
 			"github and gitlab user and github and gitlab token set and should be successful",
 			map[string]interface{}{
@@ -278,9 +349,24 @@ func TestExecute_Defaults(t *testing.T) {
 	Equals(t, dataDir, passedConfig.DataDir)
 	Equals(t, "github.com", passedConfig.GithubHostname)
 	Equals(t, "gitlab.com", passedConfig.GitlabHostname)
+	Equals(t, "https://bitbucket.org", passedConfig.BitbucketBaseURL)
 	Equals(t, "info", passedConfig.LogLevel)
 	Equals(t, false, passedConfig.RequireApproval)
 	Equals(t, 4141, passedConfig.Port)
+	Equals(t, 1, passedConfig.ParallelPoolSize)
+	Equals(t, 300, passedConfig.DrainTimeout)
+	Equals(t, 0, passedConfig.InitTimeout)
+	Equals(t, 0, passedConfig.PlanTimeout)
+	Equals(t, 0, passedConfig.ApplyTimeout)
+	Equals(t, 0, passedConfig.RunTimeout)
+	Equals(t, 7, passedConfig.JobRetentionDays)
+	Equals(t, 0, passedConfig.MaxRunningCommands)
+	Equals(t, "", passedConfig.APITokens)
+	Equals(t, 0, passedConfig.MaxRunningCommandsPerRepo)
+	Equals(t, false, passedConfig.DisableAutoplan)
+	Equals(t, "*", passedConfig.AutoplanRepos)
+	Equals(t, "", passedConfig.RepoConfig)
+	Assert(t, passedConfig.ServerRepoConfig == nil, "exp no server repo config")
 }
 
 func TestExecute_ExpandHomeDir(t *testing.T) {
@@ -298,6 +384,36 @@ func TestExecute_ExpandHomeDir(t *testing.T) {
 	Equals(t, home+"/this/is/a/path", passedConfig.DataDir)
 }
 
+func TestExecute_RepoConfig(t *testing.T) {
+	t.Log("Should read the repo config file.")
+	tmpFile := tempFile(t, "repos:\n- id: owner/repo\n  allowed_overrides: [workflow]")
+	defer os.Remove(tmpFile) // nolint: errcheck
+	c := setup(map[string]interface{}{
+		cmd.GHUserFlag:     "user",
+		cmd.GHTokenFlag:    "token",
+		cmd.RepoConfigFlag: tmpFile,
+	})
+	err := c.Execute()
+	Ok(t, err)
+	Equals(t, tmpFile, passedConfig.RepoConfig)
+	Equals(t, 1, len(passedConfig.ServerRepoConfig.Repos))
+	Equals(t, "owner/repo", passedConfig.ServerRepoConfig.Repos[0].ID)
+}
+
+func TestExecute_InvalidRepoConfig(t *testing.T) {
+	t.Log("Should error if the repo config file is invalid.")
+	tmpFile := tempFile(t, "repos:\n- allowed_overrides: [workflow]")
+	defer os.Remove(tmpFile) // nolint: errcheck
+	c := setup(map[string]interface{}{
+		cmd.GHUserFlag:     "user",
+		cmd.GHTokenFlag:    "token",
+		cmd.RepoConfigFlag: tmpFile,
+	})
+	err := c.Execute()
+	Assert(t, err != nil, "should be an error")
+	Equals(t, "invalid repo config: parsing repo config "+tmpFile+": repo 1: id is required", err.Error())
+}
+
 func TestExecute_GithubUser(t *testing.T) {
 	t.Log("Should remove the @ from the github username if it's passed.")
 	c := setup(map[string]interface{}{
@@ -322,37 +438,227 @@ func TestExecute_GitlabUser(t *testing.T) {
 	Equals(t, "user", passedConfig.GitlabUser)
 }
 
+func TestExecute_BitbucketBaseURLScheme(t *testing.T) {
+	t.Log("Should error if the bitbucket base url doesn't have a scheme.")
+	c := setup(map[string]interface{}{
+		cmd.BitbucketUserFlag:    "user",
+		cmd.BitbucketTokenFlag:   "token",
+		cmd.BitbucketBaseURLFlag: "bitbucket.example.com",
+	})
+	err := c.Execute()
+	Assert(t, err != nil, "should be an error")
+	Equals(t, `--bitbucket-base-url must have http:// or https://, got "bitbucket.example.com"`, err.Error())
+}
+
+func TestExecute_NegativeInts(t *testing.T) {
+	cases := []struct {
+		flag   string
+		expErr string
+	}{
+		{cmd.DrainTimeoutFlag, "--drain-timeout can't be negative"},
+		{cmd.InitTimeoutFlag, "--init-timeout can't be negative"},
+		{cmd.PlanTimeoutFlag, "--plan-timeout can't be negative"},
+		{cmd.ApplyTimeoutFlag, "--apply-timeout can't be negative"},
+		{cmd.RunTimeoutFlag, "--run-timeout can't be negative"},
+		{cmd.JobRetentionDaysFlag, "--job-retention-days can't be negative"},
+		{cmd.MaxRunningCommandsFlag, "--max-running-commands can't be negative"},
+		{cmd.MaxRunningCommandsPerRepoFlag, "--max-running-commands-per-repo can't be negative"},
+	}
+	for _, c := range cases {
+		t.Run(c.flag, func(t *testing.T) {
+			serverCmd := setup(map[string]interface{}{
+				cmd.GHUserFlag:  "user",
+				cmd.GHTokenFlag: "token",
+				c.flag:          -1,
+			})
+			err := serverCmd.Execute()
+			Assert(t, err != nil, "should be an error")
+			Equals(t, c.expErr, err.Error())
+		})
+	}
+}
+
+func TestExecute_ParallelPoolSize(t *testing.T) {
+	t.Log("Should error if the parallel pool size is less than 1.")
+	c := setup(map[string]interface{}{
+		cmd.GHUserFlag:           "user",
+		cmd.GHTokenFlag:          "token",
+		cmd.ParallelPoolSizeFlag: 0,
+	})
+	err := c.Execute()
+	Assert(t, err != nil, "should be an error")
+	Equals(t, "--parallel-pool-size must be at least 1", err.Error())
+}
+
+func TestExecute_AzureDevopsWebhookUserWithoutPassword(t *testing.T) {
+	t.Log("Should error if the azure devops webhook user is set without a password.")
+	c := setup(map[string]interface{}{
+		cmd.AzureDevopsUserFlag:        "user",
+		cmd.AzureDevopsTokenFlag:       "token",
+		cmd.AzureDevopsWebhookUserFlag: "webhook-user",
+	})
+	err := c.Execute()
+	Assert(t, err != nil, "should be an error")
+	Equals(t, "--azuredevops-webhook-user and --azuredevops-webhook-password must both be set", err.Error())
+}
+
+func TestExecute_GithubAppValidation(t *testing.T) {
+	cases := []struct {
+		description string
+		flags       map[string]interface{}
+		expErr      string
+	}{
+		{
+			"app id without key file",
+			map[string]interface{}{
+				cmd.GHUserFlag:  "app",
+				cmd.GHAppIDFlag: 1,
+			},
+			"--gh-app-id and --gh-app-key-file must both be set",
+		},
+		{
+			"key file without app id",
+			map[string]interface{}{
+				cmd.GHUserFlag:       "app",
+				cmd.GHAppKeyFileFlag: "key.pem",
+			},
+			"--gh-app-id and --gh-app-key-file must both be set",
+		},
+		{
+			"app and token",
+			map[string]interface{}{
+				cmd.GHUserFlag:       "app",
+				cmd.GHTokenFlag:      "token",
+				cmd.GHAppIDFlag:      1,
+				cmd.GHAppKeyFileFlag: "key.pem",
+			},
+			"--gh-app-id and --gh-token can't both be set",
+		},
+		{
+			"checks without app",
+			map[string]interface{}{
+				cmd.GHUserFlag:   "user",
+				cmd.GHTokenFlag:  "token",
+				cmd.GHChecksFlag: true,
+			},
+			"--gh-checks requires --gh-app-id",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := setup(c.flags).Execute()
+			Assert(t, err != nil, "should be an error")
+			Equals(t, c.expErr, err.Error())
+		})
+	}
+}
+
+func TestExecute_GiteaWithoutHostname(t *testing.T) {
+	t.Log("Should error if the gitea user is set without a hostname.")
+	c := setup(map[string]interface{}{
+		cmd.GiteaUserFlag:  "user",
+		cmd.GiteaTokenFlag: "token",
+	})
+	err := c.Execute()
+	Assert(t, err != nil, "should be an error")
+	Equals(t, "--gitea-hostname must be set to use Gitea", err.Error())
+}
+
+func TestExecute_BitbucketUser(t *testing.T) {
+	t.Log("Should remove the @ from the bitbucket username if it's passed.")
+	c := setup(map[string]interface{}{
+		cmd.BitbucketUserFlag:  "@user",
+		cmd.BitbucketTokenFlag: "token",
+	})
+	err := c.Execute()
+	Ok(t, err)
+
+	Equals(t, "user", passedConfig.BitbucketUser)
+}
+
 func TestExecute_Flags(t *testing.T) {
 	t.Log("Should use all flags that are set.")
 	c := setup(map[string]interface{}{
-		cmd.AtlantisURLFlag:     "url",
-		cmd.DataDirFlag:         "path",
-		cmd.GHHostnameFlag:      "ghhostname",
-		cmd.GHUserFlag:          "user",
-		cmd.GHTokenFlag:         "token",
-		cmd.GHWebHookSecret:     "secret",
-		cmd.GitlabHostnameFlag:  "gitlab-hostname",
-		cmd.GitlabUserFlag:      "gitlab-user",
-		cmd.GitlabTokenFlag:     "gitlab-token",
-		cmd.GitlabWebHookSecret: "gitlab-secret",
-		cmd.LogLevelFlag:        "debug",
-		cmd.PortFlag:            8181,
-		cmd.RequireApprovalFlag: true,
+		cmd.AllowedCommentFlagsFlag:        "-target",
+		cmd.APITokensFlag:                  "token1,token2",
+		cmd.AtlantisURLFlag:                "url",
+		cmd.AutoplanReposFlag:              "owner/repo",
+		cmd.AzureDevopsTokenFlag:           "ad-token",
+		cmd.AzureDevopsUserFlag:            "ad-user",
+		cmd.AzureDevopsWebhookPasswordFlag: "ad-webhook-password",
+		cmd.AzureDevopsWebhookUserFlag:     "ad-webhook-user",
+		cmd.BitbucketBaseURLFlag:           "https://bitbucket.example.com",
+		cmd.BitbucketTokenFlag:             "bitbucket-token",
+		cmd.BitbucketUserFlag:              "bitbucket-user",
+		cmd.BitbucketWebHookSecret:         "bitbucket-secret",
+		cmd.DataDirFlag:                    "path",
+		cmd.DeniedCommentFlagsFlag:         "-state",
+		cmd.DisableAutoplanFlag:            true,
+		cmd.DrainTimeoutFlag:               60,
+		cmd.InitTimeoutFlag:                120,
+		cmd.PlanTimeoutFlag:                600,
+		cmd.ApplyTimeoutFlag:               3600,
+		cmd.RunTimeoutFlag:                 30,
+		cmd.GHHostnameFlag:                 "ghhostname",
+		cmd.GiteaHostnameFlag:              "gitea-hostname",
+		cmd.GiteaTokenFlag:                 "gitea-token",
+		cmd.GiteaUserFlag:                  "gitea-user",
+		cmd.GiteaWebHookSecret:             "gitea-secret",
+		cmd.GHUserFlag:                     "user",
+		cmd.GHTokenFlag:                    "token",
+		cmd.GHWebHookSecret:                "secret",
+		cmd.GitlabHostnameFlag:             "gitlab-hostname",
+		cmd.GitlabUserFlag:                 "gitlab-user",
+		cmd.GitlabTokenFlag:                "gitlab-token",
+		cmd.GitlabWebHookSecret:            "gitlab-secret",
+		cmd.JobRetentionDaysFlag:           30,
+		cmd.LogLevelFlag:                   "debug",
+		cmd.MaxRunningCommandsFlag:         10,
+		cmd.MaxRunningCommandsPerRepoFlag:  2,
+		cmd.ParallelPoolSizeFlag:           4,
+		cmd.PortFlag:                       8181,
+		cmd.RequireApprovalFlag:            true,
 	})
 	err := c.Execute()
 	Ok(t, err)
 
+	Equals(t, "-target", passedConfig.AllowedCommentFlags)
+	Equals(t, "token1,token2", passedConfig.APITokens)
 	Equals(t, "url", passedConfig.AtlantisURL)
+	Equals(t, "owner/repo", passedConfig.AutoplanRepos)
+	Equals(t, "ad-token", passedConfig.AzureDevopsToken)
+	Equals(t, "ad-user", passedConfig.AzureDevopsUser)
+	Equals(t, "ad-webhook-password", passedConfig.AzureDevopsWebhookPassword)
+	Equals(t, "ad-webhook-user", passedConfig.AzureDevopsWebhookUser)
+	Equals(t, "https://bitbucket.example.com", passedConfig.BitbucketBaseURL)
+	Equals(t, "bitbucket-token", passedConfig.BitbucketToken)
+	Equals(t, "bitbucket-user", passedConfig.BitbucketUser)
+	Equals(t, "bitbucket-secret", passedConfig.BitbucketWebHookSecret)
 	Equals(t, "path", passedConfig.DataDir)
+	Equals(t, "-state", passedConfig.DeniedCommentFlags)
+	Equals(t, true, passedConfig.DisableAutoplan)
 	Equals(t, "ghhostname", passedConfig.GithubHostname)
 	Equals(t, "user", passedConfig.GithubUser)
 	Equals(t, "token", passedConfig.GithubToken)
 	Equals(t, "secret", passedConfig.GithubWebHookSecret)
+	Equals(t, "gitea-hostname", passedConfig.GiteaHostname)
+	Equals(t, "gitea-token", passedConfig.GiteaToken)
+	Equals(t, "gitea-user", passedConfig.GiteaUser)
+	Equals(t, "gitea-secret", passedConfig.GiteaWebHookSecret)
 	Equals(t, "gitlab-hostname", passedConfig.GitlabHostname)
 	Equals(t, "gitlab-user", passedConfig.GitlabUser)
 	Equals(t, "gitlab-token", passedConfig.GitlabToken)
 	Equals(t, "gitlab-secret", passedConfig.GitlabWebHookSecret)
 	Equals(t, "debug", passedConfig.LogLevel)
+	Equals(t, 60, passedConfig.DrainTimeout)
+	Equals(t, 120, passedConfig.InitTimeout)
+	Equals(t, 600, passedConfig.PlanTimeout)
+	Equals(t, 3600, passedConfig.ApplyTimeout)
+	Equals(t, 30, passedConfig.RunTimeout)
+	Equals(t, 30, passedConfig.JobRetentionDays)
+	Equals(t, 10, passedConfig.MaxRunningCommands)
+	Equals(t, 2, passedConfig.MaxRunningCommandsPerRepo)
+	Equals(t, 4, passedConfig.ParallelPoolSize)
 	Equals(t, 8181, passedConfig.Port)
 	Equals(t, true, passedConfig.RequireApproval)
 }
@@ -361,7 +667,9 @@ func TestExecute_ConfigFile(t *testing.T) {
 	t.Log("Should use all the values from the config file.")
 	tmpFile := tempFile(t, `---
 atlantis-url: "url"
+autoplan-repos: "owner/repo"
 data-dir: "path"
+disable-autoplan: true
 gh-hostname: "ghhostname"
 gh-user: "user"
 gh-token: "token"
@@ -381,7 +689,9 @@ require-approval: true`)
 	err := c.Execute()
 	Ok(t, err)
 	Equals(t, "url", passedConfig.AtlantisURL)
+	Equals(t, "owner/repo", passedConfig.AutoplanRepos)
 	Equals(t, "path", passedConfig.DataDir)
+	Equals(t, true, passedConfig.DisableAutoplan)
 	Equals(t, "ghhostname", passedConfig.GithubHostname)
 	Equals(t, "user", passedConfig.GithubUser)
 	Equals(t, "token", passedConfig.GithubToken)
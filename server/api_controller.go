@@ -0,0 +1,331 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hootsuite/atlantis/server/events"
+	"github.com/hootsuite/atlantis/server/events/jobs"
+	"github.com/hootsuite/atlantis/server/events/locking"
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/logging"
+)
+
+// APIController handles the JSON API under /api/v1. It's split out from
+// Server to make testing easier. Requests are authenticated by Authenticate.
+type APIController struct {
+	Locker    locking.Locker
+	Jobs      *jobs.Manager
+	Workspace events.AtlantisWorkspace
+	Logger    *logging.SimpleLogger
+	// APITokens are the tokens that requests can authenticate with. If
+	// empty, the API is disabled.
+	APITokens []string
+}
+
+// APILock is a lock in API responses.
+type APILock struct {
+	// ID is the lock's key, ex. owner/repo/path/default, which is used in
+	// the lock's URL, ex. /api/v1/locks/owner/repo/path/default.
+	ID           string    `json:"id"`
+	RepoFullName string    `json:"repo_full_name"`
+	Path         string    `json:"path"`
+	Workspace    string    `json:"workspace"`
+	PullNum      int       `json:"pull_num"`
+	PullURL      string    `json:"pull_url"`
+	User         string    `json:"user"`
+	Time         time.Time `json:"time"`
+}
+
+// APIPlan is a plan in API responses.
+type APIPlan struct {
+	RepoFullName string `json:"repo_full_name"`
+	Path         string `json:"path"`
+	Workspace    string `json:"workspace"`
+	// Commit is the head commit that was planned. It's empty if it wasn't
+	// recorded.
+	Commit string    `json:"commit"`
+	Time   time.Time `json:"time"`
+}
+
+// APIPull is the response of GET /api/v1/pulls/{repo}/{num}.
+type APIPull struct {
+	RepoFullName string      `json:"repo_full_name"`
+	PullNum      int         `json:"pull_num"`
+	Locks        []APILock   `json:"locks"`
+	Jobs         []jobs.Info `json:"jobs"`
+	Plans        []APIPlan   `json:"plans"`
+}
+
+// Limits on the number of jobs in API responses.
+const (
+	// defaultJobsLimit is how many jobs are listed if there's no limit query
+	// param.
+	defaultJobsLimit = 100
+	// maxJobsLimit is the highest limit query param that's accepted.
+	maxJobsLimit = 1000
+)
+
+// apiFilter narrows down what's listed by the repo, workspace and pull query
+// params. Empty fields match everything.
+type apiFilter struct {
+	RepoFullName string
+	Workspace    string
+	PullNum      int
+}
+
+// Authenticate returns a handler that calls next if the request has one of
+// the API tokens in an "Authorization: Bearer <token>" header.
+func (a *APIController) Authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(a.APITokens) == 0 {
+			a.respondErr(w, logging.Debug, http.StatusForbidden, "The API is disabled since no API tokens are configured")
+			return
+		}
+		header := r.Header.Get("Authorization")
+		if strings.HasPrefix(header, "Bearer ") {
+			token := []byte(strings.TrimPrefix(header, "Bearer "))
+			for _, t := range a.APITokens {
+				if subtle.ConstantTimeCompare(token, []byte(t)) == 1 {
+					next(w, r)
+					return
+				}
+			}
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		a.respondErr(w, logging.Warn, http.StatusUnauthorized, "Missing or invalid API token")
+	}
+}
+
+// ListLocks is the GET /api/v1/locks route. It lists the locks that match the
+// repo, workspace and pull query params.
+func (a *APIController) ListLocks(w http.ResponseWriter, r *http.Request) {
+	filter, ok := a.parseFilter(w, r)
+	if !ok {
+		return
+	}
+	locks, err := a.listLocks(filter)
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "Failed to list locks: %s", err)
+		return
+	}
+	a.respond(w, http.StatusOK, map[string][]APILock{"locks": locks})
+}
+
+// GetLockRoute is the GET /api/v1/locks/{id} route.
+func (a *APIController) GetLockRoute(w http.ResponseWriter, r *http.Request) {
+	a.GetLock(w, r, mux.Vars(r)["id"])
+}
+
+// GetLock responds with the lock with id. GetLockRoute should be called
+// first. This method is split out to make this route testable.
+func (a *APIController) GetLock(w http.ResponseWriter, _ *http.Request, id string) {
+	lock, err := a.Locker.GetLock(id)
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "Failed to get lock %s: %s", id, err)
+		return
+	}
+	if lock == nil {
+		a.respondErr(w, logging.Warn, http.StatusNotFound, "No lock found with id %s", id)
+		return
+	}
+	a.respond(w, http.StatusOK, toAPILock(id, *lock))
+}
+
+// DeleteLockRoute is the DELETE /api/v1/locks/{id} route.
+func (a *APIController) DeleteLockRoute(w http.ResponseWriter, r *http.Request) {
+	a.DeleteLock(w, r, mux.Vars(r)["id"])
+}
+
+// DeleteLock deletes the lock with id and responds with it. Like discarding a
+// lock in the UI, the plan isn't deleted. DeleteLockRoute should be called
+// first. This method is split out to make this route testable.
+func (a *APIController) DeleteLock(w http.ResponseWriter, _ *http.Request, id string) {
+	lock, err := a.Locker.Unlock(id)
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "Failed to delete lock %s: %s", id, err)
+		return
+	}
+	if lock == nil {
+		a.respondErr(w, logging.Warn, http.StatusNotFound, "No lock found with id %s", id)
+		return
+	}
+	a.Logger.Info("deleted lock id %s through the API", id)
+	a.respond(w, http.StatusOK, toAPILock(id, *lock))
+}
+
+// ListJobs is the GET /api/v1/jobs route. It lists the jobs that match the
+// repo, workspace and pull query params, most recently started first. At most
+// defaultJobsLimit are listed unless there's a limit query param.
+func (a *APIController) ListJobs(w http.ResponseWriter, r *http.Request) {
+	filter, ok := a.parseFilter(w, r)
+	if !ok {
+		return
+	}
+	limit := defaultJobsLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 || n > maxJobsLimit {
+			a.respondErr(w, logging.Warn, http.StatusBadRequest, "Invalid limit %q, must be between 1 and %d", l, maxJobsLimit)
+			return
+		}
+		limit = n
+	}
+	infos, err := a.listJobs(filter, limit)
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "Failed to list jobs: %s", err)
+		return
+	}
+	a.respond(w, http.StatusOK, map[string][]jobs.Info{"jobs": infos})
+}
+
+// GetPullRoute is the GET /api/v1/pulls/{repo}/{num} route.
+func (a *APIController) GetPullRoute(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pullNum, err := strconv.Atoi(vars["num"])
+	if err != nil {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "Invalid pull request number %q", vars["num"])
+		return
+	}
+	a.GetPull(w, r, vars["repo"], pullNum)
+}
+
+// GetPull responds with the locks, jobs and plans of pull request pullNum in
+// repoFullName. They can be filtered by the workspace query param.
+// GetPullRoute should be called first. This method is split out to make this
+// route testable.
+func (a *APIController) GetPull(w http.ResponseWriter, r *http.Request, repoFullName string, pullNum int) {
+	filter := apiFilter{
+		RepoFullName: repoFullName,
+		Workspace:    r.URL.Query().Get("workspace"),
+		PullNum:      pullNum,
+	}
+	locks, err := a.listLocks(filter)
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "Failed to list locks: %s", err)
+		return
+	}
+	infos, err := a.listJobs(filter, defaultJobsLimit)
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "Failed to list jobs: %s", err)
+		return
+	}
+	workspacePlans, err := a.Workspace.ListPlans(models.Repo{FullName: repoFullName}, models.PullRequest{Num: pullNum})
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "Failed to list plans: %s", err)
+		return
+	}
+	plans := make([]APIPlan, 0, len(workspacePlans))
+	for _, p := range workspacePlans {
+		if filter.matches(repoFullName, p.Workspace, pullNum) {
+			plans = append(plans, APIPlan{
+				RepoFullName: p.Project.RepoFullName,
+				Path:         p.Project.Path,
+				Workspace:    p.Workspace,
+				Commit:       p.Metadata.Commit,
+				Time:         p.Time,
+			})
+		}
+	}
+	a.respond(w, http.StatusOK, APIPull{
+		RepoFullName: repoFullName,
+		PullNum:      pullNum,
+		Locks:        locks,
+		Jobs:         infos,
+		Plans:        plans,
+	})
+}
+
+// listLocks returns the locks that match filter ordered by their ID.
+func (a *APIController) listLocks(filter apiFilter) ([]APILock, error) {
+	locks, err := a.Locker.List()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]APILock, 0, len(locks))
+	for id, l := range locks {
+		if filter.matches(l.Project.RepoFullName, l.Workspace, l.Pull.Num) {
+			results = append(results, toAPILock(id, l))
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+	return results, nil
+}
+
+// listJobs returns up to limit jobs that match filter, most recently started
+// first.
+func (a *APIController) listJobs(filter apiFilter, limit int) ([]jobs.Info, error) {
+	infos, err := a.Jobs.List(func(info jobs.Info) bool {
+		return filter.matches(info.RepoFullName, info.Workspace, info.PullNum)
+	}, limit)
+	if err != nil {
+		return nil, err
+	}
+	// Respond with an empty list rather than null.
+	if infos == nil {
+		infos = []jobs.Info{}
+	}
+	return infos, nil
+}
+
+// parseFilter returns the filter in r's query params. If they're invalid, it
+// responds with an error and returns false.
+func (a *APIController) parseFilter(w http.ResponseWriter, r *http.Request) (apiFilter, bool) {
+	q := r.URL.Query()
+	filter := apiFilter{
+		RepoFullName: q.Get("repo"),
+		Workspace:    q.Get("workspace"),
+	}
+	if pull := q.Get("pull"); pull != "" {
+		num, err := strconv.Atoi(pull)
+		if err != nil || num <= 0 {
+			a.respondErr(w, logging.Warn, http.StatusBadRequest, "Invalid pull request number %q", pull)
+			return filter, false
+		}
+		filter.PullNum = num
+	}
+	return filter, true
+}
+
+// matches returns true if the lock, job or plan of pull request pullNum in
+// repoFullName and workspace passes the filter.
+func (f apiFilter) matches(repoFullName string, workspace string, pullNum int) bool {
+	return (f.RepoFullName == "" || f.RepoFullName == repoFullName) &&
+		(f.Workspace == "" || f.Workspace == workspace) &&
+		(f.PullNum == 0 || f.PullNum == pullNum)
+}
+
+func toAPILock(id string, l models.ProjectLock) APILock {
+	return APILock{
+		ID:           id,
+		RepoFullName: l.Project.RepoFullName,
+		Path:         l.Project.Path,
+		Workspace:    l.Workspace,
+		PullNum:      l.Pull.Num,
+		PullURL:      l.Pull.URL,
+		User:         l.User.Username,
+		Time:         l.Time,
+	}
+}
+
+// respond writes v as the JSON response with code.
+func (a *APIController) respond(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v) // nolint: errcheck
+}
+
+// respondErr logs the error at lvl and responds with it as
+// {"error": "..."} and code.
+func (a *APIController) respondErr(w http.ResponseWriter, lvl logging.LogLevel, code int, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	a.Logger.Log(lvl, "%s", msg)
+	a.respond(w, code, map[string]string{"error": msg})
+}
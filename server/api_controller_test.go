@@ -0,0 +1,308 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hootsuite/atlantis/server"
+	"github.com/hootsuite/atlantis/server/events"
+	"github.com/hootsuite/atlantis/server/events/jobs"
+	"github.com/hootsuite/atlantis/server/events/locking/mocks"
+	emocks "github.com/hootsuite/atlantis/server/events/mocks"
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/logging"
+	. "github.com/hootsuite/atlantis/testing"
+	. "github.com/petergtz/pegomock"
+)
+
+var apiLockTime = time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+var apiLocks = map[string]models.ProjectLock{
+	"owner/repo/./default": {
+		Project:   models.Project{RepoFullName: "owner/repo", Path: "."},
+		Pull:      models.PullRequest{Num: 1, URL: "url1"},
+		User:      models.User{Username: "lkysow"},
+		Workspace: "default",
+		Time:      apiLockTime,
+	},
+	"owner/repo/vpc/staging": {
+		Project:   models.Project{RepoFullName: "owner/repo", Path: "vpc"},
+		Pull:      models.PullRequest{Num: 2, URL: "url2"},
+		User:      models.User{Username: "lkysow"},
+		Workspace: "staging",
+		Time:      apiLockTime,
+	},
+	"owner/other/./default": {
+		Project:   models.Project{RepoFullName: "owner/other", Path: "."},
+		Pull:      models.PullRequest{Num: 1, URL: "url3"},
+		User:      models.User{Username: "lkysow"},
+		Workspace: "default",
+		Time:      apiLockTime,
+	},
+}
+
+func TestAuthenticate(t *testing.T) {
+	a, _, _, cleanup := setupAPIController(t)
+	defer cleanup()
+	called := false
+	h := a.Authenticate(func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	t.Log("requests without a valid token should be rejected")
+	for _, header := range []string{"", "Bearer", "Bearer wrong", "token1"} {
+		req, _ := http.NewRequest("GET", "/api/v1/locks", bytes.NewBuffer(nil))
+		req.Header.Set("Authorization", header)
+		w := httptest.NewRecorder()
+		h(w, req)
+		responseContains(t, w, http.StatusUnauthorized, `{"error":"Missing or invalid API token"}`)
+		Equals(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+	}
+	Equals(t, false, called)
+
+	t.Log("requests with any of the tokens should be let through")
+	for _, token := range []string{"token1", "token2"} {
+		called = false
+		req, _ := http.NewRequest("GET", "/api/v1/locks", bytes.NewBuffer(nil))
+		req.Header.Set("Authorization", "Bearer "+token)
+		h(httptest.NewRecorder(), req)
+		Equals(t, true, called)
+	}
+
+	t.Log("if there are no tokens the API should be disabled")
+	a.APITokens = nil
+	called = false
+	req, _ := http.NewRequest("GET", "/api/v1/locks", bytes.NewBuffer(nil))
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	h(w, req)
+	responseContains(t, w, http.StatusForbidden, "The API is disabled since no API tokens are configured")
+	Equals(t, false, called)
+}
+
+func TestListLocks(t *testing.T) {
+	a, l, _, cleanup := setupAPIController(t)
+	defer cleanup()
+	When(l.List()).ThenReturn(apiLocks, nil)
+
+	cases := []struct {
+		query string
+		ids   []string
+	}{
+		{"", []string{"owner/other/./default", "owner/repo/./default", "owner/repo/vpc/staging"}},
+		{"?repo=owner/repo", []string{"owner/repo/./default", "owner/repo/vpc/staging"}},
+		{"?workspace=default", []string{"owner/other/./default", "owner/repo/./default"}},
+		{"?pull=1&repo=owner/repo", []string{"owner/repo/./default"}},
+		{"?repo=owner/none", nil},
+	}
+	for _, c := range cases {
+		t.Logf("listing locks with query %q", c.query)
+		req, _ := http.NewRequest("GET", "/api/v1/locks"+c.query, bytes.NewBuffer(nil))
+		w := httptest.NewRecorder()
+		a.ListLocks(w, req)
+		Equals(t, http.StatusOK, w.Code)
+		Equals(t, "application/json", w.Header().Get("Content-Type"))
+		var res map[string][]server.APILock
+		Ok(t, json.Unmarshal(w.Body.Bytes(), &res))
+		var ids []string
+		for _, lock := range res["locks"] {
+			ids = append(ids, lock.ID)
+		}
+		Equals(t, c.ids, ids)
+	}
+}
+
+func TestListLocks_InvalidPull(t *testing.T) {
+	a, _, _, cleanup := setupAPIController(t)
+	defer cleanup()
+	req, _ := http.NewRequest("GET", "/api/v1/locks?pull=abc", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	a.ListLocks(w, req)
+	responseContains(t, w, http.StatusBadRequest, `{"error":"Invalid pull request number \"abc\""}`)
+}
+
+func TestListLocks_LockerErr(t *testing.T) {
+	a, l, _, cleanup := setupAPIController(t)
+	defer cleanup()
+	When(l.List()).ThenReturn(nil, errors.New("err"))
+	req, _ := http.NewRequest("GET", "/api/v1/locks", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	a.ListLocks(w, req)
+	responseContains(t, w, http.StatusInternalServerError, `{"error":"Failed to list locks: err"}`)
+}
+
+func TestAPIGetLock(t *testing.T) {
+	a, l, _, cleanup := setupAPIController(t)
+	defer cleanup()
+	lock := apiLocks["owner/repo/vpc/staging"]
+	When(l.GetLock("owner/repo/vpc/staging")).ThenReturn(&lock, nil)
+
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	a.GetLock(w, req, "owner/repo/vpc/staging")
+	Equals(t, http.StatusOK, w.Code)
+	var res server.APILock
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &res))
+	Equals(t, server.APILock{
+		ID:           "owner/repo/vpc/staging",
+		RepoFullName: "owner/repo",
+		Path:         "vpc",
+		Workspace:    "staging",
+		PullNum:      2,
+		PullURL:      "url2",
+		User:         "lkysow",
+		Time:         apiLockTime,
+	}, res)
+
+	t.Log("if there's no lock with that id we should get a 404")
+	w = httptest.NewRecorder()
+	a.GetLock(w, req, "owner/repo/none/default")
+	responseContains(t, w, http.StatusNotFound, `{"error":"No lock found with id owner/repo/none/default"}`)
+}
+
+func TestAPIDeleteLock(t *testing.T) {
+	a, l, _, cleanup := setupAPIController(t)
+	defer cleanup()
+	lock := apiLocks["owner/repo/./default"]
+	When(l.Unlock("owner/repo/./default")).ThenReturn(&lock, nil)
+
+	req, _ := http.NewRequest("DELETE", "", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	a.DeleteLock(w, req, "owner/repo/./default")
+	responseContains(t, w, http.StatusOK, `"id":"owner/repo/./default"`)
+	l.VerifyWasCalledOnce().Unlock("owner/repo/./default")
+
+	t.Log("if there's no lock with that id we should get a 404")
+	w = httptest.NewRecorder()
+	a.DeleteLock(w, req, "owner/repo/none/default")
+	responseContains(t, w, http.StatusNotFound, `{"error":"No lock found with id owner/repo/none/default"}`)
+}
+
+func TestListJobs(t *testing.T) {
+	a, _, _, cleanup := setupAPIController(t)
+	defer cleanup()
+	staging, err := a.Jobs.Start(jobs.Info{RepoFullName: "owner/repo", PullNum: 1, Command: "plan", Workspace: "staging"})
+	Ok(t, err)
+	Ok(t, staging.Finish("success"))
+	other, err := a.Jobs.Start(jobs.Info{RepoFullName: "owner/other", PullNum: 1, Command: "plan", Workspace: "default"})
+	Ok(t, err)
+
+	cases := []struct {
+		query string
+		ids   []string
+	}{
+		{"", []string{other.Info().ID, staging.Info().ID}},
+		{"?repo=owner/repo", []string{staging.Info().ID}},
+		{"?workspace=default&pull=1", []string{other.Info().ID}},
+		{"?pull=2", nil},
+		{"?limit=1", []string{other.Info().ID}},
+		{"?repo=owner/repo&limit=1", []string{staging.Info().ID}},
+	}
+	for _, c := range cases {
+		t.Logf("listing jobs with query %q", c.query)
+		req, _ := http.NewRequest("GET", "/api/v1/jobs"+c.query, bytes.NewBuffer(nil))
+		w := httptest.NewRecorder()
+		a.ListJobs(w, req)
+		Equals(t, http.StatusOK, w.Code)
+		var res map[string][]jobs.Info
+		Ok(t, json.Unmarshal(w.Body.Bytes(), &res))
+		var ids []string
+		for _, info := range res["jobs"] {
+			ids = append(ids, info.ID)
+		}
+		Equals(t, c.ids, ids)
+	}
+}
+
+func TestListJobs_InvalidLimit(t *testing.T) {
+	a, _, _, cleanup := setupAPIController(t)
+	defer cleanup()
+	for _, limit := range []string{"a", "0", "1001"} {
+		req, _ := http.NewRequest("GET", "/api/v1/jobs?limit="+limit, bytes.NewBuffer(nil))
+		w := httptest.NewRecorder()
+		a.ListJobs(w, req)
+		responseContains(t, w, http.StatusBadRequest, "Invalid limit")
+	}
+}
+
+func TestGetPull(t *testing.T) {
+	t.Log("should respond with the pull request's locks, jobs and plans")
+	a, l, workspace, cleanup := setupAPIController(t)
+	defer cleanup()
+	When(l.List()).ThenReturn(apiLocks, nil)
+	job, err := a.Jobs.Start(jobs.Info{RepoFullName: "owner/repo", PullNum: 2, Command: "plan", Workspace: "staging"})
+	Ok(t, err)
+	_, err = a.Jobs.Start(jobs.Info{RepoFullName: "owner/repo", PullNum: 1, Command: "plan", Workspace: "default"})
+	Ok(t, err)
+	repo := models.Repo{FullName: "owner/repo"}
+	pull := models.PullRequest{Num: 2}
+	When(workspace.ListPlans(repo, pull)).ThenReturn([]events.WorkspacePlan{
+		{
+			Plan:      models.Plan{Project: models.NewProject("owner/repo", "vpc"), LocalPath: "/data/vpc/staging.tfplan"},
+			Workspace: "staging",
+			Metadata:  events.PlanMetadata{Commit: "abc123"},
+			Time:      apiLockTime,
+		},
+		{
+			Plan:      models.Plan{Project: models.NewProject("owner/repo", "."), LocalPath: "/data/default.tfplan"},
+			Workspace: "default",
+			Time:      apiLockTime,
+		},
+	}, nil)
+
+	req, _ := http.NewRequest("GET", "/api/v1/pulls/owner/repo/2?workspace=staging", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	a.GetPull(w, req, "owner/repo", 2)
+	Equals(t, http.StatusOK, w.Code)
+	var res server.APIPull
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &res))
+	Equals(t, "owner/repo", res.RepoFullName)
+	Equals(t, 2, res.PullNum)
+	Equals(t, 1, len(res.Locks))
+	Equals(t, "owner/repo/vpc/staging", res.Locks[0].ID)
+	Equals(t, 1, len(res.Jobs))
+	Equals(t, job.Info().ID, res.Jobs[0].ID)
+	t.Log("plans in other workspaces should be filtered out")
+	Equals(t, []server.APIPlan{{
+		RepoFullName: "owner/repo",
+		Path:         "vpc",
+		Workspace:    "staging",
+		Commit:       "abc123",
+		Time:         apiLockTime,
+	}}, res.Plans)
+}
+
+func TestGetPull_Empty(t *testing.T) {
+	t.Log("pull requests without anything should have empty lists rather than nulls")
+	a, l, workspace, cleanup := setupAPIController(t)
+	defer cleanup()
+	When(l.List()).ThenReturn(map[string]models.ProjectLock{}, nil)
+	When(workspace.ListPlans(models.Repo{FullName: "owner/repo"}, models.PullRequest{Num: 3})).ThenReturn(nil, nil)
+
+	req, _ := http.NewRequest("GET", "/api/v1/pulls/owner/repo/3", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	a.GetPull(w, req, "owner/repo", 3)
+	responseContains(t, w, http.StatusOK, `{"repo_full_name":"owner/repo","pull_num":3,"locks":[],"jobs":[],"plans":[]}`)
+}
+
+func setupAPIController(t *testing.T) (*server.APIController, *mocks.MockLocker, *emocks.MockAtlantisWorkspace, func()) {
+	RegisterMockTestingT(t)
+	dir, err := ioutil.TempDir("", "atlantis-jobs")
+	Ok(t, err)
+	m, err := jobs.NewManager(dir, 0)
+	Ok(t, err)
+	l := mocks.NewMockLocker()
+	workspace := emocks.NewMockAtlantisWorkspace()
+	a := &server.APIController{
+		Locker:    l,
+		Jobs:      m,
+		Workspace: workspace,
+		Logger:    logging.NewNoopLogger(),
+		APITokens: []string{"token1", "token2"},
+	}
+	return a, l, workspace, func() { os.RemoveAll(dir) } // nolint: errcheck
+}
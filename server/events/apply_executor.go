@@ -21,7 +21,14 @@ type ApplyExecutor struct {
 	Run               *run.Run
 	AtlantisWorkspace AtlantisWorkspace
 	ProjectPreExecute *DefaultProjectPreExecutor
+	RepoConfigReader  RepoConfigReader
 	Webhooks          webhooks.Sender
+	// ParallelPoolSize is the max number of projects applied at once unless
+	// ServerRepoConfig sets it for the repo.
+	ParallelPoolSize int
+	ServerRepoConfig *ServerRepoConfig
+	// StepTimeouts are the timeouts of steps that don't set their own.
+	StepTimeouts StepTimeouts
 }
 
 // Execute executes apply for the ctx.
@@ -43,6 +50,20 @@ func (a *ApplyExecutor) Execute(ctx *CommandContext) CommandResponse {
 	}
 	ctx.Log.Info("found workspace in %q", repoDir)
 
+	// If a project was specified by name, we apply the plan in its directory.
+	targetDir := ctx.Command.Dir
+	if ctx.Command.ProjectName != "" {
+		config, err := a.RepoConfigReader.Read(repoDir)
+		if err != nil {
+			return CommandResponse{Error: err}
+		}
+		project, failure := findNamedProject(ctx, config)
+		if failure != "" {
+			return CommandResponse{Failure: failure}
+		}
+		targetDir = project.Dir
+	}
+
 	// Plans are stored at project roots by their workspace names. We just
 	// need to find them.
 	var plans []models.Plan
@@ -53,6 +74,11 @@ func (a *ApplyExecutor) Execute(ctx *CommandContext) CommandResponse {
 		// Check if the plan is for the right workspace,
 		if !info.IsDir() && info.Name() == ctx.Command.Workspace+".tfplan" {
 			rel, _ := filepath.Rel(repoDir, filepath.Dir(path))
+			// and if a directory or project was specified, that it's for
+			// that directory.
+			if targetDir != "" && rel != targetDir {
+				return nil
+			}
 			plans = append(plans, models.Plan{
 				Project:   models.NewProject(ctx.BaseRepo.FullName, rel),
 				LocalPath: path,
@@ -64,6 +90,12 @@ func (a *ApplyExecutor) Execute(ctx *CommandContext) CommandResponse {
 		return CommandResponse{Error: errors.Wrap(err, "finding plans")}
 	}
 	if len(plans) == 0 {
+		if ctx.Command.ProjectName != "" {
+			return CommandResponse{Failure: fmt.Sprintf("No plan found for project %q in that workspace.", ctx.Command.ProjectName)}
+		}
+		if ctx.Command.Dir != "" {
+			return CommandResponse{Failure: fmt.Sprintf("No plan found for directory %q in that workspace.", ctx.Command.Dir)}
+		}
 		return CommandResponse{Failure: "No plans found for that workspace."}
 	}
 	var paths []string
@@ -72,13 +104,17 @@ func (a *ApplyExecutor) Execute(ctx *CommandContext) CommandResponse {
 	}
 	ctx.Log.Info("found %d plan(s) in our workspace: %v", len(plans), paths)
 
-	results := []ProjectResult{}
-	for _, plan := range plans {
-		ctx.Log.Info("running apply for project at path %q", plan.Project.Path)
-		result := a.apply(ctx, repoDir, plan)
-		result.Path = plan.LocalPath
-		results = append(results, result)
+	var projectPaths []string
+	for _, p := range plans {
+		projectPaths = append(projectPaths, p.Project.Path)
 	}
+	poolSize := parallelPoolSize(ctx, a.ParallelPoolSize, a.ServerRepoConfig)
+	results := runProjects(ctx, poolSize, projectPaths, func(projectCtx *CommandContext, i int) ProjectResult {
+		projectCtx.Log.Info("running apply for project at path %q", plans[i].Project.Path)
+		result := a.apply(projectCtx, repoDir, plans[i])
+		result.Path = plans[i].Project.Path
+		return result
+	})
 	return CommandResponse{ProjectResults: results}
 }
 
@@ -87,17 +123,16 @@ func (a *ApplyExecutor) apply(ctx *CommandContext, repoDir string, plan models.P
 	if preExecute.ProjectResult != (ProjectResult{}) {
 		return preExecute.ProjectResult
 	}
-	config := preExecute.ProjectConfig
-	terraformVersion := preExecute.TerraformVersion
+	checker := ApplyRequirementsChecker{VCSClient: a.VCSClient}
+	if failure, err := checker.Check(ctx, plan.LocalPath, preExecute.ApplyRequirements); err != nil || failure != "" {
+		return ProjectResult{Failure: failure, Error: err}
+	}
 
-	applyExtraArgs := config.GetExtraArguments(ctx.Command.Name.String())
-	absolutePath := filepath.Join(repoDir, plan.Project.Path)
-	workspace := ctx.Command.Workspace
-	tfApplyCmd := append(append(append([]string{"apply", "-no-color"}, applyExtraArgs...), ctx.Command.Flags...), plan.LocalPath)
-	output, err := a.Terraform.RunCommandWithVersion(ctx.Log, absolutePath, tfApplyCmd, terraformVersion, workspace)
+	runner := stageRunner{Terraform: a.Terraform, Run: a.Run, Timeouts: a.StepTimeouts}
+	output, err := runner.RunStage(ctx, preExecute.Workflow.Apply, repoDir, plan.Project, preExecute.TerraformVersion)
 
 	a.Webhooks.Send(ctx.Log, webhooks.ApplyResult{ // nolint: errcheck
-		Workspace: workspace,
+		Workspace: ctx.Command.Workspace,
 		User:      ctx.User,
 		Repo:      ctx.BaseRepo,
 		Pull:      ctx.Pull,
@@ -105,16 +140,7 @@ func (a *ApplyExecutor) apply(ctx *CommandContext, repoDir string, plan models.P
 	})
 
 	if err != nil {
-		return ProjectResult{Error: fmt.Errorf("%s\n%s", err.Error(), output)}
+		return stageFailedResult(err)
 	}
-	ctx.Log.Info("apply succeeded")
-
-	if len(config.PostApply) > 0 {
-		_, err := a.Run.Execute(ctx.Log, config.PostApply, absolutePath, workspace, terraformVersion, "post_apply")
-		if err != nil {
-			return ProjectResult{Error: errors.Wrap(err, "running post apply commands")}
-		}
-	}
-
 	return ProjectResult{ApplySuccess: output}
 }
@@ -0,0 +1,73 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events"
+	"github.com/hootsuite/atlantis/server/events/locking"
+	lmocks "github.com/hootsuite/atlantis/server/events/locking/mocks"
+	lmatchers "github.com/hootsuite/atlantis/server/events/locking/mocks/matchers"
+	"github.com/hootsuite/atlantis/server/events/mocks"
+	ematchers "github.com/hootsuite/atlantis/server/events/mocks/matchers"
+	"github.com/hootsuite/atlantis/server/logging"
+	. "github.com/hootsuite/atlantis/testing"
+	. "github.com/petergtz/pegomock"
+)
+
+func TestApplyExecute_ProjectPaths(t *testing.T) {
+	t.Log("results should have the repo-relative dirs of their projects, not the paths of their plans")
+	a, repoDir := setupApplyExecutorTest(t, ".", "infra/vpc")
+	defer os.RemoveAll(repoDir) // nolint: errcheck
+
+	r := a.Execute(applyCtx(events.Command{Name: events.Apply, Workspace: "default"}))
+	Equals(t, 2, len(r.ProjectResults))
+	Equals(t, ".", r.ProjectResults[0].Path)
+	Equals(t, "infra/vpc", r.ProjectResults[1].Path)
+	Equals(t, "acquiring lock: locked", r.ProjectResults[1].Error.Error())
+}
+
+func TestApplyExecute_Dir(t *testing.T) {
+	t.Log("only the plan in the dir that's specified should be applied")
+	a, repoDir := setupApplyExecutorTest(t, ".", "infra/vpc")
+	defer os.RemoveAll(repoDir) // nolint: errcheck
+
+	r := a.Execute(applyCtx(events.Command{Name: events.Apply, Workspace: "default", Dir: "infra/vpc"}))
+	Equals(t, 1, len(r.ProjectResults))
+	Equals(t, "infra/vpc", r.ProjectResults[0].Path)
+}
+
+func applyCtx(cmd events.Command) *events.CommandContext {
+	return &events.CommandContext{
+		Command: &cmd,
+		Log:     logging.NewNoopLogger(),
+		Context: context.Background(),
+	}
+}
+
+// setupApplyExecutorTest returns an executor whose workspace has a default
+// workspace plan in each of dirs. Taking the projects' locks fails so that
+// nothing is applied.
+func setupApplyExecutorTest(t *testing.T, dirs ...string) (*events.ApplyExecutor, string) {
+	RegisterMockTestingT(t)
+	repoDir, err := ioutil.TempDir("", "")
+	Ok(t, err)
+	for _, dir := range dirs {
+		Ok(t, os.MkdirAll(filepath.Join(repoDir, dir), 0700))
+		Ok(t, ioutil.WriteFile(filepath.Join(repoDir, dir, "default.tfplan"), nil, 0600))
+	}
+	workspace := mocks.NewMockAtlantisWorkspace()
+	When(workspace.GetWorkspace(ematchers.AnyModelsRepo(), ematchers.AnyModelsPullRequest(), AnyString())).ThenReturn(repoDir, nil)
+	locker := lmocks.NewMockLocker()
+	When(locker.TryLock(lmatchers.AnyModelsProject(), AnyString(), lmatchers.AnyModelsPullRequest(), lmatchers.AnyModelsUser())).
+		ThenReturn(locking.TryLockResponse{}, errors.New("locked"))
+	return &events.ApplyExecutor{
+		AtlantisWorkspace: workspace,
+		ProjectPreExecute: &events.DefaultProjectPreExecutor{Locker: locker},
+		ParallelPoolSize:  1,
+	}, repoDir
+}
@@ -0,0 +1,104 @@
+package events
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hootsuite/atlantis/server/events/vcs"
+	"github.com/pkg/errors"
+)
+
+// ApplyRequirementsChecker checks whether a pull request meets a project's
+// apply requirements.
+type ApplyRequirementsChecker struct {
+	VCSClient vcs.ClientProxy
+}
+
+// Check returns a failure message listing the requirements in reqs that the
+// pull request in ctx doesn't meet or an empty string if it meets them all.
+// planFile is the plan that's being applied. reqs must be valid.
+func (a *ApplyRequirementsChecker) Check(ctx *CommandContext, planFile string, reqs []string) (string, error) {
+	var unmet []string
+	// Approvers are fetched at most once since multiple requirements use
+	// them.
+	var approvers []string
+	var fetchedApprovers bool
+	getApprovers := func() ([]string, error) {
+		if fetchedApprovers {
+			return approvers, nil
+		}
+		var err error
+		approvers, err = a.VCSClient.GetApprovers(ctx.BaseRepo, ctx.Pull, ctx.VCSHost)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting approvers")
+		}
+		fetchedApprovers = true
+		return approvers, nil
+	}
+
+	for _, req := range reqs {
+		name, arg := splitApplyRequirement(req)
+		switch name {
+		case ApprovedApplyRequirement:
+			approved, err := a.VCSClient.PullIsApproved(ctx.BaseRepo, ctx.Pull, ctx.VCSHost)
+			if err != nil {
+				return "", errors.Wrap(err, "checking if pull request was approved")
+			}
+			if !approved {
+				unmet = append(unmet, "It must be approved.")
+			}
+		case ApprovalsApplyRequirement:
+			// The requirement was validated when the config was parsed.
+			min, _ := strconv.Atoi(arg)
+			approvers, err := getApprovers()
+			if err != nil {
+				return "", err
+			}
+			if len(approvers) < min {
+				unmet = append(unmet, fmt.Sprintf("It must have at least %d approvals but has %d.", min, len(approvers)))
+			}
+		case ApprovedByApplyRequirement:
+			approvers, err := getApprovers()
+			if err != nil {
+				return "", err
+			}
+			members, err := a.VCSClient.GetTeamMembers(ctx.BaseRepo, arg, ctx.VCSHost)
+			if err != nil {
+				return "", errors.Wrapf(err, "getting members of %s", arg)
+			}
+			if !containsAny(members, approvers) {
+				unmet = append(unmet, fmt.Sprintf("It must be approved by a member of %s.", arg))
+			}
+		case MergeableApplyRequirement:
+			mergeable, err := a.VCSClient.PullIsMergeable(ctx.BaseRepo, ctx.Pull, ctx.VCSHost)
+			if err != nil {
+				return "", errors.Wrap(err, "checking if pull request is mergeable")
+			}
+			if !mergeable {
+				unmet = append(unmet, "It must be mergeable and its required checks must pass.")
+			}
+		case NoNewCommitsApplyRequirement:
+			failure, err := checkPlanIsCurrent(ctx, planFile)
+			if err != nil {
+				return "", errors.Wrap(err, "checking if plan is current")
+			}
+			if failure != "" {
+				unmet = append(unmet, failure)
+			}
+		}
+	}
+	if len(unmet) == 0 {
+		return "", nil
+	}
+	return "Pull request doesn't meet the apply requirements:\n* " + strings.Join(unmet, "\n* "), nil
+}
+
+func containsAny(slice []string, elems []string) bool {
+	for _, e := range elems {
+		if containsStr(slice, e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package events_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events"
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs"
+	"github.com/hootsuite/atlantis/server/events/vcs/mocks"
+	"github.com/hootsuite/atlantis/server/logging"
+	. "github.com/hootsuite/atlantis/testing"
+	. "github.com/petergtz/pegomock"
+)
+
+var reqsRepo = models.Repo{FullName: "owner/repo"}
+var reqsPull = models.PullRequest{Num: 1, HeadCommit: "abc123"}
+
+func TestApplyRequirementsChecker_AllMet(t *testing.T) {
+	t.Log("should return no failure if every requirement is met")
+	checker, client := setupApplyRequirementsChecker(t)
+	When(client.PullIsApproved(reqsRepo, reqsPull, vcs.Github)).ThenReturn(true, nil)
+	When(client.GetApprovers(reqsRepo, reqsPull, vcs.Github)).ThenReturn([]string{"alice", "bob"}, nil)
+	When(client.GetTeamMembers(reqsRepo, "org/team", vcs.Github)).ThenReturn([]string{"bob", "carol"}, nil)
+	When(client.PullIsMergeable(reqsRepo, reqsPull, vcs.Github)).ThenReturn(true, nil)
+
+	failure, err := checker.Check(reqsCtx(), "", []string{"approved", "approvals:2", "approved_by:org/team", "mergeable"})
+	Ok(t, err)
+	Equals(t, "", failure)
+
+	t.Log("approvers should only be fetched once")
+	client.VerifyWasCalledOnce().GetApprovers(reqsRepo, reqsPull, vcs.Github)
+}
+
+func TestApplyRequirementsChecker_Unmet(t *testing.T) {
+	t.Log("should list every requirement that isn't met")
+	checker, client := setupApplyRequirementsChecker(t)
+	When(client.PullIsApproved(reqsRepo, reqsPull, vcs.Github)).ThenReturn(false, nil)
+	When(client.GetApprovers(reqsRepo, reqsPull, vcs.Github)).ThenReturn([]string{"alice"}, nil)
+	When(client.GetTeamMembers(reqsRepo, "org/team", vcs.Github)).ThenReturn([]string{"bob"}, nil)
+	When(client.PullIsMergeable(reqsRepo, reqsPull, vcs.Github)).ThenReturn(false, nil)
+
+	failure, err := checker.Check(reqsCtx(), "", []string{"approved", "approvals:2", "approved_by:org/team", "mergeable"})
+	Ok(t, err)
+	Equals(t, `Pull request doesn't meet the apply requirements:
+* It must be approved.
+* It must have at least 2 approvals but has 1.
+* It must be approved by a member of org/team.
+* It must be mergeable and its required checks must pass.`, failure)
+}
+
+func TestApplyRequirementsChecker_NoNewCommits(t *testing.T) {
+	dir, cleanup := tempProjectDir(t)
+	defer cleanup()
+	writeFile(t, filepath.Join(dir, "main.tf"), "resource")
+	planFile := filepath.Join(dir, "default.tfplan")
+	checker, _ := setupApplyRequirementsChecker(t)
+	reqs := []string{"no_new_commits"}
+
+	t.Log("should fail if we can't tell which commit was planned")
+	failure, err := checker.Check(reqsCtx(), planFile, reqs)
+	Ok(t, err)
+	Equals(t, "Pull request doesn't meet the apply requirements:\n* Can't tell which commit this plan was made from. Run plan again before applying.", failure)
+
+	t.Log("should pass if the plan was made from the head commit and the files haven't changed")
+	hash, err := events.HashPlanInputs(dir)
+	Ok(t, err)
+	Ok(t, events.WritePlanMetadata(planFile, events.PlanMetadata{Commit: "abc123", InputsHash: hash}))
+	failure, err = checker.Check(reqsCtx(), planFile, reqs)
+	Ok(t, err)
+	Equals(t, "", failure)
+
+	t.Log("should fail if there are new commits")
+	ctx := reqsCtx()
+	ctx.Pull.HeadCommit = "new"
+	failure, err = checker.Check(ctx, planFile, reqs)
+	Ok(t, err)
+	Equals(t, "Pull request doesn't meet the apply requirements:\n* This plan is stale: it was made from commit abc123 but the pull request's head is now new. Run plan again before applying.", failure)
+
+	t.Log("should fail if the project's files have changed")
+	writeFile(t, filepath.Join(dir, "main.tf"), "changed")
+	failure, err = checker.Check(reqsCtx(), planFile, reqs)
+	Ok(t, err)
+	Equals(t, "Pull request doesn't meet the apply requirements:\n* This plan is stale: the project's files have changed since it was made. Run plan again before applying.", failure)
+
+	t.Log("stale plans should be applied if the requirement isn't set")
+	failure, err = checker.Check(ctx, planFile, nil)
+	Ok(t, err)
+	Equals(t, "", failure)
+}
+
+func TestApplyRequirementsChecker_Err(t *testing.T) {
+	t.Log("should return an error if a requirement can't be checked")
+	checker, client := setupApplyRequirementsChecker(t)
+	When(client.PullIsMergeable(reqsRepo, reqsPull, vcs.Github)).ThenReturn(false, errors.New("err"))
+	_, err := checker.Check(reqsCtx(), "", []string{"mergeable"})
+	Assert(t, err != nil, "exp err")
+	Equals(t, "checking if pull request is mergeable: err", err.Error())
+}
+
+func setupApplyRequirementsChecker(t *testing.T) (*events.ApplyRequirementsChecker, *mocks.MockClientProxy) {
+	RegisterMockTestingT(t)
+	client := mocks.NewMockClientProxy()
+	return &events.ApplyRequirementsChecker{VCSClient: client}, client
+}
+
+func reqsCtx() *events.CommandContext {
+	return &events.CommandContext{
+		BaseRepo: reqsRepo,
+		Pull:     reqsPull,
+		VCSHost:  vcs.Github,
+		Log:      logging.NewNoopLogger(),
+	}
+}
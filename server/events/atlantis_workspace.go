@@ -1,10 +1,13 @@
 package events
 
 import (
+	"io/ioutil"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/hootsuite/atlantis/server/events/models"
 	"github.com/hootsuite/atlantis/server/logging"
@@ -24,11 +27,39 @@ type AtlantisWorkspace interface {
 	GetWorkspace(r models.Repo, p models.PullRequest, workspace string) (string, error)
 	// Delete deletes the workspace for this repo and pull.
 	Delete(r models.Repo, p models.PullRequest) error
+	// ListPlans returns the plans in each of the workspaces for this repo and
+	// pull.
+	ListPlans(r models.Repo, p models.PullRequest) ([]WorkspacePlan, error)
+}
+
+// WorkspacePlan is a plan in one of a pull request's workspaces.
+type WorkspacePlan struct {
+	models.Plan
+	Workspace string
+	// Metadata was recorded when the plan was made. It's empty for plans
+	// made before metadata was recorded.
+	Metadata PlanMetadata
+	// Time is when the plan was made.
+	Time time.Time
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_github_app_token_getter.go GithubAppTokenGetter
+
+// GithubAppTokenGetter mints tokens for the GitHub App's installation on a
+// repo.
+type GithubAppTokenGetter interface {
+	GetInstallationToken(repo models.Repo) (string, error)
 }
 
 // FileWorkspace implements AtlantisWorkspace with the file system.
 type FileWorkspace struct {
 	DataDir string
+	// GithubAppTokens is set if Atlantis authenticates to GitHub as a GitHub
+	// App. Since installation tokens expire, repos on GithubHostname are
+	// cloned with a token minted at clone time rather than with credentials
+	// in their clone URL.
+	GithubAppTokens GithubAppTokenGetter
+	GithubHostname  string
 }
 
 // Clone git clones headRepo, checks out the branch and then returns the absolute
@@ -54,8 +85,12 @@ func (w *FileWorkspace) Clone(
 		return "", errors.Wrap(err, "creating new workspace")
 	}
 
+	cloneURL, err := w.cloneURL(baseRepo, headRepo)
+	if err != nil {
+		return "", err
+	}
 	log.Info("git cloning %q into %q", headRepo.SanitizedCloneURL, cloneDir)
-	cloneCmd := exec.Command("git", "clone", headRepo.CloneURL, cloneDir) // #nosec
+	cloneCmd := exec.Command("git", "clone", cloneURL, cloneDir) // #nosec
 	if output, err := cloneCmd.CombinedOutput(); err != nil {
 		return "", errors.Wrapf(err, "cloning %s: %s", headRepo.SanitizedCloneURL, string(output))
 	}
@@ -84,6 +119,80 @@ func (w *FileWorkspace) Delete(r models.Repo, p models.PullRequest) error {
 	return os.RemoveAll(w.repoPullDir(r, p))
 }
 
+// ListPlans returns the plans in each of the workspaces for this repo and
+// pull. Like when applying, plans are found at their project's root by their
+// workspace's name.
+func (w *FileWorkspace) ListPlans(r models.Repo, p models.PullRequest) ([]WorkspacePlan, error) {
+	infos, err := ioutil.ReadDir(w.repoPullDir(r, p))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading pull request dir")
+	}
+	var plans []WorkspacePlan
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		workspace := info.Name()
+		repoDir := w.cloneDir(r, p, workspace)
+		err := filepath.Walk(repoDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() && info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if info.IsDir() || info.Name() != workspace+".tfplan" {
+				return nil
+			}
+			m, err := ReadPlanMetadata(path)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			rel, _ := filepath.Rel(repoDir, filepath.Dir(path))
+			plans = append(plans, WorkspacePlan{
+				Plan: models.Plan{
+					Project:   models.NewProject(r.FullName, rel),
+					LocalPath: path,
+				},
+				Workspace: workspace,
+				Metadata:  m,
+				Time:      info.ModTime(),
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "finding plans in workspace %s", workspace)
+		}
+	}
+	return plans, nil
+}
+
+// cloneURL returns the URL to clone headRepo with. If we're a GitHub App and
+// headRepo is on GitHub, it has a token for the app's installation on
+// baseRepo. We use baseRepo because the app might not be installed on a fork
+// but forks of repos it can read are readable with its token.
+func (w *FileWorkspace) cloneURL(baseRepo models.Repo, headRepo models.Repo) (string, error) {
+	if w.GithubAppTokens == nil {
+		return headRepo.CloneURL, nil
+	}
+	u, err := url.Parse(headRepo.SanitizedCloneURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing clone URL %s", headRepo.SanitizedCloneURL)
+	}
+	if u.Host != w.GithubHostname {
+		return headRepo.CloneURL, nil
+	}
+	token, err := w.GithubAppTokens.GetInstallationToken(baseRepo)
+	if err != nil {
+		return "", errors.Wrap(err, "getting GitHub App installation token")
+	}
+	u.User = url.UserPassword("x-access-token", token)
+	return u.String(), nil
+}
+
 func (w *FileWorkspace) repoPullDir(r models.Repo, p models.PullRequest) string {
 	return filepath.Join(w.DataDir, workspacePrefix, r.FullName, strconv.Itoa(p.Num))
 }
@@ -0,0 +1,82 @@
+package events_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events"
+	"github.com/hootsuite/atlantis/server/events/mocks"
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/logging"
+	. "github.com/hootsuite/atlantis/testing"
+	. "github.com/petergtz/pegomock"
+)
+
+func TestClone_GithubAppToken(t *testing.T) {
+	t.Log("when authenticating as a GitHub App, repos on GitHub should be cloned with a token for the base repo's installation")
+	RegisterMockTestingT(t)
+	tmp, cleanup := tempRepoDir(t)
+	defer cleanup()
+	tokens := mocks.NewMockGithubAppTokenGetter()
+	w := events.FileWorkspace{
+		DataDir:         tmp,
+		GithubAppTokens: tokens,
+		GithubHostname:  "github.com",
+	}
+	baseRepo := models.Repo{FullName: "owner/repo", SanitizedCloneURL: "https://github.com/owner/repo.git"}
+	headRepo := models.Repo{FullName: "forker/repo", SanitizedCloneURL: "https://github.com/forker/repo.git"}
+	When(tokens.GetInstallationToken(baseRepo)).ThenReturn("", errors.New("not installed"))
+
+	_, err := w.Clone(logging.NewNoopLogger(), baseRepo, headRepo, models.PullRequest{Num: 1}, "default")
+	Assert(t, err != nil, "exp err")
+	Equals(t, "getting GitHub App installation token: not installed", err.Error())
+	tokens.VerifyWasCalledOnce().GetInstallationToken(baseRepo)
+}
+
+func TestListPlans(t *testing.T) {
+	t.Log("should find the plans in each workspace at their project's root")
+	tmp, cleanup := tempRepoDir(t)
+	defer cleanup()
+	w := events.FileWorkspace{DataDir: tmp}
+	repo := models.Repo{FullName: "owner/repo"}
+	pull := models.PullRequest{Num: 2}
+
+	plans, err := w.ListPlans(repo, pull)
+	Ok(t, err)
+	Equals(t, 0, len(plans))
+
+	pullDir := filepath.Join(tmp, "repos", "owner", "repo", "2")
+	for _, file := range []string{
+		"default/default.tfplan",
+		"default/vpc/default.tfplan",
+		"default/vpc/staging.tfplan",
+		"default/.git/default.tfplan",
+		"staging/vpc/staging.tfplan",
+	} {
+		path := filepath.Join(pullDir, file)
+		Ok(t, os.MkdirAll(filepath.Dir(path), 0700))
+		Ok(t, ioutil.WriteFile(path, nil, 0600))
+	}
+	Ok(t, events.WritePlanMetadata(filepath.Join(pullDir, "staging/vpc/staging.tfplan"), events.PlanMetadata{Commit: "abc123"}))
+
+	plans, err = w.ListPlans(repo, pull)
+	Ok(t, err)
+	Equals(t, 3, len(plans))
+	for i, exp := range []struct {
+		path      string
+		workspace string
+		commit    string
+	}{
+		{".", "default", ""},
+		{"vpc", "default", ""},
+		{"vpc", "staging", "abc123"},
+	} {
+		Equals(t, models.NewProject("owner/repo", exp.path), plans[i].Project)
+		Equals(t, exp.workspace, plans[i].Workspace)
+		Equals(t, exp.commit, plans[i].Metadata.Commit)
+		Assert(t, !plans[i].Time.IsZero(), "exp plan time")
+	}
+}
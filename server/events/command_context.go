@@ -1,6 +1,9 @@
 package events
 
 import (
+	"context"
+
+	"github.com/hootsuite/atlantis/server/events/jobs"
 	"github.com/hootsuite/atlantis/server/events/models"
 	"github.com/hootsuite/atlantis/server/events/vcs"
 	"github.com/hootsuite/atlantis/server/logging"
@@ -23,4 +26,12 @@ type CommandContext struct {
 	Log     *logging.SimpleLogger
 	// VCSHost is the host that the command came from.
 	VCSHost vcs.Host
+	// Context is done once the command has been cancelled. Terraform and run
+	// steps are interrupted when it's done.
+	Context context.Context
+	// Job records the output of the command's Terraform and run steps so it
+	// can be followed at JobURL. It's nil for commands that don't run steps
+	// or if jobs aren't being recorded.
+	Job    *jobs.Job
+	JobURL string
 }
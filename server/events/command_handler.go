@@ -1,11 +1,18 @@
 package events
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/google/go-github/github"
+	"github.com/hootsuite/atlantis/server/events/jobs"
 	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/run"
 	"github.com/hootsuite/atlantis/server/events/vcs"
+	"github.com/hootsuite/atlantis/server/events/vcs/azuredevops"
+	"github.com/hootsuite/atlantis/server/events/vcs/bitbucketcloud"
+	"github.com/hootsuite/atlantis/server/events/vcs/bitbucketserver"
+	"github.com/hootsuite/atlantis/server/events/vcs/gitea"
 	"github.com/hootsuite/atlantis/server/logging"
 	"github.com/hootsuite/atlantis/server/recovery"
 	"github.com/lkysow/go-gitlab"
@@ -19,7 +26,9 @@ type CommandRunner interface {
 	// ExecuteCommand is the first step after a command request has been parsed.
 	// It handles gathering additional information needed to execute the command
 	// and then calling the appropriate services to finish executing the command.
-	ExecuteCommand(baseRepo models.Repo, headRepo models.Repo, user models.User, pullNum int, cmd *Command, vcsHost vcs.Host)
+	// It returns an error if the command couldn't be accepted, in which case
+	// it won't run.
+	ExecuteCommand(baseRepo models.Repo, headRepo models.Repo, user models.User, pullNum int, cmd *Command, vcsHost vcs.Host) error
 }
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_github_pull_getter.go GithubPullGetter
@@ -38,38 +47,122 @@ type GitlabMergeRequestGetter interface {
 	GetMergeRequest(repoFullName string, pullNum int) (*gitlab.MergeRequest, error)
 }
 
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_bitbucket_pull_getter.go BitbucketPullGetter
+
+// BitbucketPullGetter makes API calls to get Bitbucket pull requests.
+type BitbucketPullGetter interface {
+	// GetPullRequest gets the pull request with id pullNum for the repo.
+	GetPullRequest(repo models.Repo, pullNum int) (bitbucketcloud.PullRequest, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_bitbucket_server_pull_getter.go BitbucketServerPullGetter
+
+// BitbucketServerPullGetter makes API calls to get Bitbucket Server pull
+// requests.
+type BitbucketServerPullGetter interface {
+	// GetPullRequest gets the pull request with id pullNum for the repo.
+	GetPullRequest(repo models.Repo, pullNum int) (bitbucketserver.PullRequest, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_azuredevops_pull_getter.go AzureDevopsPullGetter
+
+// AzureDevopsPullGetter makes API calls to get Azure DevOps pull requests.
+type AzureDevopsPullGetter interface {
+	// GetPullRequest gets the pull request with id pullNum for the repo.
+	GetPullRequest(repo models.Repo, pullNum int) (azuredevops.PullRequest, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_gitea_pull_getter.go GiteaPullGetter
+
+// GiteaPullGetter makes API calls to get Gitea pull requests.
+type GiteaPullGetter interface {
+	// GetPullRequest gets the pull request with id pullNum for the repo.
+	GetPullRequest(repo models.Repo, pullNum int) (gitea.PullRequest, error)
+}
+
 // CommandHandler is the first step when processing a comment command.
 type CommandHandler struct {
 	PlanExecutor             Executor
 	ApplyExecutor            Executor
 	HelpExecutor             Executor
+	UnlockExecutor           Executor
 	LockURLGenerator         LockURLGenerator
 	VCSClient                vcs.ClientProxy
 	GithubPullGetter         GithubPullGetter
 	GitlabMergeRequestGetter GitlabMergeRequestGetter
-	CommitStatusUpdater      CommitStatusUpdater
-	EventParser              EventParsing
-	AtlantisWorkspaceLocker  AtlantisWorkspaceLocker
-	MarkdownRenderer         *MarkdownRenderer
-	Logger                   logging.SimpleLogging
+	BitbucketPullGetter      BitbucketPullGetter
+	// BitbucketServerPullGetter gets pull requests from Bitbucket Server.
+	BitbucketServerPullGetter BitbucketServerPullGetter
+	AzureDevopsPullGetter     AzureDevopsPullGetter
+	GiteaPullGetter           GiteaPullGetter
+	CommitStatusUpdater       CommitStatusUpdater
+	EventParser               EventParsing
+	AtlantisWorkspaceLocker   AtlantisWorkspaceLocker
+	MarkdownRenderer          *MarkdownRenderer
+	Logger                    logging.SimpleLogging
+	// CommentFlagPolicy restricts the Terraform flags that can be passed in
+	// comments.
+	CommentFlagPolicy *CommentFlagPolicy
+	// Jobs records the output of plans and applies. If nil, it isn't
+	// recorded.
+	Jobs   *jobs.Manager
+	jobURL func(info jobs.Info) string
+}
+
+// ExecuteCommand executes the command. Since the command has already run by
+// the time it returns, failures are reported on the pull request and the
+// returned error is always nil.
+func (c *CommandHandler) ExecuteCommand(baseRepo models.Repo, headRepo models.Repo, user models.User, pullNum int, cmd *Command, vcsHost vcs.Host) error {
+	c.ExecuteCommandContext(context.Background(), baseRepo, headRepo, user, pullNum, cmd, vcsHost)
+	return nil
 }
 
-// ExecuteCommand executes the command.
-func (c *CommandHandler) ExecuteCommand(baseRepo models.Repo, headRepo models.Repo, user models.User, pullNum int, cmd *Command, vcsHost vcs.Host) {
+// ExecuteCommandContext executes the command and cancels it once cancelCtx
+// is done.
+func (c *CommandHandler) ExecuteCommandContext(cancelCtx context.Context, baseRepo models.Repo, headRepo models.Repo, user models.User, pullNum int, cmd *Command, vcsHost vcs.Host) {
+	ctx, err := c.buildContext(cancelCtx, baseRepo, headRepo, user, pullNum, cmd, vcsHost)
+	if err != nil {
+		ctx.Log.Err("%s", err)
+		return
+	}
+	c.run(ctx)
+}
+
+// FailCommand reports that the command failed with failure on the pull
+// request without running it.
+func (c *CommandHandler) FailCommand(baseRepo models.Repo, headRepo models.Repo, user models.User, pullNum int, cmd *Command, vcsHost vcs.Host, failure string) {
+	ctx, err := c.buildContext(context.Background(), baseRepo, headRepo, user, pullNum, cmd, vcsHost)
+	if err != nil {
+		ctx.Log.Err("%s", err)
+		return
+	}
+	c.updatePull(ctx, CommandResponse{Failure: failure})
+}
+
+// buildContext gets the pull request's details from the VCS host. If that
+// fails, the returned context can only be used for logging.
+func (c *CommandHandler) buildContext(cancelCtx context.Context, baseRepo models.Repo, headRepo models.Repo, user models.User, pullNum int, cmd *Command, vcsHost vcs.Host) (*CommandContext, error) {
 	var err error
 	var pull models.PullRequest
 	if vcsHost == vcs.Github {
 		pull, headRepo, err = c.getGithubData(baseRepo, pullNum)
 	} else if vcsHost == vcs.Gitlab {
 		pull, err = c.getGitlabData(baseRepo.FullName, pullNum)
+	} else if vcsHost == vcs.Bitbucket {
+		pull, headRepo, err = c.getBitbucketData(baseRepo, pullNum)
+	} else if vcsHost == vcs.BitbucketServer {
+		pull, headRepo, err = c.getBitbucketServerData(baseRepo, pullNum)
+	} else if vcsHost == vcs.AzureDevops {
+		pull, headRepo, err = c.getAzureDevopsData(baseRepo, pullNum)
+	} else if vcsHost == vcs.Gitea {
+		pull, headRepo, err = c.getGiteaData(baseRepo, pullNum)
 	}
 
 	log := c.buildLogger(baseRepo.FullName, pullNum)
 	if err != nil {
-		log.Err(err.Error())
-		return
+		return &CommandContext{Log: log}, err
 	}
-	ctx := &CommandContext{
+	return &CommandContext{
 		User:     user,
 		Log:      log,
 		Pull:     pull,
@@ -77,8 +170,8 @@ func (c *CommandHandler) ExecuteCommand(baseRepo models.Repo, headRepo models.Re
 		Command:  cmd,
 		VCSHost:  vcsHost,
 		BaseRepo: baseRepo,
-	}
-	c.run(ctx)
+		Context:  cancelCtx,
+	}, nil
 }
 
 func (c *CommandHandler) getGithubData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
@@ -108,6 +201,66 @@ func (c *CommandHandler) getGitlabData(repoFullName string, pullNum int) (models
 	return pull, nil
 }
 
+func (c *CommandHandler) getBitbucketData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
+	if c.BitbucketPullGetter == nil {
+		return models.PullRequest{}, models.Repo{}, errors.New("Atlantis not configured to support Bitbucket")
+	}
+	bbPull, err := c.BitbucketPullGetter.GetPullRequest(baseRepo, pullNum)
+	if err != nil {
+		return models.PullRequest{}, models.Repo{}, errors.Wrap(err, "making pull request API call to Bitbucket")
+	}
+	pull, _, headRepo, err := c.EventParser.ParseBitbucketCloudPull(bbPull)
+	if err != nil {
+		return pull, headRepo, errors.Wrap(err, "extracting required fields from pull request data")
+	}
+	return pull, headRepo, nil
+}
+
+func (c *CommandHandler) getBitbucketServerData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
+	if c.BitbucketServerPullGetter == nil {
+		return models.PullRequest{}, models.Repo{}, errors.New("Atlantis not configured to support Bitbucket Server")
+	}
+	bbPull, err := c.BitbucketServerPullGetter.GetPullRequest(baseRepo, pullNum)
+	if err != nil {
+		return models.PullRequest{}, models.Repo{}, errors.Wrap(err, "making pull request API call to Bitbucket Server")
+	}
+	pull, _, headRepo, err := c.EventParser.ParseBitbucketServerPull(bbPull)
+	if err != nil {
+		return pull, headRepo, errors.Wrap(err, "extracting required fields from pull request data")
+	}
+	return pull, headRepo, nil
+}
+
+func (c *CommandHandler) getAzureDevopsData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
+	if c.AzureDevopsPullGetter == nil {
+		return models.PullRequest{}, models.Repo{}, errors.New("Atlantis not configured to support Azure DevOps")
+	}
+	adPull, err := c.AzureDevopsPullGetter.GetPullRequest(baseRepo, pullNum)
+	if err != nil {
+		return models.PullRequest{}, models.Repo{}, errors.Wrap(err, "making pull request API call to Azure DevOps")
+	}
+	pull, _, headRepo, err := c.EventParser.ParseAzureDevopsPull(adPull)
+	if err != nil {
+		return pull, headRepo, errors.Wrap(err, "extracting required fields from pull request data")
+	}
+	return pull, headRepo, nil
+}
+
+func (c *CommandHandler) getGiteaData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
+	if c.GiteaPullGetter == nil {
+		return models.PullRequest{}, models.Repo{}, errors.New("Atlantis not configured to support Gitea")
+	}
+	giteaPull, err := c.GiteaPullGetter.GetPullRequest(baseRepo, pullNum)
+	if err != nil {
+		return models.PullRequest{}, models.Repo{}, errors.Wrap(err, "making pull request API call to Gitea")
+	}
+	pull, _, headRepo, err := c.EventParser.ParseGiteaPull(giteaPull)
+	if err != nil {
+		return pull, headRepo, errors.Wrap(err, "extracting required fields from pull request data")
+	}
+	return pull, headRepo, nil
+}
+
 func (c *CommandHandler) buildLogger(repoFullName string, pullNum int) *logging.SimpleLogger {
 	src := fmt.Sprintf("%s#%d", repoFullName, pullNum)
 	return logging.NewSimpleLogger(src, c.Logger.Underlying(), true, c.Logger.GetLevel())
@@ -118,6 +271,45 @@ func (c *CommandHandler) SetLockURL(f func(id string) (url string)) {
 	c.LockURLGenerator.SetLockURL(f)
 }
 
+// SetJobURL sets a function that's used to return the URL for a job.
+func (c *CommandHandler) SetJobURL(f func(info jobs.Info) (url string)) {
+	c.jobURL = f
+}
+
+// startJob starts recording the output of ctx's command if it's a plan or
+// apply. The steps' output is streamed to the job through ctx.Context.
+func (c *CommandHandler) startJob(ctx *CommandContext) {
+	if c.Jobs == nil || (ctx.Command.Name != Plan && ctx.Command.Name != Apply) {
+		return
+	}
+	job, err := c.Jobs.Start(jobs.Info{
+		RepoFullName: ctx.BaseRepo.FullName,
+		PullNum:      ctx.Pull.Num,
+		Command:      ctx.Command.Name.String(),
+		Workspace:    ctx.Command.Workspace,
+	})
+	if err != nil {
+		// The command can still run, its output just can't be followed.
+		ctx.Log.Warn("failed to start job: %s", err)
+		return
+	}
+	ctx.Job = job
+	ctx.Context = run.WithOutput(ctx.Context, job)
+	if c.jobURL != nil {
+		ctx.JobURL = c.jobURL(job.Info())
+	}
+}
+
+// finishJob records that ctx's job finished with status.
+func (c *CommandHandler) finishJob(ctx *CommandContext, status string) {
+	if ctx.Job == nil {
+		return
+	}
+	if err := ctx.Job.Finish(status); err != nil {
+		ctx.Log.Warn("failed to finish job: %s", err)
+	}
+}
+
 func (c *CommandHandler) run(ctx *CommandContext) {
 	log := c.buildLogger(ctx.BaseRepo.FullName, ctx.Pull.Num)
 	ctx.Log = log
@@ -129,7 +321,18 @@ func (c *CommandHandler) run(ctx *CommandContext) {
 		return
 	}
 
-	c.CommitStatusUpdater.Update(ctx.BaseRepo, ctx.Pull, vcs.Pending, ctx.Command, ctx.VCSHost) // nolint: errcheck
+	c.startJob(ctx)
+	if c.updatesStatus(ctx.Command) {
+		c.CommitStatusUpdater.UpdateRunning(ctx) // nolint: errcheck
+	}
+	if ctx.Command.Failure != "" {
+		c.updatePull(ctx, CommandResponse{Failure: ctx.Command.Failure})
+		return
+	}
+	if failure := c.CommentFlagPolicy.Check(ctx.Command.Flags); failure != "" {
+		c.updatePull(ctx, CommandResponse{Failure: failure})
+		return
+	}
 	if !c.AtlantisWorkspaceLocker.TryLock(ctx.BaseRepo.FullName, ctx.Command.Workspace, ctx.Pull.Num) {
 		errMsg := fmt.Sprintf(
 			"The %s workspace is currently locked by another"+
@@ -150,8 +353,13 @@ func (c *CommandHandler) run(ctx *CommandContext) {
 		cr = c.ApplyExecutor.Execute(ctx)
 	case Help:
 		cr = c.HelpExecutor.Execute(ctx)
+	case Unlock:
+		cr = c.UnlockExecutor.Execute(ctx)
 	default:
-		ctx.Log.Err("failed to determine desired command, neither plan nor apply")
+		ctx.Log.Err("failed to determine desired command, neither plan, apply, unlock nor help")
+	}
+	if ctx.Context.Err() != nil {
+		cr = CommandResponse{Failure: c.cancelledFailure(ctx.Command)}
 	}
 	c.updatePull(ctx, cr)
 }
@@ -163,13 +371,37 @@ func (c *CommandHandler) updatePull(ctx *CommandContext, res CommandResponse) {
 	} else if res.Failure != "" {
 		ctx.Log.Warn(res.Failure)
 	}
+	c.finishJob(ctx, res.Status().String())
 
 	// Update the pull request's status icon and comment back.
-	c.CommitStatusUpdater.UpdateProjectResult(ctx, res) // nolint: errcheck
+	if c.updatesStatus(ctx.Command) {
+		c.CommitStatusUpdater.UpdateProjectResult(ctx, res) // nolint: errcheck
+	}
+	if ctx.Command.Autoplan && res.Error == nil && res.Failure == "" && len(res.ProjectResults) == 0 {
+		// Autoplan didn't find any projects to plan so there's nothing to
+		// comment about.
+		return
+	}
 	comment := c.MarkdownRenderer.Render(res, ctx.Command.Name, ctx.Log.History.String(), ctx.Command.Verbose)
 	c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull, comment, ctx.VCSHost) // nolint: errcheck
 }
 
+// cancelledFailure returns the failure reported for cmd when it's cancelled
+// while running.
+func (c *CommandHandler) cancelledFailure(cmd *Command) string {
+	if cmd.Name == Apply {
+		return "This apply was cancelled so it may not have finished. Run plan to see what's left to apply."
+	}
+	return fmt.Sprintf("This %s was cancelled.", cmd.Name.String())
+}
+
+// updatesStatus returns true if running cmd should update the commit status.
+// Unlock doesn't because it would overwrite the status of the last plan or
+// apply, possibly with a success even though nothing has been applied.
+func (c *CommandHandler) updatesStatus(cmd *Command) bool {
+	return cmd.Name != Unlock
+}
+
 // logPanics logs and creates a comment on the pull request for panics.
 func (c *CommandHandler) logPanics(ctx *CommandContext) {
 	if err := recover(); err != nil {
@@ -177,5 +409,6 @@ func (c *CommandHandler) logPanics(ctx *CommandContext) {
 		c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull, // nolint: errcheck
 			fmt.Sprintf("**Error: goroutine panic. This is a bug.**\n```\n%s\n%s```", err, stack), ctx.VCSHost)
 		ctx.Log.Err("PANIC: %s\n%s", err, stack)
+		c.finishJob(ctx, vcs.Failed.String())
 	}
 }
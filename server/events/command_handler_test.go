@@ -2,13 +2,17 @@ package events_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"io/ioutil"
 	"log"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/google/go-github/github"
 	"github.com/hootsuite/atlantis/server/events"
+	"github.com/hootsuite/atlantis/server/events/jobs"
 	"github.com/hootsuite/atlantis/server/events/mocks"
 	"github.com/hootsuite/atlantis/server/events/mocks/matchers"
 	"github.com/hootsuite/atlantis/server/events/models"
@@ -22,6 +26,7 @@ import (
 
 var applier *mocks.MockExecutor
 var helper *mocks.MockExecutor
+var unlocker *mocks.MockExecutor
 var planner *mocks.MockExecutor
 var eventParsing *mocks.MockEventParsing
 var vcsClient *vcsmocks.MockClientProxy
@@ -36,6 +41,7 @@ func setup(t *testing.T) {
 	RegisterMockTestingT(t)
 	applier = mocks.NewMockExecutor()
 	helper = mocks.NewMockExecutor()
+	unlocker = mocks.NewMockExecutor()
 	planner = mocks.NewMockExecutor()
 	eventParsing = mocks.NewMockEventParsing()
 	ghStatus = mocks.NewMockCommitStatusUpdater()
@@ -50,6 +56,7 @@ func setup(t *testing.T) {
 		PlanExecutor:             planner,
 		ApplyExecutor:            applier,
 		HelpExecutor:             helper,
+		UnlockExecutor:           unlocker,
 		VCSClient:                vcsClient,
 		CommitStatusUpdater:      ghStatus,
 		EventParser:              eventParsing,
@@ -64,7 +71,7 @@ func setup(t *testing.T) {
 func TestExecuteCommand_LogPanics(t *testing.T) {
 	t.Log("if there is a panic it is commented back on the pull request")
 	setup(t)
-	When(ghStatus.Update(fixtures.Repo, fixtures.Pull, vcs.Pending, nil, vcs.Github)).ThenPanic("panic")
+	When(ghStatus.UpdateRunning(matchers.AnyPtrToEventsCommandContext())).ThenPanic("panic")
 	ch.ExecuteCommand(fixtures.Repo, fixtures.Repo, fixtures.User, 1, nil, vcs.Github)
 	_, _, comment, _ := vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString(), matchers.AnyVcsHost()).GetCapturedArguments()
 	Assert(t, strings.Contains(comment, "Error: goroutine panic"), "comment should be about a goroutine panic")
@@ -147,13 +154,58 @@ func TestExecuteCommand_WorkspaceLocked(t *testing.T) {
 	msg := "The workspace workspace is currently locked by another" +
 		" command that is running for this pull request." +
 		" Wait until the previous command is complete and try again."
-	ghStatus.VerifyWasCalledOnce().Update(fixtures.Repo, fixtures.Pull, vcs.Pending, &cmd, vcs.Github)
+	ghStatus.VerifyWasCalledOnce().UpdateRunning(matchers.AnyPtrToEventsCommandContext())
 	_, response := ghStatus.VerifyWasCalledOnce().UpdateProjectResult(matchers.AnyPtrToEventsCommandContext(), matchers.AnyEventsCommandResponse()).GetCapturedArguments()
 	Equals(t, msg, response.Failure)
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.Repo, fixtures.Pull,
 		"**Plan Failed**: "+msg+"\n\n", vcs.Github)
 }
 
+func TestExecuteCommand_FlagDenied(t *testing.T) {
+	t.Log("if a flag in the comment isn't allowed, should comment back on the pull without running the command")
+	setup(t)
+	ch.CommentFlagPolicy = events.NewCommentFlagPolicy("", "-state")
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	cmd := events.Command{
+		Name:      events.Plan,
+		Workspace: "workspace",
+		Flags:     []string{"-state=/etc/passwd"},
+	}
+
+	When(githubGetter.GetPullRequest(fixtures.Repo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(fixtures.Pull, fixtures.Repo, nil)
+	ch.ExecuteCommand(fixtures.Repo, fixtures.Repo, fixtures.User, fixtures.Pull.Num, &cmd, vcs.Github)
+
+	msg := `Flag "-state=/etc/passwd" isn't allowed in comments by this Atlantis server. Denied flags: -state.`
+	planner.VerifyWasCalled(Never()).Execute(matchers.AnyPtrToEventsCommandContext())
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.Repo, fixtures.Pull,
+		"**Plan Failed**: "+msg+"\n\n", vcs.Github)
+}
+
+func TestExecuteCommand_Failure(t *testing.T) {
+	t.Log("if the comment's flags were invalid, should comment back on the pull without running the command")
+	setup(t)
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	msg := `invalid value "/abs/path" for -d flag: must be a relative path inside the repo`
+	cmd := events.Command{
+		Name:      events.Apply,
+		Workspace: "workspace",
+		Failure:   msg,
+	}
+
+	When(githubGetter.GetPullRequest(fixtures.Repo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(fixtures.Pull, fixtures.Repo, nil)
+	ch.ExecuteCommand(fixtures.Repo, fixtures.Repo, fixtures.User, fixtures.Pull.Num, &cmd, vcs.Github)
+
+	applier.VerifyWasCalled(Never()).Execute(matchers.AnyPtrToEventsCommandContext())
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.Repo, fixtures.Pull,
+		"**Apply Failed**: "+msg+"\n\n", vcs.Github)
+}
+
 func TestExecuteCommand_FullRun(t *testing.T) {
 	t.Log("when running a plan, apply or help should comment")
 	pull := &github.PullRequest{
@@ -180,10 +232,119 @@ func TestExecuteCommand_FullRun(t *testing.T) {
 
 		ch.ExecuteCommand(fixtures.Repo, fixtures.Repo, fixtures.User, fixtures.Pull.Num, &cmd, vcs.Github)
 
-		ghStatus.VerifyWasCalledOnce().Update(fixtures.Repo, fixtures.Pull, vcs.Pending, &cmd, vcs.Github)
+		ghStatus.VerifyWasCalledOnce().UpdateRunning(matchers.AnyPtrToEventsCommandContext())
 		_, response := ghStatus.VerifyWasCalledOnce().UpdateProjectResult(matchers.AnyPtrToEventsCommandContext(), matchers.AnyEventsCommandResponse()).GetCapturedArguments()
 		Equals(t, cmdResponse, response)
 		vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString(), matchers.AnyVcsHost())
 		workspaceLocker.VerifyWasCalledOnce().Unlock(fixtures.Repo.FullName, cmd.Workspace, fixtures.Pull.Num)
 	}
 }
+
+func TestExecuteCommand_AutoplanNoProjects(t *testing.T) {
+	t.Log("when autoplan doesn't find any projects to plan we shouldn't comment")
+	setup(t)
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	cmd := events.Command{
+		Name:      events.Plan,
+		Workspace: "default",
+		Autoplan:  true,
+	}
+	When(githubGetter.GetPullRequest(fixtures.Repo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(fixtures.Pull, fixtures.Repo, nil)
+	When(workspaceLocker.TryLock(fixtures.Repo.FullName, cmd.Workspace, fixtures.Pull.Num)).ThenReturn(true)
+	When(planner.Execute(matchers.AnyPtrToEventsCommandContext())).ThenReturn(events.CommandResponse{})
+
+	ch.ExecuteCommand(fixtures.Repo, fixtures.Repo, fixtures.User, fixtures.Pull.Num, &cmd, vcs.Github)
+
+	ghStatus.VerifyWasCalledOnce().UpdateProjectResult(matchers.AnyPtrToEventsCommandContext(), matchers.AnyEventsCommandResponse())
+	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString(), matchers.AnyVcsHost())
+}
+
+func TestExecuteCommand_UnlockDoesNotUpdateStatus(t *testing.T) {
+	t.Log("when running unlock we should comment but not update the commit status")
+	setup(t)
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	cmd := events.Command{
+		Name:      events.Unlock,
+		Workspace: "default",
+	}
+	When(githubGetter.GetPullRequest(fixtures.Repo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(fixtures.Pull, fixtures.Repo, nil)
+	When(workspaceLocker.TryLock(fixtures.Repo.FullName, cmd.Workspace, fixtures.Pull.Num)).ThenReturn(true)
+	When(unlocker.Execute(matchers.AnyPtrToEventsCommandContext())).ThenReturn(events.CommandResponse{})
+
+	ch.ExecuteCommand(fixtures.Repo, fixtures.Repo, fixtures.User, fixtures.Pull.Num, &cmd, vcs.Github)
+
+	unlocker.VerifyWasCalledOnce().Execute(matchers.AnyPtrToEventsCommandContext())
+	ghStatus.VerifyWasCalled(Never()).UpdateRunning(matchers.AnyPtrToEventsCommandContext())
+	ghStatus.VerifyWasCalled(Never()).UpdateProjectResult(matchers.AnyPtrToEventsCommandContext(), matchers.AnyEventsCommandResponse())
+	vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString(), matchers.AnyVcsHost())
+}
+
+func TestExecuteCommand_Job(t *testing.T) {
+	t.Log("plans should be recorded as a job that the pending status links to")
+	setup(t)
+	dir, err := ioutil.TempDir("", "atlantis-jobs")
+	Ok(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+	ch.Jobs, err = jobs.NewManager(dir, 0)
+	Ok(t, err)
+	ch.SetJobURL(func(info jobs.Info) string { return "https://atlantis/jobs/" + info.ID + "?token=" + info.Token })
+	pull := &github.PullRequest{}
+	cmd := events.Command{Name: events.Plan, Workspace: "default"}
+	When(githubGetter.GetPullRequest(fixtures.Repo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(fixtures.Pull, fixtures.Repo, nil)
+	When(workspaceLocker.TryLock(fixtures.Repo.FullName, cmd.Workspace, fixtures.Pull.Num)).ThenReturn(true)
+	When(planner.Execute(matchers.AnyPtrToEventsCommandContext())).ThenReturn(events.CommandResponse{Failure: "failure"})
+
+	ch.ExecuteCommand(fixtures.Repo, fixtures.Repo, fixtures.User, fixtures.Pull.Num, &cmd, vcs.Github)
+
+	ctx := ghStatus.VerifyWasCalledOnce().UpdateRunning(matchers.AnyPtrToEventsCommandContext()).GetCapturedArguments()
+	Assert(t, ctx.Job != nil, "exp a job")
+	info := ctx.Job.Info()
+	Equals(t, "https://atlantis/jobs/"+info.ID+"?token="+info.Token, ctx.JobURL)
+	Equals(t, fixtures.Repo.FullName, info.RepoFullName)
+	Equals(t, fixtures.Pull.Num, info.PullNum)
+	Equals(t, "plan", info.Command)
+	Equals(t, "default", info.Workspace)
+	t.Log("the job should finish with the command's status")
+	Equals(t, "failed", info.Status)
+}
+
+func TestExecuteCommandContext_Cancelled(t *testing.T) {
+	t.Log("when the command is cancelled while running its result should be replaced by a failure saying so")
+	setup(t)
+	pull := &github.PullRequest{}
+	cmd := events.Command{Name: events.Apply, Workspace: "default"}
+	When(githubGetter.GetPullRequest(fixtures.Repo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(fixtures.Pull, fixtures.Repo, nil)
+	When(workspaceLocker.TryLock(fixtures.Repo.FullName, cmd.Workspace, fixtures.Pull.Num)).ThenReturn(true)
+	When(applier.Execute(matchers.AnyPtrToEventsCommandContext())).ThenReturn(events.CommandResponse{Error: errors.New("signal: interrupt")})
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ch.ExecuteCommandContext(cancelCtx, fixtures.Repo, fixtures.Repo, fixtures.User, fixtures.Pull.Num, &cmd, vcs.Github)
+	_, res := ghStatus.VerifyWasCalledOnce().UpdateProjectResult(matchers.AnyPtrToEventsCommandContext(), matchers.AnyEventsCommandResponse()).GetCapturedArguments()
+	Equals(t, events.CommandResponse{Failure: "This apply was cancelled so it may not have finished. Run plan to see what's left to apply."}, res)
+	workspaceLocker.VerifyWasCalledOnce().Unlock(fixtures.Repo.FullName, cmd.Workspace, fixtures.Pull.Num)
+}
+
+func TestFailCommand(t *testing.T) {
+	t.Log("failing a command should update the status and comment without running it")
+	setup(t)
+	pull := &github.PullRequest{}
+	cmd := events.Command{Name: events.Apply, Workspace: "default"}
+	When(githubGetter.GetPullRequest(fixtures.Repo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(fixtures.Pull, fixtures.Repo, nil)
+
+	ch.FailCommand(fixtures.Repo, fixtures.Repo, fixtures.User, fixtures.Pull.Num, &cmd, vcs.Github, "failure")
+	applier.VerifyWasCalled(Never()).Execute(matchers.AnyPtrToEventsCommandContext())
+	_, res := ghStatus.VerifyWasCalledOnce().UpdateProjectResult(matchers.AnyPtrToEventsCommandContext(), matchers.AnyEventsCommandResponse()).GetCapturedArguments()
+	Equals(t, events.CommandResponse{Failure: "failure"}, res)
+	_, _, comment, _ := vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString(), matchers.AnyVcsHost()).GetCapturedArguments()
+	Assert(t, strings.Contains(comment, "failure"), "exp comment to contain the failure but was %q", comment)
+}
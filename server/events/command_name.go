@@ -7,6 +7,8 @@ const (
 	Apply CommandName = iota
 	Plan
 	Help
+	Unlock
+	Cancel
 	// Adding more? Don't forget to update String() below
 )
 
@@ -19,6 +21,10 @@ func (c CommandName) String() string {
 		return "plan"
 	case Help:
 		return "help"
+	case Unlock:
+		return "unlock"
+	case Cancel:
+		return "cancel"
 	}
 	return ""
 }
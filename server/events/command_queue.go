@@ -0,0 +1,369 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/run"
+	"github.com/hootsuite/atlantis/server/events/vcs"
+	"github.com/hootsuite/atlantis/server/logging"
+	"github.com/pkg/errors"
+)
+
+// CommandStore records the commands accepted by a CommandQueue until they've
+// run so that they aren't lost if Atlantis restarts.
+type CommandStore interface {
+	// Add records cmd and returns the ID it was given. IDs are never 0 and
+	// increase so that commands are listed in the order they were added.
+	Add(cmd QueuedCommand) (uint64, error)
+	// Update replaces the record of the command with cmd.ID.
+	Update(cmd QueuedCommand) error
+	// Remove deletes the record of the command with id.
+	Remove(id uint64) error
+	// List returns the recorded commands in the order they were added.
+	List() ([]QueuedCommand, error)
+}
+
+// QueuedRunner runs the commands that leave a CommandQueue.
+type QueuedRunner interface {
+	// ExecuteCommandContext runs the command and cancels it once ctx is done.
+	ExecuteCommandContext(ctx context.Context, baseRepo models.Repo, headRepo models.Repo, user models.User, pullNum int, cmd *Command, vcsHost vcs.Host)
+	// FailCommand reports that the command failed with failure on the pull
+	// request, the same as if it had run, without running it.
+	FailCommand(baseRepo models.Repo, headRepo models.Repo, user models.User, pullNum int, cmd *Command, vcsHost vcs.Host, failure string)
+}
+
+// CommandQueue is a CommandRunner that limits how many commands run at once.
+// Commands that can't run yet wait in a queue and are started in the order
+// they arrived, except that a command whose repo is at its limit doesn't hold
+// up the commands of other repos behind it. Cancel commands aren't queued,
+// they cancel the pull request's running and queued commands right away.
+type CommandQueue struct {
+	// Runner runs the commands once they leave the queue.
+	Runner    QueuedRunner
+	VCSClient vcs.ClientProxy
+	Logger    logging.SimpleLogging
+	// Store records the commands until they've run. If nil, commands are
+	// only kept in memory.
+	Store CommandStore
+	// MaxRunning is the max number of commands that can run at once. If 0,
+	// there's no limit.
+	MaxRunning int
+	// MaxRunningPerRepo is the max number of commands for the same repo that
+	// can run at once. If 0, there's no limit.
+	MaxRunningPerRepo int
+
+	mu    sync.Mutex
+	queue []*queuedJob
+	// active are the commands that have left the queue and are running.
+	active        []*queuedJob
+	running       int
+	runningByRepo map[string]int
+	// draining is true once Drain has been called.
+	draining bool
+	// idle is closed when the last running command finishes while draining.
+	idle chan struct{}
+}
+
+// QueuedCommand is a command that's been accepted by a CommandQueue.
+type QueuedCommand struct {
+	// ID is set by the CommandStore.
+	ID       uint64
+	BaseRepo models.Repo
+	HeadRepo models.Repo
+	User     models.User
+	PullNum  int
+	Command  *Command
+	VCSHost  vcs.Host
+	// Started is true once the command has left the queue.
+	Started bool
+}
+
+// queuedJob is a command in the queue.
+type queuedJob struct {
+	QueuedCommand
+	// ready is closed when the command leaves the queue and can run, or
+	// when it's dropped.
+	ready chan struct{}
+	// dropped is true if the command was cancelled while queued.
+	dropped bool
+	// ctx is cancelled to cancel the command once it's running.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// ExecuteCommand records the command and returns. The command is run in the
+// background once the limits allow. If it can't run right away, a comment
+// with its position in the queue is made on the pull request. If the command
+// can't be recorded, it isn't run and the error is returned.
+func (q *CommandQueue) ExecuteCommand(baseRepo models.Repo, headRepo models.Repo, user models.User, pullNum int, cmd *Command, vcsHost vcs.Host) error {
+	if cmd.Name == Cancel {
+		if q.CancelPull(baseRepo.FullName, pullNum, "@"+user.Username) == 0 {
+			go q.comment(&QueuedCommand{BaseRepo: baseRepo, PullNum: pullNum, VCSHost: vcsHost}, "There are no running or queued commands to cancel.")
+		}
+		return nil
+	}
+	return q.enqueue(&queuedJob{
+		QueuedCommand: QueuedCommand{
+			BaseRepo: baseRepo,
+			HeadRepo: headRepo,
+			User:     user,
+			PullNum:  pullNum,
+			Command:  cmd,
+			VCSHost:  vcsHost,
+		},
+		ready: make(chan struct{}),
+	})
+}
+
+// Recover handles the commands recorded by an earlier run of Atlantis that
+// didn't finish. Applies that had started may have partially applied so
+// rather than running them again they're failed. Everything else is queued
+// again. The pull requests are told what happened.
+func (q *CommandQueue) Recover() error {
+	if q.Store == nil {
+		return nil
+	}
+	cmds, err := q.Store.List()
+	if err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		name := cmd.Command.Name.String()
+		if cmd.Started && cmd.Command.Name == Apply {
+			if err := q.Store.Remove(cmd.ID); err != nil {
+				return err
+			}
+			q.Logger.Warn("failing %s for %s#%d since it was running when Atlantis stopped", name, cmd.BaseRepo.FullName, cmd.PullNum)
+			q.Runner.FailCommand(cmd.BaseRepo, cmd.HeadRepo, cmd.User, cmd.PullNum, cmd.Command, cmd.VCSHost,
+				"Atlantis restarted while this apply was running so it may not have finished. Run plan to see what's left to apply.")
+			continue
+		}
+		// The old record is only removed once the command has been recorded
+		// again so that it's never lost.
+		q.Logger.Info("queueing %s for %s#%d again since it didn't finish before Atlantis stopped", name, cmd.BaseRepo.FullName, cmd.PullNum)
+		if err := q.ExecuteCommand(cmd.BaseRepo, cmd.HeadRepo, cmd.User, cmd.PullNum, cmd.Command, cmd.VCSHost); err != nil {
+			return err
+		}
+		if err := q.Store.Remove(cmd.ID); err != nil {
+			return err
+		}
+		q.comment(&cmd, fmt.Sprintf("Atlantis restarted before this %s finished so it's been queued again.", name))
+	}
+	return nil
+}
+
+// CancelPull cancels the running commands of pull request pullNum in
+// repoFullName and removes its queued ones. by describes who cancelled them.
+// A comment listing what was cancelled is made on the pull request. It
+// returns the number of commands that were cancelled.
+func (q *CommandQueue) CancelPull(repoFullName string, pullNum int, by string) int {
+	q.mu.Lock()
+	var cancelled []string
+	var pull *QueuedCommand
+	matches := func(job *queuedJob) bool {
+		return job.BaseRepo.FullName == repoFullName && job.PullNum == pullNum
+	}
+	for _, job := range q.active {
+		if !matches(job) || job.ctx.Err() != nil {
+			continue
+		}
+		job.cancel()
+		cancelled = append(cancelled, fmt.Sprintf("* the running %s", job.Command.Name.String()))
+		pull = &job.QueuedCommand
+	}
+	var waiting []*queuedJob
+	for _, job := range q.queue {
+		if !matches(job) {
+			waiting = append(waiting, job)
+			continue
+		}
+		if q.Store != nil && job.ID != 0 {
+			if err := q.Store.Remove(job.ID); err != nil {
+				q.Logger.Err("failed to remove the record of %s for %s#%d: %s", job.Command.Name.String(), repoFullName, pullNum, err)
+			}
+		}
+		job.dropped = true
+		close(job.ready)
+		cancelled = append(cancelled, fmt.Sprintf("* the queued %s", job.Command.Name.String()))
+		pull = &job.QueuedCommand
+	}
+	q.queue = waiting
+	q.mu.Unlock()
+
+	if len(cancelled) > 0 {
+		q.Logger.Info("%s cancelled %d commands for %s#%d", by, len(cancelled), repoFullName, pullNum)
+		go q.comment(pull, fmt.Sprintf("Cancelled by %s:\n%s\n\nRunning commands are interrupted and have up to %s to clean up, ex. release Terraform's state lock, before they're killed.",
+			by, strings.Join(cancelled, "\n"), run.InterruptGracePeriod))
+	}
+	return len(cancelled)
+}
+
+// drainCancelWait is how long Drain waits for the running commands to exit
+// after cancelling them. It's a bit longer than run.InterruptGracePeriod so
+// that commands which ignore the interrupt have time to be killed.
+const drainCancelWait = run.InterruptGracePeriod + 5*time.Second
+
+// Drain stops the queue from starting commands and waits up to timeout for
+// the running ones to finish. If they don't finish in time, they're cancelled
+// so that Terraform can release its state locks, Drain waits up to
+// drainCancelWait for them to exit and returns false.
+// Commands received afterwards aren't run and their pull requests are told
+// that Atlantis is shutting down. Commands that were still waiting stay
+// recorded in the Store so they're run by Recover once Atlantis restarts.
+func (q *CommandQueue) Drain(timeout time.Duration) bool {
+	q.mu.Lock()
+	q.draining = true
+	if q.running == 0 {
+		q.mu.Unlock()
+		return true
+	}
+	if q.idle == nil {
+		q.idle = make(chan struct{})
+	}
+	idle := q.idle
+	q.mu.Unlock()
+
+	select {
+	case <-idle:
+		return true
+	case <-time.After(timeout):
+	}
+
+	q.mu.Lock()
+	for _, job := range q.active {
+		q.Logger.Warn("cancelling %s for %s#%d since it didn't finish before Atlantis stopped", job.Command.Name.String(), job.BaseRepo.FullName, job.PullNum)
+		job.cancel()
+	}
+	q.mu.Unlock()
+	select {
+	case <-idle:
+	case <-time.After(drainCancelWait):
+	}
+	return false
+}
+
+// Draining returns true if Drain has been called.
+func (q *CommandQueue) Draining() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.draining
+}
+
+// Len returns the number of commands waiting in the queue.
+func (q *CommandQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queue)
+}
+
+func (q *CommandQueue) enqueue(job *queuedJob) error {
+	q.mu.Lock()
+	if q.draining {
+		q.mu.Unlock()
+		go q.comment(&job.QueuedCommand, fmt.Sprintf("Atlantis is shutting down so this %s wasn't run. Run it again once Atlantis is back.", job.Command.Name.String()))
+		return nil
+	}
+	if q.Store != nil {
+		id, err := q.Store.Add(job.QueuedCommand)
+		if err != nil {
+			q.mu.Unlock()
+			return errors.Wrapf(err, "recording %s for %s#%d", job.Command.Name.String(), job.BaseRepo.FullName, job.PullNum)
+		}
+		job.ID = id
+	}
+	q.queue = append(q.queue, job)
+	q.startRunnable()
+	position := 0
+	for i, queued := range q.queue {
+		if queued == job {
+			position = i + 1
+		}
+	}
+	q.mu.Unlock()
+
+	go func() {
+		if position > 0 && !job.Command.Autoplan {
+			// Most autoplans don't find any projects to plan so we don't
+			// comment on every pull request that's opened while we're busy.
+			q.comment(&job.QueuedCommand, fmt.Sprintf("Atlantis is busy so this %s is queued, position %d. It will run once the commands ahead of it finish.", job.Command.Name.String(), position))
+		}
+		<-job.ready
+		if job.dropped {
+			return
+		}
+		q.run(job)
+	}()
+	return nil
+}
+
+// startRunnable removes the commands that can run now from the queue, in
+// order, and marks them as ready. q.mu must be held.
+func (q *CommandQueue) startRunnable() {
+	if q.draining {
+		return
+	}
+	if q.runningByRepo == nil {
+		q.runningByRepo = make(map[string]int)
+	}
+	var waiting []*queuedJob
+	for _, job := range q.queue {
+		repo := job.BaseRepo.FullName
+		if (q.MaxRunning > 0 && q.running >= q.MaxRunning) ||
+			(q.MaxRunningPerRepo > 0 && q.runningByRepo[repo] >= q.MaxRunningPerRepo) {
+			waiting = append(waiting, job)
+			continue
+		}
+		q.running++
+		q.runningByRepo[repo]++
+		job.ctx, job.cancel = context.WithCancel(context.Background())
+		q.active = append(q.active, job)
+		job.Started = true
+		if q.Store != nil && job.ID != 0 {
+			if err := q.Store.Update(job.QueuedCommand); err != nil {
+				q.Logger.Err("failed to record that %s for %s#%d started: %s", job.Command.Name.String(), repo, job.PullNum, err)
+			}
+		}
+		close(job.ready)
+	}
+	q.queue = waiting
+}
+
+// run runs job and then starts the commands that were waiting for it to
+// finish.
+func (q *CommandQueue) run(job *queuedJob) {
+	defer func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if q.Store != nil && job.ID != 0 {
+			if err := q.Store.Remove(job.ID); err != nil {
+				q.Logger.Err("failed to remove the record of %s for %s#%d: %s", job.Command.Name.String(), job.BaseRepo.FullName, job.PullNum, err)
+			}
+		}
+		job.cancel()
+		for i, active := range q.active {
+			if active == job {
+				q.active = append(q.active[:i], q.active[i+1:]...)
+				break
+			}
+		}
+		q.running--
+		q.runningByRepo[job.BaseRepo.FullName]--
+		if q.draining && q.running == 0 && q.idle != nil {
+			close(q.idle)
+			q.idle = nil
+		}
+		q.startRunnable()
+	}()
+	q.Runner.ExecuteCommandContext(job.ctx, job.BaseRepo, job.HeadRepo, job.User, job.PullNum, job.Command, job.VCSHost)
+}
+
+func (q *CommandQueue) comment(job *QueuedCommand, comment string) {
+	if err := q.VCSClient.CreateComment(job.BaseRepo, models.PullRequest{Num: job.PullNum}, comment, job.VCSHost); err != nil {
+		q.Logger.Warn("failed to comment on %s#%d: %s", job.BaseRepo.FullName, job.PullNum, err)
+	}
+}
@@ -0,0 +1,434 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hootsuite/atlantis/server/events"
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs"
+	"github.com/hootsuite/atlantis/server/logging"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+var queueRepo1 = models.Repo{FullName: "owner/repo1"}
+var queueRepo2 = models.Repo{FullName: "owner/repo2"}
+
+func TestCommandQueue_NoLimits(t *testing.T) {
+	t.Log("without limits commands should run right away")
+	q, runner, client := setupCommandQueue(1, 2)
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 1, &events.Command{Name: events.Plan}, vcs.Github)
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 2, &events.Command{Name: events.Plan}, vcs.Github)
+	waitFor(t, func() bool { return len(runner.Started()) == 2 })
+	Equals(t, 0, len(client.Comments()))
+	runner.ReleaseAll()
+}
+
+func TestCommandQueue_MaxRunning(t *testing.T) {
+	t.Log("commands over the limit should be queued and run in order")
+	q, runner, client := setupCommandQueue(1, 2, 3)
+	q.MaxRunning = 1
+
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 1, &events.Command{Name: events.Plan}, vcs.Github)
+	waitFor(t, func() bool { return len(runner.Started()) == 1 })
+	q.ExecuteCommand(queueRepo2, queueRepo2, models.User{}, 2, &events.Command{Name: events.Plan}, vcs.Github)
+	waitFor(t, func() bool { return len(client.Comments()) == 1 })
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 3, &events.Command{Name: events.Apply}, vcs.Github)
+	waitFor(t, func() bool { return len(client.Comments()) == 2 })
+
+	t.Log("the pull requests should be told their position in the queue")
+	Equals(t, []string{
+		"2: Atlantis is busy so this plan is queued, position 1. It will run once the commands ahead of it finish.",
+		"3: Atlantis is busy so this apply is queued, position 2. It will run once the commands ahead of it finish.",
+	}, client.Comments())
+	Equals(t, 2, q.Len())
+
+	runner.Release(1)
+	waitFor(t, func() bool { return len(runner.Started()) == 2 })
+	runner.Release(2)
+	waitFor(t, func() bool { return len(runner.Started()) == 3 })
+	runner.Release(3)
+	Equals(t, []int{1, 2, 3}, runner.Started())
+}
+
+func TestCommandQueue_MaxRunningPerRepo(t *testing.T) {
+	t.Log("a repo at its limit shouldn't hold up other repos")
+	q, runner, client := setupCommandQueue(1, 2, 3)
+	q.MaxRunning = 2
+	q.MaxRunningPerRepo = 1
+
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 1, &events.Command{Name: events.Plan}, vcs.Github)
+	waitFor(t, func() bool { return len(runner.Started()) == 1 })
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 2, &events.Command{Name: events.Plan}, vcs.Github)
+	waitFor(t, func() bool { return len(client.Comments()) == 1 })
+	q.ExecuteCommand(queueRepo2, queueRepo2, models.User{}, 3, &events.Command{Name: events.Plan}, vcs.Github)
+	waitFor(t, func() bool { return len(runner.Started()) == 2 })
+	Equals(t, []int{1, 3}, runner.Started())
+
+	runner.Release(1)
+	waitFor(t, func() bool { return len(runner.Started()) == 3 })
+	Equals(t, []int{1, 3, 2}, runner.Started())
+	runner.ReleaseAll()
+}
+
+func TestCommandQueue_AutoplanNoComment(t *testing.T) {
+	t.Log("queued autoplans shouldn't comment")
+	q, runner, client := setupCommandQueue(1, 2)
+	q.MaxRunning = 1
+
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 1, &events.Command{Name: events.Plan}, vcs.Github)
+	waitFor(t, func() bool { return len(runner.Started()) == 1 })
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 2, &events.Command{Name: events.Plan, Autoplan: true}, vcs.Github)
+	waitFor(t, func() bool { return q.Len() == 1 })
+
+	runner.Release(1)
+	waitFor(t, func() bool { return len(runner.Started()) == 2 })
+	Equals(t, 0, len(client.Comments()))
+	runner.ReleaseAll()
+}
+
+func TestCommandQueue_Store(t *testing.T) {
+	t.Log("commands should be recorded until they've run")
+	q, runner, _ := setupCommandQueue(1, 2)
+	store := newMemoryCommandStore()
+	q.Store = store
+	q.MaxRunning = 1
+
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 1, &events.Command{Name: events.Plan}, vcs.Github)
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 2, &events.Command{Name: events.Apply}, vcs.Github)
+	waitFor(t, func() bool { return len(runner.Started()) == 1 })
+	Equals(t, []events.QueuedCommand{
+		{ID: 1, BaseRepo: queueRepo1, HeadRepo: queueRepo1, PullNum: 1, Command: &events.Command{Name: events.Plan}, VCSHost: vcs.Github, Started: true},
+		{ID: 2, BaseRepo: queueRepo1, HeadRepo: queueRepo1, PullNum: 2, Command: &events.Command{Name: events.Apply}, VCSHost: vcs.Github},
+	}, store.Commands())
+
+	runner.Release(1)
+	waitFor(t, func() bool { return len(store.Commands()) == 1 && store.Commands()[0].Started })
+	Equals(t, uint64(2), store.Commands()[0].ID)
+	runner.Release(2)
+	waitFor(t, func() bool { return len(store.Commands()) == 0 })
+}
+
+func TestCommandQueue_StoreErr(t *testing.T) {
+	t.Log("commands that can't be recorded shouldn't be run")
+	q, runner, _ := setupCommandQueue(1)
+	store := newMemoryCommandStore()
+	store.addErr = errors.New("err")
+	q.Store = store
+
+	err := q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 1, &events.Command{Name: events.Plan}, vcs.Github)
+	Assert(t, err != nil, "exp err")
+	Equals(t, "recording plan for owner/repo1#1: err", err.Error())
+	time.Sleep(50 * time.Millisecond)
+	Equals(t, 0, len(runner.Started()))
+	Equals(t, true, q.Drain(time.Second))
+}
+
+func TestCommandQueue_Recover(t *testing.T) {
+	t.Log("unfinished commands should be queued again except applies that started, which should fail")
+	q, runner, client := setupCommandQueue(1, 2, 3)
+	store := newMemoryCommandStore()
+	q.Store = store
+	for _, cmd := range []events.QueuedCommand{
+		{BaseRepo: queueRepo1, PullNum: 1, Command: &events.Command{Name: events.Plan}, VCSHost: vcs.Github, Started: true},
+		{BaseRepo: queueRepo1, PullNum: 2, Command: &events.Command{Name: events.Apply}, VCSHost: vcs.Github, Started: true},
+		{BaseRepo: queueRepo2, PullNum: 3, Command: &events.Command{Name: events.Apply}, VCSHost: vcs.Github},
+	} {
+		_, err := store.Add(cmd)
+		Ok(t, err)
+	}
+
+	Ok(t, q.Recover())
+	waitFor(t, func() bool { return len(runner.Started()) == 2 })
+	started := runner.Started()
+	sort.Ints(started)
+	Equals(t, []int{1, 3}, started)
+	Equals(t, []string{"2: Atlantis restarted while this apply was running so it may not have finished. Run plan to see what's left to apply."}, runner.Failed())
+	Equals(t, []string{
+		"1: Atlantis restarted before this plan finished so it's been queued again.",
+		"3: Atlantis restarted before this apply finished so it's been queued again.",
+	}, client.Comments())
+
+	t.Log("the recovered commands should be recorded again")
+	Equals(t, 2, len(store.Commands()))
+	runner.ReleaseAll()
+	waitFor(t, func() bool { return len(store.Commands()) == 0 })
+}
+
+func TestCommandQueue_RecoverStoreErr(t *testing.T) {
+	t.Log("commands that can't be recorded again should be kept so they aren't lost")
+	q, runner, client := setupCommandQueue(1)
+	store := newMemoryCommandStore()
+	q.Store = store
+	_, err := store.Add(events.QueuedCommand{BaseRepo: queueRepo1, PullNum: 1, Command: &events.Command{Name: events.Plan}, VCSHost: vcs.Github})
+	Ok(t, err)
+	store.addErr = errors.New("err")
+
+	Assert(t, q.Recover() != nil, "exp err")
+	Equals(t, 1, len(store.Commands()))
+	Equals(t, 0, len(runner.Started()))
+	Equals(t, 0, len(client.Comments()))
+}
+
+func TestCommandQueue_DrainIdle(t *testing.T) {
+	t.Log("draining with nothing running should return right away")
+	q, _, _ := setupCommandQueue()
+	Equals(t, true, q.Drain(time.Minute))
+	Equals(t, true, q.Draining())
+}
+
+func TestCommandQueue_Drain(t *testing.T) {
+	t.Log("draining should wait for running commands and leave queued ones recorded")
+	q, runner, client := setupCommandQueue(1, 2, 3)
+	store := newMemoryCommandStore()
+	q.Store = store
+	q.MaxRunning = 1
+
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 1, &events.Command{Name: events.Apply}, vcs.Github)
+	waitFor(t, func() bool { return len(runner.Started()) == 1 })
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 2, &events.Command{Name: events.Plan}, vcs.Github)
+	waitFor(t, func() bool { return len(client.Comments()) == 1 })
+
+	drained := make(chan bool)
+	go func() { drained <- q.Drain(time.Minute) }()
+	waitFor(t, q.Draining)
+
+	t.Log("new commands shouldn't be run or recorded")
+	q.ExecuteCommand(queueRepo2, queueRepo2, models.User{}, 3, &events.Command{Name: events.Plan, Autoplan: true}, vcs.Github)
+	waitFor(t, func() bool { return len(client.Comments()) == 2 })
+	Equals(t, "3: Atlantis is shutting down so this plan wasn't run. Run it again once Atlantis is back.", client.Comments()[1])
+
+	runner.Release(1)
+	Equals(t, true, <-drained)
+	t.Log("the queued command shouldn't have started")
+	Equals(t, []int{1}, runner.Started())
+	Equals(t, []events.QueuedCommand{
+		{ID: 2, BaseRepo: queueRepo1, HeadRepo: queueRepo1, PullNum: 2, Command: &events.Command{Name: events.Plan}, VCSHost: vcs.Github},
+	}, store.Commands())
+}
+
+func TestCommandQueue_DrainTimeout(t *testing.T) {
+	t.Log("draining should cancel the running commands after the timeout and wait for them to exit")
+	q, runner, _ := setupCommandQueue(1, 2)
+	store := newMemoryCommandStore()
+	q.Store = store
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 1, &events.Command{Name: events.Apply}, vcs.Github)
+	q.ExecuteCommand(queueRepo2, queueRepo2, models.User{}, 2, &events.Command{Name: events.Plan}, vcs.Github)
+	waitFor(t, func() bool { return len(runner.Started()) == 2 })
+	Equals(t, false, q.Drain(10*time.Millisecond))
+	cancelled := runner.Cancelled()
+	sort.Ints(cancelled)
+	Equals(t, []int{1, 2}, cancelled)
+	Equals(t, 0, len(store.Commands()))
+}
+
+func TestCommandQueue_Cancel(t *testing.T) {
+	t.Log("cancel should cancel the pull request's running command and drop its queued ones")
+	q, runner, client := setupCommandQueue(1, 2)
+	store := newMemoryCommandStore()
+	q.Store = store
+	q.MaxRunning = 1
+
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 1, &events.Command{Name: events.Apply}, vcs.Github)
+	waitFor(t, func() bool { return len(runner.Started()) == 1 })
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 1, &events.Command{Name: events.Plan, Autoplan: true}, vcs.Github)
+	q.ExecuteCommand(queueRepo2, queueRepo2, models.User{}, 2, &events.Command{Name: events.Plan, Autoplan: true}, vcs.Github)
+	waitFor(t, func() bool { return q.Len() == 2 })
+
+	t.Log("cancel shouldn't be queued behind the commands it cancels")
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{Username: "lkysow"}, 1, &events.Command{Name: events.Cancel}, vcs.Github)
+	waitFor(t, func() bool { return len(runner.Cancelled()) == 1 })
+	Equals(t, []int{1}, runner.Cancelled())
+
+	t.Log("the other pull request's command should run next")
+	waitFor(t, func() bool { return len(runner.Started()) == 2 })
+	Equals(t, []int{1, 2}, runner.Started())
+	Equals(t, 0, q.Len())
+	waitFor(t, func() bool { return len(client.Comments()) == 1 })
+	Equals(t, "1: Cancelled by @lkysow:\n* the running apply\n* the queued plan\n\nRunning commands are interrupted and have up to 1m0s to clean up, ex. release Terraform's state lock, before they're killed.", client.Comments()[0])
+
+	runner.ReleaseAll()
+	waitFor(t, func() bool { return len(store.Commands()) == 0 })
+}
+
+func TestCommandQueue_CancelNothing(t *testing.T) {
+	t.Log("cancel should say so if there's nothing to cancel")
+	q, runner, client := setupCommandQueue(2)
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 2, &events.Command{Name: events.Plan}, vcs.Github)
+	waitFor(t, func() bool { return len(runner.Started()) == 1 })
+	q.ExecuteCommand(queueRepo1, queueRepo1, models.User{}, 1, &events.Command{Name: events.Cancel}, vcs.Github)
+	waitFor(t, func() bool { return len(client.Comments()) == 1 })
+	Equals(t, []string{"1: There are no running or queued commands to cancel."}, client.Comments())
+	Equals(t, 0, len(runner.Cancelled()))
+	runner.ReleaseAll()
+}
+
+func setupCommandQueue(pullNums ...int) (*events.CommandQueue, *blockingRunner, *commentRecorder) {
+	runner := &blockingRunner{release: make(map[int]chan struct{})}
+	for _, num := range pullNums {
+		runner.release[num] = make(chan struct{})
+	}
+	client := &commentRecorder{}
+	return &events.CommandQueue{
+		Runner:    runner,
+		VCSClient: client,
+		Logger:    logging.NewNoopLogger(),
+	}, runner, client
+}
+
+// blockingRunner records the pull requests it runs commands for and blocks
+// until they're released or cancelled.
+type blockingRunner struct {
+	mu        sync.Mutex
+	started   []int
+	cancelled []int
+	failed    []string
+	release   map[int]chan struct{}
+}
+
+func (r *blockingRunner) ExecuteCommandContext(ctx context.Context, baseRepo models.Repo, headRepo models.Repo, user models.User, pullNum int, cmd *events.Command, vcsHost vcs.Host) {
+	r.mu.Lock()
+	r.started = append(r.started, pullNum)
+	release := r.release[pullNum]
+	r.mu.Unlock()
+	select {
+	case <-release:
+	case <-ctx.Done():
+		r.mu.Lock()
+		r.cancelled = append(r.cancelled, pullNum)
+		r.mu.Unlock()
+	}
+}
+
+func (r *blockingRunner) Cancelled() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.cancelled...)
+}
+
+func (r *blockingRunner) FailCommand(baseRepo models.Repo, headRepo models.Repo, user models.User, pullNum int, cmd *events.Command, vcsHost vcs.Host, failure string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failed = append(r.failed, fmt.Sprintf("%d: %s", pullNum, failure))
+}
+
+func (r *blockingRunner) Failed() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.failed...)
+}
+
+func (r *blockingRunner) Started() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.started...)
+}
+
+func (r *blockingRunner) Release(pullNum int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	close(r.release[pullNum])
+	delete(r.release, pullNum)
+}
+
+func (r *blockingRunner) ReleaseAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for num, release := range r.release {
+		close(release)
+		delete(r.release, num)
+	}
+}
+
+// commentRecorder records the comments it's asked to create. Calling any
+// other method panics.
+type commentRecorder struct {
+	vcs.ClientProxy
+	mu       sync.Mutex
+	comments []string
+}
+
+func (c *commentRecorder) CreateComment(repo models.Repo, pull models.PullRequest, comment string, host vcs.Host) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.comments = append(c.comments, fmt.Sprintf("%d: %s", pull.Num, comment))
+	return nil
+}
+
+func (c *commentRecorder) Comments() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.comments...)
+}
+
+// memoryCommandStore is a CommandStore that keeps commands in memory. If
+// addErr is set, Add fails with it.
+type memoryCommandStore struct {
+	mu     sync.Mutex
+	nextID uint64
+	cmds   map[uint64]events.QueuedCommand
+	addErr error
+}
+
+func newMemoryCommandStore() *memoryCommandStore {
+	return &memoryCommandStore{cmds: make(map[uint64]events.QueuedCommand)}
+}
+
+func (s *memoryCommandStore) Add(cmd events.QueuedCommand) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.addErr != nil {
+		return 0, s.addErr
+	}
+	s.nextID++
+	cmd.ID = s.nextID
+	s.cmds[cmd.ID] = cmd
+	return cmd.ID, nil
+}
+
+func (s *memoryCommandStore) Update(cmd events.QueuedCommand) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cmds[cmd.ID] = cmd
+	return nil
+}
+
+func (s *memoryCommandStore) Remove(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cmds, id)
+	return nil
+}
+
+func (s *memoryCommandStore) List() ([]events.QueuedCommand, error) {
+	return s.Commands(), nil
+}
+
+// Commands returns the recorded commands ordered by ID.
+func (s *memoryCommandStore) Commands() []events.QueuedCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var cmds []events.QueuedCommand
+	for id := uint64(1); id <= s.nextID; id++ {
+		if cmd, ok := s.cmds[id]; ok {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds
+}
+
+// waitFor fails the test if cond isn't true within a second.
+func waitFor(t *testing.T, cond func() bool) {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for condition")
+}
@@ -1,8 +1,29 @@
 package events
 
+import (
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs"
+)
+
 // CommandResponse is the result of running a Command.
 type CommandResponse struct {
 	Error          error
 	Failure        string
 	ProjectResults []ProjectResult
+	// UnlockedLocks are the locks that were deleted by an unlock command.
+	UnlockedLocks []models.ProjectLock
+}
+
+// Status returns the vcs commit status of the whole command. It's failed if
+// the command or any of its projects failed.
+func (c CommandResponse) Status() vcs.CommitStatus {
+	if c.Error != nil || c.Failure != "" {
+		return vcs.Failed
+	}
+	for _, p := range c.ProjectResults {
+		if p.Status() == vcs.Failed {
+			return vcs.Failed
+		}
+	}
+	return vcs.Success
 }
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CommentFlagPolicy restricts the Terraform flags that users can pass to plan
+// and apply through pull request comments, ex. atlantis plan -- -target=foo.
+// Flags are matched by name without their leading dashes or value so
+// "-target" matches "--target=foo".
+type CommentFlagPolicy struct {
+	// Allowed is the list of flags that are allowed. If empty, all flags
+	// that aren't denied are allowed.
+	Allowed []string
+	// Denied is the list of flags that are never allowed.
+	Denied []string
+}
+
+// NewCommentFlagPolicy returns a policy from comma-separated lists of
+// allowed and denied flags, ex. "-target,-var".
+func NewCommentFlagPolicy(allowed string, denied string) *CommentFlagPolicy {
+	return &CommentFlagPolicy{
+		Allowed: splitFlagList(allowed),
+		Denied:  splitFlagList(denied),
+	}
+}
+
+// valueFlags are the Terraform plan and apply flags that take their value as
+// the next arg, ex. "-var foo=bar". Other flags, ex. -no-color and -lock, are
+// booleans that can only be given a value with "=" so an arg after them would
+// be passed to Terraform as a positional arg, ex. the config dir.
+var valueFlags = []string{
+	"backup",
+	"lock-timeout",
+	"module-depth",
+	"out",
+	"parallelism",
+	"state",
+	"state-out",
+	"target",
+	"var",
+	"var-file",
+}
+
+// Check returns a failure message suitable for commenting back to the pull
+// request if any of flags aren't allowed. Otherwise it returns an empty
+// string. A nil policy allows everything.
+func (c *CommentFlagPolicy) Check(flags []string) string {
+	if c == nil {
+		return ""
+	}
+	prevTakesValue := false
+	for _, f := range flags {
+		if !strings.HasPrefix(f, "-") {
+			// An arg that isn't a flag is only allowed as the value of the
+			// flag before it, ex. "-var foo=bar". Otherwise it would be
+			// passed to Terraform as a positional arg.
+			if !prevTakesValue {
+				return fmt.Sprintf("Argument %q isn't allowed in comments. Only Terraform flags and their values can be passed.", f)
+			}
+			prevTakesValue = false
+			continue
+		}
+		name := flagName(f)
+		if name == "" {
+			return fmt.Sprintf("Argument %q isn't allowed in comments. Only Terraform flags and their values can be passed.", f)
+		}
+		if containsStr(c.Denied, name) || (len(c.Allowed) > 0 && !containsStr(c.Allowed, name)) {
+			return fmt.Sprintf("Flag %q isn't allowed in comments by this Atlantis server. %s", f, c.allowedDesc())
+		}
+		prevTakesValue = !strings.Contains(f, "=") && containsStr(valueFlags, name)
+	}
+	return ""
+}
+
+func (c *CommentFlagPolicy) allowedDesc() string {
+	if len(c.Allowed) == 0 {
+		return fmt.Sprintf("Denied flags: -%s.", strings.Join(c.Denied, ", -"))
+	}
+	return fmt.Sprintf("Allowed flags: -%s.", strings.Join(c.Allowed, ", -"))
+}
+
+// flagName returns the name of flag f without its leading dashes or value,
+// ex. "--target=foo" returns "target".
+func flagName(f string) string {
+	name := strings.TrimLeft(f, "-")
+	return strings.SplitN(name, "=", 2)[0]
+}
+
+func splitFlagList(list string) []string {
+	var flags []string
+	for _, f := range strings.Split(list, ",") {
+		if name := flagName(strings.TrimSpace(f)); name != "" {
+			flags = append(flags, name)
+		}
+	}
+	return flags
+}
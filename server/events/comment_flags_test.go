@@ -0,0 +1,108 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+func TestCommentFlagPolicy_Check(t *testing.T) {
+	cases := []struct {
+		description string
+		allowed     string
+		denied      string
+		flags       []string
+		expFailure  string
+	}{
+		{
+			"no flags are always allowed",
+			"-target",
+			"",
+			nil,
+			"",
+		},
+		{
+			"flags are allowed by default",
+			"",
+			"",
+			[]string{"-target=a", "-var", "a=b"},
+			"",
+		},
+		{
+			"denied flags are rejected with or without their value",
+			"",
+			"-state, -var-file",
+			[]string{"-target=a", "-var-file", "secrets.tfvars"},
+			`Flag "-var-file" isn't allowed in comments by this Atlantis server. Denied flags: -state, -var-file.`,
+		},
+		{
+			"flags are matched regardless of the number of dashes",
+			"",
+			"state",
+			[]string{"--state=a"},
+			`Flag "--state=a" isn't allowed in comments by this Atlantis server. Denied flags: -state.`,
+		},
+		{
+			"only allowed flags are accepted",
+			"-target,-var",
+			"",
+			[]string{"-target=a", "-var", "a=b", "-lock=false"},
+			`Flag "-lock=false" isn't allowed in comments by this Atlantis server. Allowed flags: -target, -var.`,
+		},
+		{
+			"denied flags are rejected even if they're allowed",
+			"-target",
+			"-target",
+			[]string{"-target=a"},
+			`Flag "-target=a" isn't allowed in comments by this Atlantis server. Allowed flags: -target.`,
+		},
+		{
+			"args that aren't flag values are rejected",
+			"",
+			"",
+			[]string{"-target=a", "../other"},
+			`Argument "../other" isn't allowed in comments. Only Terraform flags and their values can be passed.`,
+		},
+		{
+			"args after boolean flags are rejected since they'd be the config dir",
+			"",
+			"",
+			[]string{"-no-color", "/some/dir"},
+			`Argument "/some/dir" isn't allowed in comments. Only Terraform flags and their values can be passed.`,
+		},
+		{
+			"boolean flags can only be given a value with =",
+			"",
+			"",
+			[]string{"-lock=false", "-lock", "/some/dir"},
+			`Argument "/some/dir" isn't allowed in comments. Only Terraform flags and their values can be passed.`,
+		},
+		{
+			"flags with values can only be given one",
+			"",
+			"",
+			[]string{"-var-file", "a.tfvars", "/some/dir"},
+			`Argument "/some/dir" isn't allowed in comments. Only Terraform flags and their values can be passed.`,
+		},
+		{
+			"shell syntax is rejected",
+			"",
+			"",
+			[]string{"--", ";", "curl", "evil"},
+			`Argument "--" isn't allowed in comments. Only Terraform flags and their values can be passed.`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			p := events.NewCommentFlagPolicy(c.allowed, c.denied)
+			Equals(t, c.expFailure, p.Check(c.flags))
+		})
+	}
+}
+
+func TestCommentFlagPolicy_Nil(t *testing.T) {
+	t.Log("a nil policy should allow everything")
+	var p *events.CommentFlagPolicy
+	Equals(t, "", p.Check([]string{"--", ";"}))
+}
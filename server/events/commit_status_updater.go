@@ -15,42 +15,81 @@ import (
 type CommitStatusUpdater interface {
 	// Update updates the status of the head commit of pull.
 	Update(repo models.Repo, pull models.PullRequest, status vcs.CommitStatus, cmd *Command, host vcs.Host) error
+	// UpdateRunning sets the status of the head commit of ctx.Pull to pending
+	// while ctx.Command runs, linking to ctx.JobURL if it's set.
+	UpdateRunning(ctx *CommandContext) error
 	// UpdateProjectResult updates the status of the head commit given the
 	// state of response.
 	UpdateProjectResult(ctx *CommandContext, res CommandResponse) error
 }
 
-// DefaultCommitStatusUpdater implements CommitStatusUpdater.
+// DefaultCommitStatusUpdater implements CommitStatusUpdater. It sets a status
+// for each project and workspace, ex. atlantis/plan: infra/vpc (staging), so
+// they can be required individually, as well as a status for the whole
+// command, ex. atlantis/plan.
 type DefaultCommitStatusUpdater struct {
 	Client vcs.ClientProxy
+	// AtlantisURL is linked to from statuses that don't have a more specific
+	// page on the Atlantis server.
+	AtlantisURL string
 }
 
-// Update updates the commit status.
+// Update updates the status for the whole command.
 func (d *DefaultCommitStatusUpdater) Update(repo models.Repo, pull models.PullRequest, status vcs.CommitStatus, cmd *Command, host vcs.Host) error {
 	description := fmt.Sprintf("%s %s", strings.Title(cmd.Name.String()), strings.Title(status.String()))
-	return d.Client.UpdateStatus(repo, pull, status, description, host)
+	return d.Client.UpdateStatus(repo, pull, status, statusName(cmd.Name, "", ""), description, d.AtlantisURL, host)
 }
 
-// UpdateProjectResult updates the commit status based on the status of res.
+// UpdateRunning updates the status for the whole command to pending. If the
+// command's output is being recorded, the status links to its job page.
+func (d *DefaultCommitStatusUpdater) UpdateRunning(ctx *CommandContext) error {
+	if ctx.JobURL == "" {
+		return d.Update(ctx.BaseRepo, ctx.Pull, vcs.Pending, ctx.Command, ctx.VCSHost)
+	}
+	description := fmt.Sprintf("%s Running, follow along on Atlantis", strings.Title(ctx.Command.Name.String()))
+	return d.Client.UpdateStatus(ctx.BaseRepo, ctx.Pull, vcs.Pending, statusName(ctx.Command.Name, "", ""), description, ctx.JobURL, ctx.VCSHost)
+}
+
+// UpdateProjectResult updates the status of each project in res and then the
+// status for the whole command. If the command's output was recorded, they
+// link to its job page so it can be replayed.
 func (d *DefaultCommitStatusUpdater) UpdateProjectResult(ctx *CommandContext, res CommandResponse) error {
-	var status vcs.CommitStatus
-	if res.Error != nil || res.Failure != "" {
-		status = vcs.Failed
-	} else {
-		var statuses []vcs.CommitStatus
-		for _, p := range res.ProjectResults {
-			statuses = append(statuses, p.Status())
+	cmdName := ctx.Command.Name
+	for _, p := range res.ProjectResults {
+		status := p.Status()
+		description := fmt.Sprintf("%s %s", strings.Title(cmdName.String()), strings.Title(status.String()))
+		url := d.url(ctx)
+		// Link successful plans to their lock's page where they can be
+		// discarded.
+		if p.PlanSuccess != nil && p.PlanSuccess.LockURL != "" {
+			url = p.PlanSuccess.LockURL
+		}
+		src := statusName(cmdName, p.Path, ctx.Command.Workspace)
+		if err := d.Client.UpdateStatus(ctx.BaseRepo, ctx.Pull, status, src, description, url, ctx.VCSHost); err != nil {
+			return err
 		}
-		status = d.worstStatus(statuses)
 	}
-	return d.Update(ctx.BaseRepo, ctx.Pull, status, ctx.Command, ctx.VCSHost)
+	status := res.Status()
+	description := fmt.Sprintf("%s %s", strings.Title(cmdName.String()), strings.Title(status.String()))
+	return d.Client.UpdateStatus(ctx.BaseRepo, ctx.Pull, status, statusName(cmdName, "", ""), description, d.url(ctx), ctx.VCSHost)
 }
 
-func (d *DefaultCommitStatusUpdater) worstStatus(ss []vcs.CommitStatus) vcs.CommitStatus {
-	for _, s := range ss {
-		if s == vcs.Failed {
-			return vcs.Failed
-		}
+// url returns the URL that the statuses for ctx's command link to by
+// default.
+func (d *DefaultCommitStatusUpdater) url(ctx *CommandContext) string {
+	if ctx.JobURL != "" {
+		return ctx.JobURL
+	}
+	return d.AtlantisURL
+}
+
+// statusName returns the name of the status for the project in dir and
+// workspace, ex. atlantis/plan: infra/vpc (staging). If dir is empty, it's
+// the name of the status for the whole command, ex. atlantis/plan.
+func statusName(cmdName CommandName, dir string, workspace string) string {
+	name := "atlantis/" + cmdName.String()
+	if dir == "" {
+		return name
 	}
-	return vcs.Success
+	return fmt.Sprintf("%s: %s (%s)", name, dir, workspace)
 }
@@ -2,6 +2,8 @@ package events_test
 
 import (
 	"errors"
+	"fmt"
+	"os"
 	"strings"
 	"testing"
 
@@ -16,6 +18,7 @@ import (
 var repoModel = models.Repo{}
 var pullModel = models.PullRequest{}
 var status = vcs.Success
+var atlantisURL = "https://atlantis.example.com"
 var cmd = events.Command{
 	Name: events.Plan,
 }
@@ -34,10 +37,50 @@ func TestStatus_String(t *testing.T) {
 func TestUpdate(t *testing.T) {
 	RegisterMockTestingT(t)
 	client := mocks.NewMockClientProxy()
-	s := events.DefaultCommitStatusUpdater{Client: client}
+	s := events.DefaultCommitStatusUpdater{Client: client, AtlantisURL: atlantisURL}
 	err := s.Update(repoModel, pullModel, status, &cmd, vcs.Github)
 	Ok(t, err)
-	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, status, "Plan Success", vcs.Github)
+	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, status, "atlantis/plan", "Plan Success", atlantisURL, vcs.Github)
+}
+
+func TestUpdateRunning(t *testing.T) {
+	RegisterMockTestingT(t)
+	client := mocks.NewMockClientProxy()
+	s := events.DefaultCommitStatusUpdater{Client: client, AtlantisURL: atlantisURL}
+	ctx := &events.CommandContext{
+		BaseRepo: repoModel,
+		Pull:     pullModel,
+		Command:  &events.Command{Name: events.Plan},
+		VCSHost:  vcs.Github,
+	}
+
+	t.Log("without a job the status should just be pending")
+	Ok(t, s.UpdateRunning(ctx))
+	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, vcs.Pending, "atlantis/plan", "Plan Pending", atlantisURL, vcs.Github)
+
+	t.Log("with a job the status should link to it")
+	ctx.JobURL = "https://atlantis.example.com/jobs/abc"
+	Ok(t, s.UpdateRunning(ctx))
+	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, vcs.Pending, "atlantis/plan", "Plan Running, follow along on Atlantis", ctx.JobURL, vcs.Github)
+}
+
+func TestUpdateProjectResult_Job(t *testing.T) {
+	t.Log("statuses should link to the command's job so its output can be replayed")
+	RegisterMockTestingT(t)
+	jobURL := "https://atlantis.example.com/jobs/abc"
+	ctx := &events.CommandContext{
+		BaseRepo: repoModel,
+		Pull:     pullModel,
+		Command:  &events.Command{Name: events.Apply, Workspace: "default"},
+		VCSHost:  vcs.Github,
+		JobURL:   jobURL,
+	}
+	client := mocks.NewMockClientProxy()
+	s := events.DefaultCommitStatusUpdater{Client: client, AtlantisURL: atlantisURL}
+	err := s.UpdateProjectResult(ctx, events.CommandResponse{ProjectResults: []events.ProjectResult{{Path: "a", Failure: "failure"}}})
+	Ok(t, err)
+	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, vcs.Failed, "atlantis/apply: a (default)", "Apply Failed", jobURL, vcs.Github)
+	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, vcs.Failed, "atlantis/apply", "Apply Failed", jobURL, vcs.Github)
 }
 
 func TestUpdateProjectResult_Error(t *testing.T) {
@@ -49,10 +92,10 @@ func TestUpdateProjectResult_Error(t *testing.T) {
 		VCSHost:  vcs.Github,
 	}
 	client := mocks.NewMockClientProxy()
-	s := events.DefaultCommitStatusUpdater{Client: client}
+	s := events.DefaultCommitStatusUpdater{Client: client, AtlantisURL: atlantisURL}
 	err := s.UpdateProjectResult(ctx, events.CommandResponse{Error: errors.New("err")})
 	Ok(t, err)
-	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, vcs.Failed, "Plan Failed", vcs.Github)
+	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, vcs.Failed, "atlantis/plan", "Plan Failed", atlantisURL, vcs.Github)
 }
 
 func TestUpdateProjectResult_Failure(t *testing.T) {
@@ -64,14 +107,14 @@ func TestUpdateProjectResult_Failure(t *testing.T) {
 		VCSHost:  vcs.Github,
 	}
 	client := mocks.NewMockClientProxy()
-	s := events.DefaultCommitStatusUpdater{Client: client}
+	s := events.DefaultCommitStatusUpdater{Client: client, AtlantisURL: atlantisURL}
 	err := s.UpdateProjectResult(ctx, events.CommandResponse{Failure: "failure"})
 	Ok(t, err)
-	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, vcs.Failed, "Plan Failed", vcs.Github)
+	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, vcs.Failed, "atlantis/plan", "Plan Failed", atlantisURL, vcs.Github)
 }
 
 func TestUpdateProjectResult(t *testing.T) {
-	t.Log("should use worst status")
+	t.Log("should use worst status for the whole command")
 	RegisterMockTestingT(t)
 
 	ctx := &events.CommandContext{
@@ -117,7 +160,7 @@ func TestUpdateProjectResult(t *testing.T) {
 
 	for _, c := range cases {
 		var results []events.ProjectResult
-		for _, statusStr := range c.Statuses {
+		for i, statusStr := range c.Statuses {
 			var result events.ProjectResult
 			switch statusStr {
 			case "failure":
@@ -127,14 +170,74 @@ func TestUpdateProjectResult(t *testing.T) {
 			default:
 				result = events.ProjectResult{}
 			}
+			result.Path = fmt.Sprintf("dir%d", i)
 			results = append(results, result)
 		}
 		resp := events.CommandResponse{ProjectResults: results}
 
 		client := mocks.NewMockClientProxy()
-		s := events.DefaultCommitStatusUpdater{Client: client}
+		s := events.DefaultCommitStatusUpdater{Client: client, AtlantisURL: atlantisURL}
 		err := s.UpdateProjectResult(ctx, resp)
 		Ok(t, err)
-		client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, c.Expected, "Plan "+strings.Title(c.Expected.String()), vcs.Github)
+		client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, c.Expected, "atlantis/plan", "Plan "+strings.Title(c.Expected.String()), atlantisURL, vcs.Github)
 	}
 }
+
+func TestUpdateProjectResult_PerProject(t *testing.T) {
+	t.Log("should set a status for each project and workspace linking to its lock if it has one")
+	RegisterMockTestingT(t)
+	ctx := &events.CommandContext{
+		BaseRepo: repoModel,
+		Pull:     pullModel,
+		Command:  &events.Command{Name: events.Plan, Workspace: "staging"},
+		VCSHost:  vcs.Gitlab,
+	}
+	client := mocks.NewMockClientProxy()
+	s := events.DefaultCommitStatusUpdater{Client: client, AtlantisURL: atlantisURL}
+	err := s.UpdateProjectResult(ctx, events.CommandResponse{
+		ProjectResults: []events.ProjectResult{
+			{Path: "infra/vpc", PlanSuccess: &events.PlanSuccess{LockURL: "lock-url"}},
+			{Path: "infra/db", Error: errors.New("err")},
+		},
+	})
+	Ok(t, err)
+	inOrder := &InOrderContext{}
+	client.VerifyWasCalledInOrder(Once(), inOrder).UpdateStatus(repoModel, pullModel, vcs.Success, "atlantis/plan: infra/vpc (staging)", "Plan Success", "lock-url", vcs.Gitlab)
+	client.VerifyWasCalledInOrder(Once(), inOrder).UpdateStatus(repoModel, pullModel, vcs.Failed, "atlantis/plan: infra/db (staging)", "Plan Failed", atlantisURL, vcs.Gitlab)
+	client.VerifyWasCalledInOrder(Once(), inOrder).UpdateStatus(repoModel, pullModel, vcs.Failed, "atlantis/plan", "Plan Failed", atlantisURL, vcs.Gitlab)
+}
+
+func TestUpdateProjectResult_Apply(t *testing.T) {
+	t.Log("apply statuses should be named after the project like its plan status, not after the plan file")
+	a, repoDir := setupApplyExecutorTest(t, ".", "infra/vpc")
+	defer os.RemoveAll(repoDir) // nolint: errcheck
+	ctx := applyCtx(events.Command{Name: events.Apply, Workspace: "default"})
+	ctx.VCSHost = vcs.Github
+	res := a.Execute(ctx)
+
+	client := mocks.NewMockClientProxy()
+	s := events.DefaultCommitStatusUpdater{Client: client, AtlantisURL: atlantisURL}
+	Ok(t, s.UpdateProjectResult(ctx, res))
+	inOrder := &InOrderContext{}
+	client.VerifyWasCalledInOrder(Once(), inOrder).UpdateStatus(repoModel, pullModel, vcs.Failed, "atlantis/apply: . (default)", "Apply Failed", atlantisURL, vcs.Github)
+	client.VerifyWasCalledInOrder(Once(), inOrder).UpdateStatus(repoModel, pullModel, vcs.Failed, "atlantis/apply: infra/vpc (default)", "Apply Failed", atlantisURL, vcs.Github)
+	client.VerifyWasCalledInOrder(Once(), inOrder).UpdateStatus(repoModel, pullModel, vcs.Failed, "atlantis/apply", "Apply Failed", atlantisURL, vcs.Github)
+}
+
+func TestUpdateProjectResult_Err(t *testing.T) {
+	t.Log("should stop and return the error if a status can't be set")
+	RegisterMockTestingT(t)
+	ctx := &events.CommandContext{
+		BaseRepo: repoModel,
+		Pull:     pullModel,
+		Command:  &events.Command{Name: events.Apply, Workspace: "default"},
+		VCSHost:  vcs.Github,
+	}
+	client := mocks.NewMockClientProxy()
+	When(client.UpdateStatus(repoModel, pullModel, vcs.Success, "atlantis/apply: dir (default)", "Apply Success", atlantisURL, vcs.Github)).ThenReturn(errors.New("err"))
+	s := events.DefaultCommitStatusUpdater{Client: client, AtlantisURL: atlantisURL}
+	err := s.UpdateProjectResult(ctx, events.CommandResponse{ProjectResults: []events.ProjectResult{{Path: "dir", ApplySuccess: "success"}}})
+	Assert(t, err != nil, "exp err")
+	Equals(t, "err", err.Error())
+	client.VerifyWasCalled(Never()).UpdateStatus(repoModel, pullModel, vcs.Success, "atlantis/apply", "Apply Success", atlantisURL, vcs.Github)
+}
@@ -1,42 +1,98 @@
 package events
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/google/go-github/github"
 	"github.com/hootsuite/atlantis/server/events/models"
 	"github.com/hootsuite/atlantis/server/events/vcs"
+	"github.com/hootsuite/atlantis/server/events/vcs/azuredevops"
+	"github.com/hootsuite/atlantis/server/events/vcs/bitbucketcloud"
+	"github.com/hootsuite/atlantis/server/events/vcs/bitbucketserver"
+	"github.com/hootsuite/atlantis/server/events/vcs/gitea"
+	"github.com/hootsuite/atlantis/server/events/vcs/githubchecks"
 	"github.com/lkysow/go-gitlab"
 )
 
 const gitlabPullOpened = "opened"
 
+// dirFlag is the flag used to target a single directory in a comment,
+// ex. atlantis plan -d path/to/project.
+const dirFlag = "-d"
+
+// projectFlag is the flag used to target a single project by the name it's
+// given in the repo's atlantis.yaml, ex. atlantis plan -p vpc.
+const projectFlag = "-p"
+
+// DefaultWorkspace is the Terraform workspace commands run in if one isn't
+// specified.
+const DefaultWorkspace = "default"
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_event_parsing.go EventParsing
 
 type Command struct {
 	Name      CommandName
 	Workspace string
 	Verbose   bool
-	Flags     []string
+	// Dir is the path to the project the command should run in, relative to
+	// the repo root. If empty, the command runs in every project.
+	Dir string
+	// ProjectName is the name of the project from the repo's atlantis.yaml
+	// that the command should run in. If empty, the command runs in every
+	// project. Only one of Dir and ProjectName will be set.
+	ProjectName string
+	Flags       []string
+	// Autoplan is true if the command wasn't commented by a user but was
+	// instead triggered by a pull request being opened or updated.
+	Autoplan bool
+	// Failure is set if the command's flags are invalid, ex. its -d dir is
+	// outside of the repo. Instead of running, the failure is commented on
+	// the pull request.
+	Failure string
 }
 
 type EventParsing interface {
 	DetermineCommand(comment string, vcsHost vcs.Host) (*Command, error)
 	ParseGithubIssueCommentEvent(comment *github.IssueCommentEvent) (baseRepo models.Repo, user models.User, pullNum int, err error)
 	ParseGithubPull(pull *github.PullRequest) (models.PullRequest, models.Repo, error)
+	ParseGithubCheckRunEvent(body []byte) (baseRepo models.Repo, user models.User, pullNum int, cmd *Command, err error)
 	ParseGithubRepo(ghRepo *github.Repository) (models.Repo, error)
-	ParseGitlabMergeEvent(event gitlab.MergeEvent) (models.PullRequest, models.Repo)
+	ParseGitlabMergeEvent(event gitlab.MergeEvent) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo)
 	ParseGitlabMergeCommentEvent(event gitlab.MergeCommentEvent) (baseRepo models.Repo, headRepo models.Repo, user models.User)
 	ParseGitlabMergeRequest(mr *gitlab.MergeRequest) models.PullRequest
+	ParseBitbucketCloudCommentEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, user models.User, comment string, err error)
+	ParseBitbucketCloudPullEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error)
+	ParseBitbucketCloudPull(pull bitbucketcloud.PullRequest) (pullModel models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error)
+	ParseBitbucketServerCommentEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, user models.User, comment string, err error)
+	ParseBitbucketServerPullEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error)
+	ParseBitbucketServerPull(pull bitbucketserver.PullRequest) (pullModel models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error)
+	ParseAzureDevopsCommentEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, user models.User, comment string, err error)
+	ParseAzureDevopsPullEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error)
+	ParseAzureDevopsPull(pull azuredevops.PullRequest) (pullModel models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error)
+	ParseGiteaIssueCommentEvent(body []byte) (baseRepo models.Repo, user models.User, pullNum int, comment string, err error)
+	ParseGiteaPullEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error)
+	ParseGiteaPull(pull gitea.PullRequest) (pullModel models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error)
 }
 
 type EventParser struct {
-	GithubUser  string
-	GithubToken string
-	GitlabUser  string
-	GitlabToken string
+	GithubUser     string
+	GithubToken    string
+	GitlabUser     string
+	GitlabToken    string
+	BitbucketUser  string
+	BitbucketToken string
+	// BitbucketServerURL is the URL of the Bitbucket Server installation, ex.
+	// https://bitbucket.example.com. It's used to build clone URLs since
+	// Bitbucket Server's webhooks don't include them.
+	BitbucketServerURL string
+	AzureDevopsUser    string
+	AzureDevopsToken   string
+	GiteaUser          string
+	GiteaToken         string
 }
 
 // DetermineCommand parses the comment as an atlantis command. If it succeeds,
@@ -45,39 +101,70 @@ type EventParser struct {
 func (e *EventParser) DetermineCommand(comment string, vcsHost vcs.Host) (*Command, error) {
 	// valid commands contain:
 	// the initial "executable" name, 'run' or 'atlantis' or '@GithubUser' where GithubUser is the api user atlantis is running as
-	// then a command, either 'plan', 'apply', or 'help'
-	// then an optional workspace argument, an optional --verbose flag and any other flags
+	// then a command, either 'plan', 'apply', 'unlock', 'cancel' or 'help'
+	// then an optional workspace argument, an optional --verbose flag, an optional
+	// -d flag to run in a single directory or -p flag to run in a single named
+	// project and any other flags
 	//
 	// examples:
 	// atlantis help
+	// atlantis unlock
+	// atlantis cancel
 	// run plan
 	// @GithubUser plan staging
 	// atlantis plan staging --verbose
 	// atlantis plan staging --verbose -key=value -key2 value2
+	// atlantis plan staging -d path/to/project
+	// atlantis apply -p project-name
 	err := errors.New("not an Atlantis command")
 	args := strings.Fields(comment)
 	if len(args) < 2 {
 		return nil, err
 	}
 
-	workspace := "default"
+	workspace := DefaultWorkspace
 	verbose := false
+	dir := ""
+	projectName := ""
 	var flags []string
 
 	vcsUser := e.GithubUser
-	if vcsHost == vcs.Gitlab {
+	switch vcsHost {
+	case vcs.Gitlab:
 		vcsUser = e.GitlabUser
+	case vcs.Bitbucket, vcs.BitbucketServer:
+		vcsUser = e.BitbucketUser
+	case vcs.AzureDevops:
+		vcsUser = e.AzureDevopsUser
+	case vcs.Gitea:
+		vcsUser = e.GiteaUser
 	}
 	if !e.stringInSlice(args[0], []string{"run", "atlantis", "@" + vcsUser}) {
 		return nil, err
 	}
-	if !e.stringInSlice(args[1], []string{"plan", "apply", "help"}) {
+	if !e.stringInSlice(args[1], []string{"plan", "apply", "unlock", "cancel", "help"}) {
 		return nil, err
 	}
 	if args[1] == "help" {
 		return &Command{Name: Help}, nil
 	}
-	command := args[1]
+	if args[1] == "unlock" {
+		// Unlock applies to every workspace so we don't parse any more args.
+		return &Command{Name: Unlock, Workspace: workspace}, nil
+	}
+	if args[1] == "cancel" {
+		// Cancel applies to every command running for the pull request.
+		return &Command{Name: Cancel, Workspace: workspace}, nil
+	}
+	c := &Command{Workspace: workspace}
+	switch args[1] {
+	case "plan":
+		c.Name = Plan
+	case "apply":
+		c.Name = Apply
+	default:
+		return nil, fmt.Errorf("something went wrong parsing the command, the command we parsed %q was not apply or plan", args[1])
+	}
 
 	if len(args) > 2 {
 		flags = args[2:]
@@ -85,7 +172,7 @@ func (e *EventParser) DetermineCommand(comment string, vcsHost vcs.Host) (*Comma
 		// if the third arg doesn't start with '-' then we assume it's a
 		// workspace, not a flag
 		if !strings.HasPrefix(args[2], "-") {
-			workspace = args[2]
+			c.Workspace = args[2]
 			flags = args[3:]
 		}
 
@@ -95,17 +182,34 @@ func (e *EventParser) DetermineCommand(comment string, vcsHost vcs.Host) (*Comma
 			verbose = true
 			flags = e.removeOccurrences("--verbose", flags)
 		}
-	}
 
-	c := &Command{Verbose: verbose, Workspace: workspace, Flags: flags}
-	switch command {
-	case "plan":
-		c.Name = Plan
-	case "apply":
-		c.Name = Apply
-	default:
-		return nil, fmt.Errorf("something went wrong parsing the command, the command we parsed %q was not apply or plan", command)
+		// -d isn't a Terraform flag so we pull it out and use it ourselves.
+		// Invalid -d and -p flags are reported back on the pull request
+		// since the user clearly meant to run a command.
+		var dirErr error
+		dir, flags, dirErr = e.extractDir(flags)
+		if dirErr != nil {
+			c.Failure = dirErr.Error()
+			return c, nil
+		}
+
+		// So is -p, which targets a project by its name in atlantis.yaml.
+		var projectErr error
+		projectName, flags, projectErr = e.extractProject(flags)
+		if projectErr != nil {
+			c.Failure = projectErr.Error()
+			return c, nil
+		}
+		if dir != "" && projectName != "" {
+			c.Failure = fmt.Sprintf("can't use both %s and %s flags", dirFlag, projectFlag)
+			return c, nil
+		}
 	}
+
+	c.Verbose = verbose
+	c.Dir = dir
+	c.ProjectName = projectName
+	c.Flags = flags
 	return c, nil
 }
 
@@ -130,6 +234,62 @@ func (e *EventParser) ParseGithubIssueCommentEvent(comment *github.IssueCommentE
 	return
 }
 
+// ParseGithubCheckRunEvent parses the body of a check_run event sent when a
+// user clicks a button on one of our check runs. cmd is the command for the
+// button and the project and workspace of the check run.
+func (e *EventParser) ParseGithubCheckRunEvent(body []byte) (baseRepo models.Repo, user models.User, pullNum int, cmd *Command, err error) {
+	var event githubchecks.CheckRunEvent
+	if err = json.Unmarshal(body, &event); err != nil {
+		err = fmt.Errorf("parsing json: %s", err)
+		return
+	}
+	if event.Repository == nil {
+		err = errors.New("repository is null")
+		return
+	}
+	baseRepo, err = e.ParseGithubRepo(event.Repository)
+	if err != nil {
+		return
+	}
+	if event.Sender.GetLogin() == "" {
+		err = errors.New("sender.login is null")
+		return
+	}
+	user = models.User{Username: event.Sender.GetLogin()}
+	// Check runs for pull requests from forks aren't linked to the pull
+	// request.
+	if len(event.CheckRun.PullRequests) == 0 {
+		err = errors.New("check_run.pull_requests is empty")
+		return
+	}
+	pullNum = event.CheckRun.PullRequests[0].Number
+
+	if event.RequestedAction == nil {
+		err = errors.New("requested_action is null")
+		return
+	}
+	var name CommandName
+	switch event.RequestedAction.Identifier {
+	case PlanCheckRunAction:
+		name = Plan
+	case ApplyCheckRunAction:
+		name = Apply
+	default:
+		err = fmt.Errorf("unknown requested action %q", event.RequestedAction.Identifier)
+		return
+	}
+	var externalID githubchecks.ExternalID
+	if err = json.Unmarshal([]byte(event.CheckRun.ExternalID), &externalID); err != nil {
+		err = fmt.Errorf("parsing check_run.external_id: %s", err)
+		return
+	}
+	if externalID.Workspace == "" {
+		externalID.Workspace = DefaultWorkspace
+	}
+	cmd = &Command{Name: name, Dir: externalID.Dir, Workspace: externalID.Workspace}
+	return
+}
+
 func (e *EventParser) ParseGithubPull(pull *github.PullRequest) (models.PullRequest, models.Repo, error) {
 	var pullModel models.PullRequest
 	var headRepoModel models.Repo
@@ -194,8 +354,13 @@ func (e *EventParser) ParseGithubRepo(ghRepo *github.Repository) (models.Repo, e
 		return repo, errors.New("repository.clone_url is null")
 	}
 
-	// Construct HTTPS repo clone url string with username and password.
-	repoCloneURL := strings.Replace(repoSanitizedCloneURL, "https://", fmt.Sprintf("https://%s:%s@", e.GithubUser, e.GithubToken), -1)
+	// Construct HTTPS repo clone url string with username and password. If
+	// there's no token we're authenticating as a GitHub App and the
+	// workspace adds an installation token when it clones.
+	repoCloneURL := repoSanitizedCloneURL
+	if e.GithubToken != "" {
+		repoCloneURL = strings.Replace(repoSanitizedCloneURL, "https://", fmt.Sprintf("https://%s:%s@", e.GithubUser, e.GithubToken), -1)
+	}
 
 	return models.Repo{
 		Owner:             repoOwner,
@@ -206,7 +371,10 @@ func (e *EventParser) ParseGithubRepo(ghRepo *github.Repository) (models.Repo, e
 	}, nil
 }
 
-func (e *EventParser) ParseGitlabMergeEvent(event gitlab.MergeEvent) (models.PullRequest, models.Repo) {
+// ParseGitlabMergeEvent creates Atlantis models out of a GitLab merge request
+// event. If the merge request's source project is unknown, headRepo will be
+// the same as baseRepo.
+func (e *EventParser) ParseGitlabMergeEvent(event gitlab.MergeEvent) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo) {
 	modelState := models.Closed
 	if event.ObjectAttributes.State == gitlabPullOpened {
 		modelState = models.Open
@@ -214,7 +382,7 @@ func (e *EventParser) ParseGitlabMergeEvent(event gitlab.MergeEvent) (models.Pul
 	// GitLab also has a "merged" state, but we map that to Closed so we don't
 	// need to check for it.
 
-	pull := models.PullRequest{
+	pull = models.PullRequest{
 		URL:        event.ObjectAttributes.URL,
 		Author:     event.User.Username,
 		Num:        event.ObjectAttributes.IID,
@@ -227,14 +395,26 @@ func (e *EventParser) ParseGitlabMergeEvent(event gitlab.MergeEvent) (models.Pul
 	// Get owner and name from PathWithNamespace because the fields
 	// event.Project.Name and event.Project.Owner can have capitals.
 	owner, name := e.getOwnerAndName(event.Project.PathWithNamespace)
-	repo := models.Repo{
+	baseRepo = models.Repo{
 		FullName:          event.Project.PathWithNamespace,
 		Name:              name,
 		SanitizedCloneURL: event.Project.GitHTTPURL,
 		Owner:             owner,
 		CloneURL:          cloneURL,
 	}
-	return pull, repo
+
+	headRepo = baseRepo
+	if source := event.ObjectAttributes.Source; source != nil && source.PathWithNamespace != "" {
+		owner, name = e.getOwnerAndName(source.PathWithNamespace)
+		headRepo = models.Repo{
+			FullName:          source.PathWithNamespace,
+			Name:              name,
+			SanitizedCloneURL: source.GitHTTPURL,
+			Owner:             owner,
+			CloneURL:          e.addGitlabAuth(source.GitHTTPURL),
+		}
+	}
+	return
 }
 
 // addGitlabAuth adds gitlab username/password to the cloneURL.
@@ -300,6 +480,505 @@ func (e *EventParser) ParseGitlabMergeRequest(mr *gitlab.MergeRequest) models.Pu
 	}
 }
 
+// ParseBitbucketCloudCommentEvent parses the body of a Bitbucket Cloud
+// pullrequest:comment_created event.
+func (e *EventParser) ParseBitbucketCloudCommentEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, user models.User, comment string, err error) {
+	var event bitbucketcloud.CommentEvent
+	if err = json.Unmarshal(body, &event); err != nil {
+		err = fmt.Errorf("parsing json: %s", err)
+		return
+	}
+	pull, baseRepo, headRepo, err = e.ParseBitbucketCloudPull(event.PullRequest)
+	if err != nil {
+		return
+	}
+	if event.Actor.Username == "" {
+		err = errors.New("actor.username is null")
+		return
+	}
+	user = models.User{Username: event.Actor.Username}
+	comment = event.Comment.Content.Raw
+	return
+}
+
+// ParseBitbucketCloudPullEvent parses the body of a Bitbucket Cloud
+// pullrequest:created, updated, fulfilled or rejected event.
+func (e *EventParser) ParseBitbucketCloudPullEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error) {
+	var event bitbucketcloud.PullRequestEvent
+	if err = json.Unmarshal(body, &event); err != nil {
+		err = fmt.Errorf("parsing json: %s", err)
+		return
+	}
+	return e.ParseBitbucketCloudPull(event.PullRequest)
+}
+
+// ParseBitbucketCloudPull creates Atlantis models out of a Bitbucket Cloud
+// pull request. baseRepo is the repo the pull request is into and headRepo is
+// the repo it's from, which differ for forks.
+func (e *EventParser) ParseBitbucketCloudPull(pull bitbucketcloud.PullRequest) (pullModel models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error) {
+	switch {
+	case pull.ID == 0:
+		err = errors.New("pullrequest.id is null")
+	case pull.Source.Commit.Hash == "":
+		err = errors.New("pullrequest.source.commit.hash is null")
+	case pull.Source.Branch.Name == "":
+		err = errors.New("pullrequest.source.branch.name is null")
+	case pull.Links.HTML.HREF == "":
+		err = errors.New("pullrequest.links.html.href is null")
+	case pull.Author.Username == "":
+		err = errors.New("pullrequest.author.username is null")
+	}
+	if err != nil {
+		return
+	}
+	baseRepo, err = e.parseBitbucketCloudRepo(pull.Destination.Repository, "pullrequest.destination.repository")
+	if err != nil {
+		return
+	}
+	headRepo, err = e.parseBitbucketCloudRepo(pull.Source.Repository, "pullrequest.source.repository")
+	if err != nil {
+		return
+	}
+
+	// Bitbucket also has merged, declined and superseded states but we map
+	// them all to Closed.
+	pullState := models.Closed
+	if pull.State == bitbucketcloud.OpenState {
+		pullState = models.Open
+	}
+	pullModel = models.PullRequest{
+		Num:        pull.ID,
+		HeadCommit: pull.Source.Commit.Hash,
+		URL:        pull.Links.HTML.HREF,
+		Branch:     pull.Source.Branch.Name,
+		Author:     pull.Author.Username,
+		State:      pullState,
+	}
+	return
+}
+
+// parseBitbucketCloudRepo creates an Atlantis model out of a Bitbucket Cloud
+// repo. field is the repo's path in the JSON and is used in errors.
+func (e *EventParser) parseBitbucketCloudRepo(repo bitbucketcloud.Repository, field string) (models.Repo, error) {
+	if repo.FullName == "" {
+		return models.Repo{}, fmt.Errorf("%s.full_name is null", field)
+	}
+	if repo.Links.HTML.HREF == "" {
+		return models.Repo{}, fmt.Errorf("%s.links.html.href is null", field)
+	}
+	// Bitbucket doesn't include the clone URL in its payloads but it's the
+	// repo's URL with .git on the end, ex. https://bitbucket.org/owner/repo.git.
+	sanitizedCloneURL := repo.Links.HTML.HREF + ".git"
+	cloneURL := strings.Replace(sanitizedCloneURL, "https://", fmt.Sprintf("https://%s:%s@", e.BitbucketUser, e.BitbucketToken), -1)
+	owner, name := e.getOwnerAndName(repo.FullName)
+	return models.Repo{
+		FullName:          repo.FullName,
+		Owner:             owner,
+		Name:              name,
+		CloneURL:          cloneURL,
+		SanitizedCloneURL: sanitizedCloneURL,
+	}, nil
+}
+
+// ParseBitbucketServerCommentEvent parses the body of a Bitbucket Server
+// pr:comment:added event.
+func (e *EventParser) ParseBitbucketServerCommentEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, user models.User, comment string, err error) {
+	var event bitbucketserver.CommentEvent
+	if err = json.Unmarshal(body, &event); err != nil {
+		err = fmt.Errorf("parsing json: %s", err)
+		return
+	}
+	pull, baseRepo, headRepo, err = e.ParseBitbucketServerPull(event.PullRequest)
+	if err != nil {
+		return
+	}
+	if event.Actor.Name == "" {
+		err = errors.New("actor.name is null")
+		return
+	}
+	user = models.User{Username: event.Actor.Name}
+	comment = event.Comment.Text
+	return
+}
+
+// ParseBitbucketServerPullEvent parses the body of a Bitbucket Server
+// pr:opened, pr:from_ref_updated, pr:merged or pr:declined event.
+func (e *EventParser) ParseBitbucketServerPullEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error) {
+	var event bitbucketserver.PullRequestEvent
+	if err = json.Unmarshal(body, &event); err != nil {
+		err = fmt.Errorf("parsing json: %s", err)
+		return
+	}
+	return e.ParseBitbucketServerPull(event.PullRequest)
+}
+
+// ParseBitbucketServerPull creates Atlantis models out of a Bitbucket Server
+// pull request. baseRepo is the repo the pull request is into and headRepo is
+// the repo it's from, which differ for forks.
+func (e *EventParser) ParseBitbucketServerPull(pull bitbucketserver.PullRequest) (pullModel models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error) {
+	switch {
+	case pull.ID == 0:
+		err = errors.New("pullRequest.id is null")
+	case pull.FromRef.LatestCommit == "":
+		err = errors.New("pullRequest.fromRef.latestCommit is null")
+	case pull.FromRef.DisplayID == "":
+		err = errors.New("pullRequest.fromRef.displayId is null")
+	case len(pull.Links.Self) == 0 || pull.Links.Self[0].HREF == "":
+		err = errors.New("pullRequest.links.self is null")
+	case pull.Author.User.Name == "":
+		err = errors.New("pullRequest.author.user.name is null")
+	}
+	if err != nil {
+		return
+	}
+	baseRepo, err = e.parseBitbucketServerRepo(pull.ToRef.Repository, "pullRequest.toRef.repository")
+	if err != nil {
+		return
+	}
+	headRepo, err = e.parseBitbucketServerRepo(pull.FromRef.Repository, "pullRequest.fromRef.repository")
+	if err != nil {
+		return
+	}
+
+	// Bitbucket Server also has merged and declined states but we map them
+	// both to Closed.
+	pullState := models.Closed
+	if pull.State == bitbucketserver.OpenState {
+		pullState = models.Open
+	}
+	pullModel = models.PullRequest{
+		Num:        pull.ID,
+		HeadCommit: pull.FromRef.LatestCommit,
+		URL:        pull.Links.Self[0].HREF,
+		Branch:     pull.FromRef.DisplayID,
+		Author:     pull.Author.User.Name,
+		State:      pullState,
+	}
+	return
+}
+
+// parseBitbucketServerRepo creates an Atlantis model out of a Bitbucket
+// Server repo. Its full name is its project key and slug, ex. "PROJ/repo".
+// field is the repo's path in the JSON and is used in errors.
+func (e *EventParser) parseBitbucketServerRepo(repo bitbucketserver.Repository, field string) (models.Repo, error) {
+	if repo.Project.Key == "" {
+		return models.Repo{}, fmt.Errorf("%s.project.key is null", field)
+	}
+	if repo.Slug == "" {
+		return models.Repo{}, fmt.Errorf("%s.slug is null", field)
+	}
+	// Bitbucket Server doesn't include the clone URL in its payloads but it's
+	// always under /scm, ex. https://bitbucket.example.com/scm/proj/repo.git.
+	sanitizedCloneURL := fmt.Sprintf("%s/scm/%s/%s.git", strings.TrimRight(e.BitbucketServerURL, "/"), strings.ToLower(repo.Project.Key), repo.Slug)
+	cloneURL, err := url.Parse(sanitizedCloneURL)
+	if err != nil {
+		return models.Repo{}, fmt.Errorf("parsing clone URL %q: %s", sanitizedCloneURL, err)
+	}
+	cloneURL.User = url.UserPassword(e.BitbucketUser, e.BitbucketToken)
+	return models.Repo{
+		FullName:          fmt.Sprintf("%s/%s", repo.Project.Key, repo.Slug),
+		Owner:             repo.Project.Key,
+		Name:              repo.Slug,
+		CloneURL:          cloneURL.String(),
+		SanitizedCloneURL: sanitizedCloneURL,
+	}, nil
+}
+
+// ParseAzureDevopsCommentEvent parses the body of an Azure DevOps
+// git.pullrequest.commented service hook event.
+func (e *EventParser) ParseAzureDevopsCommentEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, user models.User, comment string, err error) {
+	var event azuredevops.CommentEvent
+	if err = json.Unmarshal(body, &event); err != nil {
+		err = fmt.Errorf("parsing json: %s", err)
+		return
+	}
+	pull, baseRepo, headRepo, err = e.ParseAzureDevopsPull(event.Resource.PullRequest)
+	if err != nil {
+		return
+	}
+	if event.Resource.Comment.Author.UniqueName == "" {
+		err = errors.New("resource.comment.author.uniqueName is null")
+		return
+	}
+	user = models.User{Username: event.Resource.Comment.Author.UniqueName}
+	comment = event.Resource.Comment.Content
+	return
+}
+
+// ParseAzureDevopsPullEvent parses the body of an Azure DevOps
+// git.pullrequest.created or git.pullrequest.updated service hook event.
+func (e *EventParser) ParseAzureDevopsPullEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error) {
+	var event azuredevops.PullRequestEvent
+	if err = json.Unmarshal(body, &event); err != nil {
+		err = fmt.Errorf("parsing json: %s", err)
+		return
+	}
+	return e.ParseAzureDevopsPull(event.Resource)
+}
+
+// ParseAzureDevopsPull creates Atlantis models out of an Azure DevOps pull
+// request. baseRepo is the repo the pull request is into and headRepo is the
+// repo it's from, which differ for forks.
+func (e *EventParser) ParseAzureDevopsPull(pull azuredevops.PullRequest) (pullModel models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error) {
+	switch {
+	case pull.PullRequestID == 0:
+		err = errors.New("pullRequestId is null")
+	case pull.LastMergeSourceCommit.CommitID == "":
+		err = errors.New("lastMergeSourceCommit.commitId is null")
+	case pull.SourceRefName == "":
+		err = errors.New("sourceRefName is null")
+	case pull.CreatedBy.UniqueName == "":
+		err = errors.New("createdBy.uniqueName is null")
+	}
+	if err != nil {
+		return
+	}
+	baseRepo, err = e.parseAzureDevopsRepo(pull.Repository, "repository")
+	if err != nil {
+		return
+	}
+	headRepo = baseRepo
+	if pull.ForkSource != nil {
+		headRepo, err = e.parseAzureDevopsRepo(pull.ForkSource.Repository, "forkSource.repository")
+		if err != nil {
+			return
+		}
+	}
+
+	// Abandoned and completed pull requests are both Closed.
+	pullState := models.Closed
+	if pull.Status == azuredevops.ActiveStatus {
+		pullState = models.Open
+	}
+	pullModel = models.PullRequest{
+		Num:        pull.PullRequestID,
+		HeadCommit: pull.LastMergeSourceCommit.CommitID,
+		URL:        fmt.Sprintf("%s/pullrequest/%d", baseRepo.SanitizedCloneURL, pull.PullRequestID),
+		Branch:     strings.TrimPrefix(pull.SourceRefName, "refs/heads/"),
+		Author:     pull.CreatedBy.UniqueName,
+		State:      pullState,
+	}
+	return
+}
+
+// parseAzureDevopsRepo creates an Atlantis model out of an Azure DevOps repo.
+// Its full name is its organization, project and name, ex. "org/project/repo",
+// so that its owner is "org/project". field is the repo's path in the JSON
+// and is used in errors.
+func (e *EventParser) parseAzureDevopsRepo(repo azuredevops.Repository, field string) (models.Repo, error) {
+	switch {
+	case repo.Name == "":
+		return models.Repo{}, fmt.Errorf("%s.name is null", field)
+	case repo.Project.Name == "":
+		return models.Repo{}, fmt.Errorf("%s.project.name is null", field)
+	case repo.URL == "":
+		return models.Repo{}, fmt.Errorf("%s.url is null", field)
+	}
+	org, err := azureDevopsOrg(repo.URL)
+	if err != nil {
+		return models.Repo{}, fmt.Errorf("%s.url: %s", field, err)
+	}
+
+	// The payloads don't always include the clone URL but it's always under
+	// the project's _git path.
+	sanitizedCloneURL := fmt.Sprintf("https://dev.azure.com/%s/%s/_git/%s", url.PathEscape(org), url.PathEscape(repo.Project.Name), url.PathEscape(repo.Name))
+	cloneURL, err := url.Parse(sanitizedCloneURL)
+	if err != nil {
+		return models.Repo{}, fmt.Errorf("parsing clone URL %q: %s", sanitizedCloneURL, err)
+	}
+	cloneURL.User = url.UserPassword(e.AzureDevopsUser, e.AzureDevopsToken)
+	owner := fmt.Sprintf("%s/%s", org, repo.Project.Name)
+	return models.Repo{
+		FullName:          fmt.Sprintf("%s/%s", owner, repo.Name),
+		Owner:             owner,
+		Name:              repo.Name,
+		CloneURL:          cloneURL.String(),
+		SanitizedCloneURL: sanitizedCloneURL,
+	}, nil
+}
+
+// azureDevopsOrg returns the organization from a repo's API URL, ex.
+// https://dev.azure.com/org/_apis/git/repositories/<id> or, for
+// organizations that haven't moved to dev.azure.com,
+// https://org.visualstudio.com/_apis/git/repositories/<id>.
+func azureDevopsOrg(repoURL string) (string, error) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(u.Host, ".visualstudio.com") {
+		return strings.TrimSuffix(u.Host, ".visualstudio.com"), nil
+	}
+	if org := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")[0]; u.Host == "dev.azure.com" && org != "" {
+		return org, nil
+	}
+	return "", fmt.Errorf("can't determine organization from %q", repoURL)
+}
+
+// ParseGiteaIssueCommentEvent parses the body of a Gitea issue_comment
+// event. The pull request isn't in the payload so only its number is
+// returned.
+func (e *EventParser) ParseGiteaIssueCommentEvent(body []byte) (baseRepo models.Repo, user models.User, pullNum int, comment string, err error) {
+	var event gitea.IssueCommentPayload
+	if err = json.Unmarshal(body, &event); err != nil {
+		err = fmt.Errorf("parsing json: %s", err)
+		return
+	}
+	if event.Comment.User.Login == "" {
+		err = errors.New("comment.user.login is null")
+		return
+	}
+	if event.Issue.Number == 0 {
+		err = errors.New("issue.number is null")
+		return
+	}
+	baseRepo, err = e.parseGiteaRepo(&event.Repository, "repository")
+	if err != nil {
+		return
+	}
+	user = models.User{Username: event.Comment.User.Login}
+	pullNum = event.Issue.Number
+	comment = event.Comment.Body
+	return
+}
+
+// ParseGiteaPullEvent parses the body of a Gitea pull_request event.
+func (e *EventParser) ParseGiteaPullEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error) {
+	var event gitea.PullRequestPayload
+	if err = json.Unmarshal(body, &event); err != nil {
+		err = fmt.Errorf("parsing json: %s", err)
+		return
+	}
+	return e.ParseGiteaPull(event.PullRequest)
+}
+
+// ParseGiteaPull creates Atlantis models out of a Gitea pull request.
+// baseRepo is the repo the pull request is into and headRepo is the repo
+// it's from, which differ for forks.
+func (e *EventParser) ParseGiteaPull(pull gitea.PullRequest) (pullModel models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error) {
+	switch {
+	case pull.Number == 0:
+		err = errors.New("number is null")
+	case pull.Head.SHA == "":
+		err = errors.New("head.sha is null")
+	case pull.Head.Ref == "":
+		err = errors.New("head.ref is null")
+	case pull.HTMLURL == "":
+		err = errors.New("html_url is null")
+	case pull.User.Login == "":
+		err = errors.New("user.login is null")
+	}
+	if err != nil {
+		return
+	}
+	baseRepo, err = e.parseGiteaRepo(pull.Base.Repo, "base.repo")
+	if err != nil {
+		return
+	}
+	headRepo, err = e.parseGiteaRepo(pull.Head.Repo, "head.repo")
+	if err != nil {
+		return
+	}
+
+	pullState := models.Closed
+	if pull.State == gitea.OpenState {
+		pullState = models.Open
+	}
+	pullModel = models.PullRequest{
+		Num:        pull.Number,
+		HeadCommit: pull.Head.SHA,
+		URL:        pull.HTMLURL,
+		Branch:     pull.Head.Ref,
+		Author:     pull.User.Login,
+		State:      pullState,
+	}
+	return
+}
+
+// parseGiteaRepo creates an Atlantis model out of a Gitea repo. field is the
+// repo's path in the JSON and is used in errors.
+func (e *EventParser) parseGiteaRepo(repo *gitea.Repository, field string) (models.Repo, error) {
+	switch {
+	case repo == nil:
+		return models.Repo{}, fmt.Errorf("%s is null", field)
+	case repo.FullName == "":
+		return models.Repo{}, fmt.Errorf("%s.full_name is null", field)
+	case repo.Owner.Login == "":
+		return models.Repo{}, fmt.Errorf("%s.owner.login is null", field)
+	case repo.Name == "":
+		return models.Repo{}, fmt.Errorf("%s.name is null", field)
+	case repo.CloneURL == "":
+		return models.Repo{}, fmt.Errorf("%s.clone_url is null", field)
+	}
+	cloneURL, err := url.Parse(repo.CloneURL)
+	if err != nil {
+		return models.Repo{}, fmt.Errorf("parsing %s.clone_url: %s", field, err)
+	}
+	cloneURL.User = url.UserPassword(e.GiteaUser, e.GiteaToken)
+	return models.Repo{
+		FullName:          repo.FullName,
+		Owner:             repo.Owner.Login,
+		Name:              repo.Name,
+		CloneURL:          cloneURL.String(),
+		SanitizedCloneURL: repo.CloneURL,
+	}, nil
+}
+
+// extractFlag removes flag and its value from flags and returns the value
+// along with the remaining flags. The flag can be specified as "flag value" or
+// "flag=value". found is false if the flag wasn't specified.
+func (e *EventParser) extractFlag(flag string, flags []string) (value string, found bool, remaining []string, err error) {
+	for i := 0; i < len(flags); i++ {
+		f := flags[i]
+		switch {
+		case f == flag:
+			if i+1 >= len(flags) {
+				return "", false, nil, fmt.Errorf("missing value for %s flag", flag)
+			}
+			value = flags[i+1]
+			found = true
+			i++
+		case strings.HasPrefix(f, flag+"="):
+			value = strings.TrimPrefix(f, flag+"=")
+			found = true
+		default:
+			remaining = append(remaining, f)
+		}
+	}
+	if !found {
+		return "", false, flags, nil
+	}
+	return value, true, remaining, nil
+}
+
+// extractDir removes the -d flag and its value from flags and returns the
+// cleaned directory along with the remaining flags. The directory must be
+// relative to the repo root and can't be outside of it.
+func (e *EventParser) extractDir(flags []string) (string, []string, error) {
+	dir, found, remaining, err := e.extractFlag(dirFlag, flags)
+	if err != nil || !found {
+		return "", remaining, err
+	}
+	cleaned, ok := cleanRepoRelPath(dir)
+	if !ok {
+		return "", nil, fmt.Errorf("invalid value %q for %s flag: must be a relative path inside the repo", dir, dirFlag)
+	}
+	return cleaned, remaining, nil
+}
+
+// extractProject removes the -p flag and its value from flags and returns
+// the project name along with the remaining flags.
+func (e *EventParser) extractProject(flags []string) (string, []string, error) {
+	name, found, remaining, err := e.extractFlag(projectFlag, flags)
+	if err != nil || !found {
+		return "", remaining, err
+	}
+	if name == "" {
+		return "", nil, fmt.Errorf("invalid value %q for %s flag: must be the name of a project", name, projectFlag)
+	}
+	return name, remaining, nil
+}
+
 func (e *EventParser) stringInSlice(a string, list []string) bool {
 	for _, b := range list {
 		if b == a {
@@ -0,0 +1,176 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs"
+	"github.com/hootsuite/atlantis/server/events/vcs/githubchecks"
+)
+
+// Identifiers of the buttons on check runs. They're sent back to us in
+// check_run events when clicked.
+const (
+	PlanCheckRunAction  = "plan"
+	ApplyCheckRunAction = "apply"
+)
+
+// truncatedSuffix is appended to check run output that's too long.
+const truncatedSuffix = "\n\n**Warning**: Output was truncated."
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_github_check_run_creator.go GithubCheckRunCreator
+
+// GithubCheckRunCreator creates GitHub check runs.
+type GithubCheckRunCreator interface {
+	CreateCheckRun(repo models.Repo, run githubchecks.CheckRun) error
+}
+
+// GithubCheckRunUpdater implements CommitStatusUpdater with GitHub check runs
+// instead of commit statuses. It creates a check run for each project and
+// workspace with the project's output and buttons to plan or apply it again,
+// as well as a check run for the whole command. Other VCS hosts don't have
+// check runs so their statuses are updated by Fallback.
+type GithubCheckRunUpdater struct {
+	Client   GithubCheckRunCreator
+	Fallback CommitStatusUpdater
+	Renderer *MarkdownRenderer
+}
+
+// Update creates the check run for the whole command with status.
+func (g *GithubCheckRunUpdater) Update(repo models.Repo, pull models.PullRequest, status vcs.CommitStatus, cmd *Command, host vcs.Host) error {
+	if host != vcs.Github {
+		return g.Fallback.Update(repo, pull, status, cmd, host)
+	}
+	title := fmt.Sprintf("%s %s", strings.Title(cmd.Name.String()), strings.Title(status.String()))
+	return g.Client.CreateCheckRun(repo, g.checkRun(pull, cmd.Name, "", cmd.Workspace, status, title, title, ""))
+}
+
+// UpdateRunning creates the check run for the whole command as in progress.
+// If the command's output is being recorded, the check run links to it.
+func (g *GithubCheckRunUpdater) UpdateRunning(ctx *CommandContext) error {
+	if ctx.VCSHost != vcs.Github {
+		return g.Fallback.UpdateRunning(ctx)
+	}
+	cmd := ctx.Command
+	if ctx.JobURL == "" {
+		return g.Update(ctx.BaseRepo, ctx.Pull, vcs.Pending, cmd, ctx.VCSHost)
+	}
+	title := fmt.Sprintf("%s Running", strings.Title(cmd.Name.String()))
+	summary := fmt.Sprintf("%s, [follow along on Atlantis](%s).", title, ctx.JobURL)
+	run := g.checkRun(ctx.Pull, cmd.Name, "", cmd.Workspace, vcs.Pending, title, summary, "")
+	run.DetailsURL = ctx.JobURL
+	return g.Client.CreateCheckRun(ctx.BaseRepo, run)
+}
+
+// UpdateProjectResult creates a check run for each project in res and then
+// completes the check run for the whole command.
+func (g *GithubCheckRunUpdater) UpdateProjectResult(ctx *CommandContext, res CommandResponse) error {
+	if ctx.VCSHost != vcs.Github {
+		return g.Fallback.UpdateProjectResult(ctx, res)
+	}
+	cmdName := ctx.Command.Name
+	workspace := ctx.Command.Workspace
+	var annotations []githubchecks.Annotation
+	for _, p := range res.ProjectResults {
+		status := p.Status()
+		title := fmt.Sprintf("%s %s", strings.Title(cmdName.String()), strings.Title(status.String()))
+		summary := fmt.Sprintf("**%s** in `%s` for workspace `%s`.", title, p.Path, workspace)
+		text := g.Renderer.Render(CommandResponse{ProjectResults: []ProjectResult{p}}, cmdName, "", false)
+		run := g.checkRun(ctx.Pull, cmdName, p.Path, workspace, status, title, summary, text)
+		run.Actions = g.actions(cmdName, status)
+		run.DetailsURL = ctx.JobURL
+		if status == vcs.Failed {
+			annotation := g.annotation(p, title)
+			run.Output.Annotations = []githubchecks.Annotation{annotation}
+			annotations = append(annotations, annotation)
+		}
+		if err := g.Client.CreateCheckRun(ctx.BaseRepo, run); err != nil {
+			return err
+		}
+	}
+
+	status := res.Status()
+	title := fmt.Sprintf("%s %s", strings.Title(cmdName.String()), strings.Title(status.String()))
+	summary := g.Renderer.Render(res, cmdName, "", false)
+	run := g.checkRun(ctx.Pull, cmdName, "", workspace, status, title, summary, "")
+	run.Output.Annotations = annotations
+	run.DetailsURL = ctx.JobURL
+	return g.Client.CreateCheckRun(ctx.BaseRepo, run)
+}
+
+// checkRun returns a check run for the project in dir and workspace. If dir
+// is empty, it's the check run for the whole command.
+func (g *GithubCheckRunUpdater) checkRun(pull models.PullRequest, cmdName CommandName, dir string, workspace string, status vcs.CommitStatus, title string, summary string, text string) githubchecks.CheckRun {
+	// Marshalling a struct of strings can't fail.
+	externalID, _ := json.Marshal(githubchecks.ExternalID{Dir: dir, Workspace: workspace})
+	run := githubchecks.CheckRun{
+		Name:       statusName(cmdName, dir, workspace),
+		HeadSHA:    pull.HeadCommit,
+		Status:     githubchecks.StatusCompleted,
+		ExternalID: string(externalID),
+		Output: &githubchecks.Output{
+			Title:   title,
+			Summary: g.truncate(summary),
+			Text:    g.truncate(text),
+		},
+	}
+	switch status {
+	case vcs.Pending:
+		run.Status = githubchecks.StatusInProgress
+	case vcs.Success:
+		run.Conclusion = githubchecks.ConclusionSuccess
+	case vcs.Failed:
+		run.Conclusion = githubchecks.ConclusionFailure
+	}
+	return run
+}
+
+// actions returns the buttons for a project's check run. A failed apply can
+// be retried but a successful one can't since there's no plan left.
+func (g *GithubCheckRunUpdater) actions(cmdName CommandName, status vcs.CommitStatus) []githubchecks.Action {
+	replan := githubchecks.Action{
+		Label:       "Re-plan",
+		Description: "Run plan again for this project",
+		Identifier:  PlanCheckRunAction,
+	}
+	apply := githubchecks.Action{
+		Label:       "Apply",
+		Description: "Apply this project's plan",
+		Identifier:  ApplyCheckRunAction,
+	}
+	switch {
+	case cmdName == Plan && status == vcs.Success:
+		return []githubchecks.Action{replan, apply}
+	case cmdName == Plan:
+		return []githubchecks.Action{replan}
+	case cmdName == Apply && status == vcs.Failed:
+		return []githubchecks.Action{replan, apply}
+	}
+	return nil
+}
+
+// annotation marks the failed project's directory with why it failed.
+func (g *GithubCheckRunUpdater) annotation(p ProjectResult, title string) githubchecks.Annotation {
+	message := p.Failure
+	if p.Error != nil {
+		message = p.Error.Error()
+	}
+	return githubchecks.Annotation{
+		Path:            p.Path,
+		StartLine:       1,
+		EndLine:         1,
+		AnnotationLevel: githubchecks.AnnotationFailure,
+		Title:           title,
+		Message:         g.truncate(message),
+	}
+}
+
+// truncate shortens s to the maximum length of check run output.
+func (g *GithubCheckRunUpdater) truncate(s string) string {
+	if len(s) <= githubchecks.MaxOutputLen {
+		return s
+	}
+	return s[:githubchecks.MaxOutputLen-len(truncatedSuffix)] + truncatedSuffix
+}
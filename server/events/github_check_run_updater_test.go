@@ -0,0 +1,206 @@
+package events_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events"
+	"github.com/hootsuite/atlantis/server/events/mocks"
+	"github.com/hootsuite/atlantis/server/events/mocks/matchers"
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs"
+	"github.com/hootsuite/atlantis/server/events/vcs/githubchecks"
+	. "github.com/hootsuite/atlantis/testing"
+	. "github.com/petergtz/pegomock"
+)
+
+var checksRepo = models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}
+var checksPull = models.PullRequest{Num: 1, HeadCommit: "abc123"}
+
+func TestGithubCheckRunUpdater_Fallback(t *testing.T) {
+	t.Log("hosts other than GitHub should get commit statuses from the fallback")
+	u, creator, fallback := setupCheckRunUpdater(t)
+	cmd := events.Command{Name: events.Plan, Workspace: "default"}
+	ctx := &events.CommandContext{BaseRepo: checksRepo, Pull: checksPull, Command: &cmd, VCSHost: vcs.Gitlab}
+
+	Ok(t, u.Update(checksRepo, checksPull, vcs.Pending, &cmd, vcs.Gitlab))
+	Ok(t, u.UpdateRunning(ctx))
+	Ok(t, u.UpdateProjectResult(ctx, events.CommandResponse{}))
+	fallback.VerifyWasCalledOnce().Update(checksRepo, checksPull, vcs.Pending, &cmd, vcs.Gitlab)
+	fallback.VerifyWasCalledOnce().UpdateRunning(ctx)
+	fallback.VerifyWasCalledOnce().UpdateProjectResult(ctx, events.CommandResponse{})
+	creator.VerifyWasCalled(Never()).CreateCheckRun(matchers.AnyModelsRepo(), matchers.AnyGithubchecksCheckRun())
+}
+
+func TestGithubCheckRunUpdater_Update(t *testing.T) {
+	t.Log("should create an in progress check run for the whole command")
+	u, creator, _ := setupCheckRunUpdater(t)
+	err := u.Update(checksRepo, checksPull, vcs.Pending, &events.Command{Name: events.Plan, Workspace: "staging"}, vcs.Github)
+	Ok(t, err)
+	creator.VerifyWasCalledOnce().CreateCheckRun(checksRepo, githubchecks.CheckRun{
+		Name:       "atlantis/plan",
+		HeadSHA:    "abc123",
+		Status:     githubchecks.StatusInProgress,
+		ExternalID: `{"workspace":"staging"}`,
+		Output:     &githubchecks.Output{Title: "Plan Pending", Summary: "Plan Pending"},
+	})
+}
+
+func TestGithubCheckRunUpdater_UpdateRunning(t *testing.T) {
+	t.Log("should create an in progress check run for the whole command that links to its job")
+	u, creator, _ := setupCheckRunUpdater(t)
+	ctx := &events.CommandContext{
+		BaseRepo: checksRepo,
+		Pull:     checksPull,
+		Command:  &events.Command{Name: events.Apply, Workspace: "default"},
+		VCSHost:  vcs.Github,
+		JobURL:   "https://atlantis/jobs/abc",
+	}
+	Ok(t, u.UpdateRunning(ctx))
+	creator.VerifyWasCalledOnce().CreateCheckRun(checksRepo, githubchecks.CheckRun{
+		Name:       "atlantis/apply",
+		HeadSHA:    "abc123",
+		Status:     githubchecks.StatusInProgress,
+		ExternalID: `{"workspace":"default"}`,
+		DetailsURL: "https://atlantis/jobs/abc",
+		Output: &githubchecks.Output{
+			Title:   "Apply Running",
+			Summary: "Apply Running, [follow along on Atlantis](https://atlantis/jobs/abc).",
+		},
+	})
+}
+
+func TestGithubCheckRunUpdater_UpdateProjectResult(t *testing.T) {
+	t.Log("should create a check run per project with buttons and annotations and then complete the command's check run")
+	u, creator, _ := setupCheckRunUpdater(t)
+	ctx := &events.CommandContext{
+		BaseRepo: checksRepo,
+		Pull:     checksPull,
+		Command:  &events.Command{Name: events.Plan, Workspace: "staging"},
+		VCSHost:  vcs.Github,
+	}
+	err := u.UpdateProjectResult(ctx, events.CommandResponse{
+		ProjectResults: []events.ProjectResult{
+			{Path: "infra/vpc", PlanSuccess: &events.PlanSuccess{TerraformOutput: "output", LockURL: "lock-url"}},
+			{Path: "infra/db", Error: errors.New("err")},
+		},
+	})
+	Ok(t, err)
+
+	_, runs := creator.VerifyWasCalled(Times(3)).CreateCheckRun(matchers.AnyModelsRepo(), matchers.AnyGithubchecksCheckRun()).GetAllCapturedArguments()
+	success := runs[0]
+	Equals(t, "atlantis/plan: infra/vpc (staging)", success.Name)
+	Equals(t, githubchecks.StatusCompleted, success.Status)
+	Equals(t, githubchecks.ConclusionSuccess, success.Conclusion)
+	Equals(t, `{"dir":"infra/vpc","workspace":"staging"}`, success.ExternalID)
+	Equals(t, "**Plan Success** in `infra/vpc` for workspace `staging`.", success.Output.Summary)
+	Equals(t, "```diff\noutput\n```\n\n* To **discard** this plan click [here](lock-url).\n\n", success.Output.Text)
+	Equals(t, []string{events.PlanCheckRunAction, events.ApplyCheckRunAction}, actionIDs(success))
+	Equals(t, 0, len(success.Output.Annotations))
+
+	failure := runs[1]
+	Equals(t, "atlantis/plan: infra/db (staging)", failure.Name)
+	Equals(t, githubchecks.ConclusionFailure, failure.Conclusion)
+	Equals(t, []string{events.PlanCheckRunAction}, actionIDs(failure))
+	annotation := githubchecks.Annotation{
+		Path:            "infra/db",
+		StartLine:       1,
+		EndLine:         1,
+		AnnotationLevel: githubchecks.AnnotationFailure,
+		Title:           "Plan Failed",
+		Message:         "err",
+	}
+	Equals(t, []githubchecks.Annotation{annotation}, failure.Output.Annotations)
+
+	aggregate := runs[2]
+	Equals(t, "atlantis/plan", aggregate.Name)
+	Equals(t, githubchecks.ConclusionFailure, aggregate.Conclusion)
+	Equals(t, "Plan Failed", aggregate.Output.Title)
+	Equals(t, []githubchecks.Annotation{annotation}, aggregate.Output.Annotations)
+	Equals(t, 0, len(aggregate.Actions))
+}
+
+func TestGithubCheckRunUpdater_Truncates(t *testing.T) {
+	t.Log("should truncate output that's longer than GitHub allows")
+	u, creator, _ := setupCheckRunUpdater(t)
+	ctx := &events.CommandContext{
+		BaseRepo: checksRepo,
+		Pull:     checksPull,
+		Command:  &events.Command{Name: events.Apply, Workspace: "default"},
+		VCSHost:  vcs.Github,
+	}
+	output := make([]byte, githubchecks.MaxOutputLen)
+	for i := range output {
+		output[i] = 'a'
+	}
+	err := u.UpdateProjectResult(ctx, events.CommandResponse{
+		ProjectResults: []events.ProjectResult{{Path: ".", ApplySuccess: string(output)}},
+	})
+	Ok(t, err)
+	_, runs := creator.VerifyWasCalled(Times(2)).CreateCheckRun(matchers.AnyModelsRepo(), matchers.AnyGithubchecksCheckRun()).GetAllCapturedArguments()
+	Equals(t, githubchecks.MaxOutputLen, len(runs[0].Output.Text))
+	Equals(t, 0, len(runs[0].Actions))
+}
+
+func TestGithubCheckRunUpdater_ApplyRoundTrip(t *testing.T) {
+	t.Log("the buttons on a failed apply's check run should plan and apply the same project again")
+	a, repoDir := setupApplyExecutorTest(t, ".", "infra/vpc")
+	defer os.RemoveAll(repoDir) // nolint: errcheck
+	ctx := applyCtx(events.Command{Name: events.Apply, Workspace: "default", Dir: "infra/vpc"})
+	ctx.BaseRepo = checksRepo
+	ctx.Pull = checksPull
+	ctx.VCSHost = vcs.Github
+	res := a.Execute(ctx)
+
+	u, creator, _ := setupCheckRunUpdater(t)
+	Ok(t, u.UpdateProjectResult(ctx, res))
+	_, runs := creator.VerifyWasCalled(Times(2)).CreateCheckRun(matchers.AnyModelsRepo(), matchers.AnyGithubchecksCheckRun()).GetAllCapturedArguments()
+	run := runs[0]
+	Equals(t, "atlantis/apply: infra/vpc (default)", run.Name)
+	Equals(t, `{"dir":"infra/vpc","workspace":"default"}`, run.ExternalID)
+	Equals(t, "infra/vpc", run.Output.Annotations[0].Path)
+	Equals(t, []string{events.PlanCheckRunAction, events.ApplyCheckRunAction}, actionIDs(run))
+
+	externalID, err := json.Marshal(run.ExternalID)
+	Ok(t, err)
+	body := strings.Replace(githubCheckRunEventJSON, `"{\"dir\":\"infra/vpc\",\"workspace\":\"staging\"}"`, string(externalID), 1)
+
+	t.Log("apply should find the same plan")
+	_, _, _, cmd, err := parser.ParseGithubCheckRunEvent([]byte(body))
+	Ok(t, err)
+	Equals(t, events.Command{Name: events.Apply, Dir: "infra/vpc", Workspace: "default"}, *cmd)
+	retry := a.Execute(applyCtx(*cmd))
+	Equals(t, 1, len(retry.ProjectResults))
+	Equals(t, "infra/vpc", retry.ProjectResults[0].Path)
+
+	t.Log("plan should find the project's dir in the clone")
+	body = strings.Replace(body, `"identifier": "apply"`, `"identifier": "plan"`, 1)
+	_, _, _, cmd, err = parser.ParseGithubCheckRunEvent([]byte(body))
+	Ok(t, err)
+	Equals(t, events.Command{Name: events.Plan, Dir: "infra/vpc", Workspace: "default"}, *cmd)
+	_, err = os.Stat(filepath.Join(repoDir, cmd.Dir))
+	Ok(t, err)
+}
+
+func setupCheckRunUpdater(t *testing.T) (*events.GithubCheckRunUpdater, *mocks.MockGithubCheckRunCreator, *mocks.MockCommitStatusUpdater) {
+	RegisterMockTestingT(t)
+	creator := mocks.NewMockGithubCheckRunCreator()
+	fallback := mocks.NewMockCommitStatusUpdater()
+	return &events.GithubCheckRunUpdater{
+		Client:   creator,
+		Fallback: fallback,
+		Renderer: &events.MarkdownRenderer{},
+	}, creator, fallback
+}
+
+func actionIDs(run githubchecks.CheckRun) []string {
+	var ids []string
+	for _, a := range run.Actions {
+		ids = append(ids, a.Identifier)
+	}
+	return ids
+}
@@ -0,0 +1,352 @@
+// Package jobs records the output of the commands Atlantis runs so that it
+// can be followed live and replayed after the command has finished.
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Statuses of jobs other than the status of the command they ran.
+const (
+	// RunningStatus is the status of jobs that haven't finished.
+	RunningStatus = "running"
+	// InterruptedStatus is the status of jobs that were still running when
+	// Atlantis last stopped.
+	InterruptedStatus = "interrupted"
+)
+
+// Info describes a job.
+type Info struct {
+	ID           string `json:"id"`
+	RepoFullName string `json:"repo_full_name"`
+	PullNum      int    `json:"pull_num"`
+	Command      string `json:"command"`
+	Workspace    string `json:"workspace"`
+	// Status is RunningStatus until the job finishes.
+	Status  string    `json:"status"`
+	Started time.Time `json:"started"`
+	// Finished is the zero time until the job finishes.
+	Finished time.Time `json:"finished"`
+	// Token is a secret that must be in the job's URLs to view it. Unlike
+	// the ID it isn't in the job's file names.
+	Token string `json:"token"`
+}
+
+// Manager starts jobs and finds them again by their ID. Each job is kept in
+// Dir as ID.json, its Info, and ID.log, its output.
+type Manager struct {
+	Dir string
+	// MaxAge is how long jobs are kept for after they were last written to.
+	// Older jobs are deleted when the Manager is created and when jobs are
+	// started. If 0, jobs are kept forever.
+	MaxAge  time.Duration
+	mu      sync.Mutex
+	running map[string]*Job
+}
+
+// NewManager returns a Manager that keeps jobs in dir for maxAge, creating
+// dir if it doesn't exist and deleting the jobs in it that are too old.
+func NewManager(dir string, maxAge time.Duration) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrapf(err, "creating jobs dir %q", dir)
+	}
+	m := &Manager{Dir: dir, MaxAge: maxAge, running: make(map[string]*Job)}
+	if err := m.prune(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Start starts recording a job described by info. Its ID, Status, Started,
+// Finished and Token fields are set by Start.
+func (m *Manager) Start(info Info) (*Job, error) {
+	// Not being able to delete old jobs shouldn't stop this one from being
+	// recorded. It's tried again when the next job starts.
+	m.prune() // nolint: errcheck
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, errors.Wrap(err, "generating job id")
+	}
+	token, err := randomHex(16)
+	if err != nil {
+		return nil, errors.Wrap(err, "generating job token")
+	}
+	info.ID = id
+	info.Token = token
+	info.Status = RunningStatus
+	info.Started = time.Now()
+	info.Finished = time.Time{}
+	j := &Job{manager: m, info: info, updated: make(chan struct{})}
+	if err := m.writeInfo(info); err != nil {
+		return nil, err
+	}
+	j.log, err = os.OpenFile(m.logPath(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating job log")
+	}
+
+	m.mu.Lock()
+	m.running[id] = j
+	m.mu.Unlock()
+	return j, nil
+}
+
+// Get returns the job with id or nil if there isn't one. Jobs that have
+// finished are read from disk.
+func (m *Manager) Get(id string) (*Job, error) {
+	m.mu.Lock()
+	j, ok := m.running[id]
+	m.mu.Unlock()
+	if ok {
+		return j, nil
+	}
+	// IDs are hex so this also stops ids from reaching outside of Dir.
+	if _, err := hex.DecodeString(id); err != nil || id == "" {
+		return nil, nil
+	}
+
+	info, err := m.readInfo(id)
+	if os.IsNotExist(errors.Cause(err)) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	logBytes, err := ioutil.ReadFile(m.logPath(id))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "reading output of job %s", id)
+	}
+	return &Job{manager: m, info: info, lines: splitLines(logBytes), finished: true}, nil
+}
+
+// List returns the info of the jobs that match, most recently started first.
+// If match is nil, every job matches. If limit is greater than 0, at most
+// limit jobs are returned.
+func (m *Manager) List(match func(Info) bool, limit int) ([]Info, error) {
+	paths, err := filepath.Glob(filepath.Join(m.Dir, "*.json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing jobs")
+	}
+	m.mu.Lock()
+	running := make(map[string]*Job, len(m.running))
+	for id, j := range m.running {
+		running[id] = j
+	}
+	m.mu.Unlock()
+
+	var infos []Info
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), ".json")
+		var info Info
+		if j, ok := running[id]; ok {
+			info = j.Info()
+		} else {
+			info, err = m.readInfo(id)
+			if os.IsNotExist(errors.Cause(err)) {
+				// It was removed since we globbed.
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if match == nil || match(info) {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Started.After(infos[j].Started) })
+	if limit > 0 && len(infos) > limit {
+		infos = infos[:limit]
+	}
+	return infos, nil
+}
+
+// prune deletes the jobs that haven't been written to within m.MaxAge.
+// Running jobs are never deleted.
+func (m *Manager) prune() error {
+	if m.MaxAge <= 0 {
+		return nil
+	}
+	paths, err := filepath.Glob(filepath.Join(m.Dir, "*.json"))
+	if err != nil {
+		return errors.Wrap(err, "listing jobs")
+	}
+	cutoff := time.Now().Add(-m.MaxAge)
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), ".json")
+		m.mu.Lock()
+		_, running := m.running[id]
+		m.mu.Unlock()
+		if running {
+			continue
+		}
+		// The info is rewritten when the job finishes so this is when it
+		// finished, or started if it was interrupted.
+		fi, err := os.Stat(path)
+		if err != nil || fi.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(m.logPath(id)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "deleting output of job %s", id)
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "deleting job %s", id)
+		}
+	}
+	return nil
+}
+
+// readInfo reads the info of the job with id from disk. Jobs that were still
+// running when Atlantis stopped are reported as interrupted.
+func (m *Manager) readInfo(id string) (Info, error) {
+	var info Info
+	b, err := ioutil.ReadFile(m.infoPath(id))
+	if err != nil {
+		return info, errors.Wrapf(err, "reading job %s", id)
+	}
+	if err := json.Unmarshal(b, &info); err != nil {
+		return info, errors.Wrapf(err, "parsing job %s", id)
+	}
+	if info.Status == RunningStatus {
+		info.Status = InterruptedStatus
+	}
+	return info, nil
+}
+
+func (m *Manager) infoPath(id string) string {
+	return filepath.Join(m.Dir, id+".json")
+}
+
+func (m *Manager) logPath(id string) string {
+	return filepath.Join(m.Dir, id+".log")
+}
+
+func (m *Manager) writeInfo(info Info) error {
+	// Marshalling Info can't fail.
+	b, _ := json.Marshal(info)
+	if err := ioutil.WriteFile(m.infoPath(info.ID), b, 0600); err != nil {
+		return errors.Wrapf(err, "writing job %s", info.ID)
+	}
+	return nil
+}
+
+// Job is the output of a command. Output is written to it line by line while
+// the command runs and can be read by any number of followers.
+type Job struct {
+	manager  *Manager
+	mu       sync.Mutex
+	info     Info
+	lines    []string
+	finished bool
+	log      *os.File
+	// updated is closed, and replaced, whenever lines are written or the
+	// job finishes.
+	updated chan struct{}
+}
+
+// Info returns a description of the job.
+func (j *Job) Info() Info {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.info
+}
+
+// Write adds the lines in p to the job's output. A last line without a
+// newline is treated as a whole line so writers should only write whole
+// lines.
+func (j *Job) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.finished {
+		return 0, errors.Errorf("job %s has finished", j.info.ID)
+	}
+	lines := splitLines(p)
+	if len(lines) == 0 {
+		return len(p), nil
+	}
+	if _, err := j.log.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		return 0, errors.Wrapf(err, "writing output of job %s", j.info.ID)
+	}
+	j.lines = append(j.lines, lines...)
+	j.notify()
+	return len(p), nil
+}
+
+// Finish records that the job's command finished with status. Nothing more
+// can be written to the job afterwards.
+func (j *Job) Finish(status string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.finished {
+		return nil
+	}
+	j.finished = true
+	j.info.Status = status
+	j.info.Finished = time.Now()
+	j.notify()
+
+	// The info is written before the job stops being running so that it's
+	// never read from disk while it still says it's running.
+	m := j.manager
+	closeErr := j.log.Close()
+	writeErr := m.writeInfo(j.info)
+	m.mu.Lock()
+	delete(m.running, j.info.ID)
+	m.mu.Unlock()
+	if writeErr != nil {
+		return writeErr
+	}
+	return errors.Wrapf(closeErr, "closing output of job %s", j.info.ID)
+}
+
+// Lines returns the job's output from line from onwards and whether the job
+// has finished. If it hasn't, the returned channel is closed once there's
+// more output or the job finishes.
+func (j *Job) Lines(from int) ([]string, bool, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var lines []string
+	if from < len(j.lines) {
+		lines = append(lines, j.lines[from:]...)
+	}
+	return lines, j.finished, j.updated
+}
+
+// notify wakes up followers. j.mu must be held.
+func (j *Job) notify() {
+	if j.updated != nil {
+		close(j.updated)
+	}
+	if j.finished {
+		j.updated = nil
+		return
+	}
+	j.updated = make(chan struct{})
+}
+
+func splitLines(p []byte) []string {
+	s := strings.TrimSuffix(string(p), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
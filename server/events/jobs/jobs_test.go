@@ -0,0 +1,228 @@
+package jobs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hootsuite/atlantis/server/events/jobs"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+var jobInfo = jobs.Info{
+	RepoFullName: "owner/repo",
+	PullNum:      1,
+	Command:      "plan",
+	Workspace:    "default",
+}
+
+func TestStart(t *testing.T) {
+	t.Log("started jobs should get an id and be running")
+	m, cleanup := newTestManager(t)
+	defer cleanup()
+	j, err := m.Start(jobInfo)
+	Ok(t, err)
+
+	info := j.Info()
+	Assert(t, info.ID != "", "exp an id")
+	Assert(t, info.Token != "" && info.Token != info.ID, "exp a token")
+	Equals(t, jobs.RunningStatus, info.Status)
+	Assert(t, !info.Started.IsZero(), "exp a start time")
+	Assert(t, info.Finished.IsZero(), "exp no finish time")
+
+	got, err := m.Get(info.ID)
+	Ok(t, err)
+	Assert(t, got == j, "exp to get the running job")
+}
+
+func TestGet_NotFound(t *testing.T) {
+	m, cleanup := newTestManager(t)
+	defer cleanup()
+	for _, id := range []string{"", "0123456789abcdef", "../jobs", "not-hex"} {
+		j, err := m.Get(id)
+		Ok(t, err)
+		Assert(t, j == nil, "exp no job for id %q", id)
+	}
+}
+
+func TestWrite_Follow(t *testing.T) {
+	t.Log("followers should be woken up when lines are written and when the job finishes")
+	m, cleanup := newTestManager(t)
+	defer cleanup()
+	j, err := m.Start(jobInfo)
+	Ok(t, err)
+
+	lines, finished, updated := j.Lines(0)
+	Equals(t, 0, len(lines))
+	Equals(t, false, finished)
+
+	_, err = j.Write([]byte("one\ntwo\n"))
+	Ok(t, err)
+	assertClosed(t, updated)
+	lines, finished, updated = j.Lines(0)
+	Equals(t, []string{"one", "two"}, lines)
+	Equals(t, false, finished)
+
+	_, err = j.Write([]byte("three"))
+	Ok(t, err)
+	assertClosed(t, updated)
+	lines, _, updated = j.Lines(2)
+	Equals(t, []string{"three"}, lines)
+
+	Ok(t, j.Finish("success"))
+	assertClosed(t, updated)
+	lines, finished, _ = j.Lines(3)
+	Equals(t, 0, len(lines))
+	Equals(t, true, finished)
+
+	t.Log("nothing can be written once the job has finished")
+	_, err = j.Write([]byte("four\n"))
+	Assert(t, err != nil, "exp an error")
+}
+
+func TestGet_Finished(t *testing.T) {
+	t.Log("finished jobs should be read back from disk")
+	m, cleanup := newTestManager(t)
+	defer cleanup()
+	j, err := m.Start(jobInfo)
+	Ok(t, err)
+	_, err = j.Write([]byte("one\ntwo\n"))
+	Ok(t, err)
+	Ok(t, j.Finish("failed"))
+
+	t.Log("a new manager should find it too, ex. after a restart")
+	m2, err := jobs.NewManager(m.Dir, 0)
+	Ok(t, err)
+	got, err := m2.Get(j.Info().ID)
+	Ok(t, err)
+	Assert(t, got != nil, "exp to find the job")
+	Equals(t, j.Info().ID, got.Info().ID)
+	Equals(t, j.Info().Token, got.Info().Token)
+	Equals(t, "failed", got.Info().Status)
+	Equals(t, "owner/repo", got.Info().RepoFullName)
+	Assert(t, !got.Info().Finished.IsZero(), "exp a finish time")
+	lines, finished, _ := got.Lines(0)
+	Equals(t, []string{"one", "two"}, lines)
+	Equals(t, true, finished)
+}
+
+func TestGet_Interrupted(t *testing.T) {
+	t.Log("jobs that were running when Atlantis stopped should be read back as interrupted")
+	m, cleanup := newTestManager(t)
+	defer cleanup()
+	j, err := m.Start(jobInfo)
+	Ok(t, err)
+	_, err = j.Write([]byte("one\n"))
+	Ok(t, err)
+
+	m2, err := jobs.NewManager(m.Dir, 0)
+	Ok(t, err)
+	got, err := m2.Get(j.Info().ID)
+	Ok(t, err)
+	Equals(t, jobs.InterruptedStatus, got.Info().Status)
+	lines, finished, _ := got.Lines(0)
+	Equals(t, []string{"one"}, lines)
+	Equals(t, true, finished)
+}
+
+func TestList(t *testing.T) {
+	t.Log("running and finished jobs should be listed, most recently started first")
+	m, cleanup := newTestManager(t)
+	defer cleanup()
+	infos, err := m.List(nil, 0)
+	Ok(t, err)
+	Equals(t, 0, len(infos))
+
+	first, err := m.Start(jobInfo)
+	Ok(t, err)
+	Ok(t, first.Finish("success"))
+	second, err := m.Start(jobInfo)
+	Ok(t, err)
+
+	infos, err = m.List(nil, 0)
+	Ok(t, err)
+	Equals(t, 2, len(infos))
+	Equals(t, second.Info().ID, infos[0].ID)
+	Equals(t, first.Info().ID, infos[1].ID)
+	Equals(t, jobs.RunningStatus, infos[0].Status)
+	Equals(t, "success", infos[1].Status)
+}
+
+func TestList_MatchLimit(t *testing.T) {
+	t.Log("only the most recent jobs that match should be listed")
+	m, cleanup := newTestManager(t)
+	defer cleanup()
+	var ids []string
+	for _, repo := range []string{"owner/repo", "owner/other", "owner/repo", "owner/repo"} {
+		j, err := m.Start(jobs.Info{RepoFullName: repo})
+		Ok(t, err)
+		Ok(t, j.Finish("success"))
+		ids = append(ids, j.Info().ID)
+	}
+
+	infos, err := m.List(func(info jobs.Info) bool { return info.RepoFullName == "owner/repo" }, 2)
+	Ok(t, err)
+	Equals(t, 2, len(infos))
+	Equals(t, ids[3], infos[0].ID)
+	Equals(t, ids[2], infos[1].ID)
+}
+
+func TestPrune(t *testing.T) {
+	t.Log("jobs older than the max age should be deleted on startup and when jobs start, except running ones")
+	m, cleanup := newTestManager(t)
+	defer cleanup()
+	old, err := m.Start(jobInfo)
+	Ok(t, err)
+	Ok(t, old.Finish("success"))
+	running, err := m.Start(jobInfo)
+	Ok(t, err)
+	age(t, m.Dir, old.Info().ID)
+	age(t, m.Dir, running.Info().ID)
+
+	t.Log("jobs that were running when Atlantis stopped should be deleted too once they're old")
+	m2, err := jobs.NewManager(m.Dir, time.Hour)
+	Ok(t, err)
+	infos, err := m2.List(nil, 0)
+	Ok(t, err)
+	Equals(t, 0, len(infos))
+	_, err = os.Stat(filepath.Join(m.Dir, old.Info().ID+".log"))
+	Assert(t, os.IsNotExist(err), "exp the output to be deleted")
+
+	recent, err := m2.Start(jobInfo)
+	Ok(t, err)
+	Ok(t, recent.Finish("success"))
+	stillRunning, err := m2.Start(jobInfo)
+	Ok(t, err)
+	age(t, m.Dir, recent.Info().ID)
+	age(t, m.Dir, stillRunning.Info().ID)
+	_, err = m2.Start(jobInfo)
+	Ok(t, err)
+	infos, err = m2.List(nil, 0)
+	Ok(t, err)
+	Equals(t, 2, len(infos))
+	Equals(t, stillRunning.Info().ID, infos[1].ID)
+}
+
+// age makes the job with id look like it was last written to two hours ago.
+func age(t *testing.T, dir string, id string) {
+	then := time.Now().Add(-2 * time.Hour)
+	Ok(t, os.Chtimes(filepath.Join(dir, id+".json"), then, then))
+}
+
+func assertClosed(t *testing.T, c <-chan struct{}) {
+	select {
+	case <-c:
+	case <-time.After(time.Second):
+		t.Fatal("exp channel to be closed")
+	}
+}
+
+func newTestManager(t *testing.T) (*jobs.Manager, func()) {
+	dir, err := ioutil.TempDir("", "atlantis-jobs")
+	Ok(t, err)
+	m, err := jobs.NewManager(dir, 0)
+	Ok(t, err)
+	return m, func() { os.RemoveAll(dir) } // nolint: errcheck
+}
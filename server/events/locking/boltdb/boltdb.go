@@ -24,6 +24,9 @@ type BoltLocker struct {
 
 const bucketName = "runLocks"
 
+// commandBucketName is the bucket that the CommandStore records commands in.
+const commandBucketName = "commandQueue"
+
 // New returns a valid locker. We need to be able to write to dataDir
 // since bolt stores its data as a file
 func New(dataDir string) (*BoltLocker, error) {
@@ -38,8 +41,10 @@ func New(dataDir string) (*BoltLocker, error) {
 		return nil, errors.Wrap(err, "starting BoltDB")
 	}
 	err = db.Update(func(tx *bolt.Tx) error {
-		if _, err = tx.CreateBucketIfNotExists([]byte(bucketName)); err != nil {
-			return errors.Wrapf(err, "creating %q bucketName", bucketName)
+		for _, name := range []string{bucketName, commandBucketName} {
+			if _, err = tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return errors.Wrapf(err, "creating %q bucketName", name)
+			}
 		}
 		return nil
 	})
@@ -0,0 +1,92 @@
+package boltdb
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+	"github.com/hootsuite/atlantis/server/events"
+	"github.com/pkg/errors"
+)
+
+// BoltCommandStore records the commands accepted by the command queue in the
+// same database as the locks.
+type BoltCommandStore struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+// CommandStore returns a store that records commands in b's database.
+func (b *BoltLocker) CommandStore() *BoltCommandStore {
+	return &BoltCommandStore{b.db, []byte(commandBucketName)}
+}
+
+// NewCommandStoreWithDB is used for testing.
+func NewCommandStoreWithDB(db *bolt.DB, bucket string) *BoltCommandStore {
+	return &BoltCommandStore{db, []byte(bucket)}
+}
+
+// Add records cmd and returns the ID it was given.
+func (s *BoltCommandStore) Add(cmd events.QueuedCommand) (uint64, error) {
+	var id uint64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(s.bucket)
+		var err error
+		// Sequences start at 1 so IDs are never 0.
+		if id, err = bucket.NextSequence(); err != nil {
+			return err
+		}
+		cmd.ID = id
+		return s.put(bucket, cmd)
+	})
+	return id, errors.Wrap(err, "DB transaction failed")
+}
+
+// Update replaces the record of the command with cmd.ID.
+func (s *BoltCommandStore) Update(cmd events.QueuedCommand) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return s.put(tx.Bucket(s.bucket), cmd)
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// Remove deletes the record of the command with id.
+func (s *BoltCommandStore) Remove(id uint64) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Delete(s.key(id))
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// List returns the recorded commands in the order they were added.
+func (s *BoltCommandStore) List() ([]events.QueuedCommand, error) {
+	var cmds []events.QueuedCommand
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(s.bucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var cmd events.QueuedCommand
+			if err := json.Unmarshal(v, &cmd); err != nil {
+				return errors.Wrapf(err, "deserializing command with id %d", binary.BigEndian.Uint64(k))
+			}
+			cmds = append(cmds, cmd)
+		}
+		return nil
+	})
+	return cmds, errors.Wrap(err, "DB transaction failed")
+}
+
+func (s *BoltCommandStore) put(bucket *bolt.Bucket, cmd events.QueuedCommand) error {
+	serialized, err := json.Marshal(cmd)
+	if err != nil {
+		return errors.Wrap(err, "serializing command")
+	}
+	return bucket.Put(s.key(cmd.ID), serialized)
+}
+
+// key returns the key for the command with id. Keys are big endian so that
+// they sort in the order the commands were added.
+func (s *BoltCommandStore) key(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
@@ -0,0 +1,102 @@
+package boltdb_test
+
+import (
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/hootsuite/atlantis/server/events"
+	"github.com/hootsuite/atlantis/server/events/locking/boltdb"
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+var commandBucket = "commands"
+var queuedCmd = events.QueuedCommand{
+	BaseRepo: models.Repo{FullName: "owner/repo"},
+	User:     models.User{Username: "lkysow"},
+	PullNum:  1,
+	Command:  &events.Command{Name: events.Apply, Workspace: "default", Flags: []string{"-target=a"}},
+	VCSHost:  vcs.Gitlab,
+}
+
+func TestCommandStore_ListNone(t *testing.T) {
+	t.Log("listing commands when there are none should return an empty list")
+	db, s := newTestCommandStore(t)
+	defer cleanupDB(db)
+	cmds, err := s.List()
+	Ok(t, err)
+	Equals(t, 0, len(cmds))
+}
+
+func TestCommandStore_AddList(t *testing.T) {
+	t.Log("added commands should be listed in the order they were added")
+	db, s := newTestCommandStore(t)
+	defer cleanupDB(db)
+
+	var ids []uint64
+	for _, num := range []int{3, 1, 2} {
+		cmd := queuedCmd
+		cmd.PullNum = num
+		id, err := s.Add(cmd)
+		Ok(t, err)
+		ids = append(ids, id)
+	}
+	Equals(t, []uint64{1, 2, 3}, ids)
+
+	cmds, err := s.List()
+	Ok(t, err)
+	Equals(t, 3, len(cmds))
+	for i, num := range []int{3, 1, 2} {
+		exp := queuedCmd
+		exp.ID = ids[i]
+		exp.PullNum = num
+		Equals(t, exp, cmds[i])
+	}
+}
+
+func TestCommandStore_Update(t *testing.T) {
+	t.Log("updating a command should replace its record")
+	db, s := newTestCommandStore(t)
+	defer cleanupDB(db)
+	id, err := s.Add(queuedCmd)
+	Ok(t, err)
+
+	cmd := queuedCmd
+	cmd.ID = id
+	cmd.Started = true
+	Ok(t, s.Update(cmd))
+
+	cmds, err := s.List()
+	Ok(t, err)
+	Equals(t, []events.QueuedCommand{cmd}, cmds)
+}
+
+func TestCommandStore_Remove(t *testing.T) {
+	t.Log("removed commands shouldn't be listed")
+	db, s := newTestCommandStore(t)
+	defer cleanupDB(db)
+	id1, err := s.Add(queuedCmd)
+	Ok(t, err)
+	id2, err := s.Add(queuedCmd)
+	Ok(t, err)
+
+	Ok(t, s.Remove(id1))
+	t.Log("removing a command that isn't recorded shouldn't error")
+	Ok(t, s.Remove(id1))
+
+	cmds, err := s.List()
+	Ok(t, err)
+	Equals(t, 1, len(cmds))
+	Equals(t, id2, cmds[0].ID)
+}
+
+func newTestCommandStore(t *testing.T) (*bolt.DB, *boltdb.BoltCommandStore) {
+	db, _ := newTestDB()
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(commandBucket))
+		return err
+	})
+	Ok(t, err)
+	return db, boltdb.NewCommandStoreWithDB(db, commandBucket)
+}
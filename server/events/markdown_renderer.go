@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 	"text/template"
+
+	"github.com/hootsuite/atlantis/server/events/models"
 )
 
 // MarkdownRenderer renders responses as markdown.
@@ -49,9 +51,21 @@ func (g *MarkdownRenderer) Render(res CommandResponse, cmdName CommandName, log
 	if res.Failure != "" {
 		return g.renderTemplate(failureWithLogTmpl, FailureData{res.Failure, common})
 	}
+	if cmdName == Unlock {
+		return g.renderUnlock(res.UnlockedLocks, common)
+	}
 	return g.renderProjectResults(res.ProjectResults, common)
 }
 
+// renderUnlock renders the locks deleted by an unlock command in the same
+// format as when a pull request is closed.
+func (g *MarkdownRenderer) renderUnlock(locks []models.ProjectLock, common CommonData) string {
+	if len(locks) == 0 {
+		return g.renderTemplate(unlockNoLocksTmpl, common)
+	}
+	return g.renderTemplate(pullClosedTemplate, buildLocksTemplateData(locks))
+}
+
 func (g *MarkdownRenderer) renderProjectResults(pathResults []ProjectResult, common CommonData) string {
 	results := make(map[string]string)
 	for _, result := range pathResults {
@@ -101,11 +115,12 @@ var helpTmpl = template.Must(template.New("").Parse("```cmake\n" +
 	`atlantis - Terraform collaboration tool that enables you to collaborate on infrastructure
 safely and securely.
 
-Usage: atlantis <command> [workspace] [--verbose]
+Usage: atlantis <command> [workspace] [-d dir | -p project] [--verbose]
 
 Commands:
 plan           Runs 'terraform plan' on the files changed in the pull request
 apply          Runs 'terraform apply' using the plans generated by 'atlantis plan'
+unlock         Deletes all the plans and locks held by this pull request
 help           Get help
 
 Examples:
@@ -116,11 +131,29 @@ atlantis plan staging
 # Generates a plan for a standalone terraform project
 atlantis plan
 
+# Generates a plan for only the project in the infra/vpc directory
+atlantis plan -d infra/vpc
+
+# Generates a plan for only the project named vpc in atlantis.yaml
+atlantis plan -p vpc
+
 # Applies a plan for staging workspace
 atlantis apply staging
 
+# Applies only the plan for the project in the infra/vpc directory
+atlantis apply -d infra/vpc
+
+# Applies only the plan for the project named vpc in atlantis.yaml
+atlantis apply -p vpc
+
 # Applies a plan for a standalone terraform project
 atlantis apply
+
+# Discards all plans and locks so other pull requests can modify the projects
+atlantis unlock
+
+# Cancels the plans and applies that are running or queued for this pull request
+atlantis cancel
 `))
 var singleProjectTmpl = template.Must(template.New("").Parse("{{ range $result := .Results }}{{$result}}{{end}}\n" + logTmpl))
 var multiProjectTmpl = template.Must(template.New("").Parse(
@@ -151,4 +184,5 @@ var errWithLogTmpl = template.Must(template.New("").Parse(errTmplText + logTmpl)
 var failureTmplText = "**{{.Command}} Failed**: {{.Failure}}\n"
 var failureTmpl = template.Must(template.New("").Parse(failureTmplText))
 var failureWithLogTmpl = template.Must(template.New("").Parse(failureTmplText + logTmpl))
+var unlockNoLocksTmpl = template.Must(template.New("").Parse("This pull request didn't hold any locks. Any plans were deleted.\n" + logTmpl))
 var logTmpl = "{{if .Verbose}}\n<details><summary>Log</summary>\n  <p>\n\n```\n{{.Log}}```\n</p></details>{{end}}\n"
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/hootsuite/atlantis/server/events"
+	"github.com/hootsuite/atlantis/server/events/models"
 	. "github.com/hootsuite/atlantis/testing"
 )
 
@@ -241,3 +242,28 @@ func TestRenderProjectResults(t *testing.T) {
 		}
 	}
 }
+
+func TestRenderUnlock(t *testing.T) {
+	r := events.MarkdownRenderer{}
+
+	t.Log("when there were no locks we say so")
+	s := r.Render(events.CommandResponse{}, events.Unlock, "log", false)
+	Equals(t, "This pull request didn't hold any locks. Any plans were deleted.\n\n", s)
+
+	t.Log("when there were locks we list them")
+	res := events.CommandResponse{
+		UnlockedLocks: []models.ProjectLock{
+			{
+				Project:   models.NewProject("owner/repo", "path"),
+				Workspace: "default",
+			},
+			{
+				Project:   models.NewProject("owner/repo", "path"),
+				Workspace: "staging",
+			},
+		},
+	}
+	s = r.Render(res, events.Unlock, "log", false)
+	Equals(t, "Locks and plans deleted for the projects and workspaces modified in this pull request:\n\n"+
+		"- path: `owner/repo/path` workspaces: `default`, `staging`", s)
+}
@@ -0,0 +1,20 @@
+package matchers
+
+import (
+	"reflect"
+
+	azuredevops "github.com/hootsuite/atlantis/server/events/vcs/azuredevops"
+	"github.com/petergtz/pegomock"
+)
+
+func AnyAzuredevopsPullRequest() azuredevops.PullRequest {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*(azuredevops.PullRequest))(nil)).Elem()))
+	var nullValue azuredevops.PullRequest
+	return nullValue
+}
+
+func EqAzuredevopsPullRequest(value azuredevops.PullRequest) azuredevops.PullRequest {
+	pegomock.RegisterMatcher(&pegomock.EqMatcher{Value: value})
+	var nullValue azuredevops.PullRequest
+	return nullValue
+}
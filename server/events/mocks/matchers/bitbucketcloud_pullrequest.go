@@ -0,0 +1,20 @@
+package matchers
+
+import (
+	"reflect"
+
+	bitbucketcloud "github.com/hootsuite/atlantis/server/events/vcs/bitbucketcloud"
+	"github.com/petergtz/pegomock"
+)
+
+func AnyBitbucketcloudPullRequest() bitbucketcloud.PullRequest {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*(bitbucketcloud.PullRequest))(nil)).Elem()))
+	var nullValue bitbucketcloud.PullRequest
+	return nullValue
+}
+
+func EqBitbucketcloudPullRequest(value bitbucketcloud.PullRequest) bitbucketcloud.PullRequest {
+	pegomock.RegisterMatcher(&pegomock.EqMatcher{Value: value})
+	var nullValue bitbucketcloud.PullRequest
+	return nullValue
+}
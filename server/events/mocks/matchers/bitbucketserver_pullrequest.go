@@ -0,0 +1,20 @@
+package matchers
+
+import (
+	"reflect"
+
+	bitbucketserver "github.com/hootsuite/atlantis/server/events/vcs/bitbucketserver"
+	"github.com/petergtz/pegomock"
+)
+
+func AnyBitbucketserverPullRequest() bitbucketserver.PullRequest {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*(bitbucketserver.PullRequest))(nil)).Elem()))
+	var nullValue bitbucketserver.PullRequest
+	return nullValue
+}
+
+func EqBitbucketserverPullRequest(value bitbucketserver.PullRequest) bitbucketserver.PullRequest {
+	pegomock.RegisterMatcher(&pegomock.EqMatcher{Value: value})
+	var nullValue bitbucketserver.PullRequest
+	return nullValue
+}
@@ -0,0 +1,20 @@
+package matchers
+
+import (
+	"reflect"
+
+	events "github.com/hootsuite/atlantis/server/events"
+	"github.com/petergtz/pegomock"
+)
+
+func AnyEventsRepoConfig() events.RepoConfig {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*(events.RepoConfig))(nil)).Elem()))
+	var nullValue events.RepoConfig
+	return nullValue
+}
+
+func EqEventsRepoConfig(value events.RepoConfig) events.RepoConfig {
+	pegomock.RegisterMatcher(&pegomock.EqMatcher{Value: value})
+	var nullValue events.RepoConfig
+	return nullValue
+}
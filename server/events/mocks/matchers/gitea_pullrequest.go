@@ -0,0 +1,20 @@
+package matchers
+
+import (
+	"reflect"
+
+	gitea "github.com/hootsuite/atlantis/server/events/vcs/gitea"
+	"github.com/petergtz/pegomock"
+)
+
+func AnyGiteaPullRequest() gitea.PullRequest {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*(gitea.PullRequest))(nil)).Elem()))
+	var nullValue gitea.PullRequest
+	return nullValue
+}
+
+func EqGiteaPullRequest(value gitea.PullRequest) gitea.PullRequest {
+	pegomock.RegisterMatcher(&pegomock.EqMatcher{Value: value})
+	var nullValue gitea.PullRequest
+	return nullValue
+}
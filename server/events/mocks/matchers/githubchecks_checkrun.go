@@ -0,0 +1,20 @@
+package matchers
+
+import (
+	"reflect"
+
+	githubchecks "github.com/hootsuite/atlantis/server/events/vcs/githubchecks"
+	"github.com/petergtz/pegomock"
+)
+
+func AnyGithubchecksCheckRun() githubchecks.CheckRun {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*(githubchecks.CheckRun))(nil)).Elem()))
+	var nullValue githubchecks.CheckRun
+	return nullValue
+}
+
+func EqGithubchecksCheckRun(value githubchecks.CheckRun) githubchecks.CheckRun {
+	pegomock.RegisterMatcher(&pegomock.EqMatcher{Value: value})
+	var nullValue githubchecks.CheckRun
+	return nullValue
+}
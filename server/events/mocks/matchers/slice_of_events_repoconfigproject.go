@@ -0,0 +1,20 @@
+package matchers
+
+import (
+	"reflect"
+
+	events "github.com/hootsuite/atlantis/server/events"
+	"github.com/petergtz/pegomock"
+)
+
+func AnySliceOfEventsRepoConfigProject() []events.RepoConfigProject {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*([]events.RepoConfigProject))(nil)).Elem()))
+	var nullValue []events.RepoConfigProject
+	return nullValue
+}
+
+func EqSliceOfEventsRepoConfigProject(value []events.RepoConfigProject) []events.RepoConfigProject {
+	pegomock.RegisterMatcher(&pegomock.EqMatcher{Value: value})
+	var nullValue []events.RepoConfigProject
+	return nullValue
+}
@@ -0,0 +1,20 @@
+package matchers
+
+import (
+	"reflect"
+
+	events "github.com/hootsuite/atlantis/server/events"
+	"github.com/petergtz/pegomock"
+)
+
+func AnySliceOfEventsWorkspacePlan() []events.WorkspacePlan {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*([]events.WorkspacePlan))(nil)).Elem()))
+	var nullValue []events.WorkspacePlan
+	return nullValue
+}
+
+func EqSliceOfEventsWorkspacePlan(value []events.WorkspacePlan) []events.WorkspacePlan {
+	pegomock.RegisterMatcher(&pegomock.EqMatcher{Value: value})
+	var nullValue []events.WorkspacePlan
+	return nullValue
+}
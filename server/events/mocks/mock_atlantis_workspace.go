@@ -6,6 +6,7 @@ package mocks
 import (
 	"reflect"
 
+	events "github.com/hootsuite/atlantis/server/events"
 	models "github.com/hootsuite/atlantis/server/events/models"
 	logging "github.com/hootsuite/atlantis/server/logging"
 	pegomock "github.com/petergtz/pegomock"
@@ -63,6 +64,22 @@ func (mock *MockAtlantisWorkspace) Delete(r models.Repo, p models.PullRequest) e
 	return ret0
 }
 
+func (mock *MockAtlantisWorkspace) ListPlans(r models.Repo, p models.PullRequest) ([]events.WorkspacePlan, error) {
+	params := []pegomock.Param{r, p}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("ListPlans", params, []reflect.Type{reflect.TypeOf((*[]events.WorkspacePlan)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 []events.WorkspacePlan
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].([]events.WorkspacePlan)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
 func (mock *MockAtlantisWorkspace) VerifyWasCalledOnce() *VerifierAtlantisWorkspace {
 	return &VerifierAtlantisWorkspace{mock, pegomock.Times(1), nil}
 }
@@ -189,3 +206,34 @@ func (c *AtlantisWorkspace_Delete_OngoingVerification) GetAllCapturedArguments()
 	}
 	return
 }
+
+func (verifier *VerifierAtlantisWorkspace) ListPlans(r models.Repo, p models.PullRequest) *AtlantisWorkspace_ListPlans_OngoingVerification {
+	params := []pegomock.Param{r, p}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "ListPlans", params)
+	return &AtlantisWorkspace_ListPlans_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type AtlantisWorkspace_ListPlans_OngoingVerification struct {
+	mock              *MockAtlantisWorkspace
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *AtlantisWorkspace_ListPlans_OngoingVerification) GetCapturedArguments() (models.Repo, models.PullRequest) {
+	r, p := c.GetAllCapturedArguments()
+	return r[len(r)-1], p[len(p)-1]
+}
+
+func (c *AtlantisWorkspace_ListPlans_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Repo, _param1 []models.PullRequest) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.Repo, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.Repo)
+		}
+		_param1 = make([]models.PullRequest, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(models.PullRequest)
+		}
+	}
+	return
+}
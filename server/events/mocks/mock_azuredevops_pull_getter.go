@@ -0,0 +1,85 @@
+// Automatically generated by pegomock. DO NOT EDIT!
+// Source: github.com/hootsuite/atlantis/server/events (interfaces: AzureDevopsPullGetter)
+
+package mocks
+
+import (
+	"reflect"
+
+	models "github.com/hootsuite/atlantis/server/events/models"
+	azuredevops "github.com/hootsuite/atlantis/server/events/vcs/azuredevops"
+	pegomock "github.com/petergtz/pegomock"
+)
+
+type MockAzureDevopsPullGetter struct {
+	fail func(message string, callerSkip ...int)
+}
+
+func NewMockAzureDevopsPullGetter() *MockAzureDevopsPullGetter {
+	return &MockAzureDevopsPullGetter{fail: pegomock.GlobalFailHandler}
+}
+
+func (mock *MockAzureDevopsPullGetter) GetPullRequest(repo models.Repo, pullNum int) (azuredevops.PullRequest, error) {
+	params := []pegomock.Param{repo, pullNum}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("GetPullRequest", params, []reflect.Type{reflect.TypeOf((*azuredevops.PullRequest)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 azuredevops.PullRequest
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(azuredevops.PullRequest)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
+func (mock *MockAzureDevopsPullGetter) VerifyWasCalledOnce() *VerifierAzureDevopsPullGetter {
+	return &VerifierAzureDevopsPullGetter{mock, pegomock.Times(1), nil}
+}
+
+func (mock *MockAzureDevopsPullGetter) VerifyWasCalled(invocationCountMatcher pegomock.Matcher) *VerifierAzureDevopsPullGetter {
+	return &VerifierAzureDevopsPullGetter{mock, invocationCountMatcher, nil}
+}
+
+func (mock *MockAzureDevopsPullGetter) VerifyWasCalledInOrder(invocationCountMatcher pegomock.Matcher, inOrderContext *pegomock.InOrderContext) *VerifierAzureDevopsPullGetter {
+	return &VerifierAzureDevopsPullGetter{mock, invocationCountMatcher, inOrderContext}
+}
+
+type VerifierAzureDevopsPullGetter struct {
+	mock                   *MockAzureDevopsPullGetter
+	invocationCountMatcher pegomock.Matcher
+	inOrderContext         *pegomock.InOrderContext
+}
+
+func (verifier *VerifierAzureDevopsPullGetter) GetPullRequest(repo models.Repo, pullNum int) *AzureDevopsPullGetter_GetPullRequest_OngoingVerification {
+	params := []pegomock.Param{repo, pullNum}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "GetPullRequest", params)
+	return &AzureDevopsPullGetter_GetPullRequest_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type AzureDevopsPullGetter_GetPullRequest_OngoingVerification struct {
+	mock              *MockAzureDevopsPullGetter
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *AzureDevopsPullGetter_GetPullRequest_OngoingVerification) GetCapturedArguments() (models.Repo, int) {
+	repo, pullNum := c.GetAllCapturedArguments()
+	return repo[len(repo)-1], pullNum[len(pullNum)-1]
+}
+
+func (c *AzureDevopsPullGetter_GetPullRequest_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Repo, _param1 []int) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.Repo, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.Repo)
+		}
+		_param1 = make([]int, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(int)
+		}
+	}
+	return
+}
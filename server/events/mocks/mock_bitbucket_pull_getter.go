@@ -0,0 +1,85 @@
+// Automatically generated by pegomock. DO NOT EDIT!
+// Source: github.com/hootsuite/atlantis/server/events (interfaces: BitbucketPullGetter)
+
+package mocks
+
+import (
+	"reflect"
+
+	models "github.com/hootsuite/atlantis/server/events/models"
+	bitbucketcloud "github.com/hootsuite/atlantis/server/events/vcs/bitbucketcloud"
+	pegomock "github.com/petergtz/pegomock"
+)
+
+type MockBitbucketPullGetter struct {
+	fail func(message string, callerSkip ...int)
+}
+
+func NewMockBitbucketPullGetter() *MockBitbucketPullGetter {
+	return &MockBitbucketPullGetter{fail: pegomock.GlobalFailHandler}
+}
+
+func (mock *MockBitbucketPullGetter) GetPullRequest(repo models.Repo, pullNum int) (bitbucketcloud.PullRequest, error) {
+	params := []pegomock.Param{repo, pullNum}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("GetPullRequest", params, []reflect.Type{reflect.TypeOf((*bitbucketcloud.PullRequest)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 bitbucketcloud.PullRequest
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(bitbucketcloud.PullRequest)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
+func (mock *MockBitbucketPullGetter) VerifyWasCalledOnce() *VerifierBitbucketPullGetter {
+	return &VerifierBitbucketPullGetter{mock, pegomock.Times(1), nil}
+}
+
+func (mock *MockBitbucketPullGetter) VerifyWasCalled(invocationCountMatcher pegomock.Matcher) *VerifierBitbucketPullGetter {
+	return &VerifierBitbucketPullGetter{mock, invocationCountMatcher, nil}
+}
+
+func (mock *MockBitbucketPullGetter) VerifyWasCalledInOrder(invocationCountMatcher pegomock.Matcher, inOrderContext *pegomock.InOrderContext) *VerifierBitbucketPullGetter {
+	return &VerifierBitbucketPullGetter{mock, invocationCountMatcher, inOrderContext}
+}
+
+type VerifierBitbucketPullGetter struct {
+	mock                   *MockBitbucketPullGetter
+	invocationCountMatcher pegomock.Matcher
+	inOrderContext         *pegomock.InOrderContext
+}
+
+func (verifier *VerifierBitbucketPullGetter) GetPullRequest(repo models.Repo, pullNum int) *BitbucketPullGetter_GetPullRequest_OngoingVerification {
+	params := []pegomock.Param{repo, pullNum}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "GetPullRequest", params)
+	return &BitbucketPullGetter_GetPullRequest_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type BitbucketPullGetter_GetPullRequest_OngoingVerification struct {
+	mock              *MockBitbucketPullGetter
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *BitbucketPullGetter_GetPullRequest_OngoingVerification) GetCapturedArguments() (models.Repo, int) {
+	repo, pullNum := c.GetAllCapturedArguments()
+	return repo[len(repo)-1], pullNum[len(pullNum)-1]
+}
+
+func (c *BitbucketPullGetter_GetPullRequest_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Repo, _param1 []int) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.Repo, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.Repo)
+		}
+		_param1 = make([]int, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(int)
+		}
+	}
+	return
+}
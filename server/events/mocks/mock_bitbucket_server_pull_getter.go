@@ -0,0 +1,85 @@
+// Automatically generated by pegomock. DO NOT EDIT!
+// Source: github.com/hootsuite/atlantis/server/events (interfaces: BitbucketServerPullGetter)
+
+package mocks
+
+import (
+	"reflect"
+
+	models "github.com/hootsuite/atlantis/server/events/models"
+	bitbucketserver "github.com/hootsuite/atlantis/server/events/vcs/bitbucketserver"
+	pegomock "github.com/petergtz/pegomock"
+)
+
+type MockBitbucketServerPullGetter struct {
+	fail func(message string, callerSkip ...int)
+}
+
+func NewMockBitbucketServerPullGetter() *MockBitbucketServerPullGetter {
+	return &MockBitbucketServerPullGetter{fail: pegomock.GlobalFailHandler}
+}
+
+func (mock *MockBitbucketServerPullGetter) GetPullRequest(repo models.Repo, pullNum int) (bitbucketserver.PullRequest, error) {
+	params := []pegomock.Param{repo, pullNum}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("GetPullRequest", params, []reflect.Type{reflect.TypeOf((*bitbucketserver.PullRequest)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 bitbucketserver.PullRequest
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(bitbucketserver.PullRequest)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
+func (mock *MockBitbucketServerPullGetter) VerifyWasCalledOnce() *VerifierBitbucketServerPullGetter {
+	return &VerifierBitbucketServerPullGetter{mock, pegomock.Times(1), nil}
+}
+
+func (mock *MockBitbucketServerPullGetter) VerifyWasCalled(invocationCountMatcher pegomock.Matcher) *VerifierBitbucketServerPullGetter {
+	return &VerifierBitbucketServerPullGetter{mock, invocationCountMatcher, nil}
+}
+
+func (mock *MockBitbucketServerPullGetter) VerifyWasCalledInOrder(invocationCountMatcher pegomock.Matcher, inOrderContext *pegomock.InOrderContext) *VerifierBitbucketServerPullGetter {
+	return &VerifierBitbucketServerPullGetter{mock, invocationCountMatcher, inOrderContext}
+}
+
+type VerifierBitbucketServerPullGetter struct {
+	mock                   *MockBitbucketServerPullGetter
+	invocationCountMatcher pegomock.Matcher
+	inOrderContext         *pegomock.InOrderContext
+}
+
+func (verifier *VerifierBitbucketServerPullGetter) GetPullRequest(repo models.Repo, pullNum int) *BitbucketServerPullGetter_GetPullRequest_OngoingVerification {
+	params := []pegomock.Param{repo, pullNum}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "GetPullRequest", params)
+	return &BitbucketServerPullGetter_GetPullRequest_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type BitbucketServerPullGetter_GetPullRequest_OngoingVerification struct {
+	mock              *MockBitbucketServerPullGetter
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *BitbucketServerPullGetter_GetPullRequest_OngoingVerification) GetCapturedArguments() (models.Repo, int) {
+	repo, pullNum := c.GetAllCapturedArguments()
+	return repo[len(repo)-1], pullNum[len(pullNum)-1]
+}
+
+func (c *BitbucketServerPullGetter_GetPullRequest_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Repo, _param1 []int) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.Repo, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.Repo)
+		}
+		_param1 = make([]int, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(int)
+		}
+	}
+	return
+}
@@ -0,0 +1,85 @@
+// Automatically generated by pegomock. DO NOT EDIT!
+// Source: github.com/hootsuite/atlantis/server/events (interfaces: GiteaPullGetter)
+
+package mocks
+
+import (
+	"reflect"
+
+	models "github.com/hootsuite/atlantis/server/events/models"
+	gitea "github.com/hootsuite/atlantis/server/events/vcs/gitea"
+	pegomock "github.com/petergtz/pegomock"
+)
+
+type MockGiteaPullGetter struct {
+	fail func(message string, callerSkip ...int)
+}
+
+func NewMockGiteaPullGetter() *MockGiteaPullGetter {
+	return &MockGiteaPullGetter{fail: pegomock.GlobalFailHandler}
+}
+
+func (mock *MockGiteaPullGetter) GetPullRequest(repo models.Repo, pullNum int) (gitea.PullRequest, error) {
+	params := []pegomock.Param{repo, pullNum}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("GetPullRequest", params, []reflect.Type{reflect.TypeOf((*gitea.PullRequest)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 gitea.PullRequest
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(gitea.PullRequest)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
+func (mock *MockGiteaPullGetter) VerifyWasCalledOnce() *VerifierGiteaPullGetter {
+	return &VerifierGiteaPullGetter{mock, pegomock.Times(1), nil}
+}
+
+func (mock *MockGiteaPullGetter) VerifyWasCalled(invocationCountMatcher pegomock.Matcher) *VerifierGiteaPullGetter {
+	return &VerifierGiteaPullGetter{mock, invocationCountMatcher, nil}
+}
+
+func (mock *MockGiteaPullGetter) VerifyWasCalledInOrder(invocationCountMatcher pegomock.Matcher, inOrderContext *pegomock.InOrderContext) *VerifierGiteaPullGetter {
+	return &VerifierGiteaPullGetter{mock, invocationCountMatcher, inOrderContext}
+}
+
+type VerifierGiteaPullGetter struct {
+	mock                   *MockGiteaPullGetter
+	invocationCountMatcher pegomock.Matcher
+	inOrderContext         *pegomock.InOrderContext
+}
+
+func (verifier *VerifierGiteaPullGetter) GetPullRequest(repo models.Repo, pullNum int) *GiteaPullGetter_GetPullRequest_OngoingVerification {
+	params := []pegomock.Param{repo, pullNum}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "GetPullRequest", params)
+	return &GiteaPullGetter_GetPullRequest_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type GiteaPullGetter_GetPullRequest_OngoingVerification struct {
+	mock              *MockGiteaPullGetter
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *GiteaPullGetter_GetPullRequest_OngoingVerification) GetCapturedArguments() (models.Repo, int) {
+	repo, pullNum := c.GetAllCapturedArguments()
+	return repo[len(repo)-1], pullNum[len(pullNum)-1]
+}
+
+func (c *GiteaPullGetter_GetPullRequest_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Repo, _param1 []int) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.Repo, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.Repo)
+		}
+		_param1 = make([]int, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(int)
+		}
+	}
+	return
+}
@@ -0,0 +1,80 @@
+// Automatically generated by pegomock. DO NOT EDIT!
+// Source: github.com/hootsuite/atlantis/server/events (interfaces: GithubAppTokenGetter)
+
+package mocks
+
+import (
+	"reflect"
+
+	models "github.com/hootsuite/atlantis/server/events/models"
+	pegomock "github.com/petergtz/pegomock"
+)
+
+type MockGithubAppTokenGetter struct {
+	fail func(message string, callerSkip ...int)
+}
+
+func NewMockGithubAppTokenGetter() *MockGithubAppTokenGetter {
+	return &MockGithubAppTokenGetter{fail: pegomock.GlobalFailHandler}
+}
+
+func (mock *MockGithubAppTokenGetter) GetInstallationToken(repo models.Repo) (string, error) {
+	params := []pegomock.Param{repo}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("GetInstallationToken", params, []reflect.Type{reflect.TypeOf((*string)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 string
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(string)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
+func (mock *MockGithubAppTokenGetter) VerifyWasCalledOnce() *VerifierGithubAppTokenGetter {
+	return &VerifierGithubAppTokenGetter{mock, pegomock.Times(1), nil}
+}
+
+func (mock *MockGithubAppTokenGetter) VerifyWasCalled(invocationCountMatcher pegomock.Matcher) *VerifierGithubAppTokenGetter {
+	return &VerifierGithubAppTokenGetter{mock, invocationCountMatcher, nil}
+}
+
+func (mock *MockGithubAppTokenGetter) VerifyWasCalledInOrder(invocationCountMatcher pegomock.Matcher, inOrderContext *pegomock.InOrderContext) *VerifierGithubAppTokenGetter {
+	return &VerifierGithubAppTokenGetter{mock, invocationCountMatcher, inOrderContext}
+}
+
+type VerifierGithubAppTokenGetter struct {
+	mock                   *MockGithubAppTokenGetter
+	invocationCountMatcher pegomock.Matcher
+	inOrderContext         *pegomock.InOrderContext
+}
+
+func (verifier *VerifierGithubAppTokenGetter) GetInstallationToken(repo models.Repo) *GithubAppTokenGetter_GetInstallationToken_OngoingVerification {
+	params := []pegomock.Param{repo}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "GetInstallationToken", params)
+	return &GithubAppTokenGetter_GetInstallationToken_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type GithubAppTokenGetter_GetInstallationToken_OngoingVerification struct {
+	mock              *MockGithubAppTokenGetter
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *GithubAppTokenGetter_GetInstallationToken_OngoingVerification) GetCapturedArguments() models.Repo {
+	repo := c.GetAllCapturedArguments()
+	return repo[len(repo)-1]
+}
+
+func (c *GithubAppTokenGetter_GetInstallationToken_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Repo) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.Repo, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.Repo)
+		}
+	}
+	return
+}
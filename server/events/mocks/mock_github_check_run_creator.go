@@ -0,0 +1,81 @@
+// Automatically generated by pegomock. DO NOT EDIT!
+// Source: github.com/hootsuite/atlantis/server/events (interfaces: GithubCheckRunCreator)
+
+package mocks
+
+import (
+	"reflect"
+
+	models "github.com/hootsuite/atlantis/server/events/models"
+	githubchecks "github.com/hootsuite/atlantis/server/events/vcs/githubchecks"
+	pegomock "github.com/petergtz/pegomock"
+)
+
+type MockGithubCheckRunCreator struct {
+	fail func(message string, callerSkip ...int)
+}
+
+func NewMockGithubCheckRunCreator() *MockGithubCheckRunCreator {
+	return &MockGithubCheckRunCreator{fail: pegomock.GlobalFailHandler}
+}
+
+func (mock *MockGithubCheckRunCreator) CreateCheckRun(repo models.Repo, run githubchecks.CheckRun) error {
+	params := []pegomock.Param{repo, run}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("CreateCheckRun", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
+func (mock *MockGithubCheckRunCreator) VerifyWasCalledOnce() *VerifierGithubCheckRunCreator {
+	return &VerifierGithubCheckRunCreator{mock, pegomock.Times(1), nil}
+}
+
+func (mock *MockGithubCheckRunCreator) VerifyWasCalled(invocationCountMatcher pegomock.Matcher) *VerifierGithubCheckRunCreator {
+	return &VerifierGithubCheckRunCreator{mock, invocationCountMatcher, nil}
+}
+
+func (mock *MockGithubCheckRunCreator) VerifyWasCalledInOrder(invocationCountMatcher pegomock.Matcher, inOrderContext *pegomock.InOrderContext) *VerifierGithubCheckRunCreator {
+	return &VerifierGithubCheckRunCreator{mock, invocationCountMatcher, inOrderContext}
+}
+
+type VerifierGithubCheckRunCreator struct {
+	mock                   *MockGithubCheckRunCreator
+	invocationCountMatcher pegomock.Matcher
+	inOrderContext         *pegomock.InOrderContext
+}
+
+func (verifier *VerifierGithubCheckRunCreator) CreateCheckRun(repo models.Repo, run githubchecks.CheckRun) *GithubCheckRunCreator_CreateCheckRun_OngoingVerification {
+	params := []pegomock.Param{repo, run}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "CreateCheckRun", params)
+	return &GithubCheckRunCreator_CreateCheckRun_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type GithubCheckRunCreator_CreateCheckRun_OngoingVerification struct {
+	mock              *MockGithubCheckRunCreator
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *GithubCheckRunCreator_CreateCheckRun_OngoingVerification) GetCapturedArguments() (models.Repo, githubchecks.CheckRun) {
+	repo, run := c.GetAllCapturedArguments()
+	return repo[len(repo)-1], run[len(run)-1]
+}
+
+func (c *GithubCheckRunCreator_CreateCheckRun_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Repo, _param1 []githubchecks.CheckRun) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.Repo, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.Repo)
+		}
+		_param1 = make([]githubchecks.CheckRun, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(githubchecks.CheckRun)
+		}
+	}
+	return
+}
@@ -6,6 +6,7 @@ package mocks
 import (
 	"reflect"
 
+	events "github.com/hootsuite/atlantis/server/events"
 	models "github.com/hootsuite/atlantis/server/events/models"
 	logging "github.com/hootsuite/atlantis/server/logging"
 	pegomock "github.com/petergtz/pegomock"
@@ -31,6 +32,18 @@ func (mock *MockProjectFinder) FindModified(log *logging.SimpleLogger, modifiedF
 	return ret0
 }
 
+func (mock *MockProjectFinder) FindModifiedInConfig(log *logging.SimpleLogger, modifiedFiles []string, config events.RepoConfig) []events.RepoConfigProject {
+	params := []pegomock.Param{log, modifiedFiles, config}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("FindModifiedInConfig", params, []reflect.Type{reflect.TypeOf((*[]events.RepoConfigProject)(nil)).Elem()})
+	var ret0 []events.RepoConfigProject
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].([]events.RepoConfigProject)
+		}
+	}
+	return ret0
+}
+
 func (mock *MockProjectFinder) VerifyWasCalledOnce() *VerifierProjectFinder {
 	return &VerifierProjectFinder{mock, pegomock.Times(1), nil}
 }
@@ -83,3 +96,38 @@ func (c *ProjectFinder_FindModified_OngoingVerification) GetAllCapturedArguments
 	}
 	return
 }
+
+func (verifier *VerifierProjectFinder) FindModifiedInConfig(log *logging.SimpleLogger, modifiedFiles []string, config events.RepoConfig) *ProjectFinder_FindModifiedInConfig_OngoingVerification {
+	params := []pegomock.Param{log, modifiedFiles, config}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "FindModifiedInConfig", params)
+	return &ProjectFinder_FindModifiedInConfig_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type ProjectFinder_FindModifiedInConfig_OngoingVerification struct {
+	mock              *MockProjectFinder
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *ProjectFinder_FindModifiedInConfig_OngoingVerification) GetCapturedArguments() (*logging.SimpleLogger, []string, events.RepoConfig) {
+	log, modifiedFiles, config := c.GetAllCapturedArguments()
+	return log[len(log)-1], modifiedFiles[len(modifiedFiles)-1], config[len(config)-1]
+}
+
+func (c *ProjectFinder_FindModifiedInConfig_OngoingVerification) GetAllCapturedArguments() (_param0 []*logging.SimpleLogger, _param1 [][]string, _param2 []events.RepoConfig) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]*logging.SimpleLogger, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(*logging.SimpleLogger)
+		}
+		_param1 = make([][]string, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.([]string)
+		}
+		_param2 = make([]events.RepoConfig, len(params[2]))
+		for u, param := range params[2] {
+			_param2[u] = param.(events.RepoConfig)
+		}
+	}
+	return
+}
@@ -0,0 +1,80 @@
+// Automatically generated by pegomock. DO NOT EDIT!
+// Source: github.com/hootsuite/atlantis/server/events (interfaces: RepoConfigReader)
+
+package mocks
+
+import (
+	"reflect"
+
+	events "github.com/hootsuite/atlantis/server/events"
+	pegomock "github.com/petergtz/pegomock"
+)
+
+type MockRepoConfigReader struct {
+	fail func(message string, callerSkip ...int)
+}
+
+func NewMockRepoConfigReader() *MockRepoConfigReader {
+	return &MockRepoConfigReader{fail: pegomock.GlobalFailHandler}
+}
+
+func (mock *MockRepoConfigReader) Read(repoDir string) (*events.RepoConfig, error) {
+	params := []pegomock.Param{repoDir}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("Read", params, []reflect.Type{reflect.TypeOf((**events.RepoConfig)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 *events.RepoConfig
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(*events.RepoConfig)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
+func (mock *MockRepoConfigReader) VerifyWasCalledOnce() *VerifierRepoConfigReader {
+	return &VerifierRepoConfigReader{mock, pegomock.Times(1), nil}
+}
+
+func (mock *MockRepoConfigReader) VerifyWasCalled(invocationCountMatcher pegomock.Matcher) *VerifierRepoConfigReader {
+	return &VerifierRepoConfigReader{mock, invocationCountMatcher, nil}
+}
+
+func (mock *MockRepoConfigReader) VerifyWasCalledInOrder(invocationCountMatcher pegomock.Matcher, inOrderContext *pegomock.InOrderContext) *VerifierRepoConfigReader {
+	return &VerifierRepoConfigReader{mock, invocationCountMatcher, inOrderContext}
+}
+
+type VerifierRepoConfigReader struct {
+	mock                   *MockRepoConfigReader
+	invocationCountMatcher pegomock.Matcher
+	inOrderContext         *pegomock.InOrderContext
+}
+
+func (verifier *VerifierRepoConfigReader) Read(repoDir string) *RepoConfigReader_Read_OngoingVerification {
+	params := []pegomock.Param{repoDir}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "Read", params)
+	return &RepoConfigReader_Read_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type RepoConfigReader_Read_OngoingVerification struct {
+	mock              *MockRepoConfigReader
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *RepoConfigReader_Read_OngoingVerification) GetCapturedArguments() string {
+	repoDir := c.GetAllCapturedArguments()
+	return repoDir[len(repoDir)-1]
+}
+
+func (c *RepoConfigReader_Read_OngoingVerification) GetAllCapturedArguments() (_param0 []string) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+	}
+	return
+}
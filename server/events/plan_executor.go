@@ -36,6 +36,13 @@ type PlanExecutor struct {
 	Workspace         AtlantisWorkspace
 	ProjectPreExecute ProjectPreExecutor
 	ProjectFinder     ProjectFinder
+	RepoConfigReader  RepoConfigReader
+	// ParallelPoolSize is the max number of projects planned at once unless
+	// ServerRepoConfig sets it for the repo.
+	ParallelPoolSize int
+	ServerRepoConfig *ServerRepoConfig
+	// StepTimeouts are the timeouts of steps that don't set their own.
+	StepTimeouts StepTimeouts
 }
 
 // PlanSuccess is the result of a successful plan.
@@ -52,69 +59,123 @@ func (p *PlanExecutor) SetLockURL(f func(id string) (url string)) {
 
 // Execute executes terraform plan for the ctx.
 func (p *PlanExecutor) Execute(ctx *CommandContext) CommandResponse {
-	// Figure out what projects have been modified so we know where to run plan.
-	modifiedFiles, err := p.VCSClient.GetModifiedFiles(ctx.BaseRepo, ctx.Pull, ctx.VCSHost)
+	cloneDir, err := p.Workspace.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, ctx.Command.Workspace)
 	if err != nil {
-		return CommandResponse{Error: errors.Wrap(err, "getting modified files")}
-	}
-	ctx.Log.Info("found %d files modified in this pull request", len(modifiedFiles))
-	projects := p.ProjectFinder.FindModified(ctx.Log, modifiedFiles, ctx.BaseRepo.FullName)
-	if len(projects) == 0 {
-		return CommandResponse{Failure: "No Terraform files were modified."}
+		return CommandResponse{Error: err}
 	}
-
-	cloneDir, err := p.Workspace.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, ctx.Command.Workspace)
+	config, err := p.RepoConfigReader.Read(cloneDir)
 	if err != nil {
 		return CommandResponse{Error: err}
 	}
+	if config != nil {
+		ctx.Log.Info("parsed %s with %d project(s)", RepoConfigFile, len(config.Projects))
+	}
+
+	var projects []models.Project
+	switch {
+	case ctx.Command.ProjectName != "":
+		project, failure := findNamedProject(ctx, config)
+		if failure != "" {
+			return CommandResponse{Failure: failure}
+		}
+		projects = []models.Project{models.NewProject(ctx.BaseRepo.FullName, project.Dir)}
+	case ctx.Command.Dir != "":
+		// The user asked for a specific directory so we plan it whether or
+		// not it was modified.
+		if _, err := os.Stat(filepath.Join(cloneDir, ctx.Command.Dir)); err != nil {
+			return CommandResponse{Failure: fmt.Sprintf("Directory %q does not exist.", ctx.Command.Dir)}
+		}
+		projects = []models.Project{models.NewProject(ctx.BaseRepo.FullName, ctx.Command.Dir)}
+	default:
+		// Figure out what projects have been modified so we know where to run plan.
+		modifiedFiles, err := p.VCSClient.GetModifiedFiles(ctx.BaseRepo, ctx.Pull, ctx.VCSHost)
+		if err != nil {
+			return CommandResponse{Error: errors.Wrap(err, "getting modified files")}
+		}
+		ctx.Log.Info("found %d files modified in this pull request", len(modifiedFiles))
+		projects = p.findModified(ctx, modifiedFiles, config)
+		if len(projects) == 0 {
+			if ctx.Command.Autoplan {
+				// If we're autoplanning then most pull requests won't have modified
+				// any Terraform so we don't treat this as a failure.
+				ctx.Log.Info("not autoplanning since no projects were modified")
+				return CommandResponse{}
+			}
+			if config != nil {
+				return CommandResponse{Failure: fmt.Sprintf("No projects in %s that run in workspace %q were modified.", RepoConfigFile, ctx.Command.Workspace)}
+			}
+			return CommandResponse{Failure: "No Terraform files were modified."}
+		}
+	}
 
-	var results []ProjectResult
+	var paths []string
 	for _, project := range projects {
-		ctx.Log.Info("running plan for project at path %q", project.Path)
-		result := p.plan(ctx, cloneDir, project)
-		result.Path = project.Path
-		results = append(results, result)
+		paths = append(paths, project.Path)
 	}
+	poolSize := parallelPoolSize(ctx, p.ParallelPoolSize, p.ServerRepoConfig)
+	results := runProjects(ctx, poolSize, paths, func(projectCtx *CommandContext, i int) ProjectResult {
+		projectCtx.Log.Info("running plan for project at path %q", projects[i].Path)
+		result := p.plan(projectCtx, cloneDir, projects[i])
+		result.Path = projects[i].Path
+		return result
+	})
 	return CommandResponse{ProjectResults: results}
 }
 
+// findModified returns the modified projects. If the repo has a config then
+// only the projects listed in it that run in the command's workspace are
+// returned. Otherwise the projects are determined from the paths of the
+// modified files.
+func (p *PlanExecutor) findModified(ctx *CommandContext, modifiedFiles []string, config *RepoConfig) []models.Project {
+	if config == nil {
+		return p.ProjectFinder.FindModified(ctx.Log, modifiedFiles, ctx.BaseRepo.FullName)
+	}
+	var projects []models.Project
+	for _, project := range p.ProjectFinder.FindModifiedInConfig(ctx.Log, modifiedFiles, *config) {
+		if !project.HasWorkspace(ctx.Command.Workspace) {
+			ctx.Log.Info("skipping project at path %q since it doesn't run in workspace %q", project.Dir, ctx.Command.Workspace)
+			continue
+		}
+		projects = append(projects, models.NewProject(ctx.BaseRepo.FullName, project.Dir))
+	}
+	return projects
+}
+
+// findNamedProject returns the project from config that the command targeted
+// with -p. If it can't be found or doesn't run in the command's workspace,
+// failure describes why.
+func findNamedProject(ctx *CommandContext, config *RepoConfig) (project RepoConfigProject, failure string) {
+	name := ctx.Command.ProjectName
+	if config == nil {
+		return project, fmt.Sprintf("Can't find project %q since there are no projects listed in %s.", name, RepoConfigFile)
+	}
+	project, ok := config.FindByName(name)
+	if !ok {
+		return project, fmt.Sprintf("No project named %q is listed in %s.", name, RepoConfigFile)
+	}
+	if !project.HasWorkspace(ctx.Command.Workspace) {
+		return project, fmt.Sprintf("Project %q doesn't run in workspace %q.", name, ctx.Command.Workspace)
+	}
+	return project, ""
+}
+
 func (p *PlanExecutor) plan(ctx *CommandContext, repoDir string, project models.Project) ProjectResult {
 	preExecute := p.ProjectPreExecute.Execute(ctx, repoDir, project)
 	if preExecute.ProjectResult != (ProjectResult{}) {
 		return preExecute.ProjectResult
 	}
-	config := preExecute.ProjectConfig
-	terraformVersion := preExecute.TerraformVersion
-	workspace := ctx.Command.Workspace
-
-	// Run terraform plan.
-	planFile := filepath.Join(repoDir, project.Path, fmt.Sprintf("%s.tfplan", workspace))
-	userVar := fmt.Sprintf("%s=%s", atlantisUserTFVar, ctx.User.Username)
-	planExtraArgs := config.GetExtraArguments(ctx.Command.Name.String())
-	tfPlanCmd := append(append([]string{"plan", "-refresh", "-no-color", "-out", planFile, "-var", userVar}, planExtraArgs...), ctx.Command.Flags...)
-
-	// Check if env/{workspace}.tfvars exist.
-	envFileName := filepath.Join("env", workspace+".tfvars")
-	if _, err := os.Stat(filepath.Join(repoDir, project.Path, envFileName)); err == nil {
-		tfPlanCmd = append(tfPlanCmd, "-var-file", envFileName)
+
+	runner := stageRunner{Terraform: p.Terraform, Run: p.Run, Timeouts: p.StepTimeouts}
+	output, err := runner.RunStage(ctx, preExecute.Workflow.Plan, repoDir, project, preExecute.TerraformVersion)
+	if err == nil {
+		err = p.writePlanMetadata(ctx, repoDir, project)
 	}
-	output, err := p.Terraform.RunCommandWithVersion(ctx.Log, filepath.Join(repoDir, project.Path), tfPlanCmd, terraformVersion, workspace)
 	if err != nil {
 		// Plan failed so unlock the state.
 		if _, unlockErr := p.Locker.Unlock(preExecute.LockResponse.LockKey); unlockErr != nil {
 			ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
 		}
-		return ProjectResult{Error: fmt.Errorf("%s\n%s", err.Error(), output)}
-	}
-	ctx.Log.Info("plan succeeded")
-
-	// If there are post plan commands then run them.
-	if len(config.PostPlan) > 0 {
-		absolutePath := filepath.Join(repoDir, project.Path)
-		_, err := p.Run.Execute(ctx.Log, config.PostPlan, absolutePath, workspace, terraformVersion, "post_plan")
-		if err != nil {
-			return ProjectResult{Error: errors.Wrap(err, "running post plan commands")}
-		}
+		return stageFailedResult(err)
 	}
 
 	return ProjectResult{
@@ -124,3 +185,17 @@ func (p *PlanExecutor) plan(ctx *CommandContext, repoDir string, project models.
 		},
 	}
 }
+
+// writePlanMetadata records the commit and inputs of the project's plan so
+// that applying a stale plan can be refused.
+func (p *PlanExecutor) writePlanMetadata(ctx *CommandContext, repoDir string, project models.Project) error {
+	projectDir := filepath.Join(repoDir, project.Path)
+	hash, err := HashPlanInputs(projectDir)
+	if err != nil {
+		return errors.Wrap(err, "hashing plan inputs")
+	}
+	return WritePlanMetadata(planFilePath(projectDir, ctx.Command.Workspace), PlanMetadata{
+		Commit:     ctx.Pull.HeadCommit,
+		InputsHash: hash,
+	})
+}
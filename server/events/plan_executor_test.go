@@ -1,16 +1,22 @@
 package events_test
 
 import (
+	"context"
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/hootsuite/atlantis/server/events"
 	"github.com/hootsuite/atlantis/server/events/locking"
 	lmocks "github.com/hootsuite/atlantis/server/events/locking/mocks"
 	"github.com/hootsuite/atlantis/server/events/mocks"
+	ematchers "github.com/hootsuite/atlantis/server/events/mocks/matchers"
 	"github.com/hootsuite/atlantis/server/events/models"
 	rmocks "github.com/hootsuite/atlantis/server/events/run/mocks"
 	tmocks "github.com/hootsuite/atlantis/server/events/terraform/mocks"
+	tmatchers "github.com/hootsuite/atlantis/server/events/terraform/mocks/matchers"
 	vcsmocks "github.com/hootsuite/atlantis/server/events/vcs/mocks"
 	"github.com/hootsuite/atlantis/server/events/vcs/mocks/matchers"
 	"github.com/hootsuite/atlantis/server/logging"
@@ -18,6 +24,13 @@ import (
 	. "github.com/petergtz/pegomock"
 )
 
+// planWorkflow only runs plan so tests don't need to stub init.
+var planWorkflow = events.Workflow{
+	Plan: events.Stage{
+		Steps: []events.Step{{Name: events.PlanStepName}},
+	},
+}
+
 var planCtx = events.CommandContext{
 	Command: &events.Command{
 		Name:      events.Plan,
@@ -30,6 +43,7 @@ var planCtx = events.CommandContext{
 	User: models.User{
 		Username: "anubhavmishra",
 	},
+	Context: context.Background(),
 }
 
 func TestExecute_ModifiedFilesErr(t *testing.T) {
@@ -52,6 +66,20 @@ func TestExecute_NoModifiedProjects(t *testing.T) {
 	Equals(t, "No Terraform files were modified.", r.Failure)
 }
 
+func TestExecute_AutoplanNoModifiedProjects(t *testing.T) {
+	t.Log("If we're autoplanning and there are no modified projects we return an empty response")
+	p, _, _ := setupPlanExecutorTest(t)
+	ctx := planCtx
+	ctx.Command = &events.Command{
+		Name:      events.Plan,
+		Workspace: "workspace",
+		Autoplan:  true,
+	}
+	r := p.Execute(&ctx)
+
+	Equals(t, events.CommandResponse{}, r)
+}
+
 func TestExecute_CloneErr(t *testing.T) {
 	t.Log("If AtlantisWorkspace.Clone returns an error we return an error")
 	p, _, _ := setupPlanExecutorTest(t)
@@ -66,30 +94,230 @@ func TestExecute_CloneErr(t *testing.T) {
 func TestExecute_Success(t *testing.T) {
 	t.Log("If there are no errors, the plan should be returned")
 	p, runner, _ := setupPlanExecutorTest(t)
+	cloneDir, err := ioutil.TempDir("", "")
+	Ok(t, err)
+	defer os.RemoveAll(cloneDir)
+	err = ioutil.WriteFile(filepath.Join(cloneDir, "file.tf"), []byte("resource"), 0600)
+	Ok(t, err)
+	ctx := planCtx
+	ctx.Pull = models.PullRequest{HeadCommit: "abc123"}
 	When(p.VCSClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyVcsHost())).ThenReturn([]string{"file.tf"}, nil)
-	When(p.Workspace.Clone(planCtx.Log, planCtx.BaseRepo, planCtx.HeadRepo, planCtx.Pull, "workspace")).
-		ThenReturn("/tmp/clone-repo", nil)
-	When(p.ProjectPreExecute.Execute(&planCtx, "/tmp/clone-repo", models.Project{RepoFullName: "", Path: "."})).
+	When(p.Workspace.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, "workspace")).
+		ThenReturn(cloneDir, nil)
+	When(p.ProjectPreExecute.Execute(ematchers.AnyPtrToEventsCommandContext(), EqString(cloneDir), ematchers.EqModelsProject(models.Project{RepoFullName: "", Path: "."}))).
 		ThenReturn(events.PreExecuteResult{
 			LockResponse: locking.TryLockResponse{
 				LockKey: "key",
 			},
+			Workflow: planWorkflow,
 		})
 
-	r := p.Execute(&planCtx)
+	r := p.Execute(&ctx)
 
+	planFile := filepath.Join(cloneDir, "workspace.tfplan")
 	runner.VerifyWasCalledOnce().RunCommandWithVersion(
-		planCtx.Log,
-		"/tmp/clone-repo",
-		[]string{"plan", "-refresh", "-no-color", "-out", "/tmp/clone-repo/workspace.tfplan", "-var", "atlantis_user=anubhavmishra"},
-		nil,
-		"workspace",
+		tmatchers.AnyContextContext(),
+		tmatchers.AnyPtrToLoggingSimpleLogger(),
+		EqString(cloneDir),
+		tmatchers.EqSliceOfString([]string{"plan", "-refresh", "-no-color", "-out", planFile, "-var", "atlantis_user=anubhavmishra"}),
+		tmatchers.EqSliceOfString(nil),
+		tmatchers.EqPtrToGoVersionVersion(nil),
+		EqString("workspace"),
 	)
 	Assert(t, len(r.ProjectResults) == 1, "exp one project result")
 	result := r.ProjectResults[0]
 	Assert(t, result.PlanSuccess != nil, "exp plan success to not be nil")
 	Equals(t, "", result.PlanSuccess.TerraformOutput)
 	Equals(t, "lockurl-key", result.PlanSuccess.LockURL)
+
+	t.Log("the commit and inputs that were planned should be recorded")
+	m, err := events.ReadPlanMetadata(planFile)
+	Ok(t, err)
+	hash, err := events.HashPlanInputs(cloneDir)
+	Ok(t, err)
+	Equals(t, events.PlanMetadata{Commit: "abc123", InputsHash: hash}, m)
+}
+
+func TestExecute_DirNotModified(t *testing.T) {
+	t.Log("If a directory is specified we plan it even if it wasn't modified")
+	p, runner, _ := setupPlanExecutorTest(t)
+	ctx := planCtx
+	ctx.Command = &events.Command{
+		Name:      events.Plan,
+		Workspace: "workspace",
+		Dir:       "path",
+	}
+	cloneDir, err := ioutil.TempDir("", "")
+	Ok(t, err)
+	defer os.RemoveAll(cloneDir)
+	err = os.Mkdir(filepath.Join(cloneDir, "path"), 0700)
+	Ok(t, err)
+	When(p.Workspace.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, "workspace")).
+		ThenReturn(cloneDir, nil)
+	When(p.ProjectPreExecute.Execute(ematchers.AnyPtrToEventsCommandContext(), EqString(cloneDir), ematchers.EqModelsProject(models.Project{RepoFullName: "", Path: "path"}))).
+		ThenReturn(events.PreExecuteResult{
+			LockResponse: locking.TryLockResponse{
+				LockKey: "key",
+			},
+			Workflow: planWorkflow,
+		})
+
+	r := p.Execute(&ctx)
+
+	p.VCSClient.(*vcsmocks.MockClientProxy).VerifyWasCalled(Never()).GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyVcsHost())
+	planFile := filepath.Join(cloneDir, "path", "workspace.tfplan")
+	runner.VerifyWasCalledOnce().RunCommandWithVersion(
+		tmatchers.AnyContextContext(),
+		tmatchers.AnyPtrToLoggingSimpleLogger(),
+		EqString(filepath.Join(cloneDir, "path")),
+		tmatchers.EqSliceOfString([]string{"plan", "-refresh", "-no-color", "-out", planFile, "-var", "atlantis_user=anubhavmishra"}),
+		tmatchers.EqSliceOfString(nil),
+		tmatchers.EqPtrToGoVersionVersion(nil),
+		EqString("workspace"),
+	)
+	Equals(t, 1, len(r.ProjectResults))
+	Equals(t, "path", r.ProjectResults[0].Path)
+}
+
+func TestExecute_DirDoesNotExist(t *testing.T) {
+	t.Log("If a directory is specified that doesn't exist we return a failure")
+	p, _, _ := setupPlanExecutorTest(t)
+	ctx := planCtx
+	ctx.Command = &events.Command{
+		Name:      events.Plan,
+		Workspace: "workspace",
+		Dir:       "path",
+	}
+	cloneDir, err := ioutil.TempDir("", "")
+	Ok(t, err)
+	defer os.RemoveAll(cloneDir)
+	When(p.Workspace.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, "workspace")).
+		ThenReturn(cloneDir, nil)
+
+	r := p.Execute(&ctx)
+
+	Equals(t, "Directory \"path\" does not exist.", r.Failure)
+}
+
+func TestExecute_RepoConfig(t *testing.T) {
+	t.Log("If there's a repo config, only its modified projects that run in the workspace should be planned")
+	p, runner, _ := setupPlanExecutorTest(t)
+	When(p.VCSClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyVcsHost())).
+		ThenReturn([]string{"a/main.tf", "b/main.tf", "c/main.tf"}, nil)
+	When(p.Workspace.Clone(planCtx.Log, planCtx.BaseRepo, planCtx.HeadRepo, planCtx.Pull, "workspace")).
+		ThenReturn("/tmp/clone-repo", nil)
+	When(p.RepoConfigReader.Read("/tmp/clone-repo")).ThenReturn(&events.RepoConfig{
+		Projects: []events.RepoConfigProject{
+			{Dir: "a", Workspaces: []string{"workspace"}, WhenModified: events.DefaultWhenModified},
+			{Dir: "b", Workspaces: []string{"staging"}, WhenModified: events.DefaultWhenModified},
+			{Dir: "d", Workspaces: []string{"workspace"}, WhenModified: events.DefaultWhenModified},
+		},
+	}, nil)
+	When(p.ProjectPreExecute.Execute(ematchers.AnyPtrToEventsCommandContext(), EqString("/tmp/clone-repo"), ematchers.EqModelsProject(models.Project{RepoFullName: "", Path: "a"}))).
+		ThenReturn(events.PreExecuteResult{Workflow: planWorkflow})
+
+	r := p.Execute(&planCtx)
+
+	runner.VerifyWasCalledOnce().RunCommandWithVersion(
+		tmatchers.AnyContextContext(),
+		tmatchers.AnyPtrToLoggingSimpleLogger(),
+		EqString("/tmp/clone-repo/a"),
+		tmatchers.EqSliceOfString([]string{"plan", "-refresh", "-no-color", "-out", "/tmp/clone-repo/a/workspace.tfplan", "-var", "atlantis_user=anubhavmishra"}),
+		tmatchers.EqSliceOfString(nil),
+		tmatchers.EqPtrToGoVersionVersion(nil),
+		EqString("workspace"),
+	)
+	Equals(t, 1, len(r.ProjectResults))
+	Equals(t, "a", r.ProjectResults[0].Path)
+}
+
+func TestExecute_RepoConfigNoModifiedProjects(t *testing.T) {
+	t.Log("If there's a repo config and none of its projects were modified we return a failure")
+	p, _, _ := setupPlanExecutorTest(t)
+	When(p.VCSClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyVcsHost())).
+		ThenReturn([]string{"a/main.tf"}, nil)
+	When(p.RepoConfigReader.Read("")).ThenReturn(&events.RepoConfig{
+		Projects: []events.RepoConfigProject{
+			{Dir: "a", Workspaces: []string{"staging"}, WhenModified: events.DefaultWhenModified},
+		},
+	}, nil)
+
+	r := p.Execute(&planCtx)
+
+	Equals(t, "No projects in atlantis.yaml that run in workspace \"workspace\" were modified.", r.Failure)
+}
+
+func TestExecute_ProjectName(t *testing.T) {
+	t.Log("If a project is specified by name we plan it even if it wasn't modified")
+	p, runner, _ := setupPlanExecutorTest(t)
+	ctx := planCtx
+	ctx.Command = &events.Command{
+		Name:        events.Plan,
+		Workspace:   "workspace",
+		ProjectName: "vpc",
+	}
+	When(p.Workspace.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, "workspace")).
+		ThenReturn("/tmp/clone-repo", nil)
+	When(p.RepoConfigReader.Read("/tmp/clone-repo")).ThenReturn(&events.RepoConfig{
+		Projects: []events.RepoConfigProject{
+			{Name: "vpc", Dir: "infra/vpc", Workspaces: []string{"workspace"}},
+		},
+	}, nil)
+	When(p.ProjectPreExecute.Execute(ematchers.AnyPtrToEventsCommandContext(), EqString("/tmp/clone-repo"), ematchers.EqModelsProject(models.Project{RepoFullName: "", Path: "infra/vpc"}))).
+		ThenReturn(events.PreExecuteResult{Workflow: planWorkflow})
+
+	r := p.Execute(&ctx)
+
+	p.VCSClient.(*vcsmocks.MockClientProxy).VerifyWasCalled(Never()).GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyVcsHost())
+	runner.VerifyWasCalledOnce().RunCommandWithVersion(
+		tmatchers.AnyContextContext(),
+		tmatchers.AnyPtrToLoggingSimpleLogger(),
+		EqString("/tmp/clone-repo/infra/vpc"),
+		tmatchers.EqSliceOfString([]string{"plan", "-refresh", "-no-color", "-out", "/tmp/clone-repo/infra/vpc/workspace.tfplan", "-var", "atlantis_user=anubhavmishra"}),
+		tmatchers.EqSliceOfString(nil),
+		tmatchers.EqPtrToGoVersionVersion(nil),
+		EqString("workspace"),
+	)
+	Equals(t, 1, len(r.ProjectResults))
+	Equals(t, "infra/vpc", r.ProjectResults[0].Path)
+}
+
+func TestExecute_ProjectNameFailures(t *testing.T) {
+	cases := []struct {
+		description string
+		config      *events.RepoConfig
+		expFailure  string
+	}{
+		{
+			"no repo config",
+			nil,
+			"Can't find project \"vpc\" since there are no projects listed in atlantis.yaml.",
+		},
+		{
+			"no project with that name",
+			&events.RepoConfig{Projects: []events.RepoConfigProject{{Name: "db", Dir: "db", Workspaces: []string{"workspace"}}}},
+			"No project named \"vpc\" is listed in atlantis.yaml.",
+		},
+		{
+			"project doesn't run in the workspace",
+			&events.RepoConfig{Projects: []events.RepoConfigProject{{Name: "vpc", Dir: "vpc", Workspaces: []string{"staging"}}}},
+			"Project \"vpc\" doesn't run in workspace \"workspace\".",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			p, _, _ := setupPlanExecutorTest(t)
+			ctx := planCtx
+			ctx.Command = &events.Command{
+				Name:        events.Plan,
+				Workspace:   "workspace",
+				ProjectName: "vpc",
+			}
+			When(p.RepoConfigReader.Read("")).ThenReturn(c.config, nil)
+			r := p.Execute(&ctx)
+			Equals(t, c.expFailure, r.Failure)
+		})
+	}
 }
 
 func TestExecute_PreExecuteResult(t *testing.T) {
@@ -101,7 +329,7 @@ func TestExecute_PreExecuteResult(t *testing.T) {
 	projectResult := events.ProjectResult{
 		Failure: "failure",
 	}
-	When(p.ProjectPreExecute.Execute(&planCtx, "/tmp/clone-repo", models.Project{RepoFullName: "", Path: "."})).
+	When(p.ProjectPreExecute.Execute(ematchers.AnyPtrToEventsCommandContext(), EqString("/tmp/clone-repo"), ematchers.EqModelsProject(models.Project{RepoFullName: "", Path: "."}))).
 		ThenReturn(events.PreExecuteResult{ProjectResult: projectResult})
 	r := p.Execute(&planCtx)
 
@@ -113,24 +341,33 @@ func TestExecute_PreExecuteResult(t *testing.T) {
 func TestExecute_MultiProjectFailure(t *testing.T) {
 	t.Log("If is an error planning in one project it should be returned. It shouldn't affect another project though.")
 	p, runner, locker := setupPlanExecutorTest(t)
+	cloneDir, err := ioutil.TempDir("", "")
+	Ok(t, err)
+	defer os.RemoveAll(cloneDir)
+	for _, dir := range []string{"path1", "path2"} {
+		err = os.Mkdir(filepath.Join(cloneDir, dir), 0700)
+		Ok(t, err)
+	}
 	// Two projects have been modified so we should run plan in two paths.
 	When(p.VCSClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyVcsHost())).ThenReturn([]string{"path1/file.tf", "path2/file.tf"}, nil)
 	When(p.Workspace.Clone(planCtx.Log, planCtx.BaseRepo, planCtx.HeadRepo, planCtx.Pull, "workspace")).
-		ThenReturn("/tmp/clone-repo", nil)
+		ThenReturn(cloneDir, nil)
 
 	// Both projects will succeed in the PreExecute stage.
-	When(p.ProjectPreExecute.Execute(&planCtx, "/tmp/clone-repo", models.Project{RepoFullName: "", Path: "path1"})).
-		ThenReturn(events.PreExecuteResult{LockResponse: locking.TryLockResponse{LockKey: "key1"}})
-	When(p.ProjectPreExecute.Execute(&planCtx, "/tmp/clone-repo", models.Project{RepoFullName: "", Path: "path2"})).
-		ThenReturn(events.PreExecuteResult{LockResponse: locking.TryLockResponse{LockKey: "key2"}})
+	When(p.ProjectPreExecute.Execute(ematchers.AnyPtrToEventsCommandContext(), EqString(cloneDir), ematchers.EqModelsProject(models.Project{RepoFullName: "", Path: "path1"}))).
+		ThenReturn(events.PreExecuteResult{LockResponse: locking.TryLockResponse{LockKey: "key1"}, Workflow: planWorkflow})
+	When(p.ProjectPreExecute.Execute(ematchers.AnyPtrToEventsCommandContext(), EqString(cloneDir), ematchers.EqModelsProject(models.Project{RepoFullName: "", Path: "path2"}))).
+		ThenReturn(events.PreExecuteResult{LockResponse: locking.TryLockResponse{LockKey: "key2"}, Workflow: planWorkflow})
 
 	// The first project will fail when running plan
 	When(runner.RunCommandWithVersion(
-		planCtx.Log,
-		"/tmp/clone-repo/path1",
-		[]string{"plan", "-refresh", "-no-color", "-out", "/tmp/clone-repo/path1/workspace.tfplan", "-var", "atlantis_user=anubhavmishra"},
-		nil,
-		"workspace",
+		tmatchers.AnyContextContext(),
+		tmatchers.AnyPtrToLoggingSimpleLogger(),
+		EqString(filepath.Join(cloneDir, "path1")),
+		tmatchers.EqSliceOfString([]string{"plan", "-refresh", "-no-color", "-out", filepath.Join(cloneDir, "path1", "workspace.tfplan"), "-var", "atlantis_user=anubhavmishra"}),
+		tmatchers.EqSliceOfString(nil),
+		tmatchers.EqPtrToGoVersionVersion(nil),
+		EqString("workspace"),
 	)).ThenReturn("", errors.New("path1 err"))
 	// The second will succeed. We don't need to stub it because by default it
 	// will return a nil error.
@@ -151,25 +388,34 @@ func TestExecute_MultiProjectFailure(t *testing.T) {
 	Equals(t, "lockurl-key2", result2.PlanSuccess.LockURL)
 }
 
-func TestExecute_PostPlanCommands(t *testing.T) {
-	t.Log("Should execute post-plan commands and return if there is an error")
-	p, _, _ := setupPlanExecutorTest(t)
+func TestExecute_WorkflowStepErr(t *testing.T) {
+	t.Log("Should execute the steps after plan and return if there is an error")
+	p, _, locker := setupPlanExecutorTest(t)
 	When(p.VCSClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyVcsHost())).ThenReturn([]string{"file.tf"}, nil)
 	When(p.Workspace.Clone(planCtx.Log, planCtx.BaseRepo, planCtx.HeadRepo, planCtx.Pull, "workspace")).
 		ThenReturn("/tmp/clone-repo", nil)
-	When(p.ProjectPreExecute.Execute(&planCtx, "/tmp/clone-repo", models.Project{RepoFullName: "", Path: "."})).
+	When(p.ProjectPreExecute.Execute(ematchers.AnyPtrToEventsCommandContext(), EqString("/tmp/clone-repo"), ematchers.EqModelsProject(models.Project{RepoFullName: "", Path: "."}))).
 		ThenReturn(events.PreExecuteResult{
-			ProjectConfig: events.ProjectConfig{PostPlan: []string{"post-plan"}},
+			LockResponse: locking.TryLockResponse{LockKey: "key"},
+			Workflow: events.Workflow{
+				Plan: events.Stage{
+					Steps: []events.Step{
+						{Name: events.PlanStepName},
+						{Name: events.RunStepName, RunCommand: "post-plan"},
+					},
+				},
+			},
 		})
-	When(p.Run.Execute(planCtx.Log, []string{"post-plan"}, "/tmp/clone-repo", "workspace", nil, "post_plan")).
+	When(p.Run.Execute(tmatchers.AnyContextContext(), tmatchers.AnyPtrToLoggingSimpleLogger(), tmatchers.EqSliceOfString([]string{"post-plan"}), EqString("/tmp/clone-repo"), AnyStringSlice(), EqString("workspace"), tmatchers.AnyPtrToGoVersionVersion(), EqString("run"))).
 		ThenReturn("", errors.New("err"))
 
 	r := p.Execute(&planCtx)
 
+	locker.VerifyWasCalledOnce().Unlock("key")
 	Assert(t, len(r.ProjectResults) == 1, "exp one project result")
 	result := r.ProjectResults[0]
 	Assert(t, result.Error != nil, "exp plan error to not be nil")
-	Equals(t, "running post plan commands: err", result.Error.Error())
+	Equals(t, "running \"post-plan\": err", result.Error.Error())
 }
 
 func setupPlanExecutorTest(t *testing.T) (*events.PlanExecutor, *tmocks.MockClient, *lmocks.MockLocker) {
@@ -188,6 +434,7 @@ func setupPlanExecutorTest(t *testing.T) (*events.PlanExecutor, *tmocks.MockClie
 		Terraform:         runner,
 		Locker:            locker,
 		Run:               run,
+		RepoConfigReader:  mocks.NewMockRepoConfigReader(),
 	}
 	p.LockURL = func(id string) (url string) {
 		return "lockurl-" + id
@@ -0,0 +1,113 @@
+package events
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// planMetadataExt is appended to the name of a plan file, ex. default.tfplan,
+// to get the name of the file its metadata is recorded in.
+const planMetadataExt = ".json"
+
+// PlanMetadata is recorded next to each plan file so that when applying we
+// can tell if the plan is stale.
+type PlanMetadata struct {
+	// Commit is the head commit of the pull request that was planned.
+	Commit string `json:"commit"`
+	// InputsHash is the hash of the project's files when it was planned.
+	// See HashPlanInputs.
+	InputsHash string `json:"inputs_hash"`
+}
+
+// WritePlanMetadata records m for the plan at planFile.
+func WritePlanMetadata(planFile string, m PlanMetadata) error {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, "json encoding plan metadata")
+	}
+	if err := ioutil.WriteFile(planFile+planMetadataExt, raw, 0600); err != nil {
+		return errors.Wrap(err, "writing plan metadata")
+	}
+	return nil
+}
+
+// ReadPlanMetadata returns the metadata recorded for the plan at planFile. If
+// none was recorded the error satisfies os.IsNotExist.
+func ReadPlanMetadata(planFile string) (PlanMetadata, error) {
+	var m PlanMetadata
+	raw, err := ioutil.ReadFile(planFile + planMetadataExt)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return m, errors.Wrap(err, "parsing plan metadata")
+	}
+	return m, nil
+}
+
+// HashPlanInputs returns a hash of the names and contents of the files in
+// projectDir. Terraform only loads the files in the project's directory so
+// subdirectories aren't included, nor are plan files, their metadata or
+// local state files since they're written by Atlantis and Terraform.
+func HashPlanInputs(projectDir string) (string, error) {
+	infos, err := ioutil.ReadDir(projectDir)
+	if err != nil {
+		return "", errors.Wrap(err, "reading project dir")
+	}
+	var names []string
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() ||
+			strings.HasSuffix(name, ".tfplan") ||
+			strings.HasSuffix(name, ".tfplan"+planMetadataExt) ||
+			strings.HasPrefix(name, "terraform.tfstate") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		contents, err := ioutil.ReadFile(filepath.Join(projectDir, name))
+		if err != nil {
+			return "", errors.Wrapf(err, "reading %s", name)
+		}
+		fileHash := sha256.Sum256(contents)
+		fmt.Fprintf(hash, "%s\x00%x\n", name, fileHash)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// checkPlanIsCurrent returns a failure if the plan at planFile wasn't made
+// from the pull request's current head commit or the project's files have
+// changed since. It's the no_new_commits apply requirement.
+func checkPlanIsCurrent(ctx *CommandContext, planFile string) (string, error) {
+	m, err := ReadPlanMetadata(planFile)
+	if os.IsNotExist(err) {
+		return "Can't tell which commit this plan was made from. Run plan again before applying.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if m.Commit != ctx.Pull.HeadCommit {
+		return fmt.Sprintf("This plan is stale: it was made from commit %s but the pull request's head is now %s. Run plan again before applying.", m.Commit, ctx.Pull.HeadCommit), nil
+	}
+	hash, err := HashPlanInputs(filepath.Dir(planFile))
+	if err != nil {
+		return "", err
+	}
+	if hash != m.InputsHash {
+		return "This plan is stale: the project's files have changed since it was made. Run plan again before applying.", nil
+	}
+	return "", nil
+}
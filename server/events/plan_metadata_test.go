@@ -0,0 +1,73 @@
+package events_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+func TestPlanMetadata_RoundTrip(t *testing.T) {
+	t.Log("metadata that's written should be read back")
+	dir, cleanup := tempProjectDir(t)
+	defer cleanup()
+	planFile := filepath.Join(dir, "default.tfplan")
+	m := events.PlanMetadata{Commit: "abc123", InputsHash: "hash"}
+
+	Ok(t, events.WritePlanMetadata(planFile, m))
+	read, err := events.ReadPlanMetadata(planFile)
+	Ok(t, err)
+	Equals(t, m, read)
+}
+
+func TestReadPlanMetadata_NotExist(t *testing.T) {
+	t.Log("if no metadata was written the error should satisfy os.IsNotExist")
+	dir, cleanup := tempProjectDir(t)
+	defer cleanup()
+	_, err := events.ReadPlanMetadata(filepath.Join(dir, "default.tfplan"))
+	Assert(t, os.IsNotExist(err), "exp not exist err but got %v", err)
+}
+
+func TestHashPlanInputs(t *testing.T) {
+	dir, cleanup := tempProjectDir(t)
+	defer cleanup()
+	writeFile(t, filepath.Join(dir, "main.tf"), "resource")
+	hash, err := events.HashPlanInputs(dir)
+	Ok(t, err)
+
+	t.Log("files written by Atlantis and Terraform and subdirectories shouldn't change the hash")
+	writeFile(t, filepath.Join(dir, "default.tfplan"), "plan")
+	writeFile(t, filepath.Join(dir, "default.tfplan.json"), "{}")
+	writeFile(t, filepath.Join(dir, "terraform.tfstate"), "state")
+	writeFile(t, filepath.Join(dir, "terraform.tfstate.backup"), "state")
+	Ok(t, os.Mkdir(filepath.Join(dir, "modules"), 0700))
+	writeFile(t, filepath.Join(dir, "modules", "module.tf"), "module")
+	unchanged, err := events.HashPlanInputs(dir)
+	Ok(t, err)
+	Equals(t, hash, unchanged)
+
+	t.Log("changing a project file should change the hash")
+	writeFile(t, filepath.Join(dir, "main.tf"), "changed")
+	changed, err := events.HashPlanInputs(dir)
+	Ok(t, err)
+	Assert(t, hash != changed, "exp hash to change")
+
+	t.Log("adding a project file should change the hash")
+	writeFile(t, filepath.Join(dir, "vars.tf"), "variable")
+	added, err := events.HashPlanInputs(dir)
+	Ok(t, err)
+	Assert(t, changed != added, "exp hash to change")
+}
+
+func tempProjectDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "")
+	Ok(t, err)
+	return dir, func() { os.RemoveAll(dir) } // nolint: errcheck
+}
+
+func writeFile(t *testing.T, path string, contents string) {
+	Ok(t, ioutil.WriteFile(path, []byte(contents), 0600))
+}
@@ -1,9 +1,11 @@
 package events
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/hashicorp/go-version"
 	"github.com/pkg/errors"
@@ -26,9 +28,30 @@ type ProjectConfigReader interface {
 	Read(projectPath string) (ProjectConfig, error)
 }
 
+// The keys of the hooks in the project config file.
+const (
+	preInitKey   = "pre_init"
+	preGetKey    = "pre_get"
+	prePlanKey   = "pre_plan"
+	postPlanKey  = "post_plan"
+	preApplyKey  = "pre_apply"
+	postApplyKey = "post_apply"
+)
+
 // Hook represents the commands that can be run at a certain stage.
 type Hook struct {
 	Commands []string `yaml:"commands"`
+	// Timeout is how many seconds the commands can run for. If 0, the
+	// server's default is used.
+	Timeout int `yaml:"timeout"`
+}
+
+// timeoutsYAML is used to parse the timeouts of the Terraform commands in
+// seconds.
+type timeoutsYAML struct {
+	Init  int `yaml:"init"`
+	Plan  int `yaml:"plan"`
+	Apply int `yaml:"apply"`
 }
 
 // projectConfigYAML is used to parse the YAML.
@@ -41,10 +64,13 @@ type projectConfigYAML struct {
 	PostApply        Hook                    `yaml:"post_apply"`
 	TerraformVersion string                  `yaml:"terraform_version"`
 	ExtraArguments   []commandExtraArguments `yaml:"extra_arguments"`
+	Timeouts         timeoutsYAML            `yaml:"timeouts"`
 }
 
 // ProjectConfig is a more usable version of projectConfigYAML that we can
-// return to our callers. It holds the config for a project.
+// return to our callers. It holds the config for a project. Projects that
+// don't pick a workflow in the repo config have their hooks converted into
+// the equivalent workflow.
 type ProjectConfig struct {
 	// PreInit is a slice of command strings to run prior to terraform init.
 	PreInit []string
@@ -61,6 +87,10 @@ type ProjectConfig struct {
 	// TerraformVersion is the version specified in the config file or nil
 	// if version wasn't specified.
 	TerraformVersion *version.Version
+	// Timeouts are the timeouts set for the Terraform commands and hooks,
+	// keyed by the command's step name, ex. "plan", or the hook's key, ex.
+	// "pre_plan". Missing keys use the server's defaults.
+	Timeouts map[string]time.Duration
 	// extraArguments is the extra args that we should tack on to certain
 	// terraform commands. It shouldn't be used directly and instead callers
 	// should use the GetExtraArguments method on ProjectConfig.
@@ -109,8 +139,13 @@ func (c *ProjectConfigManager) Read(execPath string) (ProjectConfig, error) {
 			return pc, errors.Wrap(err, "parsing terraform_version")
 		}
 	}
+	timeouts, err := c.parseTimeouts(pcYaml)
+	if err != nil {
+		return pc, errors.Wrapf(err, "parsing %s", ProjectConfigFile)
+	}
 	return ProjectConfig{
 		TerraformVersion: v,
+		Timeouts:         timeouts,
 		extraArguments:   pcYaml.ExtraArguments,
 		PreInit:          pcYaml.PreInit.Commands,
 		PreGet:           pcYaml.PreGet.Commands,
@@ -121,6 +156,36 @@ func (c *ProjectConfigManager) Read(execPath string) (ProjectConfig, error) {
 	}, nil
 }
 
+// parseTimeouts converts the timeouts set in pcYaml from seconds into
+// durations keyed by step name or hook key.
+func (c *ProjectConfigManager) parseTimeouts(pcYaml projectConfigYAML) (map[string]time.Duration, error) {
+	seconds := map[string]int{
+		InitStepName:  pcYaml.Timeouts.Init,
+		PlanStepName:  pcYaml.Timeouts.Plan,
+		ApplyStepName: pcYaml.Timeouts.Apply,
+		preInitKey:    pcYaml.PreInit.Timeout,
+		preGetKey:     pcYaml.PreGet.Timeout,
+		prePlanKey:    pcYaml.PrePlan.Timeout,
+		postPlanKey:   pcYaml.PostPlan.Timeout,
+		preApplyKey:   pcYaml.PreApply.Timeout,
+		postApplyKey:  pcYaml.PostApply.Timeout,
+	}
+	var timeouts map[string]time.Duration
+	for key, secs := range seconds {
+		if secs < 0 {
+			return nil, fmt.Errorf("%s timeout can't be negative", key)
+		}
+		if secs == 0 {
+			continue
+		}
+		if timeouts == nil {
+			timeouts = make(map[string]time.Duration)
+		}
+		timeouts[key] = time.Duration(secs) * time.Second
+	}
+	return timeouts, nil
+}
+
 // GetExtraArguments returns the arguments that were specified to be appended
 // to command in the project config file.
 func (c *ProjectConfig) GetExtraArguments(command string) []string {
@@ -131,3 +196,15 @@ func (c *ProjectConfig) GetExtraArguments(command string) []string {
 	}
 	return nil
 }
+
+// hasCommands returns true if the config has any hooks or extra arguments,
+// ie. if it changes the commands that are run for the project.
+func (c *ProjectConfig) hasCommands() bool {
+	hooks := [][]string{c.PreInit, c.PreGet, c.PrePlan, c.PostPlan, c.PreApply, c.PostApply}
+	for _, h := range hooks {
+		if len(h) > 0 {
+			return true
+		}
+	}
+	return len(c.extraArguments) > 0
+}
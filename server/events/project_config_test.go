@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/hootsuite/atlantis/server/events"
 	. "github.com/hootsuite/atlantis/testing"
@@ -90,6 +91,24 @@ func TestRead_ValidConfig(t *testing.T) {
 	Equals(t, 0, len(config.GetExtraArguments("not-specified")))
 }
 
+func TestRead_Timeouts(t *testing.T) {
+	t.Log("step and hook timeouts should be read as durations keyed by step or hook")
+	writeAtlantisConfigFile(t, []byte("pre_plan:\n  commands: [echo]\n  timeout: 60\ntimeouts:\n  plan: 600\n"))
+	defer os.Remove(tempConfigFile) // nolint: errcheck
+	config, err := c.Read("/tmp")
+	Ok(t, err)
+	Equals(t, map[string]time.Duration{"plan": 10 * time.Minute, "pre_plan": time.Minute}, config.Timeouts)
+}
+
+func TestRead_NegativeTimeout(t *testing.T) {
+	t.Log("negative timeouts should be an error")
+	writeAtlantisConfigFile(t, []byte("post_apply:\n  commands: [echo]\n  timeout: -1\n"))
+	defer os.Remove(tempConfigFile) // nolint: errcheck
+	_, err := c.Read("/tmp")
+	Assert(t, err != nil, "exp an error")
+	Equals(t, "parsing atlantis.yaml: post_apply timeout can't be negative", err.Error())
+}
+
 func writeAtlantisConfigFile(t *testing.T, s []byte) {
 	err := ioutil.WriteFile(tempConfigFile, s, 0644)
 	Ok(t, err)
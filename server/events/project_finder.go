@@ -15,6 +15,9 @@ type ProjectFinder interface {
 	// FindModified returns the list of projects that were modified based on
 	// the modifiedFiles. The list will be de-duplicated.
 	FindModified(log *logging.SimpleLogger, modifiedFiles []string, repoFullName string) []models.Project
+	// FindModifiedInConfig returns the projects listed in config that were
+	// modified based on the modifiedFiles and their when_modified patterns.
+	FindModifiedInConfig(log *logging.SimpleLogger, modifiedFiles []string, config RepoConfig) []RepoConfigProject
 }
 
 // DefaultProjectFinder implements ProjectFinder.
@@ -47,6 +50,30 @@ func (p *DefaultProjectFinder) FindModified(log *logging.SimpleLogger, modifiedF
 	return projects
 }
 
+// FindModifiedInConfig returns the projects listed in config that were
+// modified based on the modifiedFiles and their when_modified patterns.
+// Projects are returned in the order they're listed in config.
+func (p *DefaultProjectFinder) FindModifiedInConfig(log *logging.SimpleLogger, modifiedFiles []string, config RepoConfig) []RepoConfigProject {
+	var filtered []string
+	for _, fileName := range modifiedFiles {
+		if !p.isInExcludeList(fileName) {
+			filtered = append(filtered, fileName)
+		}
+	}
+
+	var projects []RepoConfigProject
+	var dirs []string
+	for _, project := range config.Projects {
+		if project.IsModified(filtered) {
+			projects = append(projects, project)
+			dirs = append(dirs, project.Dir)
+		}
+	}
+	log.Info("there are %d modified project(s) from %s at path(s): %v",
+		len(projects), RepoConfigFile, strings.Join(dirs, ", "))
+	return projects
+}
+
 func (p *DefaultProjectFinder) filterToTerraform(files []string) []string {
 	var filtered []string
 	for _, fileName := range files {
@@ -103,3 +103,29 @@ func TestGetModified(t *testing.T) {
 		}
 	}
 }
+
+func TestFindModifiedInConfig(t *testing.T) {
+	t.Log("projects from the config should be returned in order if their when_modified patterns match")
+	config := events.RepoConfig{
+		Projects: []events.RepoConfigProject{
+			{Name: "vpc", Dir: "infra/vpc", WhenModified: []string{"*.tf", "../modules/**/*.tf"}},
+			{Name: "db", Dir: "infra/db", WhenModified: events.DefaultWhenModified},
+			{Name: "app", Dir: "app", WhenModified: events.DefaultWhenModified},
+		},
+	}
+	projects := m.FindModifiedInConfig(noopLogger, []string{"infra/modules/net/main.tf", "app/main.tf", "app/terraform.tfstate"}, config)
+	Equals(t, 2, len(projects))
+	Equals(t, "vpc", projects[0].Name)
+	Equals(t, "app", projects[1].Name)
+}
+
+func TestFindModifiedInConfig_IgnoresState(t *testing.T) {
+	t.Log("tfstate files shouldn't cause projects to be modified")
+	config := events.RepoConfig{
+		Projects: []events.RepoConfigProject{
+			{Dir: ".", WhenModified: events.DefaultWhenModified},
+		},
+	}
+	projects := m.FindModifiedInConfig(noopLogger, []string{"terraform.tfstate", "terraform.tfstate.backup"}, config)
+	Equals(t, 0, len(projects))
+}
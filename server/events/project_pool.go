@@ -0,0 +1,66 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hootsuite/atlantis/server/recovery"
+)
+
+// parallelPoolSize returns the max number of projects in ctx's repo that can
+// be planned or applied at once. The server's repo config can set it per
+// repo, otherwise serverSize is used.
+func parallelPoolSize(ctx *CommandContext, serverSize int, config *ServerRepoConfig) int {
+	if size := config.ForRepo(ctx.BaseRepo.FullName).ParallelPoolSize; size > 0 {
+		return size
+	}
+	if serverSize > 0 {
+		return serverSize
+	}
+	return 1
+}
+
+// runProjects calls run for each of the projects at paths, running at most
+// poolSize of them at once, and returns their results in the same order as
+// paths. Each call gets a copy of ctx with its own logger so that output from
+// projects running at the same time doesn't interleave. Once all the
+// projects have finished, their logs are added to ctx.Log's history in order.
+// If run panics, that project's result is an error.
+func runProjects(ctx *CommandContext, poolSize int, paths []string, run func(ctx *CommandContext, i int) ProjectResult) []ProjectResult {
+	if poolSize < 1 {
+		poolSize = 1
+	}
+	results := make([]ProjectResult, len(paths))
+	projectCtxs := make([]CommandContext, len(paths))
+	sem := make(chan struct{}, poolSize)
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		projectCtxs[i] = *ctx
+		projectCtxs[i].Log = ctx.Log.NewChild(path)
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			// A panic would take down the server along with every other
+			// command that's running so it fails just this project.
+			defer func() {
+				if err := recover(); err != nil {
+					stack := recovery.Stack(3)
+					projectCtxs[i].Log.Err("PANIC: %s\n%s", err, stack)
+					results[i] = ProjectResult{Path: paths[i], Error: fmt.Errorf("goroutine panic. This is a bug: %s", err)}
+				}
+			}()
+			results[i] = run(&projectCtxs[i], i)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, projectCtx := range projectCtxs {
+		ctx.Log.History.Write(projectCtx.Log.History.Bytes()) // nolint: errcheck
+	}
+	return results
+}
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"io/ioutil"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hootsuite/atlantis/server/logging"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+func TestRunProjects_Order(t *testing.T) {
+	t.Log("results should be in the projects' order even if they finish out of order")
+	ctx := poolCtx()
+	paths := []string{"a", "b", "c", "d"}
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	results := runProjects(ctx, 2, paths, func(projectCtx *CommandContext, i int) ProjectResult {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		// Earlier projects take longer.
+		time.Sleep(time.Duration(len(paths)-i) * 5 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return ProjectResult{Path: paths[i]}
+	})
+
+	Equals(t, []ProjectResult{{Path: "a"}, {Path: "b"}, {Path: "c"}, {Path: "d"}}, results)
+	t.Log("no more than the pool size should run at once")
+	Assert(t, maxRunning <= 2, "exp at most 2 projects running at once but got %d", maxRunning)
+}
+
+func TestRunProjects_Logs(t *testing.T) {
+	t.Log("each project should log to its own history which is added to the command's in order")
+	ctx := poolCtx()
+	paths := []string{"a", "b"}
+	runProjects(ctx, 2, paths, func(projectCtx *CommandContext, i int) ProjectResult {
+		Assert(t, projectCtx.Log != ctx.Log, "exp project to get its own logger")
+		projectCtx.Log.Info("first from %s", paths[i])
+		projectCtx.Log.Info("second from %s", paths[i])
+		return ProjectResult{}
+	})
+	Equals(t, "[INFO] First from a\n[INFO] Second from a\n[INFO] First from b\n[INFO] Second from b\n", ctx.Log.History.String())
+}
+
+func TestRunProjects_Panic(t *testing.T) {
+	t.Log("a project that panics should fail without stopping the other projects")
+	ctx := poolCtx()
+	paths := []string{"a", "b", "c"}
+	results := runProjects(ctx, 2, paths, func(projectCtx *CommandContext, i int) ProjectResult {
+		if paths[i] == "b" {
+			panic("boom")
+		}
+		return ProjectResult{Path: paths[i]}
+	})
+
+	Equals(t, 3, len(results))
+	Equals(t, ProjectResult{Path: "a"}, results[0])
+	Equals(t, ProjectResult{Path: "c"}, results[2])
+	Equals(t, "b", results[1].Path)
+	Assert(t, results[1].Error != nil, "exp the panicking project to have an error")
+	Equals(t, "goroutine panic. This is a bug: boom", results[1].Error.Error())
+	Assert(t, strings.Contains(ctx.Log.History.String(), "PANIC: boom"), "exp the panic to be logged")
+}
+
+func TestParallelPoolSize(t *testing.T) {
+	ctx := poolCtx()
+	ctx.BaseRepo.FullName = "owner/repo"
+
+	t.Log("without a server repo config the server's size should be used")
+	Equals(t, 3, parallelPoolSize(ctx, 3, nil))
+
+	t.Log("the pool size should be at least 1")
+	Equals(t, 1, parallelPoolSize(ctx, 0, nil))
+
+	t.Log("the server repo config should override the server's size")
+	config := &ServerRepoConfig{Repos: []ServerRepo{{ID: "owner/repo", ParallelPoolSize: 5}}}
+	Equals(t, 5, parallelPoolSize(ctx, 3, config))
+
+	t.Log("repos that don't set it should get the server's size")
+	config = &ServerRepoConfig{Repos: []ServerRepo{{ID: "owner/repo"}}}
+	Equals(t, 3, parallelPoolSize(ctx, 3, config))
+}
+
+func poolCtx() *CommandContext {
+	return &CommandContext{
+		Command: &Command{Name: Plan, Workspace: "default"},
+		Log:     logging.NewSimpleLogger("owner/repo#1", log.New(ioutil.Discard, "", 0), true, logging.Info),
+	}
+}
@@ -3,12 +3,10 @@ package events
 import (
 	"fmt"
 	"path/filepath"
-	"strings"
 
 	"github.com/hashicorp/go-version"
 	"github.com/hootsuite/atlantis/server/events/locking"
 	"github.com/hootsuite/atlantis/server/events/models"
-	"github.com/hootsuite/atlantis/server/events/run"
 	"github.com/hootsuite/atlantis/server/events/terraform"
 	"github.com/pkg/errors"
 )
@@ -16,7 +14,8 @@ import (
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_project_pre_executor.go ProjectPreExecutor
 
 // ProjectPreExecutor executes before the plan and apply executors. It handles
-// the setup tasks that are common to both plan and apply.
+// the setup tasks that are common to both plan and apply: locking the project
+// and determining its config.
 type ProjectPreExecutor interface {
 	// Execute executes the pre plan/apply tasks.
 	Execute(ctx *CommandContext, repoDir string, project models.Project) PreExecuteResult
@@ -24,10 +23,13 @@ type ProjectPreExecutor interface {
 
 // DefaultProjectPreExecutor implements ProjectPreExecutor.
 type DefaultProjectPreExecutor struct {
-	Locker       locking.Locker
-	ConfigReader ProjectConfigReader
-	Terraform    terraform.Client
-	Run          run.Runner
+	Locker           locking.Locker
+	ConfigReader     ProjectConfigReader
+	RepoConfigReader RepoConfigReader
+	Terraform        terraform.Client
+	// ServerRepoConfig is the server's repo config. If nil, repos can
+	// override everything.
+	ServerRepoConfig *ServerRepoConfig
 }
 
 // PreExecuteResult is the result of running the pre execute.
@@ -36,9 +38,15 @@ type PreExecuteResult struct {
 	ProjectConfig    ProjectConfig
 	TerraformVersion *version.Version
 	LockResponse     locking.TryLockResponse
+	// Workflow is the workflow to run for the project.
+	Workflow Workflow
+	// ApplyRequirements are the requirements that must be met before the
+	// project can be applied.
+	ApplyRequirements []string
 }
 
-// Execute executes the pre plan/apply tasks.
+// Execute executes the pre plan/apply tasks. Running the project's workflow
+// is left to the caller.
 func (p *DefaultProjectPreExecutor) Execute(ctx *CommandContext, repoDir string, project models.Project) PreExecuteResult {
 	workspace := ctx.Command.Workspace
 	lockAttempt, err := p.Locker.TryLock(project, workspace, ctx.Pull, ctx.User)
@@ -63,51 +71,60 @@ func (p *DefaultProjectPreExecutor) Execute(ctx *CommandContext, repoDir string,
 		ctx.Log.Info("parsed atlantis config file in %q", absolutePath)
 	}
 
-	// Check if terraform version is >= 0.9.0.
-	terraformVersion := p.Terraform.Version()
-	if config.TerraformVersion != nil {
-		terraformVersion = config.TerraformVersion
+	// The terraform version, workflow and apply requirements can also be set
+	// for the project in the repo config, which takes precedence. What the
+	// repo can set is controlled by the server.
+	settings := p.ServerRepoConfig.ForRepo(ctx.BaseRepo.FullName)
+	if err := settings.CheckProjectConfig(config, project.Path); err != nil {
+		return PreExecuteResult{ProjectResult: ProjectResult{Failure: err.Error()}}
 	}
-	constraints, _ := version.NewConstraint(">= 0.9.0")
-	if constraints.Check(terraformVersion) {
-		ctx.Log.Info("determined that we are running terraform with version >= 0.9.0. Running version %s", terraformVersion)
-		if len(config.PreInit) > 0 {
-			_, err := p.Run.Execute(ctx.Log, config.PreInit, absolutePath, workspace, terraformVersion, "pre_init")
-			if err != nil {
-				return PreExecuteResult{ProjectResult: ProjectResult{Error: errors.Wrapf(err, "running %s commands", "pre_init")}}
-			}
-		}
-		_, err := p.Terraform.Init(ctx.Log, absolutePath, workspace, config.GetExtraArguments("init"), terraformVersion)
-		if err != nil {
-			return PreExecuteResult{ProjectResult: ProjectResult{Error: err}}
-		}
-	} else {
-		ctx.Log.Info("determined that we are running terraform with version < 0.9.0. Running version %s", terraformVersion)
-		if len(config.PreGet) > 0 {
-			_, err := p.Run.Execute(ctx.Log, config.PreGet, absolutePath, workspace, terraformVersion, "pre_get")
-			if err != nil {
-				return PreExecuteResult{ProjectResult: ProjectResult{Error: errors.Wrapf(err, "running %s commands", "pre_get")}}
-			}
-		}
-		terraformGetCmd := append([]string{"get", "-no-color"}, config.GetExtraArguments("get")...)
-		_, err := p.Terraform.RunCommandWithVersion(ctx.Log, absolutePath, terraformGetCmd, terraformVersion, workspace)
-		if err != nil {
-			return PreExecuteResult{ProjectResult: ProjectResult{Error: err}}
+	repoConfig, err := p.RepoConfigReader.Read(repoDir)
+	if err != nil {
+		return PreExecuteResult{ProjectResult: ProjectResult{Error: err}}
+	}
+	if err := settings.CheckRepoConfig(repoConfig); err != nil {
+		return PreExecuteResult{ProjectResult: ProjectResult{Failure: err.Error()}}
+	}
+	var repoProject RepoConfigProject
+	if repoConfig != nil {
+		repoProject, _ = repoConfig.FindByDirAndWorkspace(project.Path, workspace)
+	}
+
+	terraformVersion := p.Terraform.Version()
+	for _, v := range []*version.Version{settings.TerraformVersion, config.TerraformVersion, repoProject.TerraformVersion} {
+		if v != nil {
+			terraformVersion = v
 		}
 	}
 
-	stage := fmt.Sprintf("pre_%s", strings.ToLower(ctx.Command.Name.String()))
-	var commands []string
-	if ctx.Command.Name == Plan {
-		commands = config.PrePlan
-	} else {
-		commands = config.PreApply
+	applyRequirements := settings.ApplyRequirements
+	if repoProject.ApplyRequirements != nil {
+		applyRequirements = repoProject.ApplyRequirements
 	}
-	if len(commands) > 0 {
-		_, err := p.Run.Execute(ctx.Log, commands, absolutePath, workspace, terraformVersion, stage)
-		if err != nil {
-			return PreExecuteResult{ProjectResult: ProjectResult{Error: errors.Wrapf(err, "running %s commands", stage)}}
-		}
+
+	// Projects that don't pick a workflow run the repo's default workflow if
+	// it defines one, then the hooks from their project config if they have
+	// any and otherwise the server's default workflow.
+	var workflow Workflow
+	var repoDefault bool
+	if repoConfig != nil {
+		workflow, repoDefault = repoConfig.FindWorkflow("")
+	}
+	switch {
+	case repoProject.Workflow != "":
+		workflow, _ = settings.FindWorkflow(repoProject.Workflow, repoConfig)
+	case repoDefault:
+	case config.hasCommands():
+		workflow = legacyWorkflow(config, terraformVersion)
+	default:
+		workflow = settings.DefaultWorkflow()
+	}
+	workflow = withTimeouts(workflow, config.Timeouts)
+	return PreExecuteResult{
+		ProjectConfig:     config,
+		TerraformVersion:  terraformVersion,
+		LockResponse:      lockAttempt,
+		Workflow:          workflow,
+		ApplyRequirements: applyRequirements,
 	}
-	return PreExecuteResult{ProjectConfig: config, TerraformVersion: terraformVersion, LockResponse: lockAttempt}
 }
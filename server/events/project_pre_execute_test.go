@@ -3,6 +3,7 @@ package events_test
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-version"
 	"github.com/hootsuite/atlantis/server/events"
@@ -10,11 +11,9 @@ import (
 	lmocks "github.com/hootsuite/atlantis/server/events/locking/mocks"
 	"github.com/hootsuite/atlantis/server/events/mocks"
 	"github.com/hootsuite/atlantis/server/events/models"
-	rmocks "github.com/hootsuite/atlantis/server/events/run/mocks"
 	tmocks "github.com/hootsuite/atlantis/server/events/terraform/mocks"
 	"github.com/hootsuite/atlantis/server/logging"
 	. "github.com/hootsuite/atlantis/testing"
-	"github.com/mohae/deepcopy"
 	. "github.com/petergtz/pegomock"
 )
 
@@ -28,7 +27,7 @@ var project = models.Project{}
 
 func TestExecute_LockErr(t *testing.T) {
 	t.Log("when there is an error returned from TryLock we return it")
-	p, l, _, _ := setupPreExecuteTest(t)
+	p, l, _ := setupPreExecuteTest(t)
 	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(locking.TryLockResponse{}, errors.New("err"))
 
 	res := p.Execute(&ctx, "", project)
@@ -37,7 +36,7 @@ func TestExecute_LockErr(t *testing.T) {
 
 func TestExecute_LockFailed(t *testing.T) {
 	t.Log("when we can't acquire a lock for this project and the lock is owned by a different pull, we get an error")
-	p, l, _, _ := setupPreExecuteTest(t)
+	p, l, _ := setupPreExecuteTest(t)
 	// The response has LockAcquired: false and the pull request is a number
 	// different than the current pull.
 	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(locking.TryLockResponse{
@@ -51,7 +50,7 @@ func TestExecute_LockFailed(t *testing.T) {
 
 func TestExecute_ConfigErr(t *testing.T) {
 	t.Log("when there is an error loading config, we return it")
-	p, l, _, _ := setupPreExecuteTest(t)
+	p, l, _ := setupPreExecuteTest(t)
 	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(locking.TryLockResponse{
 		LockAcquired: true,
 	}, nil)
@@ -62,208 +61,275 @@ func TestExecute_ConfigErr(t *testing.T) {
 	Equals(t, "err", res.ProjectResult.Error.Error())
 }
 
-func TestExecute_PreInitErr(t *testing.T) {
-	t.Log("when the project is on tf >= 0.9 and we run a `pre_init` that returns an error we return it")
-	p, l, tm, r := setupPreExecuteTest(t)
-	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(locking.TryLockResponse{
+func TestExecute_DefaultWorkflow(t *testing.T) {
+	t.Log("when there's no config the default workflow should be returned")
+	p, l, tm := setupPreExecuteTest(t)
+	lockResponse := locking.TryLockResponse{
 		LockAcquired: true,
-	}, nil)
-	When(p.ConfigReader.Exists("")).ThenReturn(true)
-	When(p.ConfigReader.Read("")).ThenReturn(events.ProjectConfig{
-		PreInit: []string{"pre-init"},
-	}, nil)
-	tfVersion, _ := version.NewVersion("0.9.0")
+	}
+	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(lockResponse, nil)
+	tfVersion, _ := version.NewVersion("0.9")
 	When(tm.Version()).ThenReturn(tfVersion)
-	When(r.Execute(ctx.Log, []string{"pre-init"}, "", "", tfVersion, "pre_init")).ThenReturn("", errors.New("err"))
 
 	res := p.Execute(&ctx, "", project)
-	Equals(t, "running pre_init commands: err", res.ProjectResult.Error.Error())
+	Equals(t, events.PreExecuteResult{
+		TerraformVersion: tfVersion,
+		LockResponse:     lockResponse,
+		Workflow:         events.DefaultWorkflow,
+	}, res)
 }
 
-func TestExecute_InitErr(t *testing.T) {
-	t.Log("when the project is on tf >= 0.9 and we run `init` that returns an error we return it")
-	p, l, tm, _ := setupPreExecuteTest(t)
+func TestExecute_TimeoutsOnly(t *testing.T) {
+	t.Log("when the project config only sets timeouts they should be used by the default workflow")
+	p, l, tm := setupPreExecuteTest(t)
 	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(locking.TryLockResponse{
 		LockAcquired: true,
 	}, nil)
 	When(p.ConfigReader.Exists("")).ThenReturn(true)
-	When(p.ConfigReader.Read("")).ThenReturn(events.ProjectConfig{}, nil)
-	tfVersion, _ := version.NewVersion("0.9.0")
+	When(p.ConfigReader.Read("")).ThenReturn(events.ProjectConfig{
+		Timeouts: map[string]time.Duration{events.PlanStepName: 10 * time.Minute},
+	}, nil)
+	tfVersion, _ := version.NewVersion("0.9")
 	When(tm.Version()).ThenReturn(tfVersion)
-	When(tm.Init(ctx.Log, "", "", nil, tfVersion)).ThenReturn(nil, errors.New("err"))
 
 	res := p.Execute(&ctx, "", project)
-	Equals(t, "err", res.ProjectResult.Error.Error())
+	Equals(t, events.Workflow{
+		Plan: events.Stage{
+			Steps: []events.Step{{Name: events.InitStepName}, {Name: events.PlanStepName, Timeout: 10 * time.Minute}},
+		},
+		Apply: events.Stage{
+			Steps: []events.Step{{Name: events.InitStepName}, {Name: events.ApplyStepName}},
+		},
+	}, res.Workflow)
+	// The default workflow is shared so it mustn't have been changed.
+	Equals(t, time.Duration(0), events.DefaultWorkflow.Plan.Steps[1].Timeout)
 }
 
-func TestExecute_PreGetErr(t *testing.T) {
-	t.Log("when the project is on tf < 0.9 and we run a `pre_get` that returns an error we return it")
-	p, l, tm, r := setupPreExecuteTest(t)
-	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(locking.TryLockResponse{
+func TestExecute_LegacyWorkflowTF9(t *testing.T) {
+	t.Log("when the project is on tf >= 0.9 its hooks should be converted to a workflow with pre_init")
+	p, l, tm := setupPreExecuteTest(t)
+	lockResponse := locking.TryLockResponse{
 		LockAcquired: true,
-	}, nil)
+	}
+	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(lockResponse, nil)
 	When(p.ConfigReader.Exists("")).ThenReturn(true)
-	When(p.ConfigReader.Read("")).ThenReturn(events.ProjectConfig{
-		PreGet: []string{"pre-get"},
-	}, nil)
-	tfVersion, _ := version.NewVersion("0.8")
+	config := readTestProjectConfig(t)
+	// Clear the version so the one from the client is used.
+	config.TerraformVersion = nil
+	When(p.ConfigReader.Read("")).ThenReturn(config, nil)
+	tfVersion, _ := version.NewVersion("0.9")
 	When(tm.Version()).ThenReturn(tfVersion)
-	When(r.Execute(ctx.Log, []string{"pre-get"}, "", "", tfVersion, "pre_get")).ThenReturn("", errors.New("err"))
 
 	res := p.Execute(&ctx, "", project)
-	Equals(t, "running pre_get commands: err", res.ProjectResult.Error.Error())
+	Equals(t, events.Workflow{
+		Plan: events.Stage{
+			Steps: []events.Step{
+				{Name: events.RunStepName, RunCommand: "echo\npre_init"},
+				{Name: events.InitStepName, ExtraArgs: []string{"arg", "init"}},
+				{Name: events.RunStepName, RunCommand: "echo\npre_plan"},
+				{Name: events.PlanStepName, ExtraArgs: []string{"arg", "plan"}},
+				{Name: events.RunStepName, RunCommand: "echo\npost_plan"},
+			},
+		},
+		Apply: events.Stage{
+			Steps: []events.Step{
+				{Name: events.RunStepName, RunCommand: "echo\npre_init"},
+				{Name: events.InitStepName, ExtraArgs: []string{"arg", "init"}},
+				{Name: events.RunStepName, RunCommand: "echo\npre_apply"},
+				{Name: events.ApplyStepName, ExtraArgs: []string{"arg", "apply"}},
+				{Name: events.RunStepName, RunCommand: "echo\npost_apply"},
+			},
+		},
+	}, res.Workflow)
 }
 
-func TestExecute_GetErr(t *testing.T) {
-	t.Log("when the project is on tf < 0.9 and we run `get` that returns an error we return it")
-	p, l, tm, _ := setupPreExecuteTest(t)
+func TestExecute_LegacyWorkflowTF8(t *testing.T) {
+	t.Log("when the project is on tf < 0.9 its hooks should be converted to a workflow with pre_get")
+	p, l, tm := setupPreExecuteTest(t)
 	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(locking.TryLockResponse{
 		LockAcquired: true,
 	}, nil)
 	When(p.ConfigReader.Exists("")).ThenReturn(true)
-	When(p.ConfigReader.Read("")).ThenReturn(events.ProjectConfig{}, nil)
+	config := readTestProjectConfig(t)
+	// Clear the version so the one from the client is used.
+	config.TerraformVersion = nil
+	When(p.ConfigReader.Read("")).ThenReturn(config, nil)
 	tfVersion, _ := version.NewVersion("0.8")
 	When(tm.Version()).ThenReturn(tfVersion)
-	When(tm.RunCommandWithVersion(ctx.Log, "", []string{"get", "-no-color"}, tfVersion, "")).ThenReturn("", errors.New("err"))
 
 	res := p.Execute(&ctx, "", project)
-	Equals(t, "err", res.ProjectResult.Error.Error())
+	Equals(t, tfVersion, res.TerraformVersion)
+	Equals(t, []events.Step{
+		{Name: events.RunStepName, RunCommand: "echo\npre_get"},
+		{Name: events.InitStepName, ExtraArgs: []string{"arg", "get"}},
+		{Name: events.RunStepName, RunCommand: "echo\npre_plan"},
+		{Name: events.PlanStepName, ExtraArgs: []string{"arg", "plan"}},
+		{Name: events.RunStepName, RunCommand: "echo\npost_plan"},
+	}, res.Workflow.Plan.Steps)
 }
 
-func TestExecute_PreCommandErr(t *testing.T) {
-	t.Log("when we get an error running pre commands we return it")
-	p, l, tm, r := setupPreExecuteTest(t)
-	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(locking.TryLockResponse{
+func TestExecute_RepoConfigTerraformVersion(t *testing.T) {
+	t.Log("when the project's terraform_version is set in the repo config it should be used")
+	p, l, tm := setupPreExecuteTest(t)
+	rootProject := models.Project{Path: "."}
+	rootCtx := ctx
+	rootCtx.Command = &events.Command{Name: events.Plan, Workspace: "default"}
+	lockResponse := locking.TryLockResponse{
 		LockAcquired: true,
-	}, nil)
-	When(p.ConfigReader.Exists("")).ThenReturn(true)
-	When(p.ConfigReader.Read("")).ThenReturn(events.ProjectConfig{
-		PrePlan: []string{"command"},
+	}
+	When(l.TryLock(rootProject, "default", rootCtx.Pull, rootCtx.User)).ThenReturn(lockResponse, nil)
+	repoVersion, _ := version.NewVersion("0.10.0")
+	When(p.RepoConfigReader.Read("")).ThenReturn(&events.RepoConfig{
+		Projects: []events.RepoConfigProject{
+			{Dir: ".", Workspaces: []string{"default"}, TerraformVersion: repoVersion},
+		},
 	}, nil)
 	tfVersion, _ := version.NewVersion("0.9")
 	When(tm.Version()).ThenReturn(tfVersion)
-	When(tm.Init(ctx.Log, "", "", nil, tfVersion)).ThenReturn(nil, nil)
-	When(r.Execute(ctx.Log, []string{"command"}, "", "", tfVersion, "pre_plan")).ThenReturn("", errors.New("err"))
 
-	res := p.Execute(&ctx, "", project)
-	Equals(t, "running pre_plan commands: err", res.ProjectResult.Error.Error())
+	res := p.Execute(&rootCtx, "", rootProject)
+	Equals(t, repoVersion, res.TerraformVersion)
 }
 
-func TestExecute_SuccessTF9(t *testing.T) {
-	t.Log("when the project is on tf >= 0.9 it should be successful")
-	p, l, tm, r := setupPreExecuteTest(t)
-	lockResponse := locking.TryLockResponse{
-		LockAcquired: true,
+func TestExecute_RepoConfigWorkflow(t *testing.T) {
+	cases := []struct {
+		description string
+		workflow    string
+		workflows   map[string]events.Workflow
+		exp         events.Workflow
+	}{
+		{
+			"project should use the workflow it names",
+			"custom",
+			map[string]events.Workflow{"custom": customWorkflow},
+			customWorkflow,
+		},
+		{
+			"project without a workflow should use the repo's default workflow",
+			"",
+			map[string]events.Workflow{"default": customWorkflow},
+			customWorkflow,
+		},
+		{
+			"project without a workflow should use the default workflow if the repo doesn't define one",
+			"",
+			map[string]events.Workflow{"custom": customWorkflow},
+			events.DefaultWorkflow,
+		},
 	}
-	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(lockResponse, nil)
-	When(p.ConfigReader.Exists("")).ThenReturn(true)
-	config := events.ProjectConfig{
-		PreInit: []string{"pre-init"},
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			p, l, tm := setupPreExecuteTest(t)
+			rootProject := models.Project{Path: "."}
+			rootCtx := ctx
+			rootCtx.Command = &events.Command{Name: events.Plan, Workspace: "default"}
+			When(l.TryLock(rootProject, "default", rootCtx.Pull, rootCtx.User)).ThenReturn(locking.TryLockResponse{
+				LockAcquired: true,
+			}, nil)
+			When(p.RepoConfigReader.Read("")).ThenReturn(&events.RepoConfig{
+				Projects: []events.RepoConfigProject{
+					{Dir: ".", Workspaces: []string{"default"}, Workflow: c.workflow},
+				},
+				Workflows: c.workflows,
+			}, nil)
+			tfVersion, _ := version.NewVersion("0.9")
+			When(tm.Version()).ThenReturn(tfVersion)
+
+			res := p.Execute(&rootCtx, "", rootProject)
+			Equals(t, c.exp, res.Workflow)
+		})
 	}
-	When(p.ConfigReader.Read("")).ThenReturn(config, nil)
+}
+
+func TestExecute_ServerRepoConfigDefaults(t *testing.T) {
+	t.Log("the server's defaults should be used for projects that don't override them")
+	p, l, tm := setupPreExecuteTest(t)
+	serverVersion, _ := version.NewVersion("0.10.0")
+	p.ServerRepoConfig = &events.ServerRepoConfig{
+		Repos: []events.ServerRepo{
+			{
+				ID:                "owner/repo",
+				Workflow:          "custom",
+				ApplyRequirements: []string{"approved"},
+				TerraformVersion:  serverVersion,
+			},
+		},
+		Workflows: map[string]events.Workflow{"custom": customWorkflow},
+	}
+	repoCtx := ctx
+	repoCtx.BaseRepo = models.Repo{FullName: "owner/repo"}
+	When(l.TryLock(project, "", repoCtx.Pull, repoCtx.User)).ThenReturn(locking.TryLockResponse{
+		LockAcquired: true,
+	}, nil)
 	tfVersion, _ := version.NewVersion("0.9")
 	When(tm.Version()).ThenReturn(tfVersion)
-	When(tm.Init(ctx.Log, "", "", nil, tfVersion)).ThenReturn(nil, nil)
 
-	res := p.Execute(&ctx, "", project)
-	Equals(t, events.PreExecuteResult{
-		ProjectConfig:    config,
-		TerraformVersion: tfVersion,
-		LockResponse:     lockResponse,
-	}, res)
-	tm.VerifyWasCalledOnce().Init(ctx.Log, "", "", nil, tfVersion)
-	r.VerifyWasCalledOnce().Execute(ctx.Log, []string{"pre-init"}, "", "", tfVersion, "pre_init")
+	res := p.Execute(&repoCtx, "", project)
+	Equals(t, events.ProjectResult{}, res.ProjectResult)
+	Equals(t, serverVersion, res.TerraformVersion)
+	Equals(t, customWorkflow, res.Workflow)
+	Equals(t, []string{"approved"}, res.ApplyRequirements)
 }
 
-func TestExecute_SuccessTF8(t *testing.T) {
-	t.Log("when the project is on tf < 0.9 it should be successful")
-	p, l, tm, r := setupPreExecuteTest(t)
-	lockResponse := locking.TryLockResponse{
+func TestExecute_ServerRepoConfigDisallowsHooks(t *testing.T) {
+	t.Log("when the server doesn't allow the repo to override workflows, project config hooks should fail")
+	p, l, _ := setupPreExecuteTest(t)
+	p.ServerRepoConfig = &events.ServerRepoConfig{}
+	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(locking.TryLockResponse{
 		LockAcquired: true,
-	}
-	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(lockResponse, nil)
+	}, nil)
 	When(p.ConfigReader.Exists("")).ThenReturn(true)
-	config := events.ProjectConfig{
-		PreGet: []string{"pre-get"},
-	}
-	When(p.ConfigReader.Read("")).ThenReturn(config, nil)
-	tfVersion, _ := version.NewVersion("0.8")
-	When(tm.Version()).ThenReturn(tfVersion)
+	When(p.ConfigReader.Read("")).ThenReturn(events.ProjectConfig{PrePlan: []string{"echo"}}, nil)
 
 	res := p.Execute(&ctx, "", project)
-	Equals(t, events.PreExecuteResult{
-		ProjectConfig:    config,
-		TerraformVersion: tfVersion,
-		LockResponse:     lockResponse,
-	}, res)
-	tm.VerifyWasCalledOnce().RunCommandWithVersion(ctx.Log, "", []string{"get", "-no-color"}, tfVersion, "")
-	r.VerifyWasCalledOnce().Execute(ctx.Log, []string{"pre-get"}, "", "", tfVersion, "pre_get")
+	Equals(t, `atlantis.yaml in dir "" can't set hooks or extra_arguments: the Atlantis server's repo config doesn't allow this repo to override "workflows". Allowed overrides: none.`, res.ProjectResult.Failure)
 }
 
-func TestExecute_SuccessPrePlan(t *testing.T) {
-	t.Log("when there are pre_plan commands they are run")
-	p, l, tm, r := setupPreExecuteTest(t)
-	lockResponse := locking.TryLockResponse{
+func TestExecute_ServerRepoConfigDisallowsWorkflows(t *testing.T) {
+	t.Log("when the server doesn't allow the repo to override workflows, repo config workflows should fail")
+	p, l, _ := setupPreExecuteTest(t)
+	p.ServerRepoConfig = &events.ServerRepoConfig{}
+	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(locking.TryLockResponse{
 		LockAcquired: true,
-	}
-	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(lockResponse, nil)
-	When(p.ConfigReader.Exists("")).ThenReturn(true)
-	config := events.ProjectConfig{
-		PrePlan: []string{"command"},
-	}
-	When(p.ConfigReader.Read("")).ThenReturn(config, nil)
-	tfVersion, _ := version.NewVersion("0.9")
-	When(tm.Version()).ThenReturn(tfVersion)
+	}, nil)
+	When(p.RepoConfigReader.Read("")).ThenReturn(&events.RepoConfig{
+		Projects:  []events.RepoConfigProject{{Dir: ".", Workspaces: []string{"default"}}},
+		Workflows: map[string]events.Workflow{"default": customWorkflow},
+	}, nil)
 
 	res := p.Execute(&ctx, "", project)
-	Equals(t, events.PreExecuteResult{
-		ProjectConfig:    config,
-		TerraformVersion: tfVersion,
-		LockResponse:     lockResponse,
-	}, res)
-	r.VerifyWasCalledOnce().Execute(ctx.Log, []string{"command"}, "", "", tfVersion, "pre_plan")
+	Equals(t, `atlantis.yaml can't define workflows: the Atlantis server's repo config doesn't allow this repo to override "workflows". Allowed overrides: none.`, res.ProjectResult.Failure)
 }
 
-func TestExecute_SuccessPreApply(t *testing.T) {
-	t.Log("when there are pre_apply commands they are run")
-	p, l, tm, r := setupPreExecuteTest(t)
-	lockResponse := locking.TryLockResponse{
-		LockAcquired: true,
-	}
-	When(l.TryLock(project, "", ctx.Pull, ctx.User)).ThenReturn(lockResponse, nil)
-	When(p.ConfigReader.Exists("")).ThenReturn(true)
-	config := events.ProjectConfig{
-		PreApply: []string{"command"},
-	}
-	When(p.ConfigReader.Read("")).ThenReturn(config, nil)
-	tfVersion, _ := version.NewVersion("0.9")
-	When(tm.Version()).ThenReturn(tfVersion)
-
-	cpCtx := deepcopy.Copy(ctx).(events.CommandContext)
-	cpCtx.Command = &events.Command{
-		Name: events.Apply,
-	}
-	cpCtx.Log = logging.NewNoopLogger()
+var customWorkflow = events.Workflow{
+	Plan: events.Stage{
+		Steps: []events.Step{
+			{Name: events.RunStepName, RunCommand: "fetch-secrets"},
+			{Name: events.PlanStepName},
+		},
+	},
+	Apply: events.DefaultWorkflow.Apply,
+}
 
-	res := p.Execute(&cpCtx, "", project)
-	Equals(t, events.PreExecuteResult{
-		ProjectConfig:    config,
-		TerraformVersion: tfVersion,
-		LockResponse:     lockResponse,
-	}, res)
-	r.VerifyWasCalledOnce().Execute(cpCtx.Log, []string{"command"}, "", "", tfVersion, "pre_apply")
+// readTestProjectConfig returns the parsed test project config.
+func readTestProjectConfig(t *testing.T) events.ProjectConfig {
+	dir, cleanup := tempRepoDir(t)
+	defer cleanup()
+	writeRepoConfigFile(t, dir, projectConfigFileStr)
+	config, err := (&events.ProjectConfigManager{}).Read(dir)
+	Ok(t, err)
+	return config
 }
 
-func setupPreExecuteTest(t *testing.T) (*events.DefaultProjectPreExecutor, *lmocks.MockLocker, *tmocks.MockClient, *rmocks.MockRunner) {
+func setupPreExecuteTest(t *testing.T) (*events.DefaultProjectPreExecutor, *lmocks.MockLocker, *tmocks.MockClient) {
 	RegisterMockTestingT(t)
 	l := lmocks.NewMockLocker()
 	cr := mocks.NewMockProjectConfigReader()
 	tm := tmocks.NewMockClient()
-	r := rmocks.NewMockRunner()
 	return &events.DefaultProjectPreExecutor{
-		Locker:       l,
-		ConfigReader: cr,
-		Terraform:    tm,
-		Run:          r,
-	}, l, tm, r
+		Locker:           l,
+		ConfigReader:     cr,
+		RepoConfigReader: mocks.NewMockRepoConfigReader(),
+		Terraform:        tm,
+	}, l, tm
 }
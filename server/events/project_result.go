@@ -4,9 +4,15 @@ import "github.com/hootsuite/atlantis/server/events/vcs"
 
 // ProjectResult is the result of executing a plan/apply for a project.
 type ProjectResult struct {
-	Path         string
-	Error        error
-	Failure      string
+	// Path is the project's dir relative to the root of the repo. It's used
+	// to name the project's commit statuses and check runs and to find the
+	// project again when their buttons are clicked.
+	Path    string
+	Error   error
+	Failure string
+	// TimedOut is true if Failure is because one of the project's steps
+	// timed out.
+	TimedOut     bool
 	PlanSuccess  *PlanSuccess
 	ApplySuccess string
 }
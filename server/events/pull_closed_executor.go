@@ -59,7 +59,7 @@ func (p *PullClosedExecutor) CleanUpPull(repo models.Repo, pull models.PullReque
 		return nil
 	}
 
-	templateData := p.buildTemplateData(locks)
+	templateData := buildLocksTemplateData(locks)
 	var buf bytes.Buffer
 	if err = pullClosedTemplate.Execute(&buf, templateData); err != nil {
 		return errors.Wrap(err, "rendering template for comment")
@@ -67,11 +67,12 @@ func (p *PullClosedExecutor) CleanUpPull(repo models.Repo, pull models.PullReque
 	return p.VCSClient.CreateComment(repo, pull, buf.String(), host)
 }
 
-// buildTemplateData formats the lock data into a slice that can easily be
+// buildLocksTemplateData formats the lock data into a slice that can easily be
 // templated for the VCS comment. We organize all the workspaces by their
 // respective project paths so the comment can look like:
 // path: {path}, workspaces: {all-workspaces}
-func (p *PullClosedExecutor) buildTemplateData(locks []models.ProjectLock) []templatedProject {
+// It's also used by the unlock command so its comment looks the same.
+func buildLocksTemplateData(locks []models.ProjectLock) []templatedProject {
 	workspacesByPath := make(map[string][]string)
 	for _, l := range locks {
 		path := l.Project.RepoFullName + "/" + l.Project.Path
@@ -0,0 +1,448 @@
+package events
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// RepoConfigFile is the filename of the Atlantis repo config. It lives at the
+// root of the repo and has the same name as project config files. It's only
+// treated as a repo config if it has a projects key.
+const RepoConfigFile = "atlantis.yaml"
+
+// DefaultWhenModified is the list of patterns used to determine if a project
+// was modified when its config doesn't specify when_modified.
+var DefaultWhenModified = []string{"**/*.tf*"}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_repo_config_reader.go RepoConfigReader
+
+// RepoConfigReader implements reading repo config.
+type RepoConfigReader interface {
+	// Read attempts to read the repo config file at the root of the repo in
+	// repoDir. If there is no repo config it returns nil.
+	Read(repoDir string) (*RepoConfig, error)
+}
+
+// repoConfigYAML is used to parse the YAML.
+type repoConfigYAML struct {
+	Projects  []repoConfigProjectYAML `yaml:"projects"`
+	Workflows map[string]workflowYAML `yaml:"workflows"`
+}
+
+// repoConfigProjectYAML is used to parse a project in the YAML.
+type repoConfigProjectYAML struct {
+	Name              string   `yaml:"name"`
+	Dir               string   `yaml:"dir"`
+	Workspaces        []string `yaml:"workspaces"`
+	TerraformVersion  string   `yaml:"terraform_version"`
+	WhenModified      []string `yaml:"when_modified"`
+	Workflow          string   `yaml:"workflow"`
+	ApplyRequirements []string `yaml:"apply_requirements"`
+}
+
+// workflowYAML is used to parse a workflow in the YAML. Stages that aren't
+// specified are nil.
+type workflowYAML struct {
+	Plan  *stageYAML `yaml:"plan"`
+	Apply *stageYAML `yaml:"apply"`
+}
+
+// stageYAML is used to parse a stage in the YAML.
+type stageYAML struct {
+	Steps []stepYAML `yaml:"steps"`
+}
+
+// stepYAML is used to parse a step in the YAML. Steps can be specified as
+// just their name, ex. "- init", as their name mapped to their options,
+// ex. "- plan: {extra_args: [-lock=false], timeout: 600}" or
+// "- run: {command: make, timeout: 60}", or for run and env steps as their
+// name mapped to a string, ex. "- run: make" or "- env: NAME=value".
+type stepYAML struct {
+	step Step
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (s *stepYAML) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		if !isTerraformStep(name) {
+			return fmt.Errorf("invalid step %q: only %s, %s and %s steps can be specified by name alone", name, InitStepName, PlanStepName, ApplyStepName)
+		}
+		s.step = Step{Name: name}
+		return nil
+	}
+
+	var strStep map[string]string
+	if err := unmarshal(&strStep); err == nil {
+		if len(strStep) != 1 {
+			return fmt.Errorf("invalid step with keys %v: steps must have exactly one key", mapKeys(strStep))
+		}
+		for name, value := range strStep {
+			return s.parseStrStep(name, value)
+		}
+	}
+
+	var optsStep map[string]struct {
+		ExtraArgs []string `yaml:"extra_args"`
+		Command   string   `yaml:"command"`
+		// Timeout is in seconds.
+		Timeout int `yaml:"timeout"`
+	}
+	if err := unmarshal(&optsStep); err != nil {
+		return errors.New("invalid step: must be a step name or a map with a single key")
+	}
+	if len(optsStep) != 1 {
+		return fmt.Errorf("invalid step with %d keys: steps must have exactly one key", len(optsStep))
+	}
+	for name, opts := range optsStep {
+		if opts.Timeout < 0 {
+			return fmt.Errorf("invalid %s step: timeout can't be negative", name)
+		}
+		timeout := time.Duration(opts.Timeout) * time.Second
+		switch {
+		case name == RunStepName:
+			if len(opts.ExtraArgs) > 0 {
+				return fmt.Errorf("invalid step %q: only %s, %s and %s steps can have extra_args", name, InitStepName, PlanStepName, ApplyStepName)
+			}
+			if opts.Command == "" {
+				return fmt.Errorf("invalid %s step: command can't be empty", RunStepName)
+			}
+			s.step = Step{Name: RunStepName, RunCommand: opts.Command, Timeout: timeout}
+		case isTerraformStep(name):
+			if opts.Command != "" {
+				return fmt.Errorf("invalid step %q: only %s steps can have a command", name, RunStepName)
+			}
+			s.step = Step{Name: name, ExtraArgs: opts.ExtraArgs, Timeout: timeout}
+		default:
+			return fmt.Errorf("invalid step %q: only %s, %s, %s and %s steps can have options", name, InitStepName, PlanStepName, ApplyStepName, RunStepName)
+		}
+	}
+	return nil
+}
+
+func (s *stepYAML) parseStrStep(name string, value string) error {
+	switch {
+	case name == RunStepName:
+		if value == "" {
+			return fmt.Errorf("invalid %s step: command can't be empty", RunStepName)
+		}
+		s.step = Step{Name: RunStepName, RunCommand: value}
+	case name == EnvStepName:
+		split := strings.SplitN(value, "=", 2)
+		if len(split) != 2 || split[0] == "" {
+			return fmt.Errorf("invalid %s step %q: must be in the form NAME=value", EnvStepName, value)
+		}
+		s.step = Step{Name: EnvStepName, EnvName: split[0], EnvValue: split[1]}
+	case isTerraformStep(name) && value == "":
+		// ex. "- init:" with no value.
+		s.step = Step{Name: name}
+	case isTerraformStep(name):
+		return fmt.Errorf("invalid %s step: options must be a map, ex. {extra_args: [...]}", name)
+	default:
+		return fmt.Errorf("invalid step %q: must be one of %s, %s, %s, %s or %s", name, InitStepName, PlanStepName, ApplyStepName, RunStepName, EnvStepName)
+	}
+	return nil
+}
+
+func isTerraformStep(name string) bool {
+	return name == InitStepName || name == PlanStepName || name == ApplyStepName
+}
+
+func mapKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RepoConfig is a more usable version of repoConfigYAML that we can return to
+// our callers. It holds the projects listed explicitly for a repo.
+type RepoConfig struct {
+	Projects []RepoConfigProject
+	// Workflows are the workflows defined by the repo, keyed by name.
+	Workflows map[string]Workflow
+}
+
+// RepoConfigProject is a project listed in the repo config.
+type RepoConfigProject struct {
+	// Name is used to target the project from comments with -p. It may be
+	// empty.
+	Name string
+	// Dir is the path to the project root relative to the repo root.
+	// If "." then project is at root. Never ends in "/".
+	Dir string
+	// Workspaces are the Terraform workspaces this project can be run in.
+	// Defaults to just the default workspace.
+	Workspaces []string
+	// TerraformVersion is the version specified in the config file or nil
+	// if version wasn't specified.
+	TerraformVersion *version.Version
+	// WhenModified is the list of patterns, relative to Dir, that determine
+	// whether the project was modified. "**" matches any number of
+	// directories.
+	WhenModified []string
+	// Workflow is the name of the workflow the project runs. If empty, the
+	// project runs the default workflow.
+	Workflow string
+	// ApplyRequirements are the requirements that must be met before the
+	// project can be applied, ex. "approved". If nil, the requirements set
+	// by the server are used.
+	ApplyRequirements []string
+}
+
+// FindByName returns the project with name or false if there isn't one.
+func (r *RepoConfig) FindByName(name string) (RepoConfigProject, bool) {
+	for _, p := range r.Projects {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return RepoConfigProject{}, false
+}
+
+// FindWorkflow returns the workflow named name, or the repo's default
+// workflow if name is empty. It returns false if the repo doesn't define the
+// workflow.
+func (r *RepoConfig) FindWorkflow(name string) (Workflow, bool) {
+	if name == "" {
+		name = DefaultWorkflowName
+	}
+	w, ok := r.Workflows[name]
+	return w, ok
+}
+
+// FindByDirAndWorkspace returns the project at dir that can be run in
+// workspace or false if there isn't one.
+func (r *RepoConfig) FindByDirAndWorkspace(dir string, workspace string) (RepoConfigProject, bool) {
+	for _, p := range r.Projects {
+		if p.Dir == dir && p.HasWorkspace(workspace) {
+			return p, true
+		}
+	}
+	return RepoConfigProject{}, false
+}
+
+// HasWorkspace returns true if the project can be run in workspace.
+func (p RepoConfigProject) HasWorkspace(workspace string) bool {
+	for _, w := range p.Workspaces {
+		if w == workspace {
+			return true
+		}
+	}
+	return false
+}
+
+// IsModified returns true if any of modifiedFiles, which are relative to the
+// repo root, match the project's when_modified patterns.
+func (p RepoConfigProject) IsModified(modifiedFiles []string) bool {
+	for _, pattern := range p.WhenModified {
+		// Patterns are relative to the project so we convert them to be
+		// relative to the repo root like the modified files.
+		repoPattern := path.Join(p.Dir, pattern)
+		for _, f := range modifiedFiles {
+			if matchPattern(repoPattern, f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RepoConfigManager deals with the repo config file that users can use to
+// list their projects explicitly.
+type RepoConfigManager struct{}
+
+// Read attempts to read the repo config file at the root of the repo in
+// repoDir. If the file doesn't exist or doesn't contain a projects key it
+// returns nil since the file may be the config for a project at the root.
+func (r *RepoConfigManager) Read(repoDir string) (*RepoConfig, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(repoDir, RepoConfigFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", RepoConfigFile)
+	}
+	var rcYaml repoConfigYAML
+	if err := yaml.Unmarshal(raw, &rcYaml); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", RepoConfigFile)
+	}
+	if rcYaml.Projects == nil {
+		return nil, nil
+	}
+
+	var config RepoConfig
+	for name, w := range rcYaml.Workflows {
+		workflow, err := parseWorkflow(w)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s: workflow %q", RepoConfigFile, name)
+		}
+		if config.Workflows == nil {
+			config.Workflows = make(map[string]Workflow)
+		}
+		config.Workflows[name] = workflow
+	}
+	for i, p := range rcYaml.Projects {
+		project, err := r.parseProject(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s: project %d", RepoConfigFile, i+1)
+		}
+		config.Projects = append(config.Projects, project)
+	}
+	if err := r.validate(config); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", RepoConfigFile)
+	}
+	return &config, nil
+}
+
+func (r *RepoConfigManager) parseProject(p repoConfigProjectYAML) (RepoConfigProject, error) {
+	if p.Dir == "" {
+		return RepoConfigProject{}, errors.New("dir is required")
+	}
+	dir, ok := cleanRepoRelPath(p.Dir)
+	if !ok {
+		return RepoConfigProject{}, fmt.Errorf("invalid dir %q: must be a relative path inside the repo", p.Dir)
+	}
+
+	var v *version.Version
+	if p.TerraformVersion != "" {
+		var err error
+		v, err = version.NewVersion(p.TerraformVersion)
+		if err != nil {
+			return RepoConfigProject{}, errors.Wrap(err, "parsing terraform_version")
+		}
+	}
+
+	if err := validateApplyRequirements(p.ApplyRequirements); err != nil {
+		return RepoConfigProject{}, err
+	}
+
+	workspaces := p.Workspaces
+	if len(workspaces) == 0 {
+		workspaces = []string{DefaultWorkspace}
+	}
+	whenModified := p.WhenModified
+	if len(whenModified) == 0 {
+		whenModified = DefaultWhenModified
+	}
+	return RepoConfigProject{
+		Name:              p.Name,
+		Dir:               dir,
+		Workspaces:        workspaces,
+		TerraformVersion:  v,
+		WhenModified:      whenModified,
+		Workflow:          p.Workflow,
+		ApplyRequirements: p.ApplyRequirements,
+	}, nil
+}
+
+// parseWorkflow converts w into a Workflow. Stages that aren't specified
+// default to the stages of the default workflow.
+func parseWorkflow(w workflowYAML) (Workflow, error) {
+	workflow := DefaultWorkflow
+	if w.Plan != nil {
+		workflow.Plan = parseStage(*w.Plan)
+		if stageHasStep(workflow.Plan, ApplyStepName) {
+			return Workflow{}, fmt.Errorf("plan stage can't have an %s step", ApplyStepName)
+		}
+	}
+	if w.Apply != nil {
+		workflow.Apply = parseStage(*w.Apply)
+		if stageHasStep(workflow.Apply, PlanStepName) {
+			return Workflow{}, fmt.Errorf("apply stage can't have a %s step", PlanStepName)
+		}
+	}
+	return workflow, nil
+}
+
+func parseStage(s stageYAML) Stage {
+	var stage Stage
+	for _, step := range s.Steps {
+		stage.Steps = append(stage.Steps, step.step)
+	}
+	return stage
+}
+
+func stageHasStep(stage Stage, name string) bool {
+	for _, step := range stage.Steps {
+		if step.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks that projects can be told apart. Names must be unique and
+// since plans are stored by directory and workspace, no two projects can share
+// both. Whether the workflows projects use exist depends on the server's repo
+// config so that's checked by RepoSettings.
+func (r *RepoConfigManager) validate(config RepoConfig) error {
+	names := make(map[string]bool)
+	dirWorkspaces := make(map[string]bool)
+	for _, p := range config.Projects {
+		if p.Name != "" {
+			if names[p.Name] {
+				return fmt.Errorf("there are multiple projects named %q", p.Name)
+			}
+			names[p.Name] = true
+		}
+		for _, w := range p.Workspaces {
+			key := p.Dir + "/" + w
+			if dirWorkspaces[key] {
+				return fmt.Errorf("there are multiple projects in dir %q with workspace %q", p.Dir, w)
+			}
+			dirWorkspaces[key] = true
+		}
+	}
+	return nil
+}
+
+// cleanRepoRelPath cleans p and returns false if it isn't a relative path
+// inside the repo.
+func cleanRepoRelPath(p string) (string, bool) {
+	cleaned := path.Clean(p)
+	if p == "" || path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// matchPattern returns true if file matches pattern. Patterns use the syntax
+// of path.Match with the addition of "**" which matches zero or more
+// directories.
+func matchPattern(pattern string, file string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(file, "/"))
+}
+
+func matchSegments(pattern []string, file []string) bool {
+	if len(pattern) == 0 {
+		return len(file) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(file); i++ {
+			if matchSegments(pattern[1:], file[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(file) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], file[0]); err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], file[1:])
+}
@@ -0,0 +1,325 @@
+package events_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hootsuite/atlantis/server/events"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+var rcm events.RepoConfigManager
+
+func TestRepoConfigRead_NoFile(t *testing.T) {
+	t.Log("if there's no atlantis.yaml we expect no config and no error")
+	tmp, cleanup := tempRepoDir(t)
+	defer cleanup()
+	config, err := rcm.Read(tmp)
+	Ok(t, err)
+	Assert(t, config == nil, "exp nil config")
+}
+
+func TestRepoConfigRead_ProjectConfig(t *testing.T) {
+	t.Log("if atlantis.yaml doesn't have a projects key it's a project config so we expect no repo config")
+	tmp, cleanup := tempRepoDir(t)
+	defer cleanup()
+	writeRepoConfigFile(t, tmp, projectConfigFileStr)
+	config, err := rcm.Read(tmp)
+	Ok(t, err)
+	Assert(t, config == nil, "exp nil config")
+}
+
+func TestRepoConfigRead_InvalidYAML(t *testing.T) {
+	t.Log("if atlantis.yaml has invalid yaml we expect an error")
+	tmp, cleanup := tempRepoDir(t)
+	defer cleanup()
+	writeRepoConfigFile(t, tmp, "---invalid")
+	_, err := rcm.Read(tmp)
+	Assert(t, err != nil, "expect an error")
+}
+
+func TestRepoConfigRead_Valid(t *testing.T) {
+	t.Log("projects should be parsed and defaults set for fields that aren't specified")
+	tmp, cleanup := tempRepoDir(t)
+	defer cleanup()
+	writeRepoConfigFile(t, tmp, `
+projects:
+- name: vpc
+  dir: infra/vpc/
+  workspaces: [staging, production]
+  terraform_version: "0.11.0"
+  when_modified: ["*.tf", "../modules/**/*.tf"]
+- dir: .
+`)
+	config, err := rcm.Read(tmp)
+	Ok(t, err)
+	Equals(t, 2, len(config.Projects))
+
+	v, _ := version.NewVersion("0.11.0")
+	Equals(t, events.RepoConfigProject{
+		Name:             "vpc",
+		Dir:              "infra/vpc",
+		Workspaces:       []string{"staging", "production"},
+		TerraformVersion: v,
+		WhenModified:     []string{"*.tf", "../modules/**/*.tf"},
+	}, config.Projects[0])
+	Equals(t, events.RepoConfigProject{
+		Dir:          ".",
+		Workspaces:   []string{"default"},
+		WhenModified: events.DefaultWhenModified,
+	}, config.Projects[1])
+}
+
+func TestRepoConfigRead_Invalid(t *testing.T) {
+	cases := []struct {
+		description string
+		config      string
+		expErr      string
+	}{
+		{
+			"dir is required",
+			"projects:\n- name: a",
+			"parsing atlantis.yaml: project 1: dir is required",
+		},
+		{
+			"dir can't be absolute",
+			"projects:\n- dir: /abs",
+			`parsing atlantis.yaml: project 1: invalid dir "/abs": must be a relative path inside the repo`,
+		},
+		{
+			"dir can't be outside the repo",
+			"projects:\n- dir: .\n- dir: ../other",
+			`parsing atlantis.yaml: project 2: invalid dir "../other": must be a relative path inside the repo`,
+		},
+		{
+			"terraform_version must be valid",
+			"projects:\n- dir: .\n  terraform_version: invalid",
+			"parsing atlantis.yaml: project 1: parsing terraform_version: Malformed version: invalid",
+		},
+		{
+			"apply_requirements must be valid",
+			"projects:\n- dir: .\n  apply_requirements: [reviewed]",
+			`parsing atlantis.yaml: project 1: invalid apply requirement "reviewed": must be one of approved, approvals:<n>, approved_by:<team>, mergeable or no_new_commits`,
+		},
+		{
+			"names must be unique",
+			"projects:\n- name: a\n  dir: a\n- name: a\n  dir: b",
+			`parsing atlantis.yaml: there are multiple projects named "a"`,
+		},
+		{
+			"dir and workspace must be unique",
+			"projects:\n- dir: a\n  workspaces: [staging]\n- dir: a/\n  workspaces: [production, staging]",
+			`parsing atlantis.yaml: there are multiple projects in dir "a" with workspace "staging"`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			tmp, cleanup := tempRepoDir(t)
+			defer cleanup()
+			writeRepoConfigFile(t, tmp, c.config)
+			_, err := rcm.Read(tmp)
+			Assert(t, err != nil, "expect an error")
+			Equals(t, c.expErr, err.Error())
+		})
+	}
+}
+
+func TestRepoConfigRead_Workflows(t *testing.T) {
+	t.Log("workflows should be parsed with stages that aren't specified defaulting to the default workflow's")
+	tmp, cleanup := tempRepoDir(t)
+	defer cleanup()
+	writeRepoConfigFile(t, tmp, `
+workflows:
+  custom:
+    plan:
+      steps:
+      - run: ./fetch-secrets.sh
+      - env: TF_VAR_token=a=b
+      - init:
+          extra_args: [-upgrade]
+      - run: terraform validate
+      - run:
+          command: ./slow-check.sh
+          timeout: 60
+      - plan:
+          extra_args: [-lock=false]
+          timeout: 600
+projects:
+- dir: .
+  workflow: custom
+`)
+	config, err := rcm.Read(tmp)
+	Ok(t, err)
+	Equals(t, "custom", config.Projects[0].Workflow)
+	workflow, ok := config.FindWorkflow("custom")
+	Assert(t, ok, "exp custom workflow")
+	Equals(t, events.Workflow{
+		Plan: events.Stage{
+			Steps: []events.Step{
+				{Name: events.RunStepName, RunCommand: "./fetch-secrets.sh"},
+				{Name: events.EnvStepName, EnvName: "TF_VAR_token", EnvValue: "a=b"},
+				{Name: events.InitStepName, ExtraArgs: []string{"-upgrade"}},
+				{Name: events.RunStepName, RunCommand: "terraform validate"},
+				{Name: events.RunStepName, RunCommand: "./slow-check.sh", Timeout: time.Minute},
+				{Name: events.PlanStepName, ExtraArgs: []string{"-lock=false"}, Timeout: 10 * time.Minute},
+			},
+		},
+		Apply: events.DefaultWorkflow.Apply,
+	}, workflow)
+}
+
+func TestRepoConfigRead_InvalidWorkflows(t *testing.T) {
+	cases := []struct {
+		description string
+		steps       string
+		expErr      string
+	}{
+		{
+			"unknown step name",
+			"[validate]",
+			`invalid step "validate": only init, plan and apply steps can be specified by name alone`,
+		},
+		{
+			"unknown step key",
+			"[{validate: terraform validate}]",
+			`invalid step "validate": must be one of init, plan, apply, run or env`,
+		},
+		{
+			"multiple keys",
+			"[{run: a, env: A=b}]",
+			"invalid step with keys [env run]: steps must have exactly one key",
+		},
+		{
+			"env without =",
+			"[{env: NAME}]",
+			`invalid env step "NAME": must be in the form NAME=value`,
+		},
+		{
+			"empty run",
+			`[{run: ""}]`,
+			"invalid run step: command can't be empty",
+		},
+		{
+			"extra_args on run",
+			"[{run: {extra_args: [a]}}]",
+			`invalid step "run": only init, plan and apply steps can have extra_args`,
+		},
+		{
+			"run without a command",
+			"[{run: {timeout: 60}}]",
+			"invalid run step: command can't be empty",
+		},
+		{
+			"command on plan",
+			"[{plan: {command: make}}]",
+			`invalid step "plan": only run steps can have a command`,
+		},
+		{
+			"options on env",
+			"[{env: {timeout: 60}}]",
+			`invalid step "env": only init, plan, apply and run steps can have options`,
+		},
+		{
+			"negative timeout",
+			"[{init: {timeout: -1}}]",
+			"invalid init step: timeout can't be negative",
+		},
+		{
+			"apply in plan stage",
+			"[init, apply]",
+			`parsing atlantis.yaml: workflow "custom": plan stage can't have an apply step`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			tmp, cleanup := tempRepoDir(t)
+			defer cleanup()
+			writeRepoConfigFile(t, tmp, "workflows:\n  custom:\n    plan:\n      steps: "+c.steps+"\nprojects:\n- dir: .\n")
+			_, err := rcm.Read(tmp)
+			Assert(t, err != nil, "expect an error")
+			Assert(t, strings.Contains(err.Error(), c.expErr), "exp %q to contain %q", err.Error(), c.expErr)
+		})
+	}
+}
+
+func TestRepoConfigProject_IsModified(t *testing.T) {
+	cases := []struct {
+		description  string
+		dir          string
+		whenModified []string
+		files        []string
+		exp          bool
+	}{
+		{
+			"default matches tf files in the project",
+			"project",
+			events.DefaultWhenModified,
+			[]string{"project/main.tf"},
+			true,
+		},
+		{
+			"default matches tf files in subdirectories of the project",
+			"project",
+			events.DefaultWhenModified,
+			[]string{"project/env/staging.tfvars"},
+			true,
+		},
+		{
+			"default doesn't match other projects",
+			"project",
+			events.DefaultWhenModified,
+			[]string{"other/main.tf", "project2/main.tf"},
+			false,
+		},
+		{
+			"default at root matches everything",
+			".",
+			events.DefaultWhenModified,
+			[]string{"a/b/c/main.tf"},
+			true,
+		},
+		{
+			"default doesn't match non-tf files",
+			".",
+			events.DefaultWhenModified,
+			[]string{"README.md"},
+			false,
+		},
+		{
+			"patterns can reference files outside the project",
+			"infra/vpc",
+			[]string{"../modules/**/*.tf"},
+			[]string{"infra/modules/network/subnets/main.tf"},
+			true,
+		},
+		{
+			"patterns without ** don't match subdirectories",
+			"project",
+			[]string{"*.tf"},
+			[]string{"project/sub/main.tf"},
+			false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			p := events.RepoConfigProject{Dir: c.dir, WhenModified: c.whenModified}
+			Equals(t, c.exp, p.IsModified(c.files))
+		})
+	}
+}
+
+func tempRepoDir(t *testing.T) (string, func()) {
+	tmp, err := ioutil.TempDir("", "")
+	Ok(t, err)
+	return tmp, func() { os.RemoveAll(tmp) } // nolint: errcheck
+}
+
+func writeRepoConfigFile(t *testing.T, repoDir string, s string) {
+	err := ioutil.WriteFile(filepath.Join(repoDir, events.RepoConfigFile), []byte(s), 0644)
+	Ok(t, err)
+}
@@ -0,0 +1,38 @@
+package events
+
+import (
+	"strings"
+)
+
+// Wildcard matches any characters in a repo whitelist entry. It's only
+// supported at the end of an entry, ex. "hootsuite/*".
+const Wildcard = "*"
+
+// RepoWhitelist restricts a feature to a subset of repos.
+type RepoWhitelist struct {
+	// Whitelist is a comma separated list of rules with wildcards '*' allowed
+	// at the end of each rule, ex. "hootsuite/atlantis,hootsuite/infra-*".
+	Whitelist string
+}
+
+// IsWhitelisted returns true if repoFullName matches any of the rules in
+// the whitelist.
+func (r *RepoWhitelist) IsWhitelisted(repoFullName string) bool {
+	candidate := strings.ToLower(repoFullName)
+	for _, rule := range strings.Split(r.Whitelist, ",") {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if rule == "" {
+			continue
+		}
+		if strings.HasSuffix(rule, Wildcard) {
+			if strings.HasPrefix(candidate, strings.TrimSuffix(rule, Wildcard)) {
+				return true
+			}
+			continue
+		}
+		if candidate == rule {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+func TestIsWhitelisted(t *testing.T) {
+	cases := []struct {
+		Description  string
+		Whitelist    string
+		RepoFullName string
+		Exp          bool
+	}{
+		{
+			"exact match",
+			"owner/repo",
+			"owner/repo",
+			true,
+		},
+		{
+			"exact match shouldn't match anything else",
+			"owner/repo",
+			"owner/rep",
+			false,
+		},
+		{
+			"* should match anything",
+			"*",
+			"owner/repo",
+			true,
+		},
+		{
+			"owner/* should match repos under that owner",
+			"owner/*",
+			"owner/repo",
+			true,
+		},
+		{
+			"owner/* shouldn't match repos under other owners",
+			"owner/*",
+			"other/repo",
+			false,
+		},
+		{
+			"should match if any rule matches",
+			"owner/a, owner/b",
+			"owner/b",
+			true,
+		},
+		{
+			"should be case insensitive",
+			"Owner/Repo",
+			"owner/repo",
+			true,
+		},
+		{
+			"empty whitelist should match nothing",
+			"",
+			"owner/repo",
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			w := events.RepoWhitelist{Whitelist: c.Whitelist}
+			Equals(t, c.Exp, w.IsWhitelisted(c.RepoFullName))
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// InterruptGracePeriod is how long a cancelled command has to exit after
+// it's interrupted before it's killed. Terraform uses this time to finish the
+// operations in progress and release its state lock.
+const InterruptGracePeriod = time.Minute
+
+// CombinedOutput runs cmd and returns its combined stdout and stderr like
+// cmd.CombinedOutput, except that once ctx is done cmd is sent SIGINT and then
+// SIGKILL if it hasn't exited after gracePeriod. In that case the error is
+// ctx.Err(). If ctx was made by WithOutput, the output is also streamed.
+func CombinedOutput(ctx context.Context, cmd *exec.Cmd, gracePeriod time.Duration) ([]byte, error) {
+	var out bytes.Buffer
+	var w io.Writer = &out
+	if stream := outputFrom(ctx); stream != nil {
+		lw := &lineWriter{w: stream}
+		defer lw.Flush()
+		w = io.MultiWriter(&out, lw)
+	}
+	cmd.Stdout = w
+	cmd.Stderr = w
+	// Run cmd in its own process group so that the signals also reach the
+	// processes it starts, ex. the commands in a script.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return out.Bytes(), err
+	case <-ctx.Done():
+	}
+
+	pgid := -cmd.Process.Pid
+	syscall.Kill(pgid, syscall.SIGINT) // nolint: errcheck
+	select {
+	case <-done:
+	case <-time.After(gracePeriod):
+		syscall.Kill(pgid, syscall.SIGKILL) // nolint: errcheck
+		<-done
+	}
+	return out.Bytes(), ctx.Err()
+}
@@ -0,0 +1,62 @@
+package run
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+func TestCombinedOutput(t *testing.T) {
+	t.Log("should return the output of commands that aren't cancelled")
+	out, err := CombinedOutput(context.Background(), exec.Command("sh", "-c", "echo out; echo err >&2"), time.Second)
+	Ok(t, err)
+	Equals(t, "out\nerr\n", string(out))
+}
+
+func TestCombinedOutput_Interrupt(t *testing.T) {
+	t.Log("cancelling should interrupt the command so it can clean up")
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	script := "trap 'echo cleaned up; exit 1' INT; echo started; while true; do sleep 0.01; done"
+	out, err := CombinedOutput(ctx, exec.Command("sh", "-c", script), time.Minute)
+	Equals(t, context.Canceled, err)
+	Equals(t, "started\ncleaned up\n", string(out))
+}
+
+func TestCombinedOutput_Kill(t *testing.T) {
+	t.Log("commands that don't exit after the grace period should be killed")
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	out, err := CombinedOutput(ctx, exec.Command("sh", "-c", "trap '' INT; echo started; sleep 60"), 100*time.Millisecond)
+	Equals(t, context.Canceled, err)
+	Equals(t, "started\n", string(out))
+	Assert(t, time.Since(start) < 10*time.Second, "exp command to be killed")
+}
+
+func TestCombinedOutput_Stream(t *testing.T) {
+	t.Log("output should be streamed a line at a time to the writer set by WithOutput")
+	var stream lineRecorder
+	ctx := WithOutput(context.Background(), &stream)
+	out, err := CombinedOutput(ctx, exec.Command("sh", "-c", "printf 'one\\ntw'; sleep 0.01; printf 'o\\nthree'"), time.Second)
+	Ok(t, err)
+	Equals(t, "one\ntwo\nthree", string(out))
+	Equals(t, "one\ntwo\nthree", strings.Join(stream.writes, ""))
+	for _, w := range stream.writes[:len(stream.writes)-1] {
+		Assert(t, strings.HasSuffix(w, "\n"), "exp only whole lines to be written but got %q", w)
+	}
+}
+
+// lineRecorder records each write to it.
+type lineRecorder struct {
+	writes []string
+}
+
+func (l *lineRecorder) Write(p []byte) (int, error) {
+	l.writes = append(l.writes, string(p))
+	return len(p), nil
+}
@@ -4,6 +4,7 @@
 package mocks
 
 import (
+	context "context"
 	"reflect"
 
 	go_version "github.com/hashicorp/go-version"
@@ -19,8 +20,8 @@ func NewMockRunner() *MockRunner {
 	return &MockRunner{fail: pegomock.GlobalFailHandler}
 }
 
-func (mock *MockRunner) Execute(log *logging.SimpleLogger, commands []string, path string, workspace string, terraformVersion *go_version.Version, stage string) (string, error) {
-	params := []pegomock.Param{log, commands, path, workspace, terraformVersion, stage}
+func (mock *MockRunner) Execute(ctx context.Context, log *logging.SimpleLogger, commands []string, path string, env []string, workspace string, terraformVersion *go_version.Version, stage string) (string, error) {
+	params := []pegomock.Param{ctx, log, commands, path, env, workspace, terraformVersion, stage}
 	result := pegomock.GetGenericMockFrom(mock).Invoke("Execute", params, []reflect.Type{reflect.TypeOf((*string)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
 	var ret0 string
 	var ret1 error
@@ -53,8 +54,8 @@ type VerifierRunner struct {
 	inOrderContext         *pegomock.InOrderContext
 }
 
-func (verifier *VerifierRunner) Execute(log *logging.SimpleLogger, commands []string, path string, workspace string, terraformVersion *go_version.Version, stage string) *Runner_Execute_OngoingVerification {
-	params := []pegomock.Param{log, commands, path, workspace, terraformVersion, stage}
+func (verifier *VerifierRunner) Execute(ctx context.Context, log *logging.SimpleLogger, commands []string, path string, env []string, workspace string, terraformVersion *go_version.Version, stage string) *Runner_Execute_OngoingVerification {
+	params := []pegomock.Param{ctx, log, commands, path, env, workspace, terraformVersion, stage}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "Execute", params)
 	return &Runner_Execute_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
 }
@@ -64,38 +65,46 @@ type Runner_Execute_OngoingVerification struct {
 	methodInvocations []pegomock.MethodInvocation
 }
 
-func (c *Runner_Execute_OngoingVerification) GetCapturedArguments() (*logging.SimpleLogger, []string, string, string, *go_version.Version, string) {
-	log, commands, path, workspace, terraformVersion, stage := c.GetAllCapturedArguments()
-	return log[len(log)-1], commands[len(commands)-1], path[len(path)-1], workspace[len(workspace)-1], terraformVersion[len(terraformVersion)-1], stage[len(stage)-1]
+func (c *Runner_Execute_OngoingVerification) GetCapturedArguments() (context.Context, *logging.SimpleLogger, []string, string, []string, string, *go_version.Version, string) {
+	ctx, log, commands, path, env, workspace, terraformVersion, stage := c.GetAllCapturedArguments()
+	return ctx[len(ctx)-1], log[len(log)-1], commands[len(commands)-1], path[len(path)-1], env[len(env)-1], workspace[len(workspace)-1], terraformVersion[len(terraformVersion)-1], stage[len(stage)-1]
 }
 
-func (c *Runner_Execute_OngoingVerification) GetAllCapturedArguments() (_param0 []*logging.SimpleLogger, _param1 [][]string, _param2 []string, _param3 []string, _param4 []*go_version.Version, _param5 []string) {
+func (c *Runner_Execute_OngoingVerification) GetAllCapturedArguments() (_param0 []context.Context, _param1 []*logging.SimpleLogger, _param2 [][]string, _param3 []string, _param4 [][]string, _param5 []string, _param6 []*go_version.Version, _param7 []string) {
 	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
 	if len(params) > 0 {
-		_param0 = make([]*logging.SimpleLogger, len(params[0]))
+		_param0 = make([]context.Context, len(params[0]))
 		for u, param := range params[0] {
-			_param0[u] = param.(*logging.SimpleLogger)
+			_param0[u] = param.(context.Context)
 		}
-		_param1 = make([][]string, len(params[1]))
+		_param1 = make([]*logging.SimpleLogger, len(params[1]))
 		for u, param := range params[1] {
-			_param1[u] = param.([]string)
+			_param1[u] = param.(*logging.SimpleLogger)
 		}
-		_param2 = make([]string, len(params[2]))
+		_param2 = make([][]string, len(params[2]))
 		for u, param := range params[2] {
-			_param2[u] = param.(string)
+			_param2[u] = param.([]string)
 		}
 		_param3 = make([]string, len(params[3]))
 		for u, param := range params[3] {
 			_param3[u] = param.(string)
 		}
-		_param4 = make([]*go_version.Version, len(params[4]))
+		_param4 = make([][]string, len(params[4]))
 		for u, param := range params[4] {
-			_param4[u] = param.(*go_version.Version)
+			_param4[u] = param.([]string)
 		}
 		_param5 = make([]string, len(params[5]))
 		for u, param := range params[5] {
 			_param5[u] = param.(string)
 		}
+		_param6 = make([]*go_version.Version, len(params[6]))
+		for u, param := range params[6] {
+			_param6[u] = param.(*go_version.Version)
+		}
+		_param7 = make([]string, len(params[7]))
+		for u, param := range params[7] {
+			_param7[u] = param.(string)
+		}
 	}
 	return
 }
@@ -0,0 +1,55 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+type outputKey struct{}
+
+// WithOutput returns a copy of ctx that makes commands run by CombinedOutput
+// with it also stream their output to w while they run. w is only written
+// whole lines so commands running at the same time don't split each other's
+// lines.
+func WithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey{}, w)
+}
+
+// outputFrom returns the writer set by WithOutput or nil if there isn't one.
+func outputFrom(ctx context.Context) io.Writer {
+	w, _ := ctx.Value(outputKey{}).(io.Writer)
+	return w
+}
+
+// lineWriter buffers what's written to it and writes it to w a line at a
+// time.
+type lineWriter struct {
+	w       io.Writer
+	mu      sync.Mutex
+	partial []byte
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.partial = append(l.partial, p...)
+	if i := bytes.LastIndexByte(l.partial, '\n'); i >= 0 {
+		lines := l.partial[:i+1]
+		l.partial = append([]byte(nil), l.partial[i+1:]...)
+		// Streaming is best effort so errors don't stop the command.
+		l.w.Write(lines) // nolint: errcheck
+	}
+	return len(p), nil
+}
+
+// Flush writes the last line if it didn't end in a newline.
+func (l *lineWriter) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.partial) > 0 {
+		l.w.Write(l.partial) // nolint: errcheck
+		l.partial = nil
+	}
+}
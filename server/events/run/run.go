@@ -4,6 +4,7 @@ package run
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -20,17 +21,21 @@ const inlineShebang = "#!/bin/sh -e"
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_runner.go Runner
 
 type Runner interface {
-	Execute(log *logging.SimpleLogger, commands []string, path string, workspace string, terraformVersion *version.Version, stage string) (string, error)
+	Execute(ctx context.Context, log *logging.SimpleLogger, commands []string, path string, env []string, workspace string, terraformVersion *version.Version, stage string) (string, error)
 }
 
 type Run struct{}
 
 // Execute runs the commands by writing them as a script to disk
-// and then executing the script.
+// and then executing the script. env is a list of additional environment
+// variables in the form "NAME=value". The environment of our process isn't
+// modified. The script is interrupted if ctx is cancelled.
 func (p *Run) Execute(
+	ctx context.Context,
 	log *logging.SimpleLogger,
 	commands []string,
 	path string,
+	env []string,
 	workspace string,
 	terraformVersion *version.Version,
 	stage string) (string, error) {
@@ -47,13 +52,17 @@ func (p *Run) Execute(
 
 	log.Info("running %s commands: %v", stage, commands)
 
-	// set environment variable for the run.
+	// set environment variables for the run.
 	// this is to support scripts to use the WORKSPACE, ATLANTIS_TERRAFORM_VERSION
-	// and DIR variables in their scripts
-	os.Setenv("WORKSPACE", workspace)                                  // nolint: errcheck
-	os.Setenv("ATLANTIS_TERRAFORM_VERSION", terraformVersion.String()) // nolint: errcheck
-	os.Setenv("DIR", path)                                             // nolint: errcheck
-	return execute(s)
+	// and DIR variables in their scripts. They're only set for this script
+	// rather than for our whole process since commands for different pull
+	// requests run concurrently. env comes last so it takes precedence.
+	runEnv := append([]string{
+		fmt.Sprintf("WORKSPACE=%s", workspace),
+		fmt.Sprintf("ATLANTIS_TERRAFORM_VERSION=%s", terraformVersion.String()),
+		fmt.Sprintf("DIR=%s", path),
+	}, env...)
+	return execute(ctx, s, runEnv)
 }
 
 func createScript(cmds []string, stage string) (string, error) {
@@ -86,9 +95,10 @@ func createScript(cmds []string, stage string) (string, error) {
 	return scriptName, nil
 }
 
-func execute(script string) (string, error) {
+func execute(ctx context.Context, script string, env []string) (string, error) {
 	localCmd := exec.Command("sh", "-c", script) // #nosec
-	out, err := localCmd.CombinedOutput()
+	localCmd.Env = append(os.Environ(), env...)
+	out, err := CombinedOutput(ctx, localCmd, InterruptGracePeriod)
 	output := string(out)
 	if err != nil {
 		return output, errors.Wrapf(err, "running script %s: %s", script, output)
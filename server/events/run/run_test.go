@@ -1,6 +1,8 @@
 package run
 
 import (
+	"context"
+	"os"
 	"testing"
 
 	"github.com/hashicorp/go-version"
@@ -21,14 +23,14 @@ func TestRunCreateScript_valid(t *testing.T) {
 func TestRunExecuteScript_invalid(t *testing.T) {
 	cmds := []string{"invalid", "command"}
 	scriptName, _ := createScript(cmds, "post_apply")
-	_, err := execute(scriptName)
+	_, err := execute(context.Background(), scriptName, nil)
 	Assert(t, err != nil, "there should be an error")
 }
 
 func TestRunExecuteScript_valid(t *testing.T) {
 	cmds := []string{"echo", "date"}
 	scriptName, _ := createScript(cmds, "post_apply")
-	output, err := execute(scriptName)
+	output, err := execute(context.Background(), scriptName, nil)
 	Assert(t, err == nil, "there should not be an error")
 	Assert(t, output != "", "there should be output")
 }
@@ -36,6 +38,23 @@ func TestRunExecuteScript_valid(t *testing.T) {
 func TestRun_valid(t *testing.T) {
 	cmds := []string{"echo", "date"}
 	v, _ := version.NewVersion("0.8.8")
-	_, err := run.Execute(logger, cmds, "/tmp/atlantis", "staging", v, "post_apply")
+	_, err := run.Execute(context.Background(), logger, cmds, "/tmp/atlantis", nil, "staging", v, "post_apply")
 	Ok(t, err)
 }
+
+func TestRun_env(t *testing.T) {
+	cmds := []string{"echo $NAME"}
+	v, _ := version.NewVersion("0.8.8")
+	output, err := run.Execute(context.Background(), logger, cmds, "/tmp/atlantis", []string{"NAME=value"}, "staging", v, "post_apply")
+	Ok(t, err)
+	Equals(t, "value\n", output)
+}
+
+func TestRun_contextEnv(t *testing.T) {
+	cmds := []string{"echo $WORKSPACE $ATLANTIS_TERRAFORM_VERSION $DIR"}
+	v, _ := version.NewVersion("0.8.8")
+	output, err := run.Execute(context.Background(), logger, cmds, "/tmp/atlantis", nil, "staging", v, "post_apply")
+	Ok(t, err)
+	Equals(t, "staging 0.8.8 /tmp/atlantis\n", output)
+	Equals(t, "", os.Getenv("WORKSPACE"))
+}
@@ -0,0 +1,376 @@
+package events
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Keys that a repo's atlantis.yaml can be allowed to override by the server's
+// repo config.
+const (
+	// WorkflowOverride allows projects to pick their workflow.
+	WorkflowOverride = "workflow"
+	// WorkflowsOverride allows repos to define their own workflows. This
+	// includes the hooks and extra_arguments of project config files since
+	// they're converted into workflows.
+	WorkflowsOverride = "workflows"
+	// TerraformVersionOverride allows projects to set their Terraform version.
+	TerraformVersionOverride = "terraform_version"
+	// ApplyRequirementsOverride allows projects to set their apply
+	// requirements.
+	ApplyRequirementsOverride = "apply_requirements"
+)
+
+// AllOverrides is every key that a repo can be allowed to override.
+var AllOverrides = []string{WorkflowOverride, WorkflowsOverride, TerraformVersionOverride, ApplyRequirementsOverride}
+
+// Apply requirements that pull requests must meet before they can be
+// applied. Requirements that take an argument are written as name:arg.
+const (
+	// ApprovedApplyRequirement requires pull requests to be approved.
+	ApprovedApplyRequirement = "approved"
+	// ApprovalsApplyRequirement requires a minimum number of approvals, ex.
+	// approvals:2.
+	ApprovalsApplyRequirement = "approvals"
+	// ApprovedByApplyRequirement requires an approval from a member of a
+	// GitHub team, ex. approved_by:org/team, or a GitLab group, ex.
+	// approved_by:group/subgroup.
+	ApprovedByApplyRequirement = "approved_by"
+	// MergeableApplyRequirement requires pull requests to be mergeable with
+	// their required checks passing.
+	MergeableApplyRequirement = "mergeable"
+	// NoNewCommitsApplyRequirement requires the plan to have been made from
+	// the pull request's head commit and the project's files not to have
+	// changed since. See checkPlanIsCurrent.
+	NoNewCommitsApplyRequirement = "no_new_commits"
+)
+
+// serverRepoConfigYAML is used to parse the YAML.
+type serverRepoConfigYAML struct {
+	Repos     []serverRepoYAML        `yaml:"repos"`
+	Workflows map[string]workflowYAML `yaml:"workflows"`
+}
+
+// serverRepoYAML is used to parse a repo in the YAML.
+type serverRepoYAML struct {
+	ID                string   `yaml:"id"`
+	Workflow          string   `yaml:"workflow"`
+	ApplyRequirements []string `yaml:"apply_requirements"`
+	TerraformVersion  string   `yaml:"terraform_version"`
+	AllowedOverrides  []string `yaml:"allowed_overrides"`
+	ParallelPoolSize  int      `yaml:"parallel_pool_size"`
+}
+
+// ServerRepoConfig is the repo config that's set on the Atlantis server. It
+// sets defaults for repos and controls which of them can be overridden by
+// the repos' atlantis.yaml files. Since hooks and custom workflows run
+// arbitrary commands on the Atlantis host, this lets operators restrict them
+// to the repos they trust.
+type ServerRepoConfig struct {
+	// Repos are matched against the repo being run in order. Settings from
+	// later matches override those of earlier ones.
+	Repos []ServerRepo
+	// Workflows are the workflows defined by the server, keyed by name. They
+	// can be used by any repo.
+	Workflows map[string]Workflow
+}
+
+// ServerRepo is the config for the repos matching its ID. Fields that are
+// nil or empty weren't set.
+type ServerRepo struct {
+	// ID is the full name of the repo, ex. hootsuite/atlantis, or if wrapped
+	// in slashes, ex. /hootsuite/.*/, a regex that must match the whole full
+	// name.
+	ID                string
+	Workflow          string
+	ApplyRequirements []string
+	TerraformVersion  *version.Version
+	AllowedOverrides  []string
+	ParallelPoolSize  int
+	idRegex           *regexp.Regexp
+}
+
+// RepoSettings are the server's settings for a specific repo.
+type RepoSettings struct {
+	// Workflow is the name of the workflow that projects run if they don't
+	// pick one. If empty, they run the default workflow.
+	Workflow string
+	// ApplyRequirements are the requirements that must be met before
+	// projects can be applied if they don't set their own.
+	ApplyRequirements []string
+	// TerraformVersion is the version that projects use if they don't set
+	// their own. If nil, the server's version is used.
+	TerraformVersion *version.Version
+	// AllowedOverrides are the keys that the repo's atlantis.yaml can set.
+	AllowedOverrides []string
+	// Workflows are the workflows defined by the server.
+	Workflows map[string]Workflow
+	// ParallelPoolSize is the max number of the repo's projects that are
+	// planned or applied at once. If 0, the server's pool size is used.
+	ParallelPoolSize int
+}
+
+// ReadServerRepoConfig reads and validates the server repo config at path.
+func ReadServerRepoConfig(path string) (*ServerRepoConfig, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading repo config %s", path)
+	}
+	config, err := parseServerRepoConfig(raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing repo config %s", path)
+	}
+	return config, nil
+}
+
+func parseServerRepoConfig(raw []byte) (*ServerRepoConfig, error) {
+	// Unlike repo config files, typos here shouldn't be ignored since they
+	// could leave repos with more access than intended.
+	var srcYaml serverRepoConfigYAML
+	if err := yaml.UnmarshalStrict(raw, &srcYaml); err != nil {
+		return nil, err
+	}
+
+	var config ServerRepoConfig
+	for name, w := range srcYaml.Workflows {
+		workflow, err := parseWorkflow(w)
+		if err != nil {
+			return nil, errors.Wrapf(err, "workflow %q", name)
+		}
+		if config.Workflows == nil {
+			config.Workflows = make(map[string]Workflow)
+		}
+		config.Workflows[name] = workflow
+	}
+	for i, r := range srcYaml.Repos {
+		repo, err := parseServerRepo(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "repo %d", i+1)
+		}
+		if repo.Workflow != "" && repo.Workflow != DefaultWorkflowName {
+			if _, ok := config.Workflows[repo.Workflow]; !ok {
+				return nil, fmt.Errorf("repo %d: workflow %q isn't defined", i+1, repo.Workflow)
+			}
+		}
+		config.Repos = append(config.Repos, repo)
+	}
+	return &config, nil
+}
+
+func parseServerRepo(r serverRepoYAML) (ServerRepo, error) {
+	repo := ServerRepo{
+		ID:                r.ID,
+		Workflow:          r.Workflow,
+		ApplyRequirements: r.ApplyRequirements,
+		AllowedOverrides:  r.AllowedOverrides,
+		ParallelPoolSize:  r.ParallelPoolSize,
+	}
+	if r.ID == "" {
+		return ServerRepo{}, errors.New("id is required")
+	}
+	if len(r.ID) > 1 && strings.HasPrefix(r.ID, "/") && strings.HasSuffix(r.ID, "/") {
+		// The regex has to match the whole name, otherwise /org/infra/ would
+		// also match evilorg/infra-fork.
+		regex, err := regexp.Compile("^(?:" + r.ID[1:len(r.ID)-1] + ")$")
+		if err != nil {
+			return ServerRepo{}, errors.Wrapf(err, "parsing id %q", r.ID)
+		}
+		repo.idRegex = regex
+	}
+	if r.TerraformVersion != "" {
+		v, err := version.NewVersion(r.TerraformVersion)
+		if err != nil {
+			return ServerRepo{}, errors.Wrap(err, "parsing terraform_version")
+		}
+		repo.TerraformVersion = v
+	}
+	if err := validateApplyRequirements(r.ApplyRequirements); err != nil {
+		return ServerRepo{}, err
+	}
+	if r.ParallelPoolSize < 0 {
+		return ServerRepo{}, errors.New("parallel_pool_size can't be negative")
+	}
+	for _, o := range r.AllowedOverrides {
+		if !containsStr(AllOverrides, o) {
+			return ServerRepo{}, fmt.Errorf("invalid allowed_overrides key %q: must be one of %s", o, strings.Join(AllOverrides, ", "))
+		}
+	}
+	return repo, nil
+}
+
+// Matches returns true if the repo with full name repoFullName matches the
+// repo's ID.
+func (r ServerRepo) Matches(repoFullName string) bool {
+	if r.idRegex != nil {
+		return r.idRegex.MatchString(repoFullName)
+	}
+	return r.ID == repoFullName
+}
+
+// ForRepo returns the settings for the repo with full name repoFullName. If
+// c is nil, the server doesn't have a repo config and repos can override
+// everything. Otherwise repos that don't match any entry get the server's
+// defaults and can't override anything.
+func (c *ServerRepoConfig) ForRepo(repoFullName string) RepoSettings {
+	if c == nil {
+		return RepoSettings{AllowedOverrides: AllOverrides}
+	}
+	settings := RepoSettings{Workflows: c.Workflows}
+	for _, r := range c.Repos {
+		if !r.Matches(repoFullName) {
+			continue
+		}
+		if r.Workflow != "" {
+			settings.Workflow = r.Workflow
+		}
+		if r.ApplyRequirements != nil {
+			settings.ApplyRequirements = r.ApplyRequirements
+		}
+		if r.TerraformVersion != nil {
+			settings.TerraformVersion = r.TerraformVersion
+		}
+		if r.AllowedOverrides != nil {
+			settings.AllowedOverrides = r.AllowedOverrides
+		}
+		if r.ParallelPoolSize != 0 {
+			settings.ParallelPoolSize = r.ParallelPoolSize
+		}
+	}
+	return settings
+}
+
+// IsAllowed returns true if the repo's atlantis.yaml can override key.
+func (s RepoSettings) IsAllowed(key string) bool {
+	return containsStr(s.AllowedOverrides, key)
+}
+
+// FindWorkflow returns the workflow named name. Workflows defined by the repo
+// take precedence over those defined by the server. It returns false if
+// neither define it and it isn't the default workflow.
+func (s RepoSettings) FindWorkflow(name string, repoConfig *RepoConfig) (Workflow, bool) {
+	if repoConfig != nil {
+		if w, ok := repoConfig.Workflows[name]; ok {
+			return w, true
+		}
+	}
+	if w, ok := s.Workflows[name]; ok {
+		return w, true
+	}
+	if name == DefaultWorkflowName {
+		return DefaultWorkflow, true
+	}
+	return Workflow{}, false
+}
+
+// DefaultWorkflow returns the workflow that the server has projects run if
+// they don't pick one.
+func (s RepoSettings) DefaultWorkflow() Workflow {
+	name := s.Workflow
+	if name == "" {
+		name = DefaultWorkflowName
+	}
+	w, _ := s.FindWorkflow(name, nil)
+	return w
+}
+
+// CheckRepoConfig returns an error if repoConfig sets keys that the repo
+// isn't allowed to override or if its projects use workflows that aren't
+// defined. repoConfig can be nil.
+func (s RepoSettings) CheckRepoConfig(repoConfig *RepoConfig) error {
+	if repoConfig == nil {
+		return nil
+	}
+	if len(repoConfig.Workflows) > 0 && !s.IsAllowed(WorkflowsOverride) {
+		return s.disallowedErr(fmt.Sprintf("%s can't define workflows", RepoConfigFile), WorkflowsOverride)
+	}
+	for _, p := range repoConfig.Projects {
+		overrides := []struct {
+			key   string
+			isSet bool
+		}{
+			{WorkflowOverride, p.Workflow != ""},
+			{TerraformVersionOverride, p.TerraformVersion != nil},
+			{ApplyRequirementsOverride, p.ApplyRequirements != nil},
+		}
+		for _, o := range overrides {
+			if o.isSet && !s.IsAllowed(o.key) {
+				return s.disallowedErr(fmt.Sprintf("%s can't set %s for the project in dir %q", RepoConfigFile, o.key, p.Dir), o.key)
+			}
+		}
+		if p.Workflow != "" {
+			if _, ok := s.FindWorkflow(p.Workflow, repoConfig); !ok {
+				return fmt.Errorf("%s: project in dir %q uses workflow %q which isn't defined", RepoConfigFile, p.Dir, p.Workflow)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckProjectConfig returns an error if the project config file in dir sets
+// keys that the repo isn't allowed to override.
+func (s RepoSettings) CheckProjectConfig(config ProjectConfig, dir string) error {
+	if config.hasCommands() && !s.IsAllowed(WorkflowsOverride) {
+		return s.disallowedErr(fmt.Sprintf("%s in dir %q can't set hooks or extra_arguments", ProjectConfigFile, dir), WorkflowsOverride)
+	}
+	if config.TerraformVersion != nil && !s.IsAllowed(TerraformVersionOverride) {
+		return s.disallowedErr(fmt.Sprintf("%s in dir %q can't set %s", ProjectConfigFile, dir, TerraformVersionOverride), TerraformVersionOverride)
+	}
+	return nil
+}
+
+func (s RepoSettings) disallowedErr(prefix string, key string) error {
+	allowed := "none"
+	if len(s.AllowedOverrides) > 0 {
+		allowed = strings.Join(s.AllowedOverrides, ", ")
+	}
+	return fmt.Errorf("%s: the Atlantis server's repo config doesn't allow this repo to override %q. Allowed overrides: %s.", prefix, key, allowed)
+}
+
+// validateApplyRequirements returns an error if any of reqs aren't valid
+// apply requirements.
+func validateApplyRequirements(reqs []string) error {
+	for _, r := range reqs {
+		name, arg := splitApplyRequirement(r)
+		switch {
+		case (name == ApprovedApplyRequirement || name == MergeableApplyRequirement || name == NoNewCommitsApplyRequirement) && arg == "":
+		case name == ApprovalsApplyRequirement:
+			if n, err := strconv.Atoi(arg); err != nil || n < 1 {
+				return fmt.Errorf("invalid apply requirement %q: %s must be followed by a positive number, ex. %s:2", r, ApprovalsApplyRequirement, ApprovalsApplyRequirement)
+			}
+		case name == ApprovedByApplyRequirement:
+			if arg == "" {
+				return fmt.Errorf("invalid apply requirement %q: %s must be followed by a team, ex. %s:org/team", r, ApprovedByApplyRequirement, ApprovedByApplyRequirement)
+			}
+		default:
+			return fmt.Errorf("invalid apply requirement %q: must be one of %s, %s:<n>, %s:<team>, %s or %s", r,
+				ApprovedApplyRequirement, ApprovalsApplyRequirement, ApprovedByApplyRequirement, MergeableApplyRequirement, NoNewCommitsApplyRequirement)
+		}
+	}
+	return nil
+}
+
+// splitApplyRequirement splits r into its name and its argument, which is
+// empty if it doesn't have one.
+func splitApplyRequirement(r string) (string, string) {
+	split := strings.SplitN(r, ":", 2)
+	if len(split) == 1 {
+		return r, ""
+	}
+	return split[0], split[1]
+}
+
+func containsStr(slice []string, s string) bool {
+	for _, e := range slice {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
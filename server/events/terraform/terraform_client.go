@@ -2,6 +2,7 @@
 package terraform
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/go-version"
+	"github.com/hootsuite/atlantis/server/events/run"
 	"github.com/hootsuite/atlantis/server/logging"
 	"github.com/pkg/errors"
 )
@@ -17,8 +19,8 @@ import (
 
 type Client interface {
 	Version() *version.Version
-	RunCommandWithVersion(log *logging.SimpleLogger, path string, args []string, v *version.Version, workspace string) (string, error)
-	Init(log *logging.SimpleLogger, path string, workspace string, extraInitArgs []string, version *version.Version) ([]string, error)
+	RunCommandWithVersion(ctx context.Context, log *logging.SimpleLogger, path string, args []string, env []string, v *version.Version, workspace string) (string, error)
+	Init(ctx context.Context, log *logging.SimpleLogger, path string, workspace string, extraInitArgs []string, env []string, version *version.Version) ([]string, error)
 }
 
 type DefaultClient struct {
@@ -62,10 +64,14 @@ func (c *DefaultClient) Version() *version.Version {
 }
 
 // RunCommandWithVersion executes the provided version of terraform with
-// the provided args in path. v is the version of terraform executable to use
+// the provided args in path. env is a list of additional environment variables
+// in the form "NAME=value". v is the version of terraform executable to use
 // and workspace is the workspace specified by the user commenting
 // "atlantis plan/apply {workspace}" which is set to "default" by default.
-func (c *DefaultClient) RunCommandWithVersion(log *logging.SimpleLogger, path string, args []string, v *version.Version, workspace string) (string, error) {
+// If ctx is cancelled, terraform is interrupted so that it can release its
+// state lock and then killed if it doesn't exit within
+// run.InterruptGracePeriod.
+func (c *DefaultClient) RunCommandWithVersion(ctx context.Context, log *logging.SimpleLogger, path string, args []string, env []string, v *version.Version, workspace string) (string, error) {
 	tfExecutable := "terraform"
 	// if version is the same as the default, don't need to prepend the version name to the executable
 	if !v.Equal(c.defaultVersion) {
@@ -83,14 +89,16 @@ func (c *DefaultClient) RunCommandWithVersion(log *logging.SimpleLogger, path st
 		fmt.Sprintf("DIR=%s", path),
 	}
 	envVars = append(envVars, os.Environ()...)
+	// env comes last so it takes precedence.
+	envVars = append(envVars, env...)
 
-	// append terraform executable name with args
-	tfCmd := fmt.Sprintf("%s %s", tfExecutable, strings.Join(args, " "))
-
-	terraformCmd := exec.Command("sh", "-c", tfCmd) // #nosec
+	// We exec terraform directly rather than through a shell so that args,
+	// which can come from pull request comments, are never interpreted by
+	// one.
+	terraformCmd := exec.Command(tfExecutable, args...) // #nosec
 	terraformCmd.Dir = path
 	terraformCmd.Env = envVars
-	out, err := terraformCmd.CombinedOutput()
+	out, err := run.CombinedOutput(ctx, terraformCmd, run.InterruptGracePeriod)
 	commandStr := strings.Join(terraformCmd.Args, " ")
 	if err != nil {
 		err = fmt.Errorf("%s: running %q in %q: \n%s", err, commandStr, path, out)
@@ -103,16 +111,18 @@ func (c *DefaultClient) RunCommandWithVersion(log *logging.SimpleLogger, path st
 
 // Init executes "terraform init" and "terraform workspace select" in path.
 // workspace is the workspace to select and extraInitArgs are additional arguments
-// applied to the init command. version is the terraform version being executed.
+// applied to the init command. env is a list of additional environment
+// variables in the form "NAME=value". version is the terraform version being
+// executed.
 // Init is guaranteed to be called with version >= 0.9 since the init command
 // was only introduced in that version. It properly handles the renaming of the
 // env command to workspace since 0.10.
 //
 // Returns the string outputs of running each command.
-func (c *DefaultClient) Init(log *logging.SimpleLogger, path string, workspace string, extraInitArgs []string, version *version.Version) ([]string, error) {
+func (c *DefaultClient) Init(ctx context.Context, log *logging.SimpleLogger, path string, workspace string, extraInitArgs []string, env []string, version *version.Version) ([]string, error) {
 	var outputs []string
 
-	output, err := c.RunCommandWithVersion(log, path, append([]string{"init", "-no-color"}, extraInitArgs...), version, workspace)
+	output, err := c.RunCommandWithVersion(ctx, log, path, append([]string{"init", "-no-color"}, extraInitArgs...), env, version, workspace)
 	outputs = append(outputs, output)
 	if err != nil {
 		return outputs, err
@@ -124,12 +134,15 @@ func (c *DefaultClient) Init(log *logging.SimpleLogger, path string, workspace s
 		workspaceCommand = "env"
 	}
 
-	output, err = c.RunCommandWithVersion(log, path, []string{workspaceCommand, "select", "-no-color", workspace}, version, workspace)
+	output, err = c.RunCommandWithVersion(ctx, log, path, []string{workspaceCommand, "select", "-no-color", workspace}, env, version, workspace)
 	outputs = append(outputs, output)
 	if err != nil {
+		if ctx.Err() != nil {
+			return outputs, err
+		}
 		// If terraform workspace select fails we run terraform workspace
 		// new to create a new workspace automatically.
-		output, err = c.RunCommandWithVersion(log, path, []string{workspaceCommand, "new", "-no-color", workspace}, version, workspace)
+		output, err = c.RunCommandWithVersion(ctx, log, path, []string{workspaceCommand, "new", "-no-color", workspace}, env, version, workspace)
 		outputs = append(outputs, output)
 		if err != nil {
 			return outputs, err
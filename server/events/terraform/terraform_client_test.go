@@ -1,10 +1,16 @@
 package terraform_test
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/go-version"
 	"github.com/hootsuite/atlantis/server/events/terraform"
+	"github.com/hootsuite/atlantis/server/logging"
 	. "github.com/hootsuite/atlantis/testing"
 )
 
@@ -26,3 +32,26 @@ func TestMustConstraint(t *testing.T) {
 	Ok(t, err)
 	Equals(t, expectedConstraint.String(), c.String())
 }
+
+func TestRunCommandWithVersion_NoShell(t *testing.T) {
+	t.Log("args should be passed to terraform as is without being interpreted by a shell")
+	tmp, err := ioutil.TempDir("", "")
+	Ok(t, err)
+	defer os.RemoveAll(tmp) // nolint: errcheck
+
+	// Our fake terraform prints each of its args on a separate line.
+	script := "#!/bin/sh\nif [ \"$1\" = version ]; then echo 'Terraform v0.11.0'; exit 0; fi\nfor a in \"$@\"; do echo \"$a\"; done\n"
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "terraform"), []byte(script), 0755))
+	oldPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", oldPath)   // nolint: errcheck
+	os.Setenv("PATH", tmp+":"+oldPath) // nolint: errcheck
+
+	projectDir := filepath.Join(tmp, "dir with spaces")
+	Ok(t, os.Mkdir(projectDir, 0755))
+	c, err := terraform.NewClient()
+	Ok(t, err)
+	args := []string{"plan", "-var", "a=b c", "--", ";", "echo", "$HOME", "`id`"}
+	out, err := c.RunCommandWithVersion(context.Background(), logging.NewNoopLogger(), projectDir, args, nil, c.Version(), "default")
+	Ok(t, err)
+	Equals(t, strings.Join(args, "\n")+"\n", out)
+}
@@ -0,0 +1,31 @@
+package events
+
+import (
+	"github.com/hootsuite/atlantis/server/events/locking"
+	"github.com/pkg/errors"
+)
+
+// UnlockExecutor handles the unlock command. It discards all the plans and
+// releases all the locks held by the pull request so other pull requests can
+// run commands against the same projects.
+type UnlockExecutor struct {
+	Locker    locking.Locker
+	Workspace AtlantisWorkspace
+}
+
+// Execute executes the unlock command for the ctx.
+func (u *UnlockExecutor) Execute(ctx *CommandContext) CommandResponse {
+	// Delete the workspaces first so that once the locks are released, no
+	// stale plans are left on disk that could be applied.
+	if err := u.Workspace.Delete(ctx.BaseRepo, ctx.Pull); err != nil {
+		return CommandResponse{Error: errors.Wrap(err, "deleting workspaces")}
+	}
+	ctx.Log.Info("deleted workspaces")
+
+	locks, err := u.Locker.UnlockByPull(ctx.BaseRepo.FullName, ctx.Pull.Num)
+	if err != nil {
+		return CommandResponse{Error: errors.Wrap(err, "deleting locks")}
+	}
+	ctx.Log.Info("deleted %d lock(s)", len(locks))
+	return CommandResponse{UnlockedLocks: locks}
+}
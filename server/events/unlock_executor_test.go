@@ -0,0 +1,65 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events"
+	lockmocks "github.com/hootsuite/atlantis/server/events/locking/mocks"
+	"github.com/hootsuite/atlantis/server/events/mocks"
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/models/fixtures"
+	"github.com/hootsuite/atlantis/server/logging"
+	. "github.com/hootsuite/atlantis/testing"
+	. "github.com/petergtz/pegomock"
+)
+
+var unlockCtx = events.CommandContext{
+	BaseRepo: fixtures.Repo,
+	Pull:     fixtures.Pull,
+	Command: &events.Command{
+		Name: events.Unlock,
+	},
+	Log: logging.NewNoopLogger(),
+}
+
+func TestUnlockExecute_WorkspaceErr(t *testing.T) {
+	t.Log("when workspace.Delete returns an error, we return it")
+	u, w, _ := setupUnlockExecutorTest(t)
+	When(w.Delete(fixtures.Repo, fixtures.Pull)).ThenReturn(errors.New("err"))
+	r := u.Execute(&unlockCtx)
+	Equals(t, "deleting workspaces: err", r.Error.Error())
+}
+
+func TestUnlockExecute_UnlockErr(t *testing.T) {
+	t.Log("when locker.UnlockByPull returns an error, we return it")
+	u, _, l := setupUnlockExecutorTest(t)
+	When(l.UnlockByPull(fixtures.Repo.FullName, fixtures.Pull.Num)).ThenReturn(nil, errors.New("err"))
+	r := u.Execute(&unlockCtx)
+	Equals(t, "deleting locks: err", r.Error.Error())
+}
+
+func TestUnlockExecute_Success(t *testing.T) {
+	t.Log("when the workspaces and locks are deleted, we return the locks")
+	u, w, l := setupUnlockExecutorTest(t)
+	locks := []models.ProjectLock{
+		{
+			Project:   models.NewProject(fixtures.Repo.FullName, "path"),
+			Workspace: "default",
+		},
+	}
+	When(l.UnlockByPull(fixtures.Repo.FullName, fixtures.Pull.Num)).ThenReturn(locks, nil)
+	r := u.Execute(&unlockCtx)
+	Equals(t, events.CommandResponse{UnlockedLocks: locks}, r)
+	w.VerifyWasCalledOnce().Delete(fixtures.Repo, fixtures.Pull)
+}
+
+func setupUnlockExecutorTest(t *testing.T) (*events.UnlockExecutor, *mocks.MockAtlantisWorkspace, *lockmocks.MockLocker) {
+	RegisterMockTestingT(t)
+	w := mocks.NewMockAtlantisWorkspace()
+	l := lockmocks.NewMockLocker()
+	return &events.UnlockExecutor{
+		Locker:    l,
+		Workspace: w,
+	}, w, l
+}
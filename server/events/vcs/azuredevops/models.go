@@ -0,0 +1,168 @@
+// Package azuredevops holds the models for Azure DevOps Repos' service hooks
+// and API. Only the fields Atlantis uses are included.
+package azuredevops
+
+// RequestIDHeader is the header that holds the ID of a service hook request.
+// It's used to tell Azure DevOps requests apart.
+const RequestIDHeader = "Request-Id"
+
+// Service hook event types.
+const (
+	PullCreatedEvent   = "git.pullrequest.created"
+	PullUpdatedEvent   = "git.pullrequest.updated"
+	PullCommentedEvent = "git.pullrequest.commented"
+)
+
+// Pull request statuses.
+const (
+	ActiveStatus = "active"
+)
+
+// Reviewer votes.
+const (
+	VoteApproved                = 10
+	VoteApprovedWithSuggestions = 5
+)
+
+// SourceBranchUpdatedText is in the message of git.pullrequest.updated events
+// that were sent because the source branch was pushed to. The event is also
+// sent for other updates like votes and title changes.
+const SourceBranchUpdatedText = "updated the source branch"
+
+// Event is a service hook event. Resource depends on EventType so it's
+// decoded separately.
+type Event struct {
+	EventType string  `json:"eventType"`
+	Message   Message `json:"message"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+// CommentEvent is the payload of git.pullrequest.commented events.
+type CommentEvent struct {
+	Resource CommentResource `json:"resource"`
+}
+
+type CommentResource struct {
+	Comment     Comment     `json:"comment"`
+	PullRequest PullRequest `json:"pullRequest"`
+}
+
+// PullRequestEvent is the payload of git.pullrequest.created and updated
+// events.
+type PullRequestEvent struct {
+	Resource PullRequest `json:"resource"`
+}
+
+type Comment struct {
+	Content string   `json:"content"`
+	Author  Identity `json:"author"`
+}
+
+// Identity is a user.
+type Identity struct {
+	// UniqueName is the user's login, usually their email address.
+	UniqueName string `json:"uniqueName"`
+}
+
+type PullRequest struct {
+	PullRequestID int `json:"pullRequestId"`
+	// Status is one of active, abandoned or completed.
+	Status                string     `json:"status"`
+	CreatedBy             Identity   `json:"createdBy"`
+	SourceRefName         string     `json:"sourceRefName"`
+	LastMergeSourceCommit Commit     `json:"lastMergeSourceCommit"`
+	Repository            Repository `json:"repository"`
+	Reviewers             []Reviewer `json:"reviewers"`
+	// ForkSource is only set if the pull request is from a fork.
+	ForkSource *ForkRef `json:"forkSource"`
+}
+
+type ForkRef struct {
+	Repository Repository `json:"repository"`
+}
+
+type Commit struct {
+	CommitID string `json:"commitId"`
+}
+
+type Repository struct {
+	Name string `json:"name"`
+	// URL is the repo's API URL, ex.
+	// https://dev.azure.com/org/_apis/git/repositories/<id>.
+	URL     string  `json:"url"`
+	Project Project `json:"project"`
+}
+
+type Project struct {
+	Name string `json:"name"`
+}
+
+type Reviewer struct {
+	UniqueName string `json:"uniqueName"`
+	// Vote is 10 for approved, 5 for approved with suggestions, 0 for no
+	// vote, -5 for waiting for author and -10 for rejected.
+	Vote int `json:"vote"`
+}
+
+// Iterations are the pushes to a pull request.
+type Iterations struct {
+	Value []Iteration `json:"value"`
+}
+
+type Iteration struct {
+	ID int `json:"id"`
+}
+
+// IterationChanges is a page of the files modified by a pull request.
+type IterationChanges struct {
+	ChangeEntries []ChangeEntry `json:"changeEntries"`
+	// NextSkip is the number of changes to skip to get the next page. It's 0
+	// on the last page.
+	NextSkip int `json:"nextSkip"`
+}
+
+type ChangeEntry struct {
+	Item Item `json:"item"`
+	// OriginalPath is only set if the file was renamed.
+	OriginalPath string `json:"originalPath"`
+}
+
+type Item struct {
+	// Path is the path of the file from the repo root with a leading slash.
+	Path string `json:"path"`
+}
+
+// CommentThreadRequest is the body used to create a comment.
+type CommentThreadRequest struct {
+	Comments []CommentRequest `json:"comments"`
+	Status   string           `json:"status"`
+}
+
+type CommentRequest struct {
+	Content     string `json:"content"`
+	CommentType string `json:"commentType"`
+}
+
+// StatusRequest is the body used to set a pull request's status.
+type StatusRequest struct {
+	State       string        `json:"state"`
+	Description string        `json:"description"`
+	TargetURL   string        `json:"targetUrl"`
+	Context     StatusContext `json:"context"`
+}
+
+// StatusContext identifies a status so it's updated rather than duplicated.
+type StatusContext struct {
+	Name  string `json:"name"`
+	Genre string `json:"genre"`
+}
+
+// Status states.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
@@ -0,0 +1,203 @@
+package vcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs/azuredevops"
+	"github.com/pkg/errors"
+)
+
+// AzureDevopsAPIURL is the base URL of Azure DevOps' API.
+const AzureDevopsAPIURL = "https://dev.azure.com"
+
+// azureDevopsAPIVersion is the version of the API that requests are made
+// against. Pull request statuses are still in preview.
+const azureDevopsAPIVersion = "5.0-preview.1"
+
+// AzureDevopsClient is used to make API calls to Azure DevOps. Repos are
+// identified by their organization, project and name so a repo's owner is
+// its organization and project, ex. "org/project".
+type AzureDevopsClient struct {
+	HTTPClient *http.Client
+	Username   string
+	// Token is the user's personal access token.
+	Token string
+	// BaseURL is the URL of the API, ex. https://dev.azure.com.
+	BaseURL string
+	// AtlantisURL is linked to from pull request statuses.
+	AtlantisURL string
+}
+
+// NewAzureDevopsClient returns a client for Azure DevOps that authenticates
+// as username with the personal access token.
+func NewAzureDevopsClient(httpClient *http.Client, username string, token string, atlantisURL string) *AzureDevopsClient {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &AzureDevopsClient{
+		HTTPClient:  httpClient,
+		Username:    username,
+		Token:       token,
+		BaseURL:     AzureDevopsAPIURL,
+		AtlantisURL: atlantisURL,
+	}
+}
+
+// GetModifiedFiles returns the names of files that were modified in the pull request.
+// The names include the path to the file from the repo root, ex. parent/child/file.txt.
+// The files are those changed by the pull request's latest iteration, ie.
+// push, compared to its base.
+func (a *AzureDevopsClient) GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	resp, err := a.makeRequest("GET", a.pullURL(repo, pull.Num, "/iterations"), nil)
+	if err != nil {
+		return nil, err
+	}
+	var iterations azuredevops.Iterations
+	if err := json.Unmarshal(resp, &iterations); err != nil {
+		return nil, errors.Wrapf(err, "parsing response %q", string(resp))
+	}
+	if len(iterations.Value) == 0 {
+		return nil, nil
+	}
+	latest := iterations.Value[len(iterations.Value)-1].ID
+
+	var files []string
+	skip := 0
+	for {
+		path := a.pullURL(repo, pull.Num, fmt.Sprintf("/iterations/%d/changes", latest)) + fmt.Sprintf("&$compareTo=0&$skip=%d", skip)
+		resp, err := a.makeRequest("GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+		var changes azuredevops.IterationChanges
+		if err := json.Unmarshal(resp, &changes); err != nil {
+			return nil, errors.Wrapf(err, "parsing response %q", string(resp))
+		}
+		for _, c := range changes.ChangeEntries {
+			files = append(files, strings.TrimPrefix(c.Item.Path, "/"))
+			// Files that were renamed also have the path they were renamed from.
+			if c.OriginalPath != "" && c.OriginalPath != c.Item.Path {
+				files = append(files, strings.TrimPrefix(c.OriginalPath, "/"))
+			}
+		}
+		if changes.NextSkip == 0 {
+			break
+		}
+		skip = changes.NextSkip
+	}
+	return files, nil
+}
+
+// CreateComment creates a comment on the pull request. It's created in its
+// own thread which is closed so it doesn't block pull requests that require
+// comments to be resolved.
+func (a *AzureDevopsClient) CreateComment(repo models.Repo, pull models.PullRequest, comment string) error {
+	body, err := json.Marshal(azuredevops.CommentThreadRequest{
+		Comments: []azuredevops.CommentRequest{{Content: comment, CommentType: "text"}},
+		Status:   "closed",
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	_, err = a.makeRequest("POST", a.pullURL(repo, pull.Num, "/threads"), bytes.NewBuffer(body))
+	return err
+}
+
+// PullIsApproved returns true if any of the pull request's reviewers voted
+// to approve it, with or without suggestions.
+func (a *AzureDevopsClient) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
+	adPull, err := a.GetPullRequest(repo, pull.Num)
+	if err != nil {
+		return false, err
+	}
+	for _, reviewer := range adPull.Reviewers {
+		if reviewer.Vote == azuredevops.VoteApproved || reviewer.Vote == azuredevops.VoteApprovedWithSuggestions {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UpdateStatus sets the pull request's status. Azure DevOps shows statuses on
+// pull requests rather than commits so the latest one is shown.
+func (a *AzureDevopsClient) UpdateStatus(repo models.Repo, pull models.PullRequest, status CommitStatus, src string, description string, url string) error {
+	adState := azuredevops.StatusFailed
+	switch status {
+	case Pending:
+		adState = azuredevops.StatusPending
+	case Success:
+		adState = azuredevops.StatusSucceeded
+	case Failed:
+		adState = azuredevops.StatusFailed
+	}
+	body, err := json.Marshal(azuredevops.StatusRequest{
+		State:       adState,
+		Description: description,
+		TargetURL:   targetURL(url, a.AtlantisURL),
+		Context:     azuredevops.StatusContext{Name: src, Genre: "atlantis"},
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	_, err = a.makeRequest("POST", a.pullURL(repo, pull.Num, "/statuses"), bytes.NewBuffer(body))
+	return err
+}
+
+// GetPullRequest returns the pull request.
+func (a *AzureDevopsClient) GetPullRequest(repo models.Repo, num int) (azuredevops.PullRequest, error) {
+	var pull azuredevops.PullRequest
+	resp, err := a.makeRequest("GET", a.pullURL(repo, num, ""), nil)
+	if err != nil {
+		return pull, err
+	}
+	if err := json.Unmarshal(resp, &pull); err != nil {
+		return pull, errors.Wrapf(err, "parsing response %q", string(resp))
+	}
+	return pull, nil
+}
+
+// pullURL returns the API URL of subPath under pull request num in repo,
+// including the api-version query param.
+func (a *AzureDevopsClient) pullURL(repo models.Repo, num int, subPath string) string {
+	var escaped []string
+	for _, s := range strings.Split(repo.Owner, "/") {
+		escaped = append(escaped, url.PathEscape(s))
+	}
+	return fmt.Sprintf("%s/%s/_apis/git/repositories/%s/pullRequests/%d%s?api-version=%s",
+		a.BaseURL, strings.Join(escaped, "/"), url.PathEscape(repo.Name), num, subPath, azureDevopsAPIVersion)
+}
+
+// makeRequest makes an authenticated request to the API and returns the
+// response body. It returns an error if the response doesn't have a 2xx
+// status.
+func (a *AzureDevopsClient) makeRequest(method string, path string, reqBody io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, path, reqBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "constructing request")
+	}
+	req.SetBasicAuth(a.Username, a.Token)
+	if reqBody != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	resp, err := a.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading response body")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("making request %s %s: unexpected status code %d, body: %s", method, path, resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
@@ -0,0 +1,118 @@
+package vcs_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs"
+	"github.com/hootsuite/atlantis/server/events/vcs/azuredevops"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+var adRepo = models.Repo{FullName: "org/my project/repo", Owner: "org/my project", Name: "repo"}
+
+func TestAzureDevopsClient_GetModifiedFiles(t *testing.T) {
+	t.Log("should page through the changes of the latest iteration")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "5.0-preview.1", r.URL.Query().Get("api-version"))
+		switch r.URL.Path {
+		case "/org/my project/_apis/git/repositories/repo/pullRequests/1/iterations":
+			w.Write([]byte(`{"value": [{"id": 1}, {"id": 2}]}`)) // nolint: errcheck
+		case "/org/my project/_apis/git/repositories/repo/pullRequests/1/iterations/2/changes":
+			Equals(t, "0", r.URL.Query().Get("$compareTo"))
+			switch r.URL.Query().Get("$skip") {
+			case "0":
+				w.Write([]byte(`{"changeEntries": [{"item": {"path": "/a.tf"}}, {"item": {"path": "/b.tf"}, "originalPath": "/c.tf"}], "nextSkip": 2}`)) // nolint: errcheck
+			case "2":
+				w.Write([]byte(`{"changeEntries": [{"item": {"path": "/d/e.tf"}}], "nextSkip": 0}`)) // nolint: errcheck
+			}
+		default:
+			t.Errorf("unexpected request %s", r.URL.RequestURI())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	files, err := newTestAzureDevopsClient(srv.URL).GetModifiedFiles(adRepo, bbPull)
+	Ok(t, err)
+	Equals(t, []string{"a.tf", "b.tf", "c.tf", "d/e.tf"}, files)
+}
+
+func TestAzureDevopsClient_CreateComment(t *testing.T) {
+	t.Log("should create a closed thread with the comment")
+	var body azuredevops.CommentThreadRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "POST", r.Method)
+		Equals(t, "/org/my project/_apis/git/repositories/repo/pullRequests/1/threads", r.URL.Path)
+		user, pass, ok := r.BasicAuth()
+		Assert(t, ok, "exp basic auth")
+		Equals(t, "user", user)
+		Equals(t, "token", pass)
+		raw, _ := ioutil.ReadAll(r.Body)
+		Ok(t, json.Unmarshal(raw, &body))
+	}))
+	defer srv.Close()
+
+	err := newTestAzureDevopsClient(srv.URL).CreateComment(adRepo, bbPull, "comment")
+	Ok(t, err)
+	Equals(t, azuredevops.CommentThreadRequest{
+		Comments: []azuredevops.CommentRequest{{Content: "comment", CommentType: "text"}},
+		Status:   "closed",
+	}, body)
+}
+
+func TestAzureDevopsClient_PullIsApproved(t *testing.T) {
+	cases := []struct {
+		reviewers string
+		exp       bool
+	}{
+		{`[]`, false},
+		{`[{"uniqueName": "a", "vote": -5}]`, false},
+		{`[{"uniqueName": "a", "vote": 0}, {"uniqueName": "b", "vote": 5}]`, true},
+		{`[{"uniqueName": "a", "vote": 10}]`, true},
+	}
+	for _, c := range cases {
+		t.Run(c.reviewers, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Equals(t, "/org/my project/_apis/git/repositories/repo/pullRequests/1", r.URL.Path)
+				fmt.Fprintf(w, `{"pullRequestId": 1, "reviewers": %s}`, c.reviewers)
+			}))
+			defer srv.Close()
+
+			approved, err := newTestAzureDevopsClient(srv.URL).PullIsApproved(adRepo, bbPull)
+			Ok(t, err)
+			Equals(t, c.exp, approved)
+		})
+	}
+}
+
+func TestAzureDevopsClient_UpdateStatus(t *testing.T) {
+	t.Log("should set the pull request's status")
+	var body azuredevops.StatusRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "/org/my project/_apis/git/repositories/repo/pullRequests/1/statuses", r.URL.Path)
+		raw, _ := ioutil.ReadAll(r.Body)
+		Ok(t, json.Unmarshal(raw, &body))
+	}))
+	defer srv.Close()
+
+	err := newTestAzureDevopsClient(srv.URL).UpdateStatus(adRepo, bbPull, vcs.Success, "atlantis/plan", "description", "")
+	Ok(t, err)
+	Equals(t, azuredevops.StatusRequest{
+		State:       azuredevops.StatusSucceeded,
+		Description: "description",
+		TargetURL:   "https://atlantis.example.com",
+		Context:     azuredevops.StatusContext{Name: "atlantis/plan", Genre: "atlantis"},
+	}, body)
+}
+
+func newTestAzureDevopsClient(url string) *vcs.AzureDevopsClient {
+	client := vcs.NewAzureDevopsClient(nil, "user", "token", "https://atlantis.example.com")
+	client.BaseURL = url
+	return client
+}
@@ -0,0 +1,169 @@
+package vcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs/bitbucketcloud"
+	"github.com/pkg/errors"
+)
+
+// BitbucketCloudAPIURL is the base URL of Bitbucket Cloud's API.
+const BitbucketCloudAPIURL = "https://api.bitbucket.org"
+
+// BitbucketClient is used to make API calls to Bitbucket Cloud.
+type BitbucketClient struct {
+	HTTPClient *http.Client
+	Username   string
+	// Password is the user's app password.
+	Password string
+	// BaseURL is the URL of the API, ex. https://api.bitbucket.org.
+	BaseURL string
+	// AtlantisURL is linked to from build statuses since Bitbucket requires
+	// them to have a URL.
+	AtlantisURL string
+}
+
+// NewBitbucketClient returns a client for Bitbucket Cloud that authenticates
+// as username with the app password.
+func NewBitbucketClient(httpClient *http.Client, username string, password string, atlantisURL string) *BitbucketClient {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &BitbucketClient{
+		HTTPClient:  httpClient,
+		Username:    username,
+		Password:    password,
+		BaseURL:     BitbucketCloudAPIURL,
+		AtlantisURL: atlantisURL,
+	}
+}
+
+// GetModifiedFiles returns the names of files that were modified in the pull request.
+// The names include the path to the file from the repo root, ex. parent/child/file.txt.
+func (b *BitbucketClient) GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	var files []string
+	nextPageURL := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d/diffstat", b.BaseURL, repo.FullName, pull.Num)
+	for nextPageURL != "" {
+		resp, err := b.makeRequest("GET", nextPageURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		var diffStat bitbucketcloud.DiffStat
+		if err := json.Unmarshal(resp, &diffStat); err != nil {
+			return nil, errors.Wrapf(err, "parsing response %q", string(resp))
+		}
+		for _, v := range diffStat.Values {
+			// Files that were removed only have an old path and files that
+			// were renamed have both so we need to check both.
+			if v.Old != nil {
+				files = append(files, v.Old.Path)
+			}
+			if v.New != nil && (v.Old == nil || v.New.Path != v.Old.Path) {
+				files = append(files, v.New.Path)
+			}
+		}
+		nextPageURL = diffStat.Next
+	}
+	return files, nil
+}
+
+// CreateComment creates a comment on the pull request.
+func (b *BitbucketClient) CreateComment(repo models.Repo, pull models.PullRequest, comment string) error {
+	body, err := json.Marshal(bitbucketcloud.CommentRequest{
+		Content: bitbucketcloud.CommentContent{Raw: comment},
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	path := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d/comments", b.BaseURL, repo.FullName, pull.Num)
+	_, err = b.makeRequest("POST", path, bytes.NewBuffer(body))
+	return err
+}
+
+// PullIsApproved returns true if the pull request was approved by any of its
+// participants.
+func (b *BitbucketClient) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
+	bbPull, err := b.GetPullRequest(repo, pull.Num)
+	if err != nil {
+		return false, err
+	}
+	for _, participant := range bbPull.Participants {
+		if participant.Approved {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UpdateStatus updates the build status of the pull request's head commit.
+func (b *BitbucketClient) UpdateStatus(repo models.Repo, pull models.PullRequest, status CommitStatus, src string, description string, url string) error {
+	bbState := bitbucketcloud.BuildFailed
+	switch status {
+	case Pending:
+		bbState = bitbucketcloud.BuildInProgress
+	case Success:
+		bbState = bitbucketcloud.BuildSuccessful
+	case Failed:
+		bbState = bitbucketcloud.BuildFailed
+	}
+	body, err := json.Marshal(bitbucketcloud.BuildStatus{
+		Key:         src,
+		Name:        src,
+		State:       bbState,
+		Description: description,
+		URL:         targetURL(url, b.AtlantisURL),
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	path := fmt.Sprintf("%s/2.0/repositories/%s/commit/%s/statuses/build", b.BaseURL, repo.FullName, pull.HeadCommit)
+	_, err = b.makeRequest("POST", path, bytes.NewBuffer(body))
+	return err
+}
+
+// GetPullRequest returns the pull request.
+func (b *BitbucketClient) GetPullRequest(repo models.Repo, num int) (bitbucketcloud.PullRequest, error) {
+	var pull bitbucketcloud.PullRequest
+	path := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d", b.BaseURL, repo.FullName, num)
+	resp, err := b.makeRequest("GET", path, nil)
+	if err != nil {
+		return pull, err
+	}
+	if err := json.Unmarshal(resp, &pull); err != nil {
+		return pull, errors.Wrapf(err, "parsing response %q", string(resp))
+	}
+	return pull, nil
+}
+
+// makeRequest makes an authenticated request to the API and returns the
+// response body. It returns an error if the response doesn't have a 2xx
+// status.
+func (b *BitbucketClient) makeRequest(method string, path string, reqBody io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, path, reqBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "constructing request")
+	}
+	req.SetBasicAuth(b.Username, b.Password)
+	if reqBody != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading response body")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("making request %s %s: unexpected status code %d, body: %s", method, path, resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
@@ -0,0 +1,136 @@
+package vcs_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs"
+	"github.com/hootsuite/atlantis/server/events/vcs/bitbucketcloud"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+var bbRepo = models.Repo{FullName: "owner/repo"}
+var bbPull = models.PullRequest{Num: 1, HeadCommit: "abc123"}
+
+func TestBitbucketClient_GetModifiedFiles(t *testing.T) {
+	t.Log("should page through the diffstat and include the old and new paths of renamed files")
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.RequestURI() {
+		case "/2.0/repositories/owner/repo/pullrequests/1/diffstat":
+			fmt.Fprintf(w, `{"values": [{"status": "modified", "old": {"path": "a.tf"}, "new": {"path": "a.tf"}}, {"status": "added", "new": {"path": "b.tf"}}], "next": "%s/page2"}`, srv.URL)
+		case "/page2":
+			w.Write([]byte(`{"values": [{"status": "removed", "old": {"path": "c.tf"}}, {"status": "renamed", "old": {"path": "d.tf"}, "new": {"path": "e.tf"}}]}`)) // nolint: errcheck
+		default:
+			t.Errorf("unexpected request %s", r.URL.RequestURI())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client := newTestBitbucketClient(srv.URL)
+	files, err := client.GetModifiedFiles(bbRepo, bbPull)
+	Ok(t, err)
+	Equals(t, []string{"a.tf", "b.tf", "c.tf", "d.tf", "e.tf"}, files)
+}
+
+func TestBitbucketClient_CreateComment(t *testing.T) {
+	t.Log("should post the comment with basic auth")
+	var body bitbucketcloud.CommentRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "POST", r.Method)
+		Equals(t, "/2.0/repositories/owner/repo/pullrequests/1/comments", r.URL.Path)
+		user, pass, ok := r.BasicAuth()
+		Assert(t, ok, "exp basic auth")
+		Equals(t, "user", user)
+		Equals(t, "password", pass)
+		raw, _ := ioutil.ReadAll(r.Body)
+		Ok(t, json.Unmarshal(raw, &body))
+	}))
+	defer srv.Close()
+
+	client := newTestBitbucketClient(srv.URL)
+	err := client.CreateComment(bbRepo, bbPull, "comment")
+	Ok(t, err)
+	Equals(t, "comment", body.Content.Raw)
+}
+
+func TestBitbucketClient_PullIsApproved(t *testing.T) {
+	cases := []struct {
+		participants string
+		exp          bool
+	}{
+		{`[]`, false},
+		{`[{"approved": false, "user": {"username": "a"}}]`, false},
+		{`[{"approved": false, "user": {"username": "a"}}, {"approved": true, "user": {"username": "b"}}]`, true},
+	}
+	for _, c := range cases {
+		t.Run(c.participants, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Equals(t, "/2.0/repositories/owner/repo/pullrequests/1", r.URL.Path)
+				fmt.Fprintf(w, `{"id": 1, "participants": %s}`, c.participants)
+			}))
+			defer srv.Close()
+
+			approved, err := newTestBitbucketClient(srv.URL).PullIsApproved(bbRepo, bbPull)
+			Ok(t, err)
+			Equals(t, c.exp, approved)
+		})
+	}
+}
+
+func TestBitbucketClient_UpdateStatus(t *testing.T) {
+	cases := []struct {
+		status   vcs.CommitStatus
+		expState string
+	}{
+		{vcs.Pending, bitbucketcloud.BuildInProgress},
+		{vcs.Success, bitbucketcloud.BuildSuccessful},
+		{vcs.Failed, bitbucketcloud.BuildFailed},
+	}
+	for _, c := range cases {
+		t.Run(c.status.String(), func(t *testing.T) {
+			var body bitbucketcloud.BuildStatus
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Equals(t, "/2.0/repositories/owner/repo/commit/abc123/statuses/build", r.URL.Path)
+				raw, _ := ioutil.ReadAll(r.Body)
+				Ok(t, json.Unmarshal(raw, &body))
+			}))
+			defer srv.Close()
+
+			err := newTestBitbucketClient(srv.URL).UpdateStatus(bbRepo, bbPull, c.status, "atlantis/plan", "description", "")
+			Ok(t, err)
+			Equals(t, bitbucketcloud.BuildStatus{
+				Key:         "atlantis/plan",
+				Name:        "atlantis/plan",
+				State:       c.expState,
+				Description: "description",
+				URL:         "https://atlantis.example.com",
+			}, body)
+		})
+	}
+}
+
+func TestBitbucketClient_ErrStatus(t *testing.T) {
+	t.Log("should return an error if the response isn't a 2xx")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("forbidden")) // nolint: errcheck
+	}))
+	defer srv.Close()
+
+	err := newTestBitbucketClient(srv.URL).CreateComment(bbRepo, bbPull, "comment")
+	Assert(t, err != nil, "exp err")
+	Equals(t, fmt.Sprintf("making request POST %s/2.0/repositories/owner/repo/pullrequests/1/comments: unexpected status code 403, body: forbidden", srv.URL), err.Error())
+}
+
+func newTestBitbucketClient(url string) *vcs.BitbucketClient {
+	client := vcs.NewBitbucketClient(nil, "user", "password", "https://atlantis.example.com")
+	client.BaseURL = url
+	return client
+}
@@ -0,0 +1,173 @@
+package vcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs/bitbucketserver"
+	"github.com/pkg/errors"
+)
+
+// BitbucketServerClient is used to make API calls to Bitbucket Server.
+// Repos are identified by their project key and slug, ie. their owner and name.
+type BitbucketServerClient struct {
+	HTTPClient *http.Client
+	Username   string
+	// Password is the user's password or personal access token.
+	Password string
+	// BaseURL is the URL of the Bitbucket Server installation, ex.
+	// https://bitbucket.example.com.
+	BaseURL string
+	// AtlantisURL is linked to from build statuses since Bitbucket requires
+	// them to have a URL.
+	AtlantisURL string
+}
+
+// NewBitbucketServerClient returns a client for the Bitbucket Server at
+// baseURL that authenticates as username with password.
+func NewBitbucketServerClient(httpClient *http.Client, username string, password string, baseURL string, atlantisURL string) *BitbucketServerClient {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &BitbucketServerClient{
+		HTTPClient:  httpClient,
+		Username:    username,
+		Password:    password,
+		BaseURL:     strings.TrimRight(baseURL, "/"),
+		AtlantisURL: atlantisURL,
+	}
+}
+
+// GetModifiedFiles returns the names of files that were modified in the pull request.
+// The names include the path to the file from the repo root, ex. parent/child/file.txt.
+func (b *BitbucketServerClient) GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	var files []string
+	nextPageStart := 0
+	for {
+		path := fmt.Sprintf("%s/changes?start=%d", b.pullURL(repo, pull.Num), nextPageStart)
+		resp, err := b.makeRequest("GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+		var changes bitbucketserver.Changes
+		if err := json.Unmarshal(resp, &changes); err != nil {
+			return nil, errors.Wrapf(err, "parsing response %q", string(resp))
+		}
+		for _, c := range changes.Values {
+			files = append(files, c.Path.ToString)
+			// Files that were moved also have the path they were moved from.
+			if c.SrcPath != nil && c.SrcPath.ToString != c.Path.ToString {
+				files = append(files, c.SrcPath.ToString)
+			}
+		}
+		if changes.IsLastPage {
+			break
+		}
+		nextPageStart = changes.NextPageStart
+	}
+	return files, nil
+}
+
+// CreateComment creates a comment on the pull request.
+func (b *BitbucketServerClient) CreateComment(repo models.Repo, pull models.PullRequest, comment string) error {
+	body, err := json.Marshal(bitbucketserver.CommentRequest{Text: comment})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	_, err = b.makeRequest("POST", b.pullURL(repo, pull.Num)+"/comments", bytes.NewBuffer(body))
+	return err
+}
+
+// PullIsApproved returns true if the pull request was approved by any of its
+// reviewers.
+func (b *BitbucketServerClient) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
+	bbPull, err := b.GetPullRequest(repo, pull.Num)
+	if err != nil {
+		return false, err
+	}
+	for _, reviewer := range bbPull.Reviewers {
+		if reviewer.Approved {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UpdateStatus updates the build status of the pull request's head commit.
+func (b *BitbucketServerClient) UpdateStatus(repo models.Repo, pull models.PullRequest, status CommitStatus, src string, description string, url string) error {
+	bbState := bitbucketserver.BuildFailed
+	switch status {
+	case Pending:
+		bbState = bitbucketserver.BuildInProgress
+	case Success:
+		bbState = bitbucketserver.BuildSuccessful
+	case Failed:
+		bbState = bitbucketserver.BuildFailed
+	}
+	body, err := json.Marshal(bitbucketserver.BuildStatus{
+		Key:         src,
+		Name:        src,
+		State:       bbState,
+		Description: description,
+		URL:         targetURL(url, b.AtlantisURL),
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	path := fmt.Sprintf("%s/rest/build-status/1.0/commits/%s", b.BaseURL, pull.HeadCommit)
+	_, err = b.makeRequest("POST", path, bytes.NewBuffer(body))
+	return err
+}
+
+// GetPullRequest returns the pull request.
+func (b *BitbucketServerClient) GetPullRequest(repo models.Repo, num int) (bitbucketserver.PullRequest, error) {
+	var pull bitbucketserver.PullRequest
+	resp, err := b.makeRequest("GET", b.pullURL(repo, num), nil)
+	if err != nil {
+		return pull, err
+	}
+	if err := json.Unmarshal(resp, &pull); err != nil {
+		return pull, errors.Wrapf(err, "parsing response %q", string(resp))
+	}
+	return pull, nil
+}
+
+// pullURL returns the API URL of pull request num in repo.
+func (b *BitbucketServerClient) pullURL(repo models.Repo, num int) string {
+	return fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d", b.BaseURL, repo.Owner, repo.Name, num)
+}
+
+// makeRequest makes an authenticated request to the API and returns the
+// response body. It returns an error if the response doesn't have a 2xx
+// status.
+func (b *BitbucketServerClient) makeRequest(method string, path string, reqBody io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, path, reqBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "constructing request")
+	}
+	req.SetBasicAuth(b.Username, b.Password)
+	if reqBody != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	// Bitbucket Server rejects POSTs without this header as a XSRF check.
+	req.Header.Add("X-Atlassian-Token", "no-check")
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading response body")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("making request %s %s: unexpected status code %d, body: %s", method, path, resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
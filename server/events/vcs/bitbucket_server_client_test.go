@@ -0,0 +1,108 @@
+package vcs_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs"
+	"github.com/hootsuite/atlantis/server/events/vcs/bitbucketserver"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+var bbServerRepo = models.Repo{FullName: "PROJ/repo", Owner: "PROJ", Name: "repo"}
+
+func TestBitbucketServerClient_GetModifiedFiles(t *testing.T) {
+	t.Log("should page through the changes and include the source paths of moved files")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "/rest/api/1.0/projects/PROJ/repos/repo/pull-requests/1/changes", r.URL.Path)
+		switch r.URL.Query().Get("start") {
+		case "0":
+			w.Write([]byte(`{"values": [{"path": {"toString": "a.tf"}}, {"path": {"toString": "b.tf"}, "srcPath": {"toString": "c.tf"}}], "isLastPage": false, "nextPageStart": 2}`)) // nolint: errcheck
+		case "2":
+			w.Write([]byte(`{"values": [{"path": {"toString": "d/e.tf"}}], "isLastPage": true}`)) // nolint: errcheck
+		default:
+			t.Errorf("unexpected request %s", r.URL.RequestURI())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client := vcs.NewBitbucketServerClient(nil, "user", "password", srv.URL+"/", "https://atlantis.example.com")
+	files, err := client.GetModifiedFiles(bbServerRepo, bbPull)
+	Ok(t, err)
+	Equals(t, []string{"a.tf", "b.tf", "c.tf", "d/e.tf"}, files)
+}
+
+func TestBitbucketServerClient_CreateComment(t *testing.T) {
+	t.Log("should post the comment with basic auth and the XSRF header")
+	var body bitbucketserver.CommentRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "POST", r.Method)
+		Equals(t, "/rest/api/1.0/projects/PROJ/repos/repo/pull-requests/1/comments", r.URL.Path)
+		Equals(t, "no-check", r.Header.Get("X-Atlassian-Token"))
+		user, pass, ok := r.BasicAuth()
+		Assert(t, ok, "exp basic auth")
+		Equals(t, "user", user)
+		Equals(t, "password", pass)
+		raw, _ := ioutil.ReadAll(r.Body)
+		Ok(t, json.Unmarshal(raw, &body))
+	}))
+	defer srv.Close()
+
+	client := vcs.NewBitbucketServerClient(nil, "user", "password", srv.URL, "https://atlantis.example.com")
+	err := client.CreateComment(bbServerRepo, bbPull, "comment")
+	Ok(t, err)
+	Equals(t, "comment", body.Text)
+}
+
+func TestBitbucketServerClient_PullIsApproved(t *testing.T) {
+	cases := []struct {
+		reviewers string
+		exp       bool
+	}{
+		{`[]`, false},
+		{`[{"approved": false, "user": {"name": "a"}}]`, false},
+		{`[{"approved": false, "user": {"name": "a"}}, {"approved": true, "user": {"name": "b"}}]`, true},
+	}
+	for _, c := range cases {
+		t.Run(c.reviewers, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Equals(t, "/rest/api/1.0/projects/PROJ/repos/repo/pull-requests/1", r.URL.Path)
+				fmt.Fprintf(w, `{"id": 1, "reviewers": %s}`, c.reviewers)
+			}))
+			defer srv.Close()
+
+			client := vcs.NewBitbucketServerClient(nil, "user", "password", srv.URL, "https://atlantis.example.com")
+			approved, err := client.PullIsApproved(bbServerRepo, bbPull)
+			Ok(t, err)
+			Equals(t, c.exp, approved)
+		})
+	}
+}
+
+func TestBitbucketServerClient_UpdateStatus(t *testing.T) {
+	t.Log("should set the build status of the head commit with a link to the given url")
+	var body bitbucketserver.BuildStatus
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "/rest/build-status/1.0/commits/abc123", r.URL.Path)
+		raw, _ := ioutil.ReadAll(r.Body)
+		Ok(t, json.Unmarshal(raw, &body))
+	}))
+	defer srv.Close()
+
+	client := vcs.NewBitbucketServerClient(nil, "user", "password", srv.URL, "https://atlantis.example.com")
+	err := client.UpdateStatus(bbServerRepo, bbPull, vcs.Pending, "atlantis/plan: dir (default)", "description", "https://atlantis.example.com/lock?id=1")
+	Ok(t, err)
+	Equals(t, bitbucketserver.BuildStatus{
+		Key:         "atlantis/plan: dir (default)",
+		Name:        "atlantis/plan: dir (default)",
+		State:       bitbucketserver.BuildInProgress,
+		Description: "description",
+		URL:         "https://atlantis.example.com/lock?id=1",
+	}, body)
+}
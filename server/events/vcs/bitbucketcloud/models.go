@@ -0,0 +1,141 @@
+// Package bitbucketcloud holds the models for Bitbucket Cloud's webhooks and
+// API, ie. bitbucket.org. Only the fields Atlantis uses are included.
+package bitbucketcloud
+
+// EventTypeHeader is the header that holds the type of a webhook event.
+const EventTypeHeader = "X-Event-Key"
+
+// RequestIDHeader is the header that holds the ID of a webhook request. It's
+// only sent by Bitbucket Cloud so it's used to tell its requests apart.
+const RequestIDHeader = "X-Request-UUID"
+
+// Webhook event types.
+const (
+	PullCreatedHeader        = "pullrequest:created"
+	PullUpdatedHeader        = "pullrequest:updated"
+	PullFulfilledHeader      = "pullrequest:fulfilled"
+	PullRejectedHeader       = "pullrequest:rejected"
+	PullCommentCreatedHeader = "pullrequest:comment_created"
+)
+
+// Pull request states.
+const (
+	OpenState = "OPEN"
+)
+
+// CommentEvent is the payload of pullrequest:comment_created events.
+type CommentEvent struct {
+	CommonEventData
+	Comment Comment `json:"comment"`
+}
+
+// PullRequestEvent is the payload of the pullrequest:created, updated,
+// fulfilled and rejected events.
+type PullRequestEvent struct {
+	CommonEventData
+}
+
+// CommonEventData is the data that's in every pull request event.
+type CommonEventData struct {
+	Actor       Actor       `json:"actor"`
+	Repository  Repository  `json:"repository"`
+	PullRequest PullRequest `json:"pullrequest"`
+}
+
+type Comment struct {
+	Content CommentContent `json:"content"`
+}
+
+type CommentContent struct {
+	Raw string `json:"raw"`
+}
+
+type Actor struct {
+	Username string `json:"username"`
+}
+
+type Repository struct {
+	// FullName is the owner and repo name, ex. "owner/repo".
+	FullName string `json:"full_name"`
+	Links    Links  `json:"links"`
+}
+
+type Links struct {
+	HTML Link `json:"html"`
+}
+
+type Link struct {
+	HREF string `json:"href"`
+}
+
+type PullRequest struct {
+	ID           int           `json:"id"`
+	Source       BranchMeta    `json:"source"`
+	Destination  BranchMeta    `json:"destination"`
+	Participants []Participant `json:"participants"`
+	Links        Links         `json:"links"`
+	State        string        `json:"state"`
+	Author       Actor         `json:"author"`
+}
+
+type BranchMeta struct {
+	Repository Repository `json:"repository"`
+	Commit     Commit     `json:"commit"`
+	Branch     Branch     `json:"branch"`
+}
+
+type Commit struct {
+	Hash string `json:"hash"`
+}
+
+type Branch struct {
+	Name string `json:"name"`
+}
+
+type Participant struct {
+	Approved bool  `json:"approved"`
+	User     Actor `json:"user"`
+}
+
+// DiffStat is a page of the files modified by a pull request.
+type DiffStat struct {
+	Values []DiffStatValue `json:"values"`
+	// Next is the URL of the next page. It's empty on the last page.
+	Next string `json:"next"`
+}
+
+type DiffStatValue struct {
+	Status string `json:"status"`
+	// Old is the file before it was modified. It's nil if the file was added.
+	Old *DiffStatFile `json:"old"`
+	// New is the file after it was modified. It's nil if the file was
+	// removed.
+	New *DiffStatFile `json:"new"`
+}
+
+type DiffStatFile struct {
+	Path string `json:"path"`
+}
+
+// CommentRequest is the body used to create a comment.
+type CommentRequest struct {
+	Content CommentContent `json:"content"`
+}
+
+// BuildStatus is the body used to set a commit's build status.
+type BuildStatus struct {
+	// Key identifies the status so it's updated rather than duplicated.
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+	// URL is required by Bitbucket.
+	URL string `json:"url"`
+}
+
+// Build statuses.
+const (
+	BuildInProgress = "INPROGRESS"
+	BuildSuccessful = "SUCCESSFUL"
+	BuildFailed     = "FAILED"
+)
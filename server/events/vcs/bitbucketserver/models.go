@@ -0,0 +1,137 @@
+// Package bitbucketserver holds the models for Bitbucket Server's (formerly
+// Stash) webhooks and API. Only the fields Atlantis uses are included.
+package bitbucketserver
+
+// EventTypeHeader is the header that holds the type of a webhook event.
+const EventTypeHeader = "X-Event-Key"
+
+// RequestIDHeader is the header that holds the ID of a webhook request. It's
+// only sent by Bitbucket Server so it's used to tell its requests apart.
+const RequestIDHeader = "X-Request-Id"
+
+// SignatureHeader is the header that holds the HMAC of a webhook request's
+// body, ex. "sha256=<hex digest>". It's only sent if the webhook has a secret.
+const SignatureHeader = "X-Hub-Signature"
+
+// Webhook event types.
+const (
+	PullCreatedHeader        = "pr:opened"
+	PullFromRefUpdatedHeader = "pr:from_ref_updated"
+	PullMergedHeader         = "pr:merged"
+	PullDeclinedHeader       = "pr:declined"
+	PullCommentCreatedHeader = "pr:comment:added"
+)
+
+// Pull request states.
+const (
+	OpenState = "OPEN"
+)
+
+// CommentEvent is the payload of pr:comment:added events.
+type CommentEvent struct {
+	CommonEventData
+	Comment Comment `json:"comment"`
+}
+
+// PullRequestEvent is the payload of the pr:opened, pr:from_ref_updated,
+// pr:merged and pr:declined events.
+type PullRequestEvent struct {
+	CommonEventData
+}
+
+// CommonEventData is the data that's in every pull request event.
+type CommonEventData struct {
+	Actor       User        `json:"actor"`
+	PullRequest PullRequest `json:"pullRequest"`
+}
+
+type Comment struct {
+	Text string `json:"text"`
+}
+
+type User struct {
+	// Name is the user's username.
+	Name string `json:"name"`
+}
+
+type PullRequest struct {
+	ID        int        `json:"id"`
+	State     string     `json:"state"`
+	Author    Reviewer   `json:"author"`
+	FromRef   Ref        `json:"fromRef"`
+	ToRef     Ref        `json:"toRef"`
+	Reviewers []Reviewer `json:"reviewers"`
+	Links     Links      `json:"links"`
+}
+
+// Reviewer is a participant in a pull request, including its author.
+type Reviewer struct {
+	User     User `json:"user"`
+	Approved bool `json:"approved"`
+}
+
+type Ref struct {
+	// DisplayID is the branch name, ex. "master".
+	DisplayID    string     `json:"displayId"`
+	LatestCommit string     `json:"latestCommit"`
+	Repository   Repository `json:"repository"`
+}
+
+type Repository struct {
+	Slug    string  `json:"slug"`
+	Project Project `json:"project"`
+}
+
+type Project struct {
+	Key string `json:"key"`
+}
+
+type Links struct {
+	Self []Link `json:"self"`
+}
+
+type Link struct {
+	HREF string `json:"href"`
+}
+
+// Changes is a page of the files modified by a pull request.
+type Changes struct {
+	Values        []Change `json:"values"`
+	IsLastPage    bool     `json:"isLastPage"`
+	NextPageStart int      `json:"nextPageStart"`
+}
+
+type Change struct {
+	Path Path `json:"path"`
+	// SrcPath is only set if the file was moved, in which case it's the path
+	// it was moved from.
+	SrcPath *Path `json:"srcPath"`
+}
+
+type Path struct {
+	// ToString is the full path of the file from the repo root.
+	ToString string `json:"toString"`
+}
+
+// CommentRequest is the body used to create a comment.
+type CommentRequest struct {
+	Text string `json:"text"`
+}
+
+// BuildStatus is the body used to set a commit's build status.
+type BuildStatus struct {
+	// Key identifies the status so it's updated rather than duplicated.
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+	// URL is required by Bitbucket.
+	URL string `json:"url"`
+}
+
+// Build statuses.
+const (
+	BuildInProgress = "INPROGRESS"
+	BuildSuccessful = "SUCCESSFUL"
+	BuildFailed     = "FAILED"
+)
@@ -0,0 +1,37 @@
+package bitbucketserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ValidateSignature returns an error if signature, the value of the
+// X-Hub-Signature header, isn't the HMAC-SHA256 of body using secretKey.
+func ValidateSignature(body []byte, signature string, secretKey []byte) error {
+	if signature == "" {
+		return errors.New("request is missing the X-Hub-Signature header")
+	}
+	sigParts := strings.SplitN(signature, "=", 2)
+	if len(sigParts) != 2 {
+		return fmt.Errorf("invalid signature %q: must be in the form sha256=<digest>", signature)
+	}
+	if sigParts[0] != "sha256" {
+		return fmt.Errorf("signature type %q is not supported, only sha256 is supported", sigParts[0])
+	}
+	expMAC, err := hex.DecodeString(sigParts[1])
+	if err != nil {
+		return errors.Wrap(err, "decoding signature")
+	}
+
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write(body) // nolint: errcheck
+	if !hmac.Equal(mac.Sum(nil), expMAC) {
+		return errors.New("request signature didn't match the webhook secret")
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package bitbucketserver_test
+
+import (
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events/vcs/bitbucketserver"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+// sig is the HMAC-SHA256 of body with the key "secret".
+const body = `{"eventKey":"pr:comment:added"}`
+const sig = "sha256=8c5fe31f046143953f56bb5a1ba77defa4abc908e14a3ed71759498aa3ade54c"
+
+func TestValidateSignature(t *testing.T) {
+	cases := []struct {
+		description string
+		signature   string
+		secret      string
+		expErr      string
+	}{
+		{"valid", sig, "secret", ""},
+		{"wrong secret", sig, "other", "request signature didn't match the webhook secret"},
+		{"missing", "", "secret", "request is missing the X-Hub-Signature header"},
+		{"no type", "abc", "secret", `invalid signature "abc": must be in the form sha256=<digest>`},
+		{"sha1", "sha1=abc", "secret", `signature type "sha1" is not supported, only sha256 is supported`},
+		{"not hex", "sha256=zz", "secret", "decoding signature: encoding/hex: invalid byte: U+007A 'z'"},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := bitbucketserver.ValidateSignature([]byte(body), c.signature, []byte(c.secret))
+			if c.expErr == "" {
+				Ok(t, err)
+				return
+			}
+			Assert(t, err != nil, "exp err")
+			Equals(t, c.expErr, err.Error())
+		})
+	}
+}
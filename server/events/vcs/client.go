@@ -11,5 +11,35 @@ type Client interface {
 	GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error)
 	CreateComment(repo models.Repo, pull models.PullRequest, comment string) error
 	PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error)
-	UpdateStatus(repo models.Repo, pull models.PullRequest, state CommitStatus, description string) error
+	// UpdateStatus sets the status named src on the pull request's head
+	// commit. url is where the status links to.
+	UpdateStatus(repo models.Repo, pull models.PullRequest, state CommitStatus, src string, description string, url string) error
 }
+
+// targetURL returns url or, if it's empty, atlantisURL since some VCS hosts
+// require statuses to link somewhere.
+func targetURL(url string, atlantisURL string) string {
+	if url == "" {
+		return atlantisURL
+	}
+	return url
+}
+
+// ReviewClient is implemented by the clients of VCS hosts that support apply
+// requirements beyond a single approval. Currently that's GitHub and GitLab.
+type ReviewClient interface {
+	// GetApprovers returns the usernames of the users who currently approve
+	// the pull request.
+	GetApprovers(repo models.Repo, pull models.PullRequest) ([]string, error)
+	// GetTeamMembers returns the usernames of the members of team. On GitHub
+	// team is an organization and team slug, ex. org/team, and on GitLab it's
+	// the path of a group.
+	GetTeamMembers(repo models.Repo, team string) ([]string, error)
+	// PullIsMergeable returns true if the pull request can be merged and its
+	// required checks have passed. Atlantis's own statuses aren't required
+	// since they're only green once the pull request is applied.
+	PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error)
+}
+
+// StatusPrefix is the prefix of the names of Atlantis's commit statuses.
+const StatusPrefix = "atlantis/"
@@ -0,0 +1,119 @@
+// Package gitea holds the models for Gitea's webhooks and API. Only the
+// fields Atlantis uses are included.
+package gitea
+
+// Webhook headers.
+const (
+	// EventTypeHeader holds the type of a webhook event.
+	EventTypeHeader = "X-Gitea-Event"
+	// RequestIDHeader holds the ID of a webhook request.
+	RequestIDHeader = "X-Gitea-Delivery"
+	// SignatureHeader holds the hex HMAC-SHA256 of a webhook request's body.
+	// It's only sent if the webhook has a secret.
+	SignatureHeader = "X-Gitea-Signature"
+)
+
+// Webhook event types.
+const (
+	IssueCommentEvent = "issue_comment"
+	PullRequestEvent  = "pull_request"
+)
+
+// Pull request states.
+const (
+	OpenState = "open"
+)
+
+// ApprovedReviewState is the state of reviews that approved the pull request.
+const ApprovedReviewState = "APPROVED"
+
+// Event is the data common to every webhook event.
+type Event struct {
+	Action string `json:"action"`
+}
+
+// IssueCommentPayload is the payload of issue_comment events. Pull requests
+// are issues in Gitea so it's sent for their comments too.
+type IssueCommentPayload struct {
+	Action     string     `json:"action"`
+	Issue      Issue      `json:"issue"`
+	Comment    Comment    `json:"comment"`
+	Repository Repository `json:"repository"`
+	// IsPull is true if the comment was on a pull request.
+	IsPull bool `json:"is_pull"`
+}
+
+// PullRequestPayload is the payload of pull_request events.
+type PullRequestPayload struct {
+	Action      string      `json:"action"`
+	PullRequest PullRequest `json:"pull_request"`
+	Repository  Repository  `json:"repository"`
+}
+
+type Issue struct {
+	Number int `json:"number"`
+}
+
+type Comment struct {
+	Body string `json:"body"`
+	User User   `json:"user"`
+}
+
+type User struct {
+	Login string `json:"login"`
+}
+
+type Repository struct {
+	// FullName is the owner and repo name, ex. "owner/repo".
+	FullName string `json:"full_name"`
+	Name     string `json:"name"`
+	Owner    User   `json:"owner"`
+	CloneURL string `json:"clone_url"`
+}
+
+type PullRequest struct {
+	Number  int    `json:"number"`
+	State   string `json:"state"`
+	HTMLURL string `json:"html_url"`
+	User    User   `json:"user"`
+	Head    Branch `json:"head"`
+	Base    Branch `json:"base"`
+}
+
+type Branch struct {
+	Ref  string      `json:"ref"`
+	SHA  string      `json:"sha"`
+	Repo *Repository `json:"repo"`
+}
+
+// ChangedFile is a file modified by a pull request.
+type ChangedFile struct {
+	Filename string `json:"filename"`
+	// PreviousFilename is only set if the file was renamed.
+	PreviousFilename string `json:"previous_filename"`
+}
+
+type Review struct {
+	State     string `json:"state"`
+	Dismissed bool   `json:"dismissed"`
+}
+
+// CommentRequest is the body used to create a comment.
+type CommentRequest struct {
+	Body string `json:"body"`
+}
+
+// StatusRequest is the body used to set a commit's status.
+type StatusRequest struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url"`
+	Description string `json:"description"`
+	Context     string `json:"context"`
+}
+
+// Commit status states.
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
@@ -0,0 +1,29 @@
+package gitea
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+// ValidateSignature returns an error if signature, the value of the
+// X-Gitea-Signature header, isn't the hex HMAC-SHA256 of body using
+// secretKey.
+func ValidateSignature(body []byte, signature string, secretKey []byte) error {
+	if signature == "" {
+		return errors.New("request is missing the X-Gitea-Signature header")
+	}
+	expMAC, err := hex.DecodeString(signature)
+	if err != nil {
+		return errors.Wrap(err, "decoding signature")
+	}
+
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write(body) // nolint: errcheck
+	if !hmac.Equal(mac.Sum(nil), expMAC) {
+		return errors.New("request signature didn't match the webhook secret")
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package gitea_test
+
+import (
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events/vcs/gitea"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+// sig is the HMAC-SHA256 of body with the key "secret".
+const body = `{"action":"created"}`
+const sig = "0031e94255b70a79704e0356204543768c078ca4f48b3ccc547edef03f4f338a"
+
+func TestValidateSignature(t *testing.T) {
+	cases := []struct {
+		description string
+		signature   string
+		secret      string
+		expErr      string
+	}{
+		{"valid", sig, "secret", ""},
+		{"wrong secret", sig, "other", "request signature didn't match the webhook secret"},
+		{"missing", "", "secret", "request is missing the X-Gitea-Signature header"},
+		{"not hex", "zz", "secret", "decoding signature: encoding/hex: invalid byte: U+007A 'z'"},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := gitea.ValidateSignature([]byte(body), c.signature, []byte(c.secret))
+			if c.expErr == "" {
+				Ok(t, err)
+				return
+			}
+			Assert(t, err != nil, "exp err")
+			Equals(t, c.expErr, err.Error())
+		})
+	}
+}
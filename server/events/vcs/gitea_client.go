@@ -0,0 +1,174 @@
+package vcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs/gitea"
+	"github.com/pkg/errors"
+)
+
+// giteaPageSize is the number of items requested per page.
+const giteaPageSize = 50
+
+// GiteaClient is used to make API calls to a Gitea installation.
+type GiteaClient struct {
+	HTTPClient *http.Client
+	Token      string
+	// BaseURL is the URL of the API, ex. https://gitea.example.com/api/v1.
+	BaseURL string
+	// AtlantisURL is linked to from commit statuses.
+	AtlantisURL string
+}
+
+// NewGiteaClient returns a client for the Gitea installation at hostname that
+// authenticates with token. If hostname doesn't have a scheme, https is used.
+func NewGiteaClient(httpClient *http.Client, hostname string, token string, atlantisURL string) *GiteaClient {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if !strings.Contains(hostname, "://") {
+		hostname = "https://" + hostname
+	}
+	return &GiteaClient{
+		HTTPClient:  httpClient,
+		Token:       token,
+		BaseURL:     strings.TrimRight(hostname, "/") + "/api/v1",
+		AtlantisURL: atlantisURL,
+	}
+}
+
+// GetModifiedFiles returns the names of files that were modified in the pull request.
+// The names include the path to the file from the repo root, ex. parent/child/file.txt.
+func (g *GiteaClient) GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	var files []string
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("%s/repos/%s/pulls/%d/files?page=%d&limit=%d", g.BaseURL, repo.FullName, pull.Num, page, giteaPageSize)
+		resp, err := g.makeRequest("GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+		var pageFiles []gitea.ChangedFile
+		if err := json.Unmarshal(resp, &pageFiles); err != nil {
+			return nil, errors.Wrapf(err, "parsing response %q", string(resp))
+		}
+		for _, f := range pageFiles {
+			files = append(files, f.Filename)
+			// Files that were renamed also have the name they were renamed from.
+			if f.PreviousFilename != "" && f.PreviousFilename != f.Filename {
+				files = append(files, f.PreviousFilename)
+			}
+		}
+		if len(pageFiles) < giteaPageSize {
+			break
+		}
+	}
+	return files, nil
+}
+
+// CreateComment creates a comment on the pull request.
+func (g *GiteaClient) CreateComment(repo models.Repo, pull models.PullRequest, comment string) error {
+	body, err := json.Marshal(gitea.CommentRequest{Body: comment})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	path := fmt.Sprintf("%s/repos/%s/issues/%d/comments", g.BaseURL, repo.FullName, pull.Num)
+	_, err = g.makeRequest("POST", path, bytes.NewBuffer(body))
+	return err
+}
+
+// PullIsApproved returns true if the pull request has a review that approved
+// it and wasn't dismissed.
+func (g *GiteaClient) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("%s/repos/%s/pulls/%d/reviews?page=%d&limit=%d", g.BaseURL, repo.FullName, pull.Num, page, giteaPageSize)
+		resp, err := g.makeRequest("GET", path, nil)
+		if err != nil {
+			return false, err
+		}
+		var reviews []gitea.Review
+		if err := json.Unmarshal(resp, &reviews); err != nil {
+			return false, errors.Wrapf(err, "parsing response %q", string(resp))
+		}
+		for _, r := range reviews {
+			if r.State == gitea.ApprovedReviewState && !r.Dismissed {
+				return true, nil
+			}
+		}
+		if len(reviews) < giteaPageSize {
+			return false, nil
+		}
+	}
+}
+
+// UpdateStatus updates the status of the pull request's head commit.
+func (g *GiteaClient) UpdateStatus(repo models.Repo, pull models.PullRequest, status CommitStatus, src string, description string, url string) error {
+	giteaState := gitea.StatusFailure
+	switch status {
+	case Pending:
+		giteaState = gitea.StatusPending
+	case Success:
+		giteaState = gitea.StatusSuccess
+	case Failed:
+		giteaState = gitea.StatusFailure
+	}
+	body, err := json.Marshal(gitea.StatusRequest{
+		State:       giteaState,
+		TargetURL:   targetURL(url, g.AtlantisURL),
+		Description: description,
+		Context:     src,
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	path := fmt.Sprintf("%s/repos/%s/statuses/%s", g.BaseURL, repo.FullName, pull.HeadCommit)
+	_, err = g.makeRequest("POST", path, bytes.NewBuffer(body))
+	return err
+}
+
+// GetPullRequest returns the pull request.
+func (g *GiteaClient) GetPullRequest(repo models.Repo, num int) (gitea.PullRequest, error) {
+	var pull gitea.PullRequest
+	path := fmt.Sprintf("%s/repos/%s/pulls/%d", g.BaseURL, repo.FullName, num)
+	resp, err := g.makeRequest("GET", path, nil)
+	if err != nil {
+		return pull, err
+	}
+	if err := json.Unmarshal(resp, &pull); err != nil {
+		return pull, errors.Wrapf(err, "parsing response %q", string(resp))
+	}
+	return pull, nil
+}
+
+// makeRequest makes an authenticated request to the API and returns the
+// response body. It returns an error if the response doesn't have a 2xx
+// status.
+func (g *GiteaClient) makeRequest(method string, path string, reqBody io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, path, reqBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "constructing request")
+	}
+	req.Header.Add("Authorization", "token "+g.Token)
+	if reqBody != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	resp, err := g.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading response body")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("making request %s %s: unexpected status code %d, body: %s", method, path, resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
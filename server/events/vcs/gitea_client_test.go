@@ -0,0 +1,143 @@
+package vcs_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events/vcs"
+	"github.com/hootsuite/atlantis/server/events/vcs/gitea"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+func TestNewGiteaClient_BaseURL(t *testing.T) {
+	cases := []struct {
+		hostname string
+		exp      string
+	}{
+		{"gitea.example.com", "https://gitea.example.com/api/v1"},
+		{"http://gitea.example.com:3000/", "http://gitea.example.com:3000/api/v1"},
+	}
+	for _, c := range cases {
+		t.Run(c.hostname, func(t *testing.T) {
+			Equals(t, c.exp, vcs.NewGiteaClient(nil, c.hostname, "token", "").BaseURL)
+		})
+	}
+}
+
+func TestGiteaClient_GetModifiedFiles(t *testing.T) {
+	t.Log("should page through the files and include the previous names of renamed files")
+	var firstPage []string
+	for i := 0; i < 50; i++ {
+		firstPage = append(firstPage, fmt.Sprintf(`{"filename": "%d.tf"}`, i))
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "/api/v1/repos/owner/repo/pulls/1/files", r.URL.Path)
+		Equals(t, "token token", r.Header.Get("Authorization"))
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprintf(w, "[%s]", strings.Join(firstPage, ","))
+		case "2":
+			w.Write([]byte(`[{"filename": "new.tf", "previous_filename": "old.tf"}]`)) // nolint: errcheck
+		default:
+			t.Errorf("unexpected request %s", r.URL.RequestURI())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	files, err := vcs.NewGiteaClient(nil, srv.URL, "token", "").GetModifiedFiles(bbRepo, bbPull)
+	Ok(t, err)
+	Equals(t, 52, len(files))
+	Equals(t, "0.tf", files[0])
+	Equals(t, []string{"new.tf", "old.tf"}, files[50:])
+}
+
+func TestGiteaClient_CreateComment(t *testing.T) {
+	t.Log("should post the comment to the pull request's issue")
+	var body gitea.CommentRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "POST", r.Method)
+		Equals(t, "/api/v1/repos/owner/repo/issues/1/comments", r.URL.Path)
+		raw, _ := ioutil.ReadAll(r.Body)
+		Ok(t, json.Unmarshal(raw, &body))
+	}))
+	defer srv.Close()
+
+	err := vcs.NewGiteaClient(nil, srv.URL, "token", "").CreateComment(bbRepo, bbPull, "comment")
+	Ok(t, err)
+	Equals(t, "comment", body.Body)
+}
+
+func TestGiteaClient_PullIsApproved(t *testing.T) {
+	cases := []struct {
+		reviews string
+		exp     bool
+	}{
+		{`[]`, false},
+		{`[{"state": "COMMENT"}, {"state": "REQUEST_CHANGES"}]`, false},
+		{`[{"state": "APPROVED", "dismissed": true}]`, false},
+		{`[{"state": "COMMENT"}, {"state": "APPROVED"}]`, true},
+	}
+	for _, c := range cases {
+		t.Run(c.reviews, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Equals(t, "/api/v1/repos/owner/repo/pulls/1/reviews", r.URL.Path)
+				w.Write([]byte(c.reviews)) // nolint: errcheck
+			}))
+			defer srv.Close()
+
+			approved, err := vcs.NewGiteaClient(nil, srv.URL, "token", "").PullIsApproved(bbRepo, bbPull)
+			Ok(t, err)
+			Equals(t, c.exp, approved)
+		})
+	}
+}
+
+func TestGiteaClient_UpdateStatus(t *testing.T) {
+	cases := []struct {
+		status   vcs.CommitStatus
+		expState string
+	}{
+		{vcs.Pending, gitea.StatusPending},
+		{vcs.Success, gitea.StatusSuccess},
+		{vcs.Failed, gitea.StatusFailure},
+	}
+	for _, c := range cases {
+		t.Run(c.status.String(), func(t *testing.T) {
+			var body gitea.StatusRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Equals(t, "/api/v1/repos/owner/repo/statuses/abc123", r.URL.Path)
+				raw, _ := ioutil.ReadAll(r.Body)
+				Ok(t, json.Unmarshal(raw, &body))
+			}))
+			defer srv.Close()
+
+			err := vcs.NewGiteaClient(nil, srv.URL, "token", "https://atlantis.example.com").UpdateStatus(bbRepo, bbPull, c.status, "atlantis/plan", "description", "")
+			Ok(t, err)
+			Equals(t, gitea.StatusRequest{
+				State:       c.expState,
+				TargetURL:   "https://atlantis.example.com",
+				Description: "description",
+				Context:     "atlantis/plan",
+			}, body)
+		})
+	}
+}
+
+func TestGiteaClient_ErrStatus(t *testing.T) {
+	t.Log("should return an error if the response isn't a 2xx")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("unauthorized")) // nolint: errcheck
+	}))
+	defer srv.Close()
+
+	err := vcs.NewGiteaClient(nil, srv.URL, "token", "").CreateComment(bbRepo, bbPull, "comment")
+	Assert(t, err != nil, "exp err")
+	Equals(t, fmt.Sprintf("making request POST %s/api/v1/repos/owner/repo/issues/1/comments: unexpected status code 401, body: unauthorized", srv.URL), err.Error())
+}
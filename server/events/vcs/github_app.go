@@ -0,0 +1,301 @@
+package vcs
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/pkg/errors"
+)
+
+// githubAppAccept is the media type required by the GitHub Apps API while
+// it's in preview.
+const githubAppAccept = "application/vnd.github.machine-man-preview+json"
+
+// installationTokenRefreshBuffer is how long before an installation token
+// expires that we mint a new one so that a token never expires mid-command.
+const installationTokenRefreshBuffer = 5 * time.Minute
+
+// GithubAppCredentials authenticates as a GitHub App. It mints installation
+// tokens for the installation of the app on each repo or org and caches them
+// until they're close to expiring.
+type GithubAppCredentials struct {
+	HTTPClient *http.Client
+	AppID      int
+	Key        *rsa.PrivateKey
+	// BaseURL is the URL of the API, ex. https://api.github.com/.
+	BaseURL string
+
+	mutex sync.Mutex
+	// installations maps the API path of repos and orgs, ex. repos/owner/repo
+	// or orgs/org, to the ID of the app's installation on them.
+	installations map[string]int
+	// tokens maps installation IDs to their most recent token.
+	tokens map[int]installationToken
+}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewGithubAppCredentials returns credentials for the app with appID on the
+// GitHub installation at hostname. keyPEM is the app's private key in PEM
+// format as downloaded from GitHub.
+func NewGithubAppCredentials(httpClient *http.Client, hostname string, appID int, keyPEM []byte) (*GithubAppCredentials, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("private key is not in PEM format")
+	}
+	key, err := parseRSAPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	baseURL, err := githubBaseURL(hostname)
+	if err != nil {
+		return nil, err
+	}
+	return &GithubAppCredentials{
+		HTTPClient:    httpClient,
+		AppID:         appID,
+		Key:           key,
+		BaseURL:       baseURL.String(),
+		installations: make(map[string]int),
+		tokens:        make(map[int]installationToken),
+	}, nil
+}
+
+// GetInstallationToken returns a token for the app's installation on repo.
+// A cached token is returned unless it's about to expire.
+func (g *GithubAppCredentials) GetInstallationToken(repo models.Repo) (string, error) {
+	return g.getInstallationToken("repos/" + repo.FullName)
+}
+
+// GetOrgInstallationToken returns a token for the app's installation on org.
+// It's needed for requests about the org rather than one of its repos, ex.
+// listing its teams.
+func (g *GithubAppCredentials) GetOrgInstallationToken(org string) (string, error) {
+	return g.getInstallationToken("orgs/" + org)
+}
+
+// getInstallationToken returns a token for the app's installation on the repo
+// or org at path, ex. repos/owner/repo or orgs/org.
+func (g *GithubAppCredentials) getInstallationToken(path string) (string, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	installationID, ok := g.installations[path]
+	if !ok {
+		var err error
+		installationID, err = g.findInstallation(path)
+		if err != nil {
+			return "", err
+		}
+		g.installations[path] = installationID
+	}
+	if token, ok := g.tokens[installationID]; ok && time.Now().Add(installationTokenRefreshBuffer).Before(token.ExpiresAt) {
+		return token.Token, nil
+	}
+	token, err := g.createInstallationToken(installationID)
+	if err != nil {
+		return "", err
+	}
+	g.tokens[installationID] = token
+	return token.Token, nil
+}
+
+// Transport returns a RoundTripper that authenticates requests about a repo,
+// ex. repos/owner/repo/pulls/1, or an org, ex. orgs/org/teams, with the token
+// for the app's installation on that repo or org.
+func (g *GithubAppCredentials) Transport() http.RoundTripper {
+	return &githubAppTransport{creds: g, base: g.HTTPClient.Transport}
+}
+
+// findInstallation returns the ID of the app's installation on the repo or
+// org at path.
+func (g *GithubAppCredentials) findInstallation(path string) (int, error) {
+	resp, err := g.makeAppRequest("GET", path+"/installation")
+	if err != nil {
+		return 0, errors.Wrapf(err, "finding installation for %s", strings.SplitN(path, "/", 2)[1])
+	}
+	var installation struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &installation); err != nil {
+		return 0, errors.Wrapf(err, "parsing response %q", string(resp))
+	}
+	return installation.ID, nil
+}
+
+func (g *GithubAppCredentials) createInstallationToken(installationID int) (installationToken, error) {
+	var token installationToken
+	resp, err := g.makeAppRequest("POST", fmt.Sprintf("app/installations/%d/access_tokens", installationID))
+	if err != nil {
+		return token, errors.Wrapf(err, "creating token for installation %d", installationID)
+	}
+	if err := json.Unmarshal(resp, &token); err != nil {
+		return token, errors.Wrapf(err, "parsing response %q", string(resp))
+	}
+	return token, nil
+}
+
+// makeAppRequest makes a request authenticated as the app itself rather than
+// one of its installations.
+func (g *GithubAppCredentials) makeAppRequest(method string, path string) ([]byte, error) {
+	jwt, err := g.signJWT()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, g.BaseURL+path, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "constructing request")
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", githubAppAccept)
+	resp, err := g.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading response body")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("making request %s %s: unexpected status code %d, body: %s", method, req.URL, resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
+
+// signJWT returns a JWT identifying the app, signed with its private key.
+// GitHub rejects JWTs that expire more than 10 minutes in the future.
+func (g *GithubAppCredentials) signJWT() (string, error) {
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", errors.Wrap(err, "json encoding")
+	}
+	claims, err := json.Marshal(map[string]int64{
+		// Backdate the token in case our clock is ahead of GitHub's.
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": int64(g.AppID),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "json encoding")
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, g.Key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", errors.Wrap(err, "signing JWT")
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// githubAppOrgKey is the context key for the org whose installation token
+// authenticates requests that aren't about a repo or org in their path.
+type githubAppOrgKey struct{}
+
+// withGithubAppOrg returns a copy of ctx that makes GitHub App transports
+// authenticate requests made with it as the app's installation on org. This
+// is needed for requests like teams/:id/members whose path doesn't say which
+// org they're about.
+func withGithubAppOrg(ctx context.Context, org string) context.Context {
+	return context.WithValue(ctx, githubAppOrgKey{}, org)
+}
+
+// githubAppTransport authenticates API requests with the installation token
+// for the repo or org the request is about.
+type githubAppTransport struct {
+	creds *GithubAppCredentials
+	base  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *githubAppTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path, err := t.installationPath(req)
+	if err == nil {
+		var token string
+		token, err = t.creds.getInstallationToken(path)
+		if err == nil {
+			return t.roundTripWithToken(req, token)
+		}
+	}
+	// RoundTrippers must close the request body even on errors.
+	if req.Body != nil {
+		req.Body.Close() // nolint: errcheck
+	}
+	return nil, err
+}
+
+func (t *githubAppTransport) roundTripWithToken(req *http.Request, token string) (*http.Response, error) {
+	// RoundTrippers must not modify the request so we copy it and its
+	// headers before adding the token.
+	authReq := new(http.Request)
+	*authReq = *req
+	authReq.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		authReq.Header[k] = append([]string(nil), v...)
+	}
+	authReq.Header.Set("Authorization", "token "+token)
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(authReq)
+}
+
+// installationPath returns the API path of the repo or org that req is
+// about, ex. repos/owner/repo for /api/v3/repos/owner/repo/pulls or orgs/org
+// for /api/v3/orgs/org/teams. An org set with withGithubAppOrg takes
+// precedence.
+func (t *githubAppTransport) installationPath(req *http.Request) (string, error) {
+	if org, ok := req.Context().Value(githubAppOrgKey{}).(string); ok && org != "" {
+		return "orgs/" + org, nil
+	}
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i, part := range parts {
+		if part == "repos" && i+2 < len(parts) && parts[i+1] != "" && parts[i+2] != "" {
+			return "repos/" + parts[i+1] + "/" + parts[i+2], nil
+		}
+		if part == "orgs" && i+1 < len(parts) && parts[i+1] != "" {
+			return "orgs/" + parts[i+1], nil
+		}
+	}
+	return "", fmt.Errorf("can't authenticate request to %s as a GitHub App since it isn't for a repo or org", req.URL.Path)
+}
+
+// parseRSAPrivateKey parses keys in PKCS #1 format, which is what GitHub
+// generates, or PKCS #8 format.
+func parseRSAPrivateKey(der []byte) (*rsa.PrivateKey, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing private key")
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
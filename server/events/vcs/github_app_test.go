@@ -0,0 +1,188 @@
+package vcs_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+var appRepo = models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}
+
+func TestNewGithubAppCredentials_InvalidKey(t *testing.T) {
+	t.Log("should error if the private key isn't PEM")
+	_, err := vcs.NewGithubAppCredentials(nil, "github.com", 1, []byte("not a key"))
+	Assert(t, err != nil, "exp err")
+	Equals(t, "private key is not in PEM format", err.Error())
+}
+
+func TestGithubAppCredentials_GetInstallationToken(t *testing.T) {
+	t.Log("should find the installation with a JWT signed by the app's key and then cache its token")
+	key, keyPEM := generateAppKey(t)
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		verifyAppJWT(t, r, &key.PublicKey)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/owner/repo/installation":
+			w.Write([]byte(`{"id": 5}`)) // nolint: errcheck
+		case "POST /app/installations/5/access_tokens":
+			fmt.Fprintf(w, `{"token": "token%d", "expires_at": %q}`, requests, time.Now().Add(time.Hour).Format(time.RFC3339))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	creds := newTestGithubAppCredentials(t, srv.URL, keyPEM)
+	token, err := creds.GetInstallationToken(appRepo)
+	Ok(t, err)
+	Equals(t, "token2", token)
+
+	token, err = creds.GetInstallationToken(appRepo)
+	Ok(t, err)
+	Equals(t, "token2", token)
+	Equals(t, 2, requests)
+}
+
+func TestGithubAppCredentials_RefreshesExpiringToken(t *testing.T) {
+	t.Log("should mint a new token if the cached one is about to expire")
+	_, keyPEM := generateAppKey(t)
+	tokens := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/owner/repo/installation" {
+			w.Write([]byte(`{"id": 5}`)) // nolint: errcheck
+			return
+		}
+		tokens++
+		fmt.Fprintf(w, `{"token": "token%d", "expires_at": %q}`, tokens, time.Now().Add(time.Minute).Format(time.RFC3339))
+	}))
+	defer srv.Close()
+
+	creds := newTestGithubAppCredentials(t, srv.URL, keyPEM)
+	token, err := creds.GetInstallationToken(appRepo)
+	Ok(t, err)
+	Equals(t, "token1", token)
+	token, err = creds.GetInstallationToken(appRepo)
+	Ok(t, err)
+	Equals(t, "token2", token)
+}
+
+func TestGithubAppCredentials_NotInstalled(t *testing.T) {
+	t.Log("should error if the app isn't installed on the repo")
+	_, keyPEM := generateAppKey(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found")) // nolint: errcheck
+	}))
+	defer srv.Close()
+
+	_, err := newTestGithubAppCredentials(t, srv.URL, keyPEM).GetInstallationToken(appRepo)
+	Assert(t, err != nil, "exp err")
+	Equals(t, fmt.Sprintf("finding installation for owner/repo: making request GET %s/repos/owner/repo/installation: unexpected status code 404, body: not found", srv.URL), err.Error())
+}
+
+func TestGithubAppCredentials_Transport(t *testing.T) {
+	t.Log("should authenticate requests about a repo with its installation token")
+	_, keyPEM := generateAppKey(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/installation":
+			w.Write([]byte(`{"id": 5}`)) // nolint: errcheck
+		case "/app/installations/5/access_tokens":
+			fmt.Fprintf(w, `{"token": "installation-token", "expires_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+		case "/api/v3/repos/owner/repo/pulls/1":
+			Equals(t, "token installation-token", r.Header.Get("Authorization"))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: newTestGithubAppCredentials(t, srv.URL, keyPEM).Transport()}
+	req, err := http.NewRequest("GET", srv.URL+"/api/v3/repos/owner/repo/pulls/1", nil)
+	Ok(t, err)
+	resp, err := client.Do(req)
+	Ok(t, err)
+	resp.Body.Close() // nolint: errcheck
+	Equals(t, http.StatusOK, resp.StatusCode)
+	Equals(t, "", req.Header.Get("Authorization"))
+
+	t.Log("should error for requests that aren't about a repo or org")
+	_, err = client.Get(srv.URL + "/user")
+	Assert(t, err != nil, "exp err")
+	Assert(t, strings.Contains(err.Error(), "can't authenticate request to /user as a GitHub App since it isn't for a repo or org"), "got %q", err.Error())
+}
+
+func TestGithubAppCredentials_TransportOrg(t *testing.T) {
+	t.Log("should authenticate requests about an org with the token of the app's installation on the org")
+	_, keyPEM := generateAppKey(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orgs/org/installation":
+			w.Write([]byte(`{"id": 7}`)) // nolint: errcheck
+		case "/app/installations/7/access_tokens":
+			fmt.Fprintf(w, `{"token": "org-token", "expires_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+		case "/api/v3/orgs/org/teams":
+			Equals(t, "token org-token", r.Header.Get("Authorization"))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: newTestGithubAppCredentials(t, srv.URL, keyPEM).Transport()}
+	resp, err := client.Get(srv.URL + "/api/v3/orgs/org/teams")
+	Ok(t, err)
+	resp.Body.Close() // nolint: errcheck
+	Equals(t, http.StatusOK, resp.StatusCode)
+}
+
+func newTestGithubAppCredentials(t *testing.T, url string, keyPEM []byte) *vcs.GithubAppCredentials {
+	creds, err := vcs.NewGithubAppCredentials(nil, "github.com", 1234, keyPEM)
+	Ok(t, err)
+	creds.BaseURL = url + "/"
+	return creds
+}
+
+func generateAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	Ok(t, err)
+	return key, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+// verifyAppJWT checks that r is authenticated with a JWT for app 1234 signed
+// by the key.
+func verifyAppJWT(t *testing.T, r *http.Request, key *rsa.PublicKey) {
+	Equals(t, "application/vnd.github.machine-man-preview+json", r.Header.Get("Accept"))
+	jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	parts := strings.Split(jwt, ".")
+	Equals(t, 3, len(parts))
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	Ok(t, err)
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	Ok(t, rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig))
+
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	Ok(t, err)
+	var claims map[string]int64
+	Ok(t, json.Unmarshal(rawClaims, &claims))
+	Equals(t, int64(1234), claims["iss"])
+	Assert(t, claims["exp"]-claims["iat"] <= 600, "exp JWT to expire within 10 minutes")
+}
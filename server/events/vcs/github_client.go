@@ -3,11 +3,13 @@ package vcs
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 
 	"github.com/google/go-github/github"
 	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs/githubchecks"
 	"github.com/pkg/errors"
 )
 
@@ -23,25 +25,45 @@ func NewGithubClient(hostname string, user string, pass string) (*GithubClient,
 		Username: strings.TrimSpace(user),
 		Password: strings.TrimSpace(pass),
 	}
-	client := github.NewClient(tp.Client())
-	// If we're using github.com then we don't need to do any additional configuration
-	// for the client. It we're using Github Enterprise, then we need to manually
-	// set the base url for the API.
-	if hostname != "github.com" {
-		baseURL := fmt.Sprintf("https://%s/api/v3/", hostname)
-		base, err := url.Parse(baseURL)
-		if err != nil {
-			return nil, errors.Wrapf(err, "Invalid github hostname trying to parse %s", baseURL)
-		}
-		client.BaseURL = base
-	}
+	return newGithubClient(hostname, tp.Client())
+}
+
+// NewGithubAppClient returns a GitHub client that authenticates as an
+// installation of the GitHub App with creds.
+func NewGithubAppClient(hostname string, creds *GithubAppCredentials) (*GithubClient, error) {
+	return newGithubClient(hostname, &http.Client{Transport: creds.Transport()})
+}
 
+func newGithubClient(hostname string, httpClient *http.Client) (*GithubClient, error) {
+	client := github.NewClient(httpClient)
+	base, err := githubBaseURL(hostname)
+	if err != nil {
+		return nil, err
+	}
+	client.BaseURL = base
 	return &GithubClient{
 		client: client,
 		ctx:    context.Background(),
 	}, nil
 }
 
+// githubBaseURL returns the URL of the API for the GitHub installation at
+// hostname.
+func githubBaseURL(hostname string) (*url.URL, error) {
+	// If we're using github.com then we don't need to do any additional configuration
+	// for the client. It we're using Github Enterprise, then we need to manually
+	// set the base url for the API.
+	baseURL := "https://api.github.com/"
+	if hostname != "github.com" {
+		baseURL = fmt.Sprintf("https://%s/api/v3/", hostname)
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid github hostname trying to parse %s", baseURL)
+	}
+	return base, nil
+}
+
 // GetModifiedFiles returns the names of files that were modified in the pull request.
 // The names include the path to the file from the repo root, ex. parent/child/file.txt.
 func (g *GithubClient) GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error) {
@@ -89,6 +111,173 @@ func (g *GithubClient) PullIsApproved(repo models.Repo, pull models.PullRequest)
 	return false, nil
 }
 
+// GetApprovers returns the users whose most recent review of the pull
+// request approves it.
+func (g *GithubClient) GetApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	// Reviews are listed oldest first so later reviews overwrite earlier ones.
+	// Comments don't change a user's approval.
+	states := make(map[string]string)
+	var users []string
+	nextPage := 0
+	for {
+		opts := github.ListOptions{PerPage: 100, Page: nextPage}
+		reviews, resp, err := g.client.PullRequests.ListReviews(g.ctx, repo.Owner, repo.Name, pull.Num, &opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting reviews")
+		}
+		for _, review := range reviews {
+			user := review.User.GetLogin()
+			if user == "" || review.GetState() == "COMMENTED" {
+				continue
+			}
+			if _, ok := states[user]; !ok {
+				users = append(users, user)
+			}
+			states[user] = review.GetState()
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+	var approvers []string
+	for _, user := range users {
+		if states[user] == "APPROVED" {
+			approvers = append(approvers, user)
+		}
+	}
+	return approvers, nil
+}
+
+// GetTeamMembers returns the members of team, ex. org/team.
+func (g *GithubClient) GetTeamMembers(repo models.Repo, team string) ([]string, error) {
+	split := strings.SplitN(team, "/", 2)
+	if len(split) != 2 || split[0] == "" || split[1] == "" {
+		return nil, fmt.Errorf("invalid team %q: must be in the form org/team", team)
+	}
+	org, slug := split[0], split[1]
+	// Team requests aren't about a repo so if we're authenticated as a
+	// GitHub App, this tells it to use the token of its installation on org.
+	ctx := withGithubAppOrg(g.ctx, org)
+
+	// Teams are looked up by ID so we need to find it from the slug.
+	teamID := 0
+	opts := github.ListOptions{PerPage: 100}
+	for teamID == 0 {
+		teams, resp, err := g.client.Organizations.ListTeams(ctx, org, &opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "listing teams in %s", org)
+		}
+		for _, t := range teams {
+			if t.GetSlug() == slug {
+				teamID = t.GetID()
+			}
+		}
+		if teamID == 0 && resp.NextPage == 0 {
+			return nil, fmt.Errorf("team %q not found", team)
+		}
+		opts.Page = resp.NextPage
+	}
+
+	var members []string
+	memberOpts := github.OrganizationListTeamMembersOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		users, resp, err := g.client.Organizations.ListTeamMembers(ctx, teamID, &memberOpts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "listing members of %s", team)
+		}
+		for _, u := range users {
+			members = append(members, u.GetLogin())
+		}
+		if resp.NextPage == 0 {
+			return members, nil
+		}
+		memberOpts.Page = resp.NextPage
+	}
+}
+
+// PullIsMergeable returns true if the pull request has no conflicts and the
+// checks required by the base branch's protection have passed. Required
+// checks can be commit statuses or check runs.
+func (g *GithubClient) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
+	githubPull, err := g.GetPullRequest(repo, pull.Num)
+	if err != nil {
+		return false, errors.Wrap(err, "getting pull request")
+	}
+	// Mergeable is null while GitHub is still computing it.
+	if !githubPull.GetMergeable() {
+		return false, nil
+	}
+
+	required, resp, err := g.client.Repositories.GetRequiredStatusChecks(g.ctx, repo.Owner, repo.Name, githubPull.Base.GetRef())
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		// The branch isn't protected so no checks are required.
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "getting required status checks")
+	}
+	passed, err := g.passedChecks(repo, pull.HeadCommit)
+	if err != nil {
+		return false, err
+	}
+	for _, requiredContext := range required.Contexts {
+		if !strings.HasPrefix(requiredContext, StatusPrefix) && !passed[requiredContext] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// passedChecks returns the names of the commit statuses and check runs that
+// passed on commit.
+func (g *GithubClient) passedChecks(repo models.Repo, commit string) (map[string]bool, error) {
+	passed := make(map[string]bool)
+	opts := github.ListOptions{PerPage: 100}
+	for {
+		combined, resp, err := g.client.Repositories.GetCombinedStatus(g.ctx, repo.Owner, repo.Name, commit, &opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting commit statuses")
+		}
+		for _, status := range combined.Statuses {
+			if status.GetState() == "success" {
+				passed[status.GetContext()] = true
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	page := 1
+	for {
+		req, err := g.client.NewRequest("GET", fmt.Sprintf("repos/%s/%s/commits/%s/check-runs?per_page=100&page=%d", repo.Owner, repo.Name, commit, page), nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "constructing request")
+		}
+		req.Header.Set("Accept", githubchecks.Accept)
+		var list githubchecks.CheckRunList
+		resp, err := g.client.Do(g.ctx, req, &list)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting check runs")
+		}
+		for _, run := range list.CheckRuns {
+			if run.Status != githubchecks.StatusCompleted {
+				continue
+			}
+			switch run.Conclusion {
+			case githubchecks.ConclusionSuccess, githubchecks.ConclusionNeutral, githubchecks.ConclusionSkipped:
+				passed[run.Name] = true
+			}
+		}
+		if resp.NextPage == 0 {
+			return passed, nil
+		}
+		page = resp.NextPage
+	}
+}
+
 // GetPullRequest returns the pull request.
 func (g *GithubClient) GetPullRequest(repo models.Repo, num int) (*github.PullRequest, error) {
 	pull, _, err := g.client.PullRequests.Get(g.ctx, repo.Owner, repo.Name, num)
@@ -97,8 +286,7 @@ func (g *GithubClient) GetPullRequest(repo models.Repo, num int) (*github.PullRe
 
 // UpdateStatus updates the status badge on the pull request.
 // See https://github.com/blog/1227-commit-status-api.
-func (g *GithubClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state CommitStatus, description string) error {
-	const statusContext = "Atlantis"
+func (g *GithubClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state CommitStatus, src string, description string, url string) error {
 	ghState := "error"
 	switch state {
 	case Pending:
@@ -111,7 +299,21 @@ func (g *GithubClient) UpdateStatus(repo models.Repo, pull models.PullRequest, s
 	status := &github.RepoStatus{
 		State:       github.String(ghState),
 		Description: github.String(description),
-		Context:     github.String(statusContext)}
+		Context:     github.String(src),
+		TargetURL:   github.String(url)}
 	_, _, err := g.client.Repositories.CreateStatus(g.ctx, repo.Owner, repo.Name, pull.HeadCommit, status)
 	return err
 }
+
+// CreateCheckRun creates a check run on the pull request's head commit.
+// GitHub shows the most recent check run with a given name so this is also
+// how a check run is updated. Only GitHub Apps can create check runs.
+func (g *GithubClient) CreateCheckRun(repo models.Repo, run githubchecks.CheckRun) error {
+	req, err := g.client.NewRequest("POST", fmt.Sprintf("repos/%s/%s/check-runs", repo.Owner, repo.Name), run)
+	if err != nil {
+		return errors.Wrap(err, "constructing request")
+	}
+	req.Header.Set("Accept", githubchecks.Accept)
+	_, err = g.client.Do(g.ctx, req, nil)
+	return err
+}
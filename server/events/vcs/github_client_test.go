@@ -0,0 +1,143 @@
+package vcs_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs"
+	. "github.com/hootsuite/atlantis/testing"
+)
+
+var githubPull = models.PullRequest{Num: 1, HeadCommit: "abc123"}
+
+func TestGithubClient_PullIsMergeable(t *testing.T) {
+	cases := []struct {
+		description string
+		conclusion  string
+		exp         bool
+	}{
+		{"required statuses and check runs on any page should pass", "success", true},
+		{"neutral check runs should pass", "neutral", true},
+		{"failed check runs shouldn't pass", "failure", false},
+		{"check runs that haven't completed shouldn't pass", "", false},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			client, srv := newTestGithubAppClient(t, func(w http.ResponseWriter, r *http.Request) {
+				page := r.URL.Query().Get("page")
+				switch r.URL.Path {
+				case "/api/v3/repos/owner/repo/pulls/1":
+					w.Write([]byte(`{"mergeable": true, "base": {"ref": "master"}}`)) // nolint: errcheck
+				case "/api/v3/repos/owner/repo/branches/master/protection/required_status_checks":
+					w.Write([]byte(`{"contexts": ["ci/status", "ci/check-run", "atlantis/plan"]}`)) // nolint: errcheck
+				case "/api/v3/repos/owner/repo/commits/abc123/status":
+					if page != "2" {
+						setNextPage(w, r)
+						w.Write([]byte(`{"statuses": [{"context": "other", "state": "success"}]}`)) // nolint: errcheck
+						return
+					}
+					w.Write([]byte(`{"statuses": [{"context": "ci/status", "state": "success"}]}`)) // nolint: errcheck
+				case "/api/v3/repos/owner/repo/commits/abc123/check-runs":
+					Equals(t, "application/vnd.github.antiope-preview+json", r.Header.Get("Accept"))
+					if page != "2" {
+						setNextPage(w, r)
+						w.Write([]byte(`{"check_runs": [{"name": "other", "status": "completed", "conclusion": "success"}]}`)) // nolint: errcheck
+						return
+					}
+					status := "completed"
+					if c.conclusion == "" {
+						status = "in_progress"
+					}
+					fmt.Fprintf(w, `{"check_runs": [{"name": "ci/check-run", "status": %q, "conclusion": %q}]}`, status, c.conclusion)
+				default:
+					t.Errorf("unexpected request %s", r.URL.RequestURI())
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+			defer srv.Close()
+
+			mergeable, err := client.PullIsMergeable(appRepo, githubPull)
+			Ok(t, err)
+			Equals(t, c.exp, mergeable)
+		})
+	}
+}
+
+func TestGithubClient_PullIsMergeable_Unprotected(t *testing.T) {
+	t.Log("pull requests into unprotected branches should be mergeable without checks")
+	client, srv := newTestGithubAppClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/repo/pulls/1":
+			w.Write([]byte(`{"mergeable": true, "base": {"ref": "master"}}`)) // nolint: errcheck
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer srv.Close()
+
+	mergeable, err := client.PullIsMergeable(appRepo, githubPull)
+	Ok(t, err)
+	Equals(t, true, mergeable)
+}
+
+func TestGithubClient_GetTeamMembers_App(t *testing.T) {
+	t.Log("team requests should be authenticated as the app's installation on the team's org")
+	client, srv := newTestGithubAppClient(t, func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "token org-token", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/v3/orgs/org/teams":
+			w.Write([]byte(`[{"id": 5, "slug": "other"}, {"id": 6, "slug": "team"}]`)) // nolint: errcheck
+		case "/api/v3/teams/6/members":
+			w.Write([]byte(`[{"login": "alice"}, {"login": "bob"}]`)) // nolint: errcheck
+		default:
+			t.Errorf("unexpected request %s", r.URL.RequestURI())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer srv.Close()
+
+	members, err := client.GetTeamMembers(appRepo, "org/team")
+	Ok(t, err)
+	Equals(t, []string{"alice", "bob"}, members)
+}
+
+// newTestGithubAppClient returns a client authenticated as a GitHub App whose
+// API requests are handled by handler. Its installations on owner/repo and
+// org have the tokens repo-token and org-token.
+func newTestGithubAppClient(t *testing.T, handler http.HandlerFunc) (*vcs.GithubClient, *httptest.Server) {
+	_, keyPEM := generateAppKey(t)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/installation":
+			w.Write([]byte(`{"id": 5}`)) // nolint: errcheck
+		case "/orgs/org/installation":
+			w.Write([]byte(`{"id": 6}`)) // nolint: errcheck
+		case "/app/installations/5/access_tokens":
+			fmt.Fprintf(w, `{"token": "repo-token", "expires_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+		case "/app/installations/6/access_tokens":
+			fmt.Fprintf(w, `{"token": "org-token", "expires_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+		default:
+			handler(w, r)
+		}
+	}))
+	creds, err := vcs.NewGithubAppCredentials(srv.Client(), "github.com", 1234, keyPEM)
+	Ok(t, err)
+	creds.BaseURL = srv.URL + "/"
+	client, err := vcs.NewGithubAppClient(strings.TrimPrefix(srv.URL, "https://"), creds)
+	Ok(t, err)
+	return client, srv
+}
+
+// setNextPage adds a Link header to w pointing at page 2 of r's URL.
+func setNextPage(w http.ResponseWriter, r *http.Request) {
+	next := *r.URL
+	q := next.Query()
+	q.Set("page", "2")
+	next.RawQuery = q.Encode()
+	w.Header().Set("Link", fmt.Sprintf(`<https://%s%s>; rel="next"`, r.Host, next.RequestURI()))
+}
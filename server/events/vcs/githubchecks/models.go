@@ -0,0 +1,123 @@
+// Package githubchecks contains models for GitHub's Checks API which isn't
+// supported by the go-github version we use.
+// See https://developer.github.com/v3/checks/runs/.
+package githubchecks
+
+import "github.com/google/go-github/github"
+
+// EventType is the value of the X-Github-Event header for check run events.
+const EventType = "check_run"
+
+// RequestedAction is the action of check run events sent when a user clicks
+// one of the check run's buttons.
+const RequestedAction = "requested_action"
+
+// Accept is the media type required by the Checks API while it's in preview.
+const Accept = "application/vnd.github.antiope-preview+json"
+
+// MaxOutputLen is the maximum length of a check run output's summary or
+// text.
+const MaxOutputLen = 65535
+
+// Statuses of check runs.
+const (
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+)
+
+// Conclusions of completed check runs.
+const (
+	ConclusionSuccess = "success"
+	ConclusionFailure = "failure"
+	ConclusionNeutral = "neutral"
+	ConclusionSkipped = "skipped"
+)
+
+// AnnotationFailure is the level of annotations for failures.
+const AnnotationFailure = "failure"
+
+// CheckRun is the request body for creating a check run.
+type CheckRun struct {
+	Name    string `json:"name"`
+	HeadSHA string `json:"head_sha"`
+	Status  string `json:"status"`
+	// Conclusion is only set if Status is completed.
+	Conclusion string `json:"conclusion,omitempty"`
+	// ExternalID is sent back to us in check run events.
+	ExternalID string   `json:"external_id,omitempty"`
+	DetailsURL string   `json:"details_url,omitempty"`
+	Output     *Output  `json:"output,omitempty"`
+	Actions    []Action `json:"actions,omitempty"`
+}
+
+// Output is what's shown on the check run's page.
+type Output struct {
+	Title       string       `json:"title"`
+	Summary     string       `json:"summary"`
+	Text        string       `json:"text,omitempty"`
+	Annotations []Annotation `json:"annotations,omitempty"`
+}
+
+// Annotation marks a path in the repo with a message.
+type Annotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	AnnotationLevel string `json:"annotation_level"`
+	Title           string `json:"title,omitempty"`
+	Message         string `json:"message"`
+}
+
+// Action is a button on the check run. GitHub limits Label to 20
+// characters, Description to 40 and Identifier to 20.
+type Action struct {
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	Identifier  string `json:"identifier"`
+}
+
+// CheckRunList is the response body for listing the check runs of a commit.
+type CheckRunList struct {
+	TotalCount int              `json:"total_count"`
+	CheckRuns  []CheckRunResult `json:"check_runs"`
+}
+
+// CheckRunResult is a check run in a CheckRunList.
+type CheckRunResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Conclusion is empty until Status is completed.
+	Conclusion string `json:"conclusion"`
+}
+
+// CheckRunEvent is the payload of check_run webhooks.
+type CheckRunEvent struct {
+	Action          string             `json:"action"`
+	CheckRun        CheckRunPayload    `json:"check_run"`
+	RequestedAction *RequestedActionID `json:"requested_action"`
+	Repository      *github.Repository `json:"repository"`
+	Sender          *github.User       `json:"sender"`
+}
+
+// CheckRunPayload is the check run in a CheckRunEvent.
+type CheckRunPayload struct {
+	ExternalID string `json:"external_id"`
+	// PullRequests are the pull requests whose head is the check run's
+	// commit. It's empty for pull requests from forks.
+	PullRequests []struct {
+		Number int `json:"number"`
+	} `json:"pull_requests"`
+}
+
+// RequestedActionID identifies the button a user clicked.
+type RequestedActionID struct {
+	Identifier string `json:"identifier"`
+}
+
+// ExternalID is JSON encoded into the external ID of Atlantis's check runs
+// so that when a button is clicked we know what to run. Dir is empty for the
+// check run that summarizes every project.
+type ExternalID struct {
+	Dir       string `json:"dir,omitempty"`
+	Workspace string `json:"workspace"`
+}
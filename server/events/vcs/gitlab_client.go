@@ -3,6 +3,7 @@ package vcs
 import (
 	"fmt"
 	"net/url"
+	"strings"
 
 	"github.com/hootsuite/atlantis/server/events/models"
 	"github.com/lkysow/go-gitlab"
@@ -65,10 +66,62 @@ func (g *GitlabClient) PullIsApproved(repo models.Repo, pull models.PullRequest)
 	return true, nil
 }
 
-// UpdateStatus updates the build status of a commit.
-func (g *GitlabClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state CommitStatus, description string) error {
-	const statusContext = "Atlantis"
+// GetApprovers returns the users who approved the merge request.
+func (g *GitlabClient) GetApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	approvals, _, err := g.Client.MergeRequests.GetMergeRequestApprovals(repo.FullName, pull.Num)
+	if err != nil {
+		return nil, err
+	}
+	var approvers []string
+	for _, a := range approvals.ApprovedBy {
+		approvers = append(approvers, a.User.Username)
+	}
+	return approvers, nil
+}
+
+// GetTeamMembers returns the members of the group whose path is team.
+func (g *GitlabClient) GetTeamMembers(repo models.Repo, team string) ([]string, error) {
+	var members []string
+	opts := gitlab.ListGroupMembersOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+	for {
+		groupMembers, resp, err := g.Client.Groups.ListGroupMembers(team, &opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range groupMembers {
+			members = append(members, m.Username)
+		}
+		if resp.NextPage == 0 {
+			return members, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
 
+// PullIsMergeable returns true if the merge request has no conflicts and all
+// the statuses of its head commit, ex. its pipeline's jobs, have passed.
+func (g *GitlabClient) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
+	mr, err := g.GetMergeRequest(repo.FullName, pull.Num)
+	if err != nil {
+		return false, err
+	}
+	if mr.MergeStatus != "can_be_merged" {
+		return false, nil
+	}
+	statuses, _, err := g.Client.Commits.GetCommitStatuses(repo.FullName, pull.HeadCommit, nil)
+	if err != nil {
+		return false, err
+	}
+	for _, s := range statuses {
+		if !strings.HasPrefix(s.Name, StatusPrefix) && s.Status != string(gitlab.Success) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// UpdateStatus updates the build status of a commit.
+func (g *GitlabClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state CommitStatus, src string, description string, url string) error {
 	gitlabState := gitlab.Failed
 	switch state {
 	case Pending:
@@ -80,7 +133,8 @@ func (g *GitlabClient) UpdateStatus(repo models.Repo, pull models.PullRequest, s
 	}
 	_, _, err := g.Client.Commits.SetCommitStatus(repo.FullName, pull.HeadCommit, &gitlab.SetCommitStatusOptions{
 		State:       gitlabState,
-		Context:     gitlab.String(statusContext),
+		Name:        gitlab.String(src),
+		TargetURL:   gitlab.String(url),
 		Description: gitlab.String(description),
 	})
 	return err
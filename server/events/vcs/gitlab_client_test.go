@@ -0,0 +1,105 @@
+package vcs_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hootsuite/atlantis/server/events/models"
+	"github.com/hootsuite/atlantis/server/events/vcs"
+	. "github.com/hootsuite/atlantis/testing"
+	"github.com/lkysow/go-gitlab"
+)
+
+func TestGitlabClient_UpdateStatus(t *testing.T) {
+	t.Log("should set a commit status with the given name and url")
+	var body map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "/api/v4/projects/owner%2Frepo/statuses/abc123", r.URL.EscapedPath())
+		raw, _ := ioutil.ReadAll(r.Body)
+		Ok(t, json.Unmarshal(raw, &body))
+		w.Write([]byte("{}")) // nolint: errcheck
+	}))
+	defer srv.Close()
+
+	gl := gitlab.NewClient(nil, "token")
+	Ok(t, gl.SetBaseURL(srv.URL+"/api/v4"))
+	client := &vcs.GitlabClient{Client: gl}
+	repo := models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}
+	err := client.UpdateStatus(repo, models.PullRequest{HeadCommit: "abc123"}, vcs.Failed, "atlantis/plan: dir (default)", "Plan Failed", "https://atlantis.example.com")
+	Ok(t, err)
+	Equals(t, map[string]string{
+		"state":       "failed",
+		"name":        "atlantis/plan: dir (default)",
+		"target_url":  "https://atlantis.example.com",
+		"description": "Plan Failed",
+	}, body)
+}
+
+func TestGitlabClient_PullIsMergeable(t *testing.T) {
+	cases := []struct {
+		description string
+		mergeStatus string
+		statuses    string
+		exp         bool
+	}{
+		{
+			"mergeable with passing statuses",
+			"can_be_merged",
+			`[{"name": "test", "status": "success"}, {"name": "atlantis/apply", "status": "pending"}]`,
+			true,
+		},
+		{
+			"conflicts",
+			"cannot_be_merged",
+			`[]`,
+			false,
+		},
+		{
+			"failing status",
+			"can_be_merged",
+			`[{"name": "test", "status": "failed"}]`,
+			false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.EscapedPath() {
+				case "/api/v4/projects/owner%2Frepo/merge_requests/1":
+					w.Write([]byte(`{"merge_status": "` + c.mergeStatus + `"}`)) // nolint: errcheck
+				case "/api/v4/projects/owner%2Frepo/repository/commits/abc123/statuses":
+					w.Write([]byte(c.statuses)) // nolint: errcheck
+				default:
+					t.Errorf("unexpected request %s", r.URL.EscapedPath())
+				}
+			}))
+			defer srv.Close()
+
+			gl := gitlab.NewClient(nil, "token")
+			Ok(t, gl.SetBaseURL(srv.URL+"/api/v4"))
+			client := &vcs.GitlabClient{Client: gl}
+			mergeable, err := client.PullIsMergeable(models.Repo{FullName: "owner/repo"}, models.PullRequest{Num: 1, HeadCommit: "abc123"})
+			Ok(t, err)
+			Equals(t, c.exp, mergeable)
+		})
+	}
+}
+
+func TestGitlabClient_GetApprovers(t *testing.T) {
+	t.Log("should return the usernames of the users who approved the merge request")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "/api/v4/projects/owner%2Frepo/merge_requests/1/approvals", r.URL.EscapedPath())
+		w.Write([]byte(`{"approved_by": [{"user": {"username": "alice"}}, {"user": {"username": "bob"}}]}`)) // nolint: errcheck
+	}))
+	defer srv.Close()
+
+	gl := gitlab.NewClient(nil, "token")
+	Ok(t, gl.SetBaseURL(srv.URL+"/api/v4"))
+	client := &vcs.GitlabClient{Client: gl}
+	approvers, err := client.GetApprovers(models.Repo{FullName: "owner/repo"}, models.PullRequest{Num: 1})
+	Ok(t, err)
+	Equals(t, []string{"alice", "bob"}, approvers)
+}
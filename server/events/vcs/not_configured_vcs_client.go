@@ -22,9 +22,18 @@ func (a *NotConfiguredVCSClient) CreateComment(repo models.Repo, pull models.Pul
 func (a *NotConfiguredVCSClient) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
 	return false, a.err()
 }
-func (a *NotConfiguredVCSClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state CommitStatus, description string) error {
+func (a *NotConfiguredVCSClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state CommitStatus, src string, description string, url string) error {
 	return a.err()
 }
+func (a *NotConfiguredVCSClient) GetApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	return nil, a.err()
+}
+func (a *NotConfiguredVCSClient) GetTeamMembers(repo models.Repo, team string) ([]string, error) {
+	return nil, a.err()
+}
+func (a *NotConfiguredVCSClient) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
+	return false, a.err()
+}
 func (a *NotConfiguredVCSClient) err() error {
 	//noinspection GoErrorStringFormat
 	return fmt.Errorf("Atlantis was not configured to support repos from %s", a.Host.String())
@@ -1,6 +1,8 @@
 package vcs
 
 import (
+	"fmt"
+
 	"github.com/hootsuite/atlantis/server/events/models"
 	"github.com/pkg/errors"
 )
@@ -13,26 +15,50 @@ type ClientProxy interface {
 	GetModifiedFiles(repo models.Repo, pull models.PullRequest, host Host) ([]string, error)
 	CreateComment(repo models.Repo, pull models.PullRequest, comment string, host Host) error
 	PullIsApproved(repo models.Repo, pull models.PullRequest, host Host) (bool, error)
-	UpdateStatus(repo models.Repo, pull models.PullRequest, state CommitStatus, description string, host Host) error
+	UpdateStatus(repo models.Repo, pull models.PullRequest, state CommitStatus, src string, description string, url string, host Host) error
+	GetApprovers(repo models.Repo, pull models.PullRequest, host Host) ([]string, error)
+	GetTeamMembers(repo models.Repo, team string, host Host) ([]string, error)
+	PullIsMergeable(repo models.Repo, pull models.PullRequest, host Host) (bool, error)
 }
 
 // DefaultClientProxy proxies calls to the correct VCS client depending on which
 // VCS host is required.
 type DefaultClientProxy struct {
-	GithubClient Client
-	GitlabClient Client
+	GithubClient    Client
+	GitlabClient    Client
+	BitbucketClient Client
+	// BitbucketServerClient is the client for Bitbucket Server.
+	BitbucketServerClient Client
+	AzureDevopsClient     Client
+	GiteaClient           Client
 }
 
-func NewDefaultClientProxy(githubClient Client, gitlabClient Client) *DefaultClientProxy {
+func NewDefaultClientProxy(githubClient Client, gitlabClient Client, bitbucketClient Client, bitbucketServerClient Client, azureDevopsClient Client, giteaClient Client) *DefaultClientProxy {
 	if githubClient == nil {
-		githubClient = &NotConfiguredVCSClient{}
+		githubClient = &NotConfiguredVCSClient{Host: Github}
 	}
 	if gitlabClient == nil {
-		gitlabClient = &NotConfiguredVCSClient{}
+		gitlabClient = &NotConfiguredVCSClient{Host: Gitlab}
+	}
+	if bitbucketClient == nil {
+		bitbucketClient = &NotConfiguredVCSClient{Host: Bitbucket}
+	}
+	if bitbucketServerClient == nil {
+		bitbucketServerClient = &NotConfiguredVCSClient{Host: BitbucketServer}
+	}
+	if azureDevopsClient == nil {
+		azureDevopsClient = &NotConfiguredVCSClient{Host: AzureDevops}
+	}
+	if giteaClient == nil {
+		giteaClient = &NotConfiguredVCSClient{Host: Gitea}
 	}
 	return &DefaultClientProxy{
-		GitlabClient: gitlabClient,
-		GithubClient: githubClient,
+		GitlabClient:          gitlabClient,
+		GithubClient:          githubClient,
+		BitbucketClient:       bitbucketClient,
+		BitbucketServerClient: bitbucketServerClient,
+		AzureDevopsClient:     azureDevopsClient,
+		GiteaClient:           giteaClient,
 	}
 }
 
@@ -44,6 +70,14 @@ func (d *DefaultClientProxy) GetModifiedFiles(repo models.Repo, pull models.Pull
 		return d.GithubClient.GetModifiedFiles(repo, pull)
 	case Gitlab:
 		return d.GitlabClient.GetModifiedFiles(repo, pull)
+	case Bitbucket:
+		return d.BitbucketClient.GetModifiedFiles(repo, pull)
+	case BitbucketServer:
+		return d.BitbucketServerClient.GetModifiedFiles(repo, pull)
+	case AzureDevops:
+		return d.AzureDevopsClient.GetModifiedFiles(repo, pull)
+	case Gitea:
+		return d.GiteaClient.GetModifiedFiles(repo, pull)
 	}
 	return nil, invalidVCSErr
 }
@@ -54,6 +88,14 @@ func (d *DefaultClientProxy) CreateComment(repo models.Repo, pull models.PullReq
 		return d.GithubClient.CreateComment(repo, pull, comment)
 	case Gitlab:
 		return d.GitlabClient.CreateComment(repo, pull, comment)
+	case Bitbucket:
+		return d.BitbucketClient.CreateComment(repo, pull, comment)
+	case BitbucketServer:
+		return d.BitbucketServerClient.CreateComment(repo, pull, comment)
+	case AzureDevops:
+		return d.AzureDevopsClient.CreateComment(repo, pull, comment)
+	case Gitea:
+		return d.GiteaClient.CreateComment(repo, pull, comment)
 	}
 	return invalidVCSErr
 }
@@ -64,16 +106,76 @@ func (d *DefaultClientProxy) PullIsApproved(repo models.Repo, pull models.PullRe
 		return d.GithubClient.PullIsApproved(repo, pull)
 	case Gitlab:
 		return d.GitlabClient.PullIsApproved(repo, pull)
+	case Bitbucket:
+		return d.BitbucketClient.PullIsApproved(repo, pull)
+	case BitbucketServer:
+		return d.BitbucketServerClient.PullIsApproved(repo, pull)
+	case AzureDevops:
+		return d.AzureDevopsClient.PullIsApproved(repo, pull)
+	case Gitea:
+		return d.GiteaClient.PullIsApproved(repo, pull)
 	}
 	return false, invalidVCSErr
 }
 
-func (d *DefaultClientProxy) UpdateStatus(repo models.Repo, pull models.PullRequest, state CommitStatus, description string, host Host) error {
+func (d *DefaultClientProxy) UpdateStatus(repo models.Repo, pull models.PullRequest, state CommitStatus, src string, description string, url string, host Host) error {
 	switch host {
 	case Github:
-		return d.GithubClient.UpdateStatus(repo, pull, state, description)
+		return d.GithubClient.UpdateStatus(repo, pull, state, src, description, url)
 	case Gitlab:
-		return d.GitlabClient.UpdateStatus(repo, pull, state, description)
+		return d.GitlabClient.UpdateStatus(repo, pull, state, src, description, url)
+	case Bitbucket:
+		return d.BitbucketClient.UpdateStatus(repo, pull, state, src, description, url)
+	case BitbucketServer:
+		return d.BitbucketServerClient.UpdateStatus(repo, pull, state, src, description, url)
+	case AzureDevops:
+		return d.AzureDevopsClient.UpdateStatus(repo, pull, state, src, description, url)
+	case Gitea:
+		return d.GiteaClient.UpdateStatus(repo, pull, state, src, description, url)
 	}
 	return invalidVCSErr
 }
+
+func (d *DefaultClientProxy) GetApprovers(repo models.Repo, pull models.PullRequest, host Host) ([]string, error) {
+	client, err := d.reviewClient(host)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetApprovers(repo, pull)
+}
+
+func (d *DefaultClientProxy) GetTeamMembers(repo models.Repo, team string, host Host) ([]string, error) {
+	client, err := d.reviewClient(host)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetTeamMembers(repo, team)
+}
+
+func (d *DefaultClientProxy) PullIsMergeable(repo models.Repo, pull models.PullRequest, host Host) (bool, error) {
+	client, err := d.reviewClient(host)
+	if err != nil {
+		return false, err
+	}
+	return client.PullIsMergeable(repo, pull)
+}
+
+// reviewClient returns the client for host if it implements ReviewClient.
+func (d *DefaultClientProxy) reviewClient(host Host) (ReviewClient, error) {
+	var client Client
+	switch host {
+	case Github:
+		client = d.GithubClient
+	case Gitlab:
+		client = d.GitlabClient
+	case Bitbucket, BitbucketServer, AzureDevops, Gitea:
+		return nil, fmt.Errorf("%s doesn't support this apply requirement", host)
+	default:
+		return nil, invalidVCSErr
+	}
+	reviewClient, ok := client.(ReviewClient)
+	if !ok {
+		return nil, fmt.Errorf("%s doesn't support this apply requirement", host)
+	}
+	return reviewClient, nil
+}
@@ -5,6 +5,15 @@ type Host int
 const (
 	Github Host = iota
 	Gitlab
+	// Bitbucket is Bitbucket Cloud, ie. bitbucket.org.
+	Bitbucket
+	// BitbucketServer is Bitbucket Server, formerly known as Stash, which is
+	// self-hosted.
+	BitbucketServer
+	// AzureDevops is Azure DevOps Repos, ie. dev.azure.com.
+	AzureDevops
+	// Gitea is a self-hosted Gitea installation.
+	Gitea
 )
 
 func (h Host) String() string {
@@ -13,6 +22,14 @@ func (h Host) String() string {
 		return "Github"
 	case Gitlab:
 		return "Gitlab"
+	case Bitbucket:
+		return "Bitbucket"
+	case BitbucketServer:
+		return "BitbucketServer"
+	case AzureDevops:
+		return "AzureDevops"
+	case Gitea:
+		return "Gitea"
 	}
 	return "<missing String() implementation>"
 }
@@ -20,6 +37,10 @@ func (h Host) String() string {
 // CommitStatus is the result of executing an Atlantis command for the commit.
 // In Github the options are: error, failure, pending, success.
 // In Gitlab the options are: failed, canceled, pending, running, success.
+// In Bitbucket the options are: FAILED, INPROGRESS, STOPPED, SUCCESSFUL.
+// In Azure DevOps the options are: error, failed, notApplicable, notSet,
+// pending, succeeded.
+// In Gitea the options are: error, failure, pending, success, warning.
 // We only support Failed, Pending, Success.
 type CommitStatus int
 
@@ -0,0 +1,150 @@
+package events
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-version"
+)
+
+// Step names. init, plan and apply run the Terraform commands of the same
+// name, run runs a shell command and env sets an environment variable for the
+// steps after it.
+const (
+	InitStepName  = "init"
+	PlanStepName  = "plan"
+	ApplyStepName = "apply"
+	RunStepName   = "run"
+	EnvStepName   = "env"
+)
+
+// DefaultWorkflowName is the name of the workflow projects use if they don't
+// specify one. Repos can override it by defining a workflow with this name.
+const DefaultWorkflowName = "default"
+
+// DefaultWorkflow is the workflow used if a project doesn't specify one.
+var DefaultWorkflow = Workflow{
+	Plan: Stage{
+		Steps: []Step{{Name: InitStepName}, {Name: PlanStepName}},
+	},
+	Apply: Stage{
+		Steps: []Step{{Name: InitStepName}, {Name: ApplyStepName}},
+	},
+}
+
+// Workflow defines the steps run for each command.
+type Workflow struct {
+	// Plan is run for plan commands.
+	Plan Stage
+	// Apply is run for apply commands.
+	Apply Stage
+}
+
+// Stage is the ordered list of steps run for a command.
+type Stage struct {
+	Steps []Step
+}
+
+// Step is a single step in a stage.
+type Step struct {
+	// Name is one of the step names, ex. InitStepName.
+	Name string
+	// ExtraArgs are appended to the Terraform command for init, plan and
+	// apply steps.
+	ExtraArgs []string
+	// RunCommand is the shell command to run for run steps.
+	RunCommand string
+	// EnvName is the name of the environment variable to set for env steps.
+	EnvName string
+	// EnvValue is the value of the environment variable to set for env steps.
+	EnvValue string
+	// Timeout is how long the step can run before it's interrupted. If 0,
+	// the server's default for the step is used.
+	Timeout time.Duration
+}
+
+// StepTimeouts are the server's default timeouts for each kind of step. A
+// timeout of 0 means there isn't one.
+type StepTimeouts struct {
+	Init  time.Duration
+	Plan  time.Duration
+	Apply time.Duration
+	// Run is the timeout of run steps, which hooks are converted to.
+	Run time.Duration
+}
+
+// ForStep returns the timeout for step, which is the step's own timeout if it
+// has one, otherwise the default for its kind.
+func (t StepTimeouts) ForStep(step Step) time.Duration {
+	if step.Timeout > 0 {
+		return step.Timeout
+	}
+	switch step.Name {
+	case InitStepName:
+		return t.Init
+	case PlanStepName:
+		return t.Plan
+	case ApplyStepName:
+		return t.Apply
+	case RunStepName:
+		return t.Run
+	}
+	return 0
+}
+
+// legacyWorkflow converts the hooks and extra arguments from a project config
+// into the equivalent workflow so projects that haven't moved to workflows
+// keep working. tfVersion is needed because projects on Terraform < 0.9 run
+// pre_get hooks instead of pre_init.
+func legacyWorkflow(config ProjectConfig, tfVersion *version.Version) Workflow {
+	initHooks := runSteps(config.PreInit, config.Timeouts[preInitKey])
+	initArgs := config.GetExtraArguments(InitStepName)
+	if !supportsInit(tfVersion) {
+		initHooks = runSteps(config.PreGet, config.Timeouts[preGetKey])
+		initArgs = config.GetExtraArguments("get")
+	}
+	initSteps := append(initHooks, Step{Name: InitStepName, ExtraArgs: initArgs, Timeout: config.Timeouts[InitStepName]})
+
+	var plan []Step
+	plan = append(plan, initSteps...)
+	plan = append(plan, runSteps(config.PrePlan, config.Timeouts[prePlanKey])...)
+	plan = append(plan, Step{Name: PlanStepName, ExtraArgs: config.GetExtraArguments(PlanStepName), Timeout: config.Timeouts[PlanStepName]})
+	plan = append(plan, runSteps(config.PostPlan, config.Timeouts[postPlanKey])...)
+
+	var apply []Step
+	apply = append(apply, initSteps...)
+	apply = append(apply, runSteps(config.PreApply, config.Timeouts[preApplyKey])...)
+	apply = append(apply, Step{Name: ApplyStepName, ExtraArgs: config.GetExtraArguments(ApplyStepName), Timeout: config.Timeouts[ApplyStepName]})
+	apply = append(apply, runSteps(config.PostApply, config.Timeouts[postApplyKey])...)
+
+	return Workflow{Plan: Stage{Steps: plan}, Apply: Stage{Steps: apply}}
+}
+
+// withTimeouts returns a copy of w where the init, plan and apply steps that
+// don't set their own timeout use the one from the project config's
+// timeouts, so that they apply whichever workflow the project runs.
+func withTimeouts(w Workflow, timeouts map[string]time.Duration) Workflow {
+	if len(timeouts) == 0 {
+		return w
+	}
+	stage := func(s Stage) Stage {
+		steps := make([]Step, len(s.Steps))
+		for i, step := range s.Steps {
+			if step.Timeout == 0 && step.Name != RunStepName && step.Name != EnvStepName {
+				step.Timeout = timeouts[step.Name]
+			}
+			steps[i] = step
+		}
+		return Stage{Steps: steps}
+	}
+	return Workflow{Plan: stage(w.Plan), Apply: stage(w.Apply)}
+}
+
+// runSteps returns a run step for hook commands. Hooks run all their commands
+// in the same script so they become a single step.
+func runSteps(commands []string, timeout time.Duration) []Step {
+	if len(commands) == 0 {
+		return nil
+	}
+	return []Step{{Name: RunStepName, RunCommand: strings.Join(commands, "\n"), Timeout: timeout}}
+}
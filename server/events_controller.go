@@ -1,13 +1,22 @@
 package server
 
 import (
+	"crypto/subtle"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/google/go-github/github"
 	"github.com/hootsuite/atlantis/server/events"
 	"github.com/hootsuite/atlantis/server/events/models"
 	"github.com/hootsuite/atlantis/server/events/vcs"
+	"github.com/hootsuite/atlantis/server/events/vcs/azuredevops"
+	"github.com/hootsuite/atlantis/server/events/vcs/bitbucketcloud"
+	"github.com/hootsuite/atlantis/server/events/vcs/bitbucketserver"
+	"github.com/hootsuite/atlantis/server/events/vcs/gitea"
+	"github.com/hootsuite/atlantis/server/events/vcs/githubchecks"
 	"github.com/hootsuite/atlantis/server/logging"
 	"github.com/lkysow/go-gitlab"
 )
@@ -18,6 +27,9 @@ const gitlabHeader = "X-Gitlab-Event"
 // EventsController handles all webhook requests which signify 'events' in the
 // VCS host, ex. GitHub. It's split out from Server to make testing easier.
 type EventsController struct {
+	// CommandRunner is called with the commands to run. It's expected to
+	// return quickly since the webhook request waits for it, ex. because it
+	// queues the command and runs it in the background.
 	CommandRunner events.CommandRunner
 	PullCleaner   events.PullCleaner
 	Logger        *logging.SimpleLogger
@@ -32,14 +44,44 @@ type EventsController struct {
 	// UI that identifies this call as coming from GitLab. If empty, no
 	// request validation is done.
 	GitlabWebHookSecret []byte
+	// BitbucketWebHookSecret is the secret added to this webhook via the
+	// Bitbucket Server UI that identifies this call as coming from Bitbucket
+	// Server. If empty, no request validation is done. Bitbucket Cloud
+	// doesn't support webhook secrets.
+	BitbucketWebHookSecret []byte
+	// AzureDevopsWebhookUser and AzureDevopsWebhookPassword are the basic
+	// auth credentials added to the service hook via the Azure DevOps UI
+	// that identify this call as coming from Azure DevOps. If the user is
+	// empty, no request validation is done.
+	AzureDevopsWebhookUser     []byte
+	AzureDevopsWebhookPassword []byte
+	// GiteaWebHookSecret is the secret added to this webhook via the Gitea
+	// UI that identifies this call as coming from Gitea. If empty, no
+	// request validation is done.
+	GiteaWebHookSecret []byte
 	// SupportedVCSHosts is which VCS hosts Atlantis was configured upon
 	// startup to support.
 	SupportedVCSHosts []vcs.Host
+	// DisableAutoplan is true if plan should not be run automatically when
+	// pull requests are opened or updated.
+	DisableAutoplan bool
+	// AutoplanWhitelist is the set of repos that plan will be run on
+	// automatically when pull requests are opened or updated.
+	AutoplanWhitelist *events.RepoWhitelist
 }
 
 // Post handles POST webhook requests.
 func (e *EventsController) Post(w http.ResponseWriter, r *http.Request) {
-	if r.Header.Get(githubHeader) != "" {
+	// Gitea also sends GitHub's event header for compatibility so it has to be
+	// checked first.
+	if r.Header.Get(gitea.EventTypeHeader) != "" {
+		if !e.supportsHost(vcs.Gitea) {
+			e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support Gitea")
+			return
+		}
+		e.handleGiteaPost(w, r)
+		return
+	} else if r.Header.Get(githubHeader) != "" {
 		if !e.supportsHost(vcs.Github) {
 			e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support GitHub")
 			return
@@ -53,6 +95,29 @@ func (e *EventsController) Post(w http.ResponseWriter, r *http.Request) {
 		}
 		e.handleGitlabPost(w, r)
 		return
+	} else if r.Header.Get(bitbucketcloud.RequestIDHeader) != "" {
+		// Bitbucket Server also sends X-Event-Key so we identify Bitbucket
+		// Cloud by its request ID header.
+		if !e.supportsHost(vcs.Bitbucket) {
+			e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support Bitbucket")
+			return
+		}
+		e.handleBitbucketCloudPost(w, r)
+		return
+	} else if r.Header.Get(bitbucketserver.RequestIDHeader) != "" {
+		if !e.supportsHost(vcs.BitbucketServer) {
+			e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support Bitbucket Server")
+			return
+		}
+		e.handleBitbucketServerPost(w, r)
+		return
+	} else if r.Header.Get(azuredevops.RequestIDHeader) != "" {
+		if !e.supportsHost(vcs.AzureDevops) {
+			e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support Azure DevOps")
+			return
+		}
+		e.handleAzureDevopsPost(w, r)
+		return
 	}
 	e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request")
 }
@@ -66,6 +131,12 @@ func (e *EventsController) handleGithubPost(w http.ResponseWriter, r *http.Reque
 	}
 
 	githubReqID := "X-Github-Delivery=" + r.Header.Get("X-Github-Delivery")
+	// Our version of go-github doesn't support check run events so we parse
+	// them ourselves.
+	if github.WebHookType(r) == githubchecks.EventType {
+		e.HandleGithubCheckRunEvent(w, payload, githubReqID)
+		return
+	}
 	event, _ := github.ParseWebHook(github.WebHookType(r), payload)
 	switch event := event.(type) {
 	case *github.IssueCommentEvent:
@@ -97,22 +168,41 @@ func (e *EventsController) HandleGithubCommentEvent(w http.ResponseWriter, event
 		return
 	}
 
-	// Respond with success and then actually execute the command asynchronously.
-	// We use a goroutine so that this function returns and the connection is
-	// closed.
-	fmt.Fprintln(w, "Processing...")
-	go e.CommandRunner.ExecuteCommand(baseRepo, models.Repo{}, user, pullNum, command, vcs.Github)
+	e.executeCommand(w, baseRepo, models.Repo{}, user, pullNum, command, vcs.Github)
 }
 
-// HandleGithubPullRequestEvent will delete any locks associated with the pull
-// request if the event is a pull request closed event. It's exported to make
-// testing easier.
+// HandleGithubCheckRunEvent runs the command for the button a user clicked on
+// one of our check runs. It's exported to make testing easier.
+func (e *EventsController) HandleGithubCheckRunEvent(w http.ResponseWriter, payload []byte, githubReqID string) {
+	var event githubchecks.CheckRunEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %s %s", err, githubReqID)
+		return
+	}
+	if event.Action != githubchecks.RequestedAction {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring check run event since action was not %s %s", githubchecks.RequestedAction, githubReqID)
+		return
+	}
+
+	baseRepo, user, pullNum, command, err := e.Parser.ParseGithubCheckRunEvent(payload)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %v %s", err, githubReqID)
+		return
+	}
+
+	e.executeCommand(w, baseRepo, models.Repo{}, user, pullNum, command, vcs.Github)
+}
+
+// HandleGithubPullRequestEvent will run plan automatically if the pull request
+// was opened, reopened or updated and delete any locks associated with the pull request
+// if it was closed. It's exported to make testing easier.
 func (e *EventsController) HandleGithubPullRequestEvent(w http.ResponseWriter, pullEvent *github.PullRequestEvent, githubReqID string) {
-	if pullEvent.GetAction() != "closed" {
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring pull request event since action was not closed %s", githubReqID)
+	action := pullEvent.GetAction()
+	if action != "opened" && action != "reopened" && action != "synchronize" && action != "closed" {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring pull request event since action was not opened, reopened, synchronize or closed %s", githubReqID)
 		return
 	}
-	pull, _, err := e.Parser.ParseGithubPull(pullEvent.PullRequest)
+	pull, headRepo, err := e.Parser.ParseGithubPull(pullEvent.PullRequest)
 	if err != nil {
 		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s", err)
 		return
@@ -123,6 +213,10 @@ func (e *EventsController) HandleGithubPullRequestEvent(w http.ResponseWriter, p
 		return
 	}
 
+	if action != "closed" {
+		e.autoplan(w, repo, headRepo, pull, vcs.Github)
+		return
+	}
 	if err := e.PullCleaner.CleanUpPull(repo, pull, vcs.Github); err != nil {
 		e.respond(w, logging.Error, http.StatusInternalServerError, "Error cleaning pull request: %s", err)
 		return
@@ -158,20 +252,21 @@ func (e *EventsController) HandleGitlabCommentEvent(w http.ResponseWriter, event
 		return
 	}
 
-	// Respond with success and then actually execute the command asynchronously.
-	// We use a goroutine so that this function returns and the connection is
-	// closed.
-	fmt.Fprintln(w, "Processing...")
-	go e.CommandRunner.ExecuteCommand(baseRepo, headRepo, user, event.MergeRequest.IID, command, vcs.Gitlab)
+	e.executeCommand(w, baseRepo, headRepo, user, event.MergeRequest.IID, command, vcs.Gitlab)
 }
 
-// HandleGitlabMergeRequestEvent will delete any locks associated with the pull
-// request if the event is a merge request closed event. It's exported to make
-// testing easier.
+// HandleGitlabMergeRequestEvent will run plan automatically if the merge
+// request was opened, reopened or updated and delete any locks associated with the
+// merge request if it was closed. It's exported to make testing easier.
 func (e *EventsController) HandleGitlabMergeRequestEvent(w http.ResponseWriter, event gitlab.MergeEvent) {
-	pull, repo := e.Parser.ParseGitlabMergeEvent(event)
-	if pull.State != models.Closed {
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring opened merge request event")
+	pull, repo, headRepo := e.Parser.ParseGitlabMergeEvent(event)
+	if pull.State == models.Open {
+		action := event.ObjectAttributes.Action
+		if action != "open" && action != "reopen" && action != "update" {
+			e.respond(w, logging.Debug, http.StatusOK, "Ignoring merge request event since action was not open, reopen or update")
+			return
+		}
+		e.autoplan(w, repo, headRepo, pull, vcs.Gitlab)
 		return
 	}
 	if err := e.PullCleaner.CleanUpPull(repo, pull, vcs.Gitlab); err != nil {
@@ -182,6 +277,323 @@ func (e *EventsController) HandleGitlabMergeRequestEvent(w http.ResponseWriter,
 	fmt.Fprintln(w, "Merge request cleaned successfully")
 }
 
+func (e *EventsController) handleBitbucketCloudPost(w http.ResponseWriter, r *http.Request) {
+	eventType := r.Header.Get(bitbucketcloud.EventTypeHeader)
+	reqID := "X-Request-UUID=" + r.Header.Get(bitbucketcloud.RequestIDHeader)
+	defer r.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Unable to read body: %s %s", err, reqID)
+		return
+	}
+	switch eventType {
+	case bitbucketcloud.PullCommentCreatedHeader:
+		e.HandleBitbucketCloudCommentEvent(w, body, reqID)
+	case bitbucketcloud.PullCreatedHeader, bitbucketcloud.PullUpdatedHeader, bitbucketcloud.PullFulfilledHeader, bitbucketcloud.PullRejectedHeader:
+		e.HandleBitbucketCloudPullRequestEvent(w, eventType, body, reqID)
+	default:
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event type %s %s", eventType, reqID)
+	}
+}
+
+// HandleBitbucketCloudCommentEvent handles comment events from Bitbucket
+// Cloud where Atlantis commands can come from. It's exported to make testing
+// easier.
+func (e *EventsController) HandleBitbucketCloudCommentEvent(w http.ResponseWriter, body []byte, reqID string) {
+	pull, baseRepo, headRepo, user, comment, err := e.Parser.ParseBitbucketCloudCommentEvent(body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %v %s", err, reqID)
+		return
+	}
+	command, err := e.Parser.DetermineCommand(comment, vcs.Bitbucket)
+	if err != nil {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring: %s %s", err, reqID)
+		return
+	}
+
+	e.executeCommand(w, baseRepo, headRepo, user, pull.Num, command, vcs.Bitbucket)
+}
+
+// HandleBitbucketCloudPullRequestEvent will run plan automatically if the
+// pull request was created or updated and delete any locks associated with
+// the pull request if it was fulfilled (merged) or rejected (declined). It's
+// exported to make testing easier.
+func (e *EventsController) HandleBitbucketCloudPullRequestEvent(w http.ResponseWriter, eventType string, body []byte, reqID string) {
+	pull, baseRepo, headRepo, err := e.Parser.ParseBitbucketCloudPullEvent(body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s", err, reqID)
+		return
+	}
+	if eventType == bitbucketcloud.PullCreatedHeader || eventType == bitbucketcloud.PullUpdatedHeader {
+		e.autoplan(w, baseRepo, headRepo, pull, vcs.Bitbucket)
+		return
+	}
+	if err := e.PullCleaner.CleanUpPull(baseRepo, pull, vcs.Bitbucket); err != nil {
+		e.respond(w, logging.Error, http.StatusInternalServerError, "Error cleaning pull request: %s", err)
+		return
+	}
+	e.Logger.Info("deleted locks and workspace for repo %s, pull %d", baseRepo.FullName, pull.Num)
+	fmt.Fprintln(w, "Pull request cleaned successfully")
+}
+
+func (e *EventsController) handleBitbucketServerPost(w http.ResponseWriter, r *http.Request) {
+	eventType := r.Header.Get(bitbucketserver.EventTypeHeader)
+	reqID := "X-Request-Id=" + r.Header.Get(bitbucketserver.RequestIDHeader)
+	defer r.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Unable to read body: %s %s", err, reqID)
+		return
+	}
+	if len(e.BitbucketWebHookSecret) > 0 {
+		if err := bitbucketserver.ValidateSignature(body, r.Header.Get(bitbucketserver.SignatureHeader), e.BitbucketWebHookSecret); err != nil {
+			e.respond(w, logging.Warn, http.StatusBadRequest, "%s %s", err, reqID)
+			return
+		}
+	}
+	switch eventType {
+	case bitbucketserver.PullCommentCreatedHeader:
+		e.HandleBitbucketServerCommentEvent(w, body, reqID)
+	case bitbucketserver.PullCreatedHeader, bitbucketserver.PullFromRefUpdatedHeader, bitbucketserver.PullMergedHeader, bitbucketserver.PullDeclinedHeader:
+		e.HandleBitbucketServerPullRequestEvent(w, eventType, body, reqID)
+	default:
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event type %s %s", eventType, reqID)
+	}
+}
+
+// HandleBitbucketServerCommentEvent handles comment events from Bitbucket
+// Server where Atlantis commands can come from. It's exported to make testing
+// easier.
+func (e *EventsController) HandleBitbucketServerCommentEvent(w http.ResponseWriter, body []byte, reqID string) {
+	pull, baseRepo, headRepo, user, comment, err := e.Parser.ParseBitbucketServerCommentEvent(body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %v %s", err, reqID)
+		return
+	}
+	command, err := e.Parser.DetermineCommand(comment, vcs.BitbucketServer)
+	if err != nil {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring: %s %s", err, reqID)
+		return
+	}
+
+	e.executeCommand(w, baseRepo, headRepo, user, pull.Num, command, vcs.BitbucketServer)
+}
+
+// HandleBitbucketServerPullRequestEvent will run plan automatically if the
+// pull request was opened or its branch was updated and delete any locks
+// associated with the pull request if it was merged or declined. It's
+// exported to make testing easier.
+func (e *EventsController) HandleBitbucketServerPullRequestEvent(w http.ResponseWriter, eventType string, body []byte, reqID string) {
+	pull, baseRepo, headRepo, err := e.Parser.ParseBitbucketServerPullEvent(body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s", err, reqID)
+		return
+	}
+	if eventType == bitbucketserver.PullCreatedHeader || eventType == bitbucketserver.PullFromRefUpdatedHeader {
+		e.autoplan(w, baseRepo, headRepo, pull, vcs.BitbucketServer)
+		return
+	}
+	if err := e.PullCleaner.CleanUpPull(baseRepo, pull, vcs.BitbucketServer); err != nil {
+		e.respond(w, logging.Error, http.StatusInternalServerError, "Error cleaning pull request: %s", err)
+		return
+	}
+	e.Logger.Info("deleted locks and workspace for repo %s, pull %d", baseRepo.FullName, pull.Num)
+	fmt.Fprintln(w, "Pull request cleaned successfully")
+}
+
+func (e *EventsController) handleAzureDevopsPost(w http.ResponseWriter, r *http.Request) {
+	reqID := "Request-Id=" + r.Header.Get(azuredevops.RequestIDHeader)
+	if len(e.AzureDevopsWebhookUser) > 0 {
+		user, pass, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(user), e.AzureDevopsWebhookUser) != 1 || subtle.ConstantTimeCompare([]byte(pass), e.AzureDevopsWebhookPassword) != 1 {
+			e.respond(w, logging.Warn, http.StatusUnauthorized, "Request didn't have the expected basic auth credentials %s", reqID)
+			return
+		}
+	}
+	defer r.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Unable to read body: %s %s", err, reqID)
+		return
+	}
+	var event azuredevops.Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %s %s", err, reqID)
+		return
+	}
+	switch event.EventType {
+	case azuredevops.PullCommentedEvent:
+		e.HandleAzureDevopsCommentEvent(w, body, reqID)
+	case azuredevops.PullCreatedEvent, azuredevops.PullUpdatedEvent:
+		e.HandleAzureDevopsPullRequestEvent(w, event, body, reqID)
+	default:
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event type %s %s", event.EventType, reqID)
+	}
+}
+
+// HandleAzureDevopsCommentEvent handles comment events from Azure DevOps
+// where Atlantis commands can come from. It's exported to make testing
+// easier.
+func (e *EventsController) HandleAzureDevopsCommentEvent(w http.ResponseWriter, body []byte, reqID string) {
+	pull, baseRepo, headRepo, user, comment, err := e.Parser.ParseAzureDevopsCommentEvent(body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %v %s", err, reqID)
+		return
+	}
+	command, err := e.Parser.DetermineCommand(comment, vcs.AzureDevops)
+	if err != nil {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring: %s %s", err, reqID)
+		return
+	}
+
+	e.executeCommand(w, baseRepo, headRepo, user, pull.Num, command, vcs.AzureDevops)
+}
+
+// HandleAzureDevopsPullRequestEvent will run plan automatically if the pull
+// request was created or its source branch was updated and delete any locks
+// associated with the pull request if it was completed or abandoned. It's
+// exported to make testing easier.
+func (e *EventsController) HandleAzureDevopsPullRequestEvent(w http.ResponseWriter, event azuredevops.Event, body []byte, reqID string) {
+	pull, baseRepo, headRepo, err := e.Parser.ParseAzureDevopsPullEvent(body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s", err, reqID)
+		return
+	}
+	if pull.State == models.Open {
+		if event.EventType == azuredevops.PullUpdatedEvent && !strings.Contains(event.Message.Text, azuredevops.SourceBranchUpdatedText) {
+			e.respond(w, logging.Debug, http.StatusOK, "Ignoring pull request event since the source branch wasn't updated %s", reqID)
+			return
+		}
+		e.autoplan(w, baseRepo, headRepo, pull, vcs.AzureDevops)
+		return
+	}
+	if err := e.PullCleaner.CleanUpPull(baseRepo, pull, vcs.AzureDevops); err != nil {
+		e.respond(w, logging.Error, http.StatusInternalServerError, "Error cleaning pull request: %s", err)
+		return
+	}
+	e.Logger.Info("deleted locks and workspace for repo %s, pull %d", baseRepo.FullName, pull.Num)
+	fmt.Fprintln(w, "Pull request cleaned successfully")
+}
+
+func (e *EventsController) handleGiteaPost(w http.ResponseWriter, r *http.Request) {
+	eventType := r.Header.Get(gitea.EventTypeHeader)
+	reqID := "X-Gitea-Delivery=" + r.Header.Get(gitea.RequestIDHeader)
+	defer r.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Unable to read body: %s %s", err, reqID)
+		return
+	}
+	if len(e.GiteaWebHookSecret) > 0 {
+		if err := gitea.ValidateSignature(body, r.Header.Get(gitea.SignatureHeader), e.GiteaWebHookSecret); err != nil {
+			e.respond(w, logging.Warn, http.StatusBadRequest, "%s %s", err, reqID)
+			return
+		}
+	}
+	switch eventType {
+	case gitea.IssueCommentEvent:
+		e.HandleGiteaCommentEvent(w, body, reqID)
+	case gitea.PullRequestEvent:
+		e.HandleGiteaPullRequestEvent(w, body, reqID)
+	default:
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event %s", reqID)
+	}
+}
+
+// HandleGiteaCommentEvent handles comment events from Gitea where Atlantis
+// commands can come from. It's exported to make testing easier.
+func (e *EventsController) HandleGiteaCommentEvent(w http.ResponseWriter, body []byte, reqID string) {
+	var event gitea.IssueCommentPayload
+	if err := json.Unmarshal(body, &event); err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %s %s", err, reqID)
+		return
+	}
+	if event.Action != "created" {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring comment event since action was not created %s", reqID)
+		return
+	}
+	if !event.IsPull {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring comment event since it wasn't on a pull request %s", reqID)
+		return
+	}
+
+	baseRepo, user, pullNum, comment, err := e.Parser.ParseGiteaIssueCommentEvent(body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %v %s", err, reqID)
+		return
+	}
+	command, err := e.Parser.DetermineCommand(comment, vcs.Gitea)
+	if err != nil {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring: %s %s", err, reqID)
+		return
+	}
+
+	e.executeCommand(w, baseRepo, models.Repo{}, user, pullNum, command, vcs.Gitea)
+}
+
+// HandleGiteaPullRequestEvent will run plan automatically if the pull request
+// was opened, reopened or updated and delete any locks associated with the
+// pull request if it was closed. It's exported to make testing easier.
+func (e *EventsController) HandleGiteaPullRequestEvent(w http.ResponseWriter, body []byte, reqID string) {
+	var event gitea.Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %s %s", err, reqID)
+		return
+	}
+	action := event.Action
+	if action != "opened" && action != "reopened" && action != "synchronized" && action != "closed" {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring pull request event since action was not opened, reopened, synchronized or closed %s", reqID)
+		return
+	}
+	pull, baseRepo, headRepo, err := e.Parser.ParseGiteaPullEvent(body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s", err, reqID)
+		return
+	}
+	if action != "closed" {
+		e.autoplan(w, baseRepo, headRepo, pull, vcs.Gitea)
+		return
+	}
+	if err := e.PullCleaner.CleanUpPull(baseRepo, pull, vcs.Gitea); err != nil {
+		e.respond(w, logging.Error, http.StatusInternalServerError, "Error cleaning pull request: %s", err)
+		return
+	}
+	e.Logger.Info("deleted locks and workspace for repo %s, pull %d", baseRepo.FullName, pull.Num)
+	fmt.Fprintln(w, "Pull request cleaned successfully")
+}
+
+// autoplan runs plan in the default workspace for pull requests that have
+// just been opened or updated, if autoplanning is enabled for baseRepo.
+func (e *EventsController) autoplan(w http.ResponseWriter, baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, host vcs.Host) {
+	if e.DisableAutoplan {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring pull request event since autoplan is disabled")
+		return
+	}
+	if e.AutoplanWhitelist != nil && !e.AutoplanWhitelist.IsWhitelisted(baseRepo.FullName) {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring pull request event since autoplan is not enabled for repo %s", baseRepo.FullName)
+		return
+	}
+
+	// The command is run as the pull request author since there was no
+	// comment.
+	user := models.User{Username: pull.Author}
+	cmd := &events.Command{Name: events.Plan, Workspace: events.DefaultWorkspace, Autoplan: true}
+
+	e.executeCommand(w, baseRepo, headRepo, user, pull.Num, cmd, host)
+}
+
+// executeCommand hands the command to the CommandRunner, which queues it to
+// run in the background, and responds once it's been accepted. If it can't
+// be, the VCS host gets an error so the failed delivery can be seen and
+// redelivered.
+func (e *EventsController) executeCommand(w http.ResponseWriter, baseRepo models.Repo, headRepo models.Repo, user models.User, pullNum int, cmd *events.Command, host vcs.Host) {
+	if err := e.CommandRunner.ExecuteCommand(baseRepo, headRepo, user, pullNum, cmd, host); err != nil {
+		e.respond(w, logging.Error, http.StatusInternalServerError, "Failed to queue command: %s", err)
+		return
+	}
+	fmt.Fprintln(w, "Processing...")
+}
+
 // supportsHost returns true if h is in e.SupportedVCSHosts and false otherwise.
 func (e *EventsController) supportsHost(h vcs.Host) bool {
 	for _, supported := range e.SupportedVCSHosts {
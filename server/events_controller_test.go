@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
-	"time"
 
 	"github.com/hootsuite/atlantis/server"
 	"github.com/hootsuite/atlantis/server/events"
@@ -14,6 +13,10 @@ import (
 	"github.com/hootsuite/atlantis/server/events/mocks/matchers"
 	"github.com/hootsuite/atlantis/server/events/models"
 	"github.com/hootsuite/atlantis/server/events/vcs"
+	"github.com/hootsuite/atlantis/server/events/vcs/azuredevops"
+	"github.com/hootsuite/atlantis/server/events/vcs/bitbucketcloud"
+	"github.com/hootsuite/atlantis/server/events/vcs/bitbucketserver"
+	"github.com/hootsuite/atlantis/server/events/vcs/gitea"
 	"github.com/hootsuite/atlantis/server/logging"
 	"github.com/hootsuite/atlantis/server/mocks"
 	"github.com/lkysow/go-gitlab"
@@ -151,8 +154,6 @@ func TestPost_GitlabCommentSuccess(t *testing.T) {
 	e.Post(w, eventsReq)
 	responseContains(t, w, http.StatusOK, "Processing...")
 
-	// wait for 200ms so goroutine is called
-	time.Sleep(200 * time.Millisecond)
 	cr.VerifyWasCalledOnce().ExecuteCommand(models.Repo{}, models.Repo{}, models.User{}, 0, nil, vcs.Gitlab)
 }
 
@@ -171,32 +172,173 @@ func TestPost_GithubCommentSuccess(t *testing.T) {
 	e.Post(w, eventsReq)
 	responseContains(t, w, http.StatusOK, "Processing...")
 
-	// wait for 200ms so goroutine is called
-	time.Sleep(200 * time.Millisecond)
 	cr.VerifyWasCalledOnce().ExecuteCommand(baseRepo, baseRepo, user, 1, &cmd, vcs.Github)
 }
 
-func TestPost_GithubPullRequestNotClosed(t *testing.T) {
-	t.Log("when the event is a github pull reuqest but it's not a closed event we ignore it")
+func TestPost_GithubCommentNotQueued(t *testing.T) {
+	t.Log("when the command can't be queued we return an error instead of saying it's processing")
+	e, v, _, p, cr, _ := setup(t)
+	eventsReq.Header.Set(githubHeader, "issue_comment")
+	event := `{"action": "created"}`
+	When(v.Validate(eventsReq, secret)).ThenReturn([]byte(event), nil)
+	baseRepo := models.Repo{}
+	user := models.User{}
+	cmd := events.Command{}
+	When(p.ParseGithubIssueCommentEvent(matchers.AnyPtrToGithubIssueCommentEvent())).ThenReturn(baseRepo, user, 1, nil)
+	When(p.DetermineCommand("", vcs.Github)).ThenReturn(&cmd, nil)
+	When(cr.ExecuteCommand(baseRepo, baseRepo, user, 1, &cmd, vcs.Github)).ThenReturn(errors.New("err"))
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusInternalServerError, "Failed to queue command: err")
+}
+
+func TestPost_GithubCheckRunIgnoredAction(t *testing.T) {
+	t.Log("when the event is a github check run event but a button wasn't clicked we ignore it")
+	e, v, _, p, cr, _ := setup(t)
+	eventsReq.Header.Set(githubHeader, "check_run")
+	When(v.Validate(eventsReq, secret)).ThenReturn([]byte(`{"action": "created"}`), nil)
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusOK, "Ignoring check run event since action was not requested_action")
+	p.VerifyWasCalled(Never()).ParseGithubCheckRunEvent(matchers.AnySliceOfByte())
+	cr.VerifyWasCalled(Never()).ExecuteCommand(matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsUser(), AnyInt(), matchers.AnyPtrToEventsCommand(), matchers.AnyVcsHost())
+}
+
+func TestPost_GithubCheckRunInvalid(t *testing.T) {
+	t.Log("when the check run event can't be parsed we return a 400")
+	e, v, _, p, _, _ := setup(t)
+	eventsReq.Header.Set(githubHeader, "check_run")
+	event := []byte(`{"action": "requested_action"}`)
+	When(v.Validate(eventsReq, secret)).ThenReturn(event, nil)
+	When(p.ParseGithubCheckRunEvent(event)).ThenReturn(models.Repo{}, models.User{}, 0, nil, errors.New("err"))
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusBadRequest, "Failed parsing event: err")
+}
+
+func TestPost_GithubCheckRunSuccess(t *testing.T) {
+	t.Log("when a button on a check run is clicked we run its command")
+	e, v, _, p, cr, _ := setup(t)
+	eventsReq.Header.Set(githubHeader, "check_run")
+	event := []byte(`{"action": "requested_action"}`)
+	When(v.Validate(eventsReq, secret)).ThenReturn(event, nil)
+	baseRepo := models.Repo{FullName: "owner/repo"}
+	user := models.User{Username: "user"}
+	cmd := events.Command{Name: events.Apply, Dir: "dir", Workspace: "default"}
+	When(p.ParseGithubCheckRunEvent(event)).ThenReturn(baseRepo, user, 1, &cmd, nil)
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusOK, "Processing...")
+
+	cr.VerifyWasCalledOnce().ExecuteCommand(baseRepo, models.Repo{}, user, 1, &cmd, vcs.Github)
+}
+
+func TestPost_GithubPullRequestUnsupportedAction(t *testing.T) {
+	t.Log("when the event is a github pull request but it's not opened, reopened, synchronize or closed we ignore it")
 	e, v, _, _, _, _ := setup(t)
 	eventsReq.Header.Set(githubHeader, "pull_request")
+	event := `{"action": "edited"}`
+	When(v.Validate(eventsReq, secret)).ThenReturn([]byte(event), nil)
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusOK, "Ignoring pull request event since action was not opened, reopened, synchronize or closed")
+}
+
+func TestPost_GithubPullRequestAutoplan(t *testing.T) {
+	for _, action := range []string{"opened", "reopened", "synchronize"} {
+		t.Run(action, func(t *testing.T) {
+			t.Log("when the event is a github pull request that was opened, reopened or updated we run plan automatically")
+			e, v, _, p, cr, _ := setup(t)
+			eventsReq.Header.Set(githubHeader, "pull_request")
+			event := `{"action": "` + action + `"}`
+			When(v.Validate(eventsReq, secret)).ThenReturn([]byte(event), nil)
+			baseRepo := models.Repo{FullName: "owner/base"}
+			headRepo := models.Repo{FullName: "owner/head"}
+			pull := models.PullRequest{Num: 1, Author: "author"}
+			When(p.ParseGithubPull(matchers.AnyPtrToGithubPullRequest())).ThenReturn(pull, headRepo, nil)
+			When(p.ParseGithubRepo(matchers.AnyPtrToGithubRepository())).ThenReturn(baseRepo, nil)
+			w := httptest.NewRecorder()
+			e.Post(w, eventsReq)
+			responseContains(t, w, http.StatusOK, "Processing...")
+
+			cr.VerifyWasCalledOnce().ExecuteCommand(
+				baseRepo,
+				headRepo,
+				models.User{Username: "author"},
+				1,
+				&events.Command{Name: events.Plan, Workspace: "default", Autoplan: true},
+				vcs.Github)
+		})
+	}
+}
+
+func TestPost_GithubPullRequestAutoplanDisabled(t *testing.T) {
+	t.Log("when autoplan is disabled we don't run plan when a pull request is opened")
+	e, v, _, p, _, _ := setup(t)
+	e.DisableAutoplan = true
+	eventsReq.Header.Set(githubHeader, "pull_request")
+	event := `{"action": "opened"}`
+	When(v.Validate(eventsReq, secret)).ThenReturn([]byte(event), nil)
+	When(p.ParseGithubPull(matchers.AnyPtrToGithubPullRequest())).ThenReturn(models.PullRequest{}, models.Repo{}, nil)
+	When(p.ParseGithubRepo(matchers.AnyPtrToGithubRepository())).ThenReturn(models.Repo{}, nil)
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusOK, "Ignoring pull request event since autoplan is disabled")
+}
+
+func TestPost_GithubPullRequestAutoplanNotWhitelisted(t *testing.T) {
+	t.Log("when the repo isn't in the autoplan whitelist we don't run plan when a pull request is opened")
+	e, v, _, p, _, _ := setup(t)
+	e.AutoplanWhitelist = &events.RepoWhitelist{Whitelist: "owner/other"}
+	eventsReq.Header.Set(githubHeader, "pull_request")
 	event := `{"action": "opened"}`
 	When(v.Validate(eventsReq, secret)).ThenReturn([]byte(event), nil)
+	When(p.ParseGithubPull(matchers.AnyPtrToGithubPullRequest())).ThenReturn(models.PullRequest{}, models.Repo{}, nil)
+	When(p.ParseGithubRepo(matchers.AnyPtrToGithubRepository())).ThenReturn(models.Repo{FullName: "owner/repo"}, nil)
 	w := httptest.NewRecorder()
 	e.Post(w, eventsReq)
-	responseContains(t, w, http.StatusOK, "Ignoring pull request event since action was not closed")
+	responseContains(t, w, http.StatusOK, "Ignoring pull request event since autoplan is not enabled for repo owner/repo")
 }
 
-func TestPost_GitlabMergeRequestNotClosed(t *testing.T) {
-	t.Log("when the event is a gitlab merge request but it's not a closed event we ignore it")
+func TestPost_GitlabMergeRequestUnsupportedAction(t *testing.T) {
+	t.Log("when the event is an open gitlab merge request but the action isn't open, reopen or update we ignore it")
 	e, _, gl, p, _, _ := setup(t)
 	eventsReq.Header.Set(gitlabHeader, "value")
 	event := gitlab.MergeEvent{}
+	event.ObjectAttributes.Action = "approved"
 	When(gl.Validate(eventsReq, secret)).ThenReturn(event, nil)
-	When(p.ParseGitlabMergeEvent(event)).ThenReturn(models.PullRequest{State: models.Open}, models.Repo{})
+	When(p.ParseGitlabMergeEvent(event)).ThenReturn(models.PullRequest{State: models.Open}, models.Repo{}, models.Repo{})
 	w := httptest.NewRecorder()
 	e.Post(w, eventsReq)
-	responseContains(t, w, http.StatusOK, "Ignoring opened merge request event")
+	responseContains(t, w, http.StatusOK, "Ignoring merge request event since action was not open, reopen or update")
+}
+
+func TestPost_GitlabMergeRequestAutoplan(t *testing.T) {
+	for _, action := range []string{"open", "reopen", "update"} {
+		t.Run(action, func(t *testing.T) {
+			t.Log("when the event is a gitlab merge request that was opened, reopened or updated we run plan automatically")
+			e, _, gl, p, cr, _ := setup(t)
+			eventsReq.Header.Set(gitlabHeader, "value")
+			event := gitlab.MergeEvent{}
+			event.ObjectAttributes.Action = action
+			When(gl.Validate(eventsReq, secret)).ThenReturn(event, nil)
+			baseRepo := models.Repo{FullName: "owner/base"}
+			headRepo := models.Repo{FullName: "owner/head"}
+			pull := models.PullRequest{Num: 1, Author: "author", State: models.Open}
+			When(p.ParseGitlabMergeEvent(event)).ThenReturn(pull, baseRepo, headRepo)
+			w := httptest.NewRecorder()
+			e.Post(w, eventsReq)
+			responseContains(t, w, http.StatusOK, "Processing...")
+
+			cr.VerifyWasCalledOnce().ExecuteCommand(
+				baseRepo,
+				headRepo,
+				models.User{Username: "author"},
+				1,
+				&events.Command{Name: events.Plan, Workspace: "default", Autoplan: true},
+				vcs.Gitlab)
+		})
+	}
 }
 
 func TestPost_GithubPullRequestInvalid(t *testing.T) {
@@ -252,7 +394,7 @@ func TestPost_GitlabMergeRequestErrCleaningPull(t *testing.T) {
 	When(gl.Validate(eventsReq, secret)).ThenReturn(event, nil)
 	repo := models.Repo{}
 	pullRequest := models.PullRequest{State: models.Closed}
-	When(p.ParseGitlabMergeEvent(event)).ThenReturn(pullRequest, repo)
+	When(p.ParseGitlabMergeEvent(event)).ThenReturn(pullRequest, repo, repo)
 	When(c.CleanUpPull(repo, pullRequest, vcs.Gitlab)).ThenReturn(errors.New("err"))
 	w := httptest.NewRecorder()
 	e.Post(w, eventsReq)
@@ -284,12 +426,436 @@ func TestPost_GitlabMergeRequestSuccess(t *testing.T) {
 	When(gl.Validate(eventsReq, secret)).ThenReturn(event, nil)
 	repo := models.Repo{}
 	pullRequest := models.PullRequest{State: models.Closed}
-	When(p.ParseGitlabMergeEvent(event)).ThenReturn(pullRequest, repo)
+	When(p.ParseGitlabMergeEvent(event)).ThenReturn(pullRequest, repo, repo)
 	w := httptest.NewRecorder()
 	e.Post(w, eventsReq)
 	responseContains(t, w, http.StatusOK, "Merge request cleaned successfully")
 }
 
+func TestPost_UnsupportedVCSBitbucket(t *testing.T) {
+	t.Log("when the request is for an unsupported vcs a 400 is returned")
+	e, _, _, _, _, _ := setup(t)
+	e.SupportedVCSHosts = nil
+	setBitbucketCloudHeaders(bitbucketcloud.PullCommentCreatedHeader)
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusBadRequest, "Ignoring request since not configured to support Bitbucket")
+}
+
+func TestPost_UnsupportedBitbucketEvent(t *testing.T) {
+	t.Log("when the event type is an unsupported bitbucket event we ignore it")
+	e, _, _, _, _, _ := setup(t)
+	setBitbucketCloudHeaders("repo:push")
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusOK, "Ignoring unsupported event type repo:push")
+}
+
+func TestPost_BitbucketCommentInvalid(t *testing.T) {
+	t.Log("when the event is a bitbucket comment that can't be parsed a 400 is returned")
+	e, _, _, p, _, _ := setup(t)
+	setBitbucketCloudHeaders(bitbucketcloud.PullCommentCreatedHeader)
+	When(p.ParseBitbucketCloudCommentEvent(matchers.AnySliceOfByte())).ThenReturn(models.PullRequest{}, models.Repo{}, models.Repo{}, models.User{}, "", errors.New("err"))
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusBadRequest, "Failed parsing event: err")
+}
+
+func TestPost_BitbucketCommentSuccess(t *testing.T) {
+	t.Log("when the event is a bitbucket comment with a valid command we call the command handler")
+	e, _, _, p, cr, _ := setup(t)
+	setBitbucketCloudHeaders(bitbucketcloud.PullCommentCreatedHeader)
+	baseRepo := models.Repo{FullName: "owner/base"}
+	headRepo := models.Repo{FullName: "owner/head"}
+	user := models.User{Username: "user"}
+	cmd := events.Command{Name: events.Plan}
+	When(p.ParseBitbucketCloudCommentEvent(matchers.AnySliceOfByte())).ThenReturn(models.PullRequest{Num: 1}, baseRepo, headRepo, user, "atlantis plan", nil)
+	When(p.DetermineCommand("atlantis plan", vcs.Bitbucket)).ThenReturn(&cmd, nil)
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusOK, "Processing...")
+
+	cr.VerifyWasCalledOnce().ExecuteCommand(baseRepo, headRepo, user, 1, &cmd, vcs.Bitbucket)
+}
+
+func TestPost_BitbucketPullRequestAutoplan(t *testing.T) {
+	for _, eventType := range []string{bitbucketcloud.PullCreatedHeader, bitbucketcloud.PullUpdatedHeader} {
+		t.Run(eventType, func(t *testing.T) {
+			t.Log("when the event is a bitbucket pull request that was created or updated we run plan automatically")
+			e, _, _, p, cr, _ := setup(t)
+			setBitbucketCloudHeaders(eventType)
+			baseRepo := models.Repo{FullName: "owner/base"}
+			headRepo := models.Repo{FullName: "owner/head"}
+			pull := models.PullRequest{Num: 1, Author: "author"}
+			When(p.ParseBitbucketCloudPullEvent(matchers.AnySliceOfByte())).ThenReturn(pull, baseRepo, headRepo, nil)
+			w := httptest.NewRecorder()
+			e.Post(w, eventsReq)
+			responseContains(t, w, http.StatusOK, "Processing...")
+
+			cr.VerifyWasCalledOnce().ExecuteCommand(
+				baseRepo,
+				headRepo,
+				models.User{Username: "author"},
+				1,
+				&events.Command{Name: events.Plan, Workspace: "default", Autoplan: true},
+				vcs.Bitbucket)
+		})
+	}
+}
+
+func TestPost_BitbucketPullRequestClosed(t *testing.T) {
+	for _, eventType := range []string{bitbucketcloud.PullFulfilledHeader, bitbucketcloud.PullRejectedHeader} {
+		t.Run(eventType, func(t *testing.T) {
+			t.Log("when the event is a bitbucket pull request that was merged or declined we clean it up")
+			e, _, _, p, _, c := setup(t)
+			setBitbucketCloudHeaders(eventType)
+			repo := models.Repo{FullName: "owner/repo"}
+			pull := models.PullRequest{Num: 1, State: models.Closed}
+			When(p.ParseBitbucketCloudPullEvent(matchers.AnySliceOfByte())).ThenReturn(pull, repo, repo, nil)
+			w := httptest.NewRecorder()
+			e.Post(w, eventsReq)
+			responseContains(t, w, http.StatusOK, "Pull request cleaned successfully")
+			c.VerifyWasCalledOnce().CleanUpPull(repo, pull, vcs.Bitbucket)
+		})
+	}
+}
+
+func setBitbucketCloudHeaders(eventType string) {
+	eventsReq.Header.Set(bitbucketcloud.EventTypeHeader, eventType)
+	eventsReq.Header.Set(bitbucketcloud.RequestIDHeader, "request-id")
+}
+
+func TestPost_UnsupportedVCSBitbucketServer(t *testing.T) {
+	t.Log("when the request is for an unsupported vcs a 400 is returned")
+	e, _, _, _, _, _ := setup(t)
+	e.SupportedVCSHosts = nil
+	setBitbucketServerHeaders(bitbucketserver.PullCommentCreatedHeader)
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusBadRequest, "Ignoring request since not configured to support Bitbucket Server")
+}
+
+func TestPost_BitbucketServerInvalidSignature(t *testing.T) {
+	t.Log("when the bitbucket server request isn't signed by the secret a 400 is returned")
+	e, _, _, p, _, _ := setup(t)
+	e.BitbucketWebHookSecret = secret
+	setBitbucketServerHeaders(bitbucketserver.PullCommentCreatedHeader)
+	eventsReq.Header.Set(bitbucketserver.SignatureHeader, "sha256=abcd")
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusBadRequest, "request signature didn't match the webhook secret")
+	p.VerifyWasCalled(Never()).ParseBitbucketServerCommentEvent(matchers.AnySliceOfByte())
+}
+
+func TestPost_BitbucketServerCommentSuccess(t *testing.T) {
+	t.Log("when the event is a signed bitbucket server comment with a valid command we call the command handler")
+	e, _, _, p, cr, _ := setup(t)
+	e.BitbucketWebHookSecret = secret
+	setBitbucketServerHeaders(bitbucketserver.PullCommentCreatedHeader)
+	// The HMAC-SHA256 of an empty body with the key "secret".
+	eventsReq.Header.Set(bitbucketserver.SignatureHeader, "sha256=f9e66e179b6747ae54108f82f8ade8b3c25d76fd30afde6c395822c530196169")
+	baseRepo := models.Repo{FullName: "PROJ/base"}
+	headRepo := models.Repo{FullName: "PROJ/head"}
+	user := models.User{Username: "user"}
+	cmd := events.Command{Name: events.Plan}
+	When(p.ParseBitbucketServerCommentEvent(matchers.AnySliceOfByte())).ThenReturn(models.PullRequest{Num: 1}, baseRepo, headRepo, user, "atlantis plan", nil)
+	When(p.DetermineCommand("atlantis plan", vcs.BitbucketServer)).ThenReturn(&cmd, nil)
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusOK, "Processing...")
+
+	cr.VerifyWasCalledOnce().ExecuteCommand(baseRepo, headRepo, user, 1, &cmd, vcs.BitbucketServer)
+}
+
+func TestPost_BitbucketServerPullRequestAutoplan(t *testing.T) {
+	for _, eventType := range []string{bitbucketserver.PullCreatedHeader, bitbucketserver.PullFromRefUpdatedHeader} {
+		t.Run(eventType, func(t *testing.T) {
+			t.Log("when the event is a bitbucket server pull request that was opened or updated we run plan automatically")
+			e, _, _, p, cr, _ := setup(t)
+			setBitbucketServerHeaders(eventType)
+			baseRepo := models.Repo{FullName: "PROJ/base"}
+			headRepo := models.Repo{FullName: "PROJ/head"}
+			pull := models.PullRequest{Num: 1, Author: "author"}
+			When(p.ParseBitbucketServerPullEvent(matchers.AnySliceOfByte())).ThenReturn(pull, baseRepo, headRepo, nil)
+			w := httptest.NewRecorder()
+			e.Post(w, eventsReq)
+			responseContains(t, w, http.StatusOK, "Processing...")
+
+			cr.VerifyWasCalledOnce().ExecuteCommand(
+				baseRepo,
+				headRepo,
+				models.User{Username: "author"},
+				1,
+				&events.Command{Name: events.Plan, Workspace: "default", Autoplan: true},
+				vcs.BitbucketServer)
+		})
+	}
+}
+
+func TestPost_BitbucketServerPullRequestClosed(t *testing.T) {
+	for _, eventType := range []string{bitbucketserver.PullMergedHeader, bitbucketserver.PullDeclinedHeader} {
+		t.Run(eventType, func(t *testing.T) {
+			t.Log("when the event is a bitbucket server pull request that was merged or declined we clean it up")
+			e, _, _, p, _, c := setup(t)
+			setBitbucketServerHeaders(eventType)
+			repo := models.Repo{FullName: "PROJ/repo"}
+			pull := models.PullRequest{Num: 1, State: models.Closed}
+			When(p.ParseBitbucketServerPullEvent(matchers.AnySliceOfByte())).ThenReturn(pull, repo, repo, nil)
+			w := httptest.NewRecorder()
+			e.Post(w, eventsReq)
+			responseContains(t, w, http.StatusOK, "Pull request cleaned successfully")
+			c.VerifyWasCalledOnce().CleanUpPull(repo, pull, vcs.BitbucketServer)
+		})
+	}
+}
+
+func setBitbucketServerHeaders(eventType string) {
+	eventsReq.Header.Set(bitbucketserver.EventTypeHeader, eventType)
+	eventsReq.Header.Set(bitbucketserver.RequestIDHeader, "request-id")
+}
+
+func TestPost_UnsupportedVCSAzureDevops(t *testing.T) {
+	t.Log("when the request is for an unsupported vcs a 400 is returned")
+	e, _, _, _, _, _ := setup(t)
+	e.SupportedVCSHosts = nil
+	azureDevopsReq(t, "{}")
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusBadRequest, "Ignoring request since not configured to support Azure DevOps")
+}
+
+func TestPost_AzureDevopsInvalidBasicAuth(t *testing.T) {
+	t.Log("when the azure devops request doesn't have the expected basic auth a 401 is returned")
+	e, _, _, p, _, _ := setup(t)
+	e.AzureDevopsWebhookUser = []byte("user")
+	e.AzureDevopsWebhookPassword = []byte("password")
+	azureDevopsReq(t, `{"eventType": "git.pullrequest.commented"}`)
+	eventsReq.SetBasicAuth("user", "wrong")
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusUnauthorized, "Request didn't have the expected basic auth credentials")
+	p.VerifyWasCalled(Never()).ParseAzureDevopsCommentEvent(matchers.AnySliceOfByte())
+}
+
+func TestPost_AzureDevopsUnsupportedEvent(t *testing.T) {
+	t.Log("when the event type is an unsupported azure devops event we ignore it")
+	e, _, _, _, _, _ := setup(t)
+	azureDevopsReq(t, `{"eventType": "git.push"}`)
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusOK, "Ignoring unsupported event type git.push")
+}
+
+func TestPost_AzureDevopsCommentSuccess(t *testing.T) {
+	t.Log("when the event is an authenticated azure devops comment with a valid command we call the command handler")
+	e, _, _, p, cr, _ := setup(t)
+	e.AzureDevopsWebhookUser = []byte("user")
+	e.AzureDevopsWebhookPassword = []byte("password")
+	azureDevopsReq(t, `{"eventType": "git.pullrequest.commented"}`)
+	eventsReq.SetBasicAuth("user", "password")
+	baseRepo := models.Repo{FullName: "org/project/base"}
+	user := models.User{Username: "user"}
+	cmd := events.Command{Name: events.Plan}
+	When(p.ParseAzureDevopsCommentEvent(matchers.AnySliceOfByte())).ThenReturn(models.PullRequest{Num: 1}, baseRepo, baseRepo, user, "atlantis plan", nil)
+	When(p.DetermineCommand("atlantis plan", vcs.AzureDevops)).ThenReturn(&cmd, nil)
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusOK, "Processing...")
+
+	cr.VerifyWasCalledOnce().ExecuteCommand(baseRepo, baseRepo, user, 1, &cmd, vcs.AzureDevops)
+}
+
+func TestPost_AzureDevopsPullRequestAutoplan(t *testing.T) {
+	cases := []struct {
+		event       string
+		expAutoplan bool
+	}{
+		{`{"eventType": "git.pullrequest.created"}`, true},
+		{`{"eventType": "git.pullrequest.updated", "message": {"text": "user updated the source branch of pull request 1"}}`, true},
+		{`{"eventType": "git.pullrequest.updated", "message": {"text": "user approved pull request 1"}}`, false},
+	}
+	for _, c := range cases {
+		t.Run(c.event, func(t *testing.T) {
+			t.Log("when the event is an azure devops pull request that was created or had its source branch updated we run plan automatically")
+			e, _, _, p, cr, _ := setup(t)
+			azureDevopsReq(t, c.event)
+			repo := models.Repo{FullName: "org/project/repo"}
+			pull := models.PullRequest{Num: 1, Author: "author", State: models.Open}
+			When(p.ParseAzureDevopsPullEvent(matchers.AnySliceOfByte())).ThenReturn(pull, repo, repo, nil)
+			w := httptest.NewRecorder()
+			e.Post(w, eventsReq)
+			if !c.expAutoplan {
+				responseContains(t, w, http.StatusOK, "Ignoring pull request event since the source branch wasn't updated")
+				return
+			}
+			responseContains(t, w, http.StatusOK, "Processing...")
+
+			cr.VerifyWasCalledOnce().ExecuteCommand(
+				repo,
+				repo,
+				models.User{Username: "author"},
+				1,
+				&events.Command{Name: events.Plan, Workspace: "default", Autoplan: true},
+				vcs.AzureDevops)
+		})
+	}
+}
+
+func TestPost_AzureDevopsPullRequestClosed(t *testing.T) {
+	t.Log("when the event is an azure devops pull request that was completed or abandoned we clean it up")
+	e, _, _, p, _, c := setup(t)
+	azureDevopsReq(t, `{"eventType": "git.pullrequest.updated", "message": {"text": "user completed pull request 1"}}`)
+	repo := models.Repo{FullName: "org/project/repo"}
+	pull := models.PullRequest{Num: 1, State: models.Closed}
+	When(p.ParseAzureDevopsPullEvent(matchers.AnySliceOfByte())).ThenReturn(pull, repo, repo, nil)
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusOK, "Pull request cleaned successfully")
+	c.VerifyWasCalledOnce().CleanUpPull(repo, pull, vcs.AzureDevops)
+}
+
+func TestPost_UnsupportedVCSGitea(t *testing.T) {
+	t.Log("when the request is for an unsupported vcs a 400 is returned")
+	e, _, _, _, _, _ := setup(t)
+	e.SupportedVCSHosts = nil
+	giteaReq(t, gitea.IssueCommentEvent, "{}")
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusBadRequest, "Ignoring request since not configured to support Gitea")
+}
+
+func TestPost_GiteaTakesPrecedenceOverGithubHeader(t *testing.T) {
+	t.Log("when gitea sends github's event header too the request is handled as a gitea request")
+	e, _, _, _, _, _ := setup(t)
+	giteaReq(t, "push", "{}")
+	eventsReq.Header.Set(githubHeader, "push")
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusOK, "Ignoring unsupported event X-Gitea-Delivery=delivery-id")
+}
+
+func TestPost_GiteaInvalidSignature(t *testing.T) {
+	t.Log("when the gitea request isn't signed by the secret a 400 is returned")
+	e, _, _, p, _, _ := setup(t)
+	e.GiteaWebHookSecret = secret
+	giteaReq(t, gitea.IssueCommentEvent, `{"action": "created", "is_pull": true}`)
+	eventsReq.Header.Set(gitea.SignatureHeader, "abcd")
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusBadRequest, "request signature didn't match the webhook secret")
+	p.VerifyWasCalled(Never()).ParseGiteaIssueCommentEvent(matchers.AnySliceOfByte())
+}
+
+func TestPost_GiteaCommentIgnored(t *testing.T) {
+	cases := []struct {
+		description string
+		body        string
+		expResp     string
+	}{
+		{"edited comment", `{"action": "edited", "is_pull": true}`, "Ignoring comment event since action was not created"},
+		{"comment on an issue", `{"action": "created", "is_pull": false}`, "Ignoring comment event since it wasn't on a pull request"},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			e, _, _, p, _, _ := setup(t)
+			giteaReq(t, gitea.IssueCommentEvent, c.body)
+			w := httptest.NewRecorder()
+			e.Post(w, eventsReq)
+			responseContains(t, w, http.StatusOK, c.expResp)
+			p.VerifyWasCalled(Never()).ParseGiteaIssueCommentEvent(matchers.AnySliceOfByte())
+		})
+	}
+}
+
+func TestPost_GiteaCommentSuccess(t *testing.T) {
+	t.Log("when the event is a signed gitea comment with a valid command we call the command handler")
+	e, _, _, p, cr, _ := setup(t)
+	e.GiteaWebHookSecret = secret
+	giteaReq(t, gitea.IssueCommentEvent, `{"action":"created","is_pull":true}`)
+	// The HMAC-SHA256 of the body with the key "secret".
+	eventsReq.Header.Set(gitea.SignatureHeader, "32662b27bc32a094421e2be6e18cdb73fc96cffe5ec791be3ddc10d019aec798")
+	baseRepo := models.Repo{FullName: "owner/repo"}
+	user := models.User{Username: "user"}
+	cmd := events.Command{Name: events.Plan}
+	When(p.ParseGiteaIssueCommentEvent(matchers.AnySliceOfByte())).ThenReturn(baseRepo, user, 1, "atlantis plan", nil)
+	When(p.DetermineCommand("atlantis plan", vcs.Gitea)).ThenReturn(&cmd, nil)
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusOK, "Processing...")
+
+	cr.VerifyWasCalledOnce().ExecuteCommand(baseRepo, models.Repo{}, user, 1, &cmd, vcs.Gitea)
+}
+
+func TestPost_GiteaPullRequestAutoplan(t *testing.T) {
+	for _, action := range []string{"opened", "reopened", "synchronized"} {
+		t.Run(action, func(t *testing.T) {
+			t.Log("when the event is a gitea pull request that was opened or updated we run plan automatically")
+			e, _, _, p, cr, _ := setup(t)
+			giteaReq(t, gitea.PullRequestEvent, `{"action": "`+action+`"}`)
+			baseRepo := models.Repo{FullName: "owner/repo"}
+			headRepo := models.Repo{FullName: "forker/fork"}
+			pull := models.PullRequest{Num: 1, Author: "author"}
+			When(p.ParseGiteaPullEvent(matchers.AnySliceOfByte())).ThenReturn(pull, baseRepo, headRepo, nil)
+			w := httptest.NewRecorder()
+			e.Post(w, eventsReq)
+			responseContains(t, w, http.StatusOK, "Processing...")
+
+			cr.VerifyWasCalledOnce().ExecuteCommand(
+				baseRepo,
+				headRepo,
+				models.User{Username: "author"},
+				1,
+				&events.Command{Name: events.Plan, Workspace: "default", Autoplan: true},
+				vcs.Gitea)
+		})
+	}
+}
+
+func TestPost_GiteaPullRequestIgnoredAction(t *testing.T) {
+	t.Log("when the event is a gitea pull request with an action we don't handle it's ignored")
+	e, _, _, p, _, _ := setup(t)
+	giteaReq(t, gitea.PullRequestEvent, `{"action": "label_updated"}`)
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusOK, "Ignoring pull request event since action was not opened, reopened, synchronized or closed")
+	p.VerifyWasCalled(Never()).ParseGiteaPullEvent(matchers.AnySliceOfByte())
+}
+
+func TestPost_GiteaPullRequestClosed(t *testing.T) {
+	t.Log("when the event is a gitea pull request that was closed we clean it up")
+	e, _, _, p, _, c := setup(t)
+	giteaReq(t, gitea.PullRequestEvent, `{"action": "closed"}`)
+	repo := models.Repo{FullName: "owner/repo"}
+	pull := models.PullRequest{Num: 1, State: models.Closed}
+	When(p.ParseGiteaPullEvent(matchers.AnySliceOfByte())).ThenReturn(pull, repo, repo, nil)
+	w := httptest.NewRecorder()
+	e.Post(w, eventsReq)
+	responseContains(t, w, http.StatusOK, "Pull request cleaned successfully")
+	c.VerifyWasCalledOnce().CleanUpPull(repo, pull, vcs.Gitea)
+}
+
+// giteaReq sets eventsReq to a Gitea webhook request for eventType with body.
+func giteaReq(t *testing.T, eventType string, body string) {
+	var err error
+	eventsReq, err = http.NewRequest("POST", "", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventsReq.Header.Set(gitea.EventTypeHeader, eventType)
+	eventsReq.Header.Set(gitea.RequestIDHeader, "delivery-id")
+}
+
+// azureDevopsReq sets eventsReq to an Azure DevOps service hook request with
+// body.
+func azureDevopsReq(t *testing.T, body string) {
+	var err error
+	eventsReq, err = http.NewRequest("POST", "", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventsReq.Header.Set(azuredevops.RequestIDHeader, "request-id")
+}
+
 func setup(t *testing.T) (server.EventsController, *mocks.MockGithubRequestValidator, *mocks.MockGitlabRequestParser, *emocks.MockEventParsing, *emocks.MockCommandRunner, *emocks.MockPullCleaner) {
 	RegisterMockTestingT(t)
 	eventsReq, _ = http.NewRequest("GET", "", bytes.NewBuffer(nil))
@@ -305,7 +871,7 @@ func setup(t *testing.T) (server.EventsController, *mocks.MockGithubRequestValid
 		CommandRunner:          cr,
 		PullCleaner:            c,
 		GithubWebHookSecret:    secret,
-		SupportedVCSHosts:      []vcs.Host{vcs.Github, vcs.Gitlab},
+		SupportedVCSHosts:      []vcs.Host{vcs.Github, vcs.Gitlab, vcs.Bitbucket, vcs.BitbucketServer, vcs.AzureDevops, vcs.Gitea},
 		GitlabWebHookSecret:    secret,
 		GitlabRequestParser:    gl,
 	}
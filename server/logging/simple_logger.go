@@ -78,6 +78,20 @@ func NewSimpleLogger(source string, logger *log.Logger, keepHistory bool, level
 	}
 }
 
+// NewChild creates a logger for work that runs alongside other work logged
+// by l, ex. one of the projects being planned for a pull request. It logs to
+// the same underlying logger at the same level with name added to the source
+// but keeps its own history so that concurrent work doesn't interleave in
+// l's history.
+func (l *SimpleLogger) NewChild(name string) *SimpleLogger {
+	return &SimpleLogger{
+		Source:      fmt.Sprintf("%s %s", l.Source, name),
+		Logger:      l.Logger,
+		Level:       l.Level,
+		KeepHistory: l.KeepHistory,
+	}
+}
+
 // NewNoopLogger creates a logger instance that discards all logs and never
 // writes them. Used for testing.
 func NewNoopLogger() *SimpleLogger {
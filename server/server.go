@@ -4,13 +4,20 @@ package server
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -18,6 +25,7 @@ import (
 	"github.com/elazarl/go-bindata-assetfs"
 	"github.com/gorilla/mux"
 	"github.com/hootsuite/atlantis/server/events"
+	"github.com/hootsuite/atlantis/server/events/jobs"
 	"github.com/hootsuite/atlantis/server/events/locking"
 	"github.com/hootsuite/atlantis/server/events/locking/boltdb"
 	"github.com/hootsuite/atlantis/server/events/run"
@@ -34,40 +42,135 @@ import (
 
 const LockRouteName = "lock-detail"
 
+// JobRouteName is the name of the route of a job's page.
+const JobRouteName = "job-detail"
+
+// CancelTokenHeader is the header that the lock page sends the token from
+// CancelToken in when cancelling a pull request's commands.
+const CancelTokenHeader = "X-Atlantis-Cancel-Token"
+
+// DefaultBitbucketBaseURL is the base URL of Bitbucket Cloud. Any other
+// Bitbucket base URL is treated as a Bitbucket Server installation.
+const DefaultBitbucketBaseURL = "https://bitbucket.org"
+
 // Server runs the Atlantis web server.
 type Server struct {
-	Router             *mux.Router
-	Port               int
-	CommandHandler     *events.CommandHandler
+	Router         *mux.Router
+	Port           int
+	CommandHandler *events.CommandHandler
+	CommandQueue   *events.CommandQueue
+	// DrainTimeout is how long to wait for running commands to finish when
+	// shutting down.
+	DrainTimeout       time.Duration
 	Logger             *logging.SimpleLogger
 	Locker             locking.Locker
 	AtlantisURL        string
 	EventsController   *EventsController
+	APIController      *APIController
 	IndexTemplate      TemplateWriter
 	LockDetailTemplate TemplateWriter
-	SSLCertFile        string
-	SSLKeyFile         string
+	// Jobs records the output of plans and applies so it can be followed on
+	// the job pages.
+	Jobs              *jobs.Manager
+	JobDetailTemplate TemplateWriter
+	// CSRFKey signs the tokens that the lock page sends to cancel commands so
+	// that other sites can't make users' browsers cancel them. It's random
+	// each time Atlantis starts.
+	CSRFKey     []byte
+	SSLCertFile string
+	SSLKeyFile  string
 }
 
 // Config configures Server.
 // The mapstructure tags correspond to flags in cmd/server.go and are used when
 // the config is parsed from a YAML file.
 type Config struct {
-	AtlantisURL         string `mapstructure:"atlantis-url"`
-	DataDir             string `mapstructure:"data-dir"`
+	// AllowedCommentFlags is a comma-separated list of Terraform flags that
+	// can be passed in comments. If empty, all flags that aren't denied are
+	// allowed.
+	AllowedCommentFlags string `mapstructure:"allowed-comment-flags"`
+	// APITokens is a comma-separated list of the tokens that authenticate
+	// requests to the JSON API. If empty, the API is disabled.
+	APITokens string `mapstructure:"api-tokens"`
+	// ApplyTimeout, InitTimeout, PlanTimeout and RunTimeout are how many
+	// seconds each kind of step can run for by default. If 0, there's no
+	// timeout.
+	ApplyTimeout int    `mapstructure:"apply-timeout"`
+	AtlantisURL  string `mapstructure:"atlantis-url"`
+	// AzureDevopsToken is the personal access token of AzureDevopsUser.
+	AzureDevopsToken string `mapstructure:"azuredevops-token"`
+	AzureDevopsUser  string `mapstructure:"azuredevops-user"`
+	// AzureDevopsWebhookUser and AzureDevopsWebhookPassword are the basic
+	// auth credentials that Azure DevOps service hooks are expected to use.
+	AzureDevopsWebhookUser     string `mapstructure:"azuredevops-webhook-user"`
+	AzureDevopsWebhookPassword string `mapstructure:"azuredevops-webhook-password"`
+	// AutoplanRepos is a comma-separated list of repos that plan will be run
+	// on automatically when pull requests are opened or updated.
+	AutoplanRepos string `mapstructure:"autoplan-repos"`
+	DataDir       string `mapstructure:"data-dir"`
+	// DeniedCommentFlags is a comma-separated list of Terraform flags that
+	// can't be passed in comments.
+	DeniedCommentFlags string `mapstructure:"denied-comment-flags"`
+	// BitbucketBaseURL is the URL of the Bitbucket Server installation. If
+	// it's DefaultBitbucketBaseURL, Bitbucket Cloud is used instead.
+	BitbucketBaseURL string `mapstructure:"bitbucket-base-url"`
+	// BitbucketToken is the app password of BitbucketUser for Bitbucket
+	// Cloud or their password or personal access token for Bitbucket Server.
+	BitbucketToken string `mapstructure:"bitbucket-token"`
+	BitbucketUser  string `mapstructure:"bitbucket-user"`
+	// BitbucketWebHookSecret is used to validate Bitbucket Server webhooks.
+	BitbucketWebHookSecret string `mapstructure:"bitbucket-webhook-secret"`
+	// DisableAutoplan is true if plan should never be run automatically.
+	DisableAutoplan bool `mapstructure:"disable-autoplan"`
+	// DrainTimeout is how many seconds to wait for running commands to
+	// finish when shutting down.
+	DrainTimeout int `mapstructure:"drain-timeout"`
+	// GithubAppID and GithubAppKeyFile are set instead of GithubToken to
+	// authenticate as a GitHub App.
+	GithubAppID      int    `mapstructure:"gh-app-id"`
+	GithubAppKeyFile string `mapstructure:"gh-app-key-file"`
+	// GithubChecks is true if results on GitHub should be published as check
+	// runs instead of commit statuses. It requires GitHub App auth.
+	GithubChecks        bool   `mapstructure:"gh-checks"`
 	GithubHostname      string `mapstructure:"gh-hostname"`
 	GithubToken         string `mapstructure:"gh-token"`
 	GithubUser          string `mapstructure:"gh-user"`
 	GithubWebHookSecret string `mapstructure:"gh-webhook-secret"`
+	// GiteaHostname is the hostname of the Gitea installation, optionally
+	// with a scheme. If there's no scheme, https is used.
+	GiteaHostname       string `mapstructure:"gitea-hostname"`
+	GiteaToken          string `mapstructure:"gitea-token"`
+	GiteaUser           string `mapstructure:"gitea-user"`
+	GiteaWebHookSecret  string `mapstructure:"gitea-webhook-secret"`
 	GitlabHostname      string `mapstructure:"gitlab-hostname"`
 	GitlabToken         string `mapstructure:"gitlab-token"`
 	GitlabUser          string `mapstructure:"gitlab-user"`
 	GitlabWebHookSecret string `mapstructure:"gitlab-webhook-secret"`
-	LogLevel            string `mapstructure:"log-level"`
-	Port                int    `mapstructure:"port"`
+	InitTimeout         int    `mapstructure:"init-timeout"`
+	// JobRetentionDays is how many days the output of plans and applies is
+	// kept for. If 0, it's kept forever.
+	JobRetentionDays int    `mapstructure:"job-retention-days"`
+	LogLevel         string `mapstructure:"log-level"`
+	// MaxRunningCommands is the max number of commands that can run at once.
+	// If 0, there's no limit.
+	MaxRunningCommands int `mapstructure:"max-running-commands"`
+	// MaxRunningCommandsPerRepo is the max number of commands for the same
+	// repo that can run at once. If 0, there's no limit.
+	MaxRunningCommandsPerRepo int `mapstructure:"max-running-commands-per-repo"`
+	// ParallelPoolSize is the max number of projects that are planned or
+	// applied at once for a pull request.
+	ParallelPoolSize int `mapstructure:"parallel-pool-size"`
+	PlanTimeout      int `mapstructure:"plan-timeout"`
+	Port             int `mapstructure:"port"`
+	// RepoConfig is the path to the server-side repo config file.
+	RepoConfig string `mapstructure:"repo-config"`
+	// ServerRepoConfig is the parsed server-side repo config. It's set from
+	// RepoConfig rather than by a flag and is nil if RepoConfig is empty.
+	ServerRepoConfig *events.ServerRepoConfig
 	// RequireApproval is whether to require pull request approval before
 	// allowing terraform apply's to be run.
 	RequireApproval bool            `mapstructure:"require-approval"`
+	RunTimeout      int             `mapstructure:"run-timeout"`
 	SlackToken      string          `mapstructure:"slack-token"`
 	SSLCertFile     string          `mapstructure:"ssl-cert-file"`
 	SSLKeyFile      string          `mapstructure:"ssl-key-file"`
@@ -96,10 +199,28 @@ func NewServer(config Config) (*Server, error) {
 	var supportedVCSHosts []vcs.Host
 	var githubClient *vcs.GithubClient
 	var gitlabClient *vcs.GitlabClient
+	var bitbucketClient *vcs.BitbucketClient
+	var bitbucketServerClient *vcs.BitbucketServerClient
+	var azureDevopsClient *vcs.AzureDevopsClient
+	var giteaClient *vcs.GiteaClient
+	var githubAppCredentials *vcs.GithubAppCredentials
 	if config.GithubUser != "" {
 		supportedVCSHosts = append(supportedVCSHosts, vcs.Github)
 		var err error
-		githubClient, err = vcs.NewGithubClient(config.GithubHostname, config.GithubUser, config.GithubToken)
+		if config.GithubAppID != 0 {
+			var key []byte
+			key, err = ioutil.ReadFile(config.GithubAppKeyFile)
+			if err != nil {
+				return nil, errors.Wrapf(err, "reading GitHub App private key %s", config.GithubAppKeyFile)
+			}
+			githubAppCredentials, err = vcs.NewGithubAppCredentials(http.DefaultClient, config.GithubHostname, config.GithubAppID, key)
+			if err != nil {
+				return nil, errors.Wrap(err, "initializing GitHub App credentials")
+			}
+			githubClient, err = vcs.NewGithubAppClient(config.GithubHostname, githubAppCredentials)
+		} else {
+			githubClient, err = vcs.NewGithubClient(config.GithubHostname, config.GithubUser, config.GithubToken)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -125,6 +246,23 @@ func NewServer(config Config) (*Server, error) {
 			}
 		}
 	}
+	if config.BitbucketUser != "" {
+		if config.BitbucketBaseURL == "" || config.BitbucketBaseURL == DefaultBitbucketBaseURL {
+			supportedVCSHosts = append(supportedVCSHosts, vcs.Bitbucket)
+			bitbucketClient = vcs.NewBitbucketClient(http.DefaultClient, config.BitbucketUser, config.BitbucketToken, config.AtlantisURL)
+		} else {
+			supportedVCSHosts = append(supportedVCSHosts, vcs.BitbucketServer)
+			bitbucketServerClient = vcs.NewBitbucketServerClient(http.DefaultClient, config.BitbucketUser, config.BitbucketToken, config.BitbucketBaseURL, config.AtlantisURL)
+		}
+	}
+	if config.AzureDevopsUser != "" {
+		supportedVCSHosts = append(supportedVCSHosts, vcs.AzureDevops)
+		azureDevopsClient = vcs.NewAzureDevopsClient(http.DefaultClient, config.AzureDevopsUser, config.AzureDevopsToken, config.AtlantisURL)
+	}
+	if config.GiteaUser != "" {
+		supportedVCSHosts = append(supportedVCSHosts, vcs.Gitea)
+		giteaClient = vcs.NewGiteaClient(http.DefaultClient, config.GiteaHostname, config.GiteaToken, config.AtlantisURL)
+	}
 	var webhooksConfig []webhooks.Config
 	for _, c := range config.Webhooks {
 		config := webhooks.Config{
@@ -139,8 +277,16 @@ func NewServer(config Config) (*Server, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "initializing webhooks")
 	}
-	vcsClient := vcs.NewDefaultClientProxy(githubClient, gitlabClient)
-	commitStatusUpdater := &events.DefaultCommitStatusUpdater{Client: vcsClient}
+	vcsClient := vcs.NewDefaultClientProxy(githubClient, gitlabClient, bitbucketClient, bitbucketServerClient, azureDevopsClient, giteaClient)
+	markdownRenderer := &events.MarkdownRenderer{}
+	var commitStatusUpdater events.CommitStatusUpdater = &events.DefaultCommitStatusUpdater{Client: vcsClient, AtlantisURL: config.AtlantisURL}
+	if config.GithubChecks && githubClient != nil {
+		commitStatusUpdater = &events.GithubCheckRunUpdater{
+			Client:   githubClient,
+			Fallback: commitStatusUpdater,
+			Renderer: markdownRenderer,
+		}
+	}
 	terraformClient, err := terraform.NewClient()
 	// The flag.Lookup call is to detect if we're running in a unit test. If we
 	// are, then we don't error out because we don't have/want terraform
@@ -148,23 +294,40 @@ func NewServer(config Config) (*Server, error) {
 	if err != nil && flag.Lookup("test.v") == nil {
 		return nil, errors.Wrap(err, "initializing terraform")
 	}
-	markdownRenderer := &events.MarkdownRenderer{}
 	boltdb, err := boltdb.New(config.DataDir)
 	if err != nil {
 		return nil, err
 	}
 	lockingClient := locking.NewClient(boltdb)
+	jobManager, err := jobs.NewManager(filepath.Join(config.DataDir, "jobs"), time.Duration(config.JobRetentionDays)*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
 	run := &run.Run{}
 	configReader := &events.ProjectConfigManager{}
+	repoConfigReader := &events.RepoConfigManager{}
 	workspaceLocker := events.NewDefaultAtlantisWorkspaceLocker()
 	workspace := &events.FileWorkspace{
 		DataDir: config.DataDir,
 	}
+	// Only set the token getter if we're a GitHub App since a nil
+	// *GithubAppCredentials would be a non-nil interface.
+	if githubAppCredentials != nil {
+		workspace.GithubAppTokens = githubAppCredentials
+		workspace.GithubHostname = config.GithubHostname
+	}
 	projectPreExecute := &events.DefaultProjectPreExecutor{
-		Locker:       lockingClient,
-		Run:          run,
-		ConfigReader: configReader,
-		Terraform:    terraformClient,
+		Locker:           lockingClient,
+		ConfigReader:     configReader,
+		RepoConfigReader: repoConfigReader,
+		Terraform:        terraformClient,
+		ServerRepoConfig: config.ServerRepoConfig,
+	}
+	stepTimeouts := events.StepTimeouts{
+		Init:  time.Duration(config.InitTimeout) * time.Second,
+		Plan:  time.Duration(config.PlanTimeout) * time.Second,
+		Apply: time.Duration(config.ApplyTimeout) * time.Second,
+		Run:   time.Duration(config.RunTimeout) * time.Second,
 	}
 	applyExecutor := &events.ApplyExecutor{
 		VCSClient:         vcsClient,
@@ -173,7 +336,11 @@ func NewServer(config Config) (*Server, error) {
 		Run:               run,
 		AtlantisWorkspace: workspace,
 		ProjectPreExecute: projectPreExecute,
+		RepoConfigReader:  repoConfigReader,
 		Webhooks:          webhooksManager,
+		ParallelPoolSize:  config.ParallelPoolSize,
+		ServerRepoConfig:  config.ServerRepoConfig,
+		StepTimeouts:      stepTimeouts,
 	}
 	planExecutor := &events.PlanExecutor{
 		VCSClient:         vcsClient,
@@ -183,8 +350,16 @@ func NewServer(config Config) (*Server, error) {
 		ProjectPreExecute: projectPreExecute,
 		Locker:            lockingClient,
 		ProjectFinder:     &events.DefaultProjectFinder{},
+		RepoConfigReader:  repoConfigReader,
+		ParallelPoolSize:  config.ParallelPoolSize,
+		ServerRepoConfig:  config.ServerRepoConfig,
+		StepTimeouts:      stepTimeouts,
 	}
 	helpExecutor := &events.HelpExecutor{}
+	unlockExecutor := &events.UnlockExecutor{
+		Locker:    lockingClient,
+		Workspace: workspace,
+	}
 	pullClosedExecutor := &events.PullClosedExecutor{
 		VCSClient: vcsClient,
 		Locker:    lockingClient,
@@ -192,68 +367,146 @@ func NewServer(config Config) (*Server, error) {
 	}
 	logger := logging.NewSimpleLogger("server", nil, false, logging.ToLogLevel(config.LogLevel))
 	eventParser := &events.EventParser{
-		GithubUser:  config.GithubUser,
-		GithubToken: config.GithubToken,
-		GitlabUser:  config.GitlabUser,
-		GitlabToken: config.GitlabToken,
+		GithubUser:         config.GithubUser,
+		GithubToken:        config.GithubToken,
+		GitlabUser:         config.GitlabUser,
+		GitlabToken:        config.GitlabToken,
+		BitbucketUser:      config.BitbucketUser,
+		BitbucketToken:     config.BitbucketToken,
+		BitbucketServerURL: config.BitbucketBaseURL,
+		AzureDevopsUser:    config.AzureDevopsUser,
+		AzureDevopsToken:   config.AzureDevopsToken,
+		GiteaUser:          config.GiteaUser,
+		GiteaToken:         config.GiteaToken,
 	}
 	commandHandler := &events.CommandHandler{
-		ApplyExecutor:            applyExecutor,
-		PlanExecutor:             planExecutor,
-		HelpExecutor:             helpExecutor,
-		LockURLGenerator:         planExecutor,
-		EventParser:              eventParser,
-		VCSClient:                vcsClient,
-		GithubPullGetter:         githubClient,
-		GitlabMergeRequestGetter: gitlabClient,
-		CommitStatusUpdater:      commitStatusUpdater,
-		AtlantisWorkspaceLocker:  workspaceLocker,
-		MarkdownRenderer:         markdownRenderer,
-		Logger:                   logger,
+		ApplyExecutor:             applyExecutor,
+		PlanExecutor:              planExecutor,
+		HelpExecutor:              helpExecutor,
+		UnlockExecutor:            unlockExecutor,
+		LockURLGenerator:          planExecutor,
+		EventParser:               eventParser,
+		VCSClient:                 vcsClient,
+		GithubPullGetter:          githubClient,
+		GitlabMergeRequestGetter:  gitlabClient,
+		BitbucketPullGetter:       bitbucketClient,
+		BitbucketServerPullGetter: bitbucketServerClient,
+		AzureDevopsPullGetter:     azureDevopsClient,
+		GiteaPullGetter:           giteaClient,
+		CommitStatusUpdater:       commitStatusUpdater,
+		AtlantisWorkspaceLocker:   workspaceLocker,
+		MarkdownRenderer:          markdownRenderer,
+		Logger:                    logger,
+		CommentFlagPolicy:         events.NewCommentFlagPolicy(config.AllowedCommentFlags, config.DeniedCommentFlags),
+		Jobs:                      jobManager,
+	}
+	commandQueue := &events.CommandQueue{
+		Runner:            commandHandler,
+		VCSClient:         vcsClient,
+		Logger:            logger,
+		Store:             boltdb.CommandStore(),
+		MaxRunning:        config.MaxRunningCommands,
+		MaxRunningPerRepo: config.MaxRunningCommandsPerRepo,
 	}
 	eventsController := &EventsController{
-		CommandRunner:          commandHandler,
-		PullCleaner:            pullClosedExecutor,
-		Parser:                 eventParser,
-		Logger:                 logger,
-		GithubWebHookSecret:    []byte(config.GithubWebHookSecret),
-		GithubRequestValidator: &DefaultGithubRequestValidator{},
-		GitlabRequestParser:    &DefaultGitlabRequestParser{},
-		GitlabWebHookSecret:    []byte(config.GitlabWebHookSecret),
-		SupportedVCSHosts:      supportedVCSHosts,
+		CommandRunner:              commandQueue,
+		PullCleaner:                pullClosedExecutor,
+		Parser:                     eventParser,
+		Logger:                     logger,
+		GithubWebHookSecret:        []byte(config.GithubWebHookSecret),
+		GithubRequestValidator:     &DefaultGithubRequestValidator{},
+		GitlabRequestParser:        &DefaultGitlabRequestParser{},
+		GitlabWebHookSecret:        []byte(config.GitlabWebHookSecret),
+		BitbucketWebHookSecret:     []byte(config.BitbucketWebHookSecret),
+		AzureDevopsWebhookUser:     []byte(config.AzureDevopsWebhookUser),
+		AzureDevopsWebhookPassword: []byte(config.AzureDevopsWebhookPassword),
+		GiteaWebHookSecret:         []byte(config.GiteaWebHookSecret),
+		SupportedVCSHosts:          supportedVCSHosts,
+		DisableAutoplan:            config.DisableAutoplan,
+		AutoplanWhitelist:          &events.RepoWhitelist{Whitelist: config.AutoplanRepos},
+	}
+	apiController := &APIController{
+		Locker:    lockingClient,
+		Jobs:      jobManager,
+		Workspace: workspace,
+		Logger:    logger,
+		APITokens: splitAPITokens(config.APITokens),
+	}
+	csrfKey := make([]byte, 32)
+	if _, err := rand.Read(csrfKey); err != nil {
+		return nil, errors.Wrap(err, "generating csrf key")
 	}
 	router := mux.NewRouter()
 	return &Server{
 		Router:             router,
 		Port:               config.Port,
 		CommandHandler:     commandHandler,
+		CommandQueue:       commandQueue,
+		DrainTimeout:       time.Duration(config.DrainTimeout) * time.Second,
 		Logger:             logger,
 		Locker:             lockingClient,
 		AtlantisURL:        config.AtlantisURL,
 		EventsController:   eventsController,
+		APIController:      apiController,
 		IndexTemplate:      indexTemplate,
 		LockDetailTemplate: lockTemplate,
+		Jobs:               jobManager,
+		JobDetailTemplate:  jobTemplate,
+		CSRFKey:            csrfKey,
 		SSLKeyFile:         config.SSLKeyFile,
 		SSLCertFile:        config.SSLCertFile,
 	}, nil
 }
 
-// Start creates the routes and starts serving traffic.
-func (s *Server) Start() error {
+// AddRoutes adds the routes to s.Router. It's called by Start.
+func (s *Server) AddRoutes() {
+	// Lock ids of projects at the root of their repo contain "/./", ex.
+	// owner/repo/./default, so paths mustn't be cleaned and redirected.
+	s.Router.SkipClean(true)
 	s.Router.HandleFunc("/", s.Index).Methods("GET").MatcherFunc(func(r *http.Request, rm *mux.RouteMatch) bool {
 		return r.URL.Path == "/" || r.URL.Path == "/index.html"
 	})
 	s.Router.PathPrefix("/static/").Handler(http.FileServer(&assetfs.AssetFS{Asset: static.Asset, AssetDir: static.AssetDir, AssetInfo: static.AssetInfo}))
 	s.Router.HandleFunc("/events", s.postEvents).Methods("POST")
+	s.Router.HandleFunc("/healthz", s.Healthz).Methods("GET")
 	s.Router.HandleFunc("/locks", s.DeleteLockRoute).Methods("DELETE").Queries("id", "{id:.*}")
-	lockRoute := s.Router.HandleFunc("/lock", s.GetLockRoute).Methods("GET").Queries("id", "{id}").Name(LockRouteName)
+	s.Router.HandleFunc("/lock", s.GetLockRoute).Methods("GET").Queries("id", "{id}").Name(LockRouteName)
+	a := s.APIController
+	// Job output can contain secrets so unlike the rest of the UI these
+	// require the job's token.
+	s.Router.HandleFunc("/jobs/{id}", s.GetJobRoute).Methods("GET").Name(JobRouteName)
+	s.Router.HandleFunc("/jobs/{id}/stream", s.StreamJobRoute).Methods("GET")
+	s.Router.HandleFunc("/cancel", s.CancelRoute).Methods("POST").Queries("repo", "{repo}", "pull", "{pull}")
+	// Lock ids contain slashes, ex. /api/v1/locks/owner/repo/path/default,
+	// and so do repos, ex. /api/v1/pulls/owner/repo/1.
+	api := s.Router.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/locks", a.Authenticate(a.ListLocks)).Methods("GET")
+	api.HandleFunc("/locks/{id:.+}", a.Authenticate(a.GetLockRoute)).Methods("GET")
+	api.HandleFunc("/locks/{id:.+}", a.Authenticate(a.DeleteLockRoute)).Methods("DELETE")
+	api.HandleFunc("/jobs", a.Authenticate(a.ListJobs)).Methods("GET")
+	api.HandleFunc("/pulls/{repo:.+}/{num:[0-9]+}", a.Authenticate(a.GetPullRoute)).Methods("GET")
+}
+
+// Start creates the routes and starts serving traffic.
+func (s *Server) Start() error {
+	s.AddRoutes()
 	// function that planExecutor can use to construct detail view url
 	// injecting this here because this is the earliest routes are created
 	s.CommandHandler.SetLockURL(func(lockID string) string {
 		// ignoring error since guaranteed to succeed if "id" is specified
-		u, _ := lockRoute.URL("id", url.QueryEscape(lockID))
+		u, _ := s.Router.Get(LockRouteName).URL("id", url.QueryEscape(lockID))
 		return s.AtlantisURL + u.RequestURI()
 	})
+	// Job pages need the job's token so it's part of their URL.
+	s.CommandHandler.SetJobURL(func(info jobs.Info) string {
+		u, _ := s.Router.Get(JobRouteName).URL("id", info.ID)
+		return s.AtlantisURL + u.RequestURI() + "?token=" + url.QueryEscape(info.Token)
+	})
+	// Pick up the commands that didn't finish before we last stopped.
+	if err := s.CommandQueue.Recover(); err != nil {
+		return errors.Wrap(err, "recovering commands")
+	}
+
 	n := negroni.New(&negroni.Recovery{
 		Logger:     log.New(os.Stdout, "", log.LstdFlags),
 		PrintStack: false,
@@ -285,7 +538,21 @@ func (s *Server) Start() error {
 	}()
 	<-stop
 
-	s.Logger.Warn("Received interrupt. Safely shutting down")
+	// Keep serving while we drain so that new webhooks are told we're
+	// shutting down and /healthz reports that we're draining.
+	s.Logger.Warn("Received interrupt. Waiting up to %s for running commands to finish before cancelling them, interrupt again to stop now", s.DrainTimeout)
+	drained := make(chan bool, 1)
+	go func() { drained <- s.CommandQueue.Drain(s.DrainTimeout) }()
+	select {
+	case ok := <-drained:
+		if !ok {
+			s.Logger.Warn("Running commands didn't finish within %s so they were cancelled", s.DrainTimeout)
+		}
+	case <-stop:
+		s.Logger.Warn("Received second interrupt. Not waiting for running commands")
+	}
+
+	s.Logger.Warn("Safely shutting down")
 	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second) // nolint: vet
 	if err := server.Shutdown(ctx); err != nil {
 		return cli.NewExitError(fmt.Sprintf("while shutting down: %s", err), 1)
@@ -293,6 +560,18 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// Healthz is the /healthz route. It responds with 503 once the server has
+// started shutting down so that load balancers stop sending it traffic.
+func (s *Server) Healthz(w http.ResponseWriter, _ *http.Request) {
+	status, code := "ok", http.StatusOK
+	if s.CommandQueue.Draining() {
+		status, code = "draining", http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `{"status":%q}`+"\n", status)
+}
+
 // Index is the / route.
 func (s *Server) Index(w http.ResponseWriter, _ *http.Request) {
 	locks, err := s.Locker.List()
@@ -356,6 +635,8 @@ func (s *Server) GetLock(w http.ResponseWriter, _ *http.Request, id string) {
 		LockKey:         idUnencoded,
 		RepoOwner:       repo[0],
 		RepoName:        repo[1],
+		PullNum:         lock.Pull.Num,
+		CancelToken:     s.CancelToken(lock.Project.RepoFullName, lock.Pull.Num),
 		PullRequestLink: lock.Pull.URL,
 		LockedBy:        lock.Pull.Author,
 		Workspace:       lock.Workspace,
@@ -394,12 +675,166 @@ func (s *Server) DeleteLock(w http.ResponseWriter, _ *http.Request, id string) {
 	s.respond(w, logging.Info, http.StatusOK, "Deleted lock id %s", idUnencoded)
 }
 
+// CancelRoute is the POST /cancel route. It cancels the running and queued
+// commands of the pull request in the repo and pull query params. Requests
+// from API clients must have an API token and requests from the lock page
+// must have the pull request's CancelToken in the CancelTokenHeader header.
+func (s *Server) CancelRoute(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pullNum, err := strconv.Atoi(vars["pull"])
+	if err != nil {
+		s.respond(w, logging.Warn, http.StatusBadRequest, "Invalid pull request number %q", vars["pull"])
+		return
+	}
+	repoFullName := vars["repo"]
+	if r.Header.Get("Authorization") != "" {
+		s.APIController.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+			s.Cancel(w, r, repoFullName, pullNum, "an Atlantis API client")
+		})(w, r)
+		return
+	}
+	token := []byte(r.Header.Get(CancelTokenHeader))
+	if !hmac.Equal(token, []byte(s.CancelToken(repoFullName, pullNum))) {
+		s.respond(w, logging.Warn, http.StatusForbidden, "Missing or invalid cancel token for %s#%d", repoFullName, pullNum)
+		return
+	}
+	s.Cancel(w, r, repoFullName, pullNum, "the Atlantis UI")
+}
+
+// CancelToken returns the token that the lock page must send to cancel the
+// commands of pull request pullNum in repoFullName. Other sites can't read
+// the page so they can't get the token.
+func (s *Server) CancelToken(repoFullName string, pullNum int) string {
+	mac := hmac.New(sha256.New, s.CSRFKey)
+	fmt.Fprintf(mac, "cancel %s#%d", repoFullName, pullNum) // nolint: errcheck
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Cancel cancels the running and queued commands of pull request pullNum in
+// repoFullName. by describes who cancelled them. CancelRoute should be called
+// first.
+// This method is split out to make this route testable.
+func (s *Server) Cancel(w http.ResponseWriter, _ *http.Request, repoFullName string, pullNum int, by string) {
+	if n := s.CommandQueue.CancelPull(repoFullName, pullNum, by); n == 0 {
+		s.respond(w, logging.Warn, http.StatusNotFound, "No running or queued commands for %s#%d", repoFullName, pullNum)
+		return
+	}
+	s.respond(w, logging.Info, http.StatusOK, "Cancelled the commands for %s#%d", repoFullName, pullNum)
+}
+
+// GetJobRoute is the GET /jobs/{id} route. It renders the job detail view
+// which follows the job's output. The job's token must be in the token query
+// param.
+func (s *Server) GetJobRoute(w http.ResponseWriter, r *http.Request) {
+	s.GetJob(w, r, mux.Vars(r)["id"])
+}
+
+// GetJob handles a job detail page view. GetJobRoute should be called first.
+// This method is split out to make this route testable.
+func (s *Server) GetJob(w http.ResponseWriter, r *http.Request, id string) {
+	job, ok := s.findJob(w, r, id)
+	if !ok {
+		return
+	}
+	info := job.Info()
+	s.JobDetailTemplate.Execute(w, JobDetailData{ // nolint: errcheck
+		ID:           info.ID,
+		RepoFullName: info.RepoFullName,
+		PullNum:      info.PullNum,
+		Command:      info.Command,
+		Workspace:    info.Workspace,
+		Status:       info.Status,
+		Started:      info.Started,
+		StreamURL:    fmt.Sprintf("/jobs/%s/stream?token=%s", info.ID, url.QueryEscape(info.Token)),
+	})
+}
+
+// StreamJobRoute is the GET /jobs/{id}/stream route. It streams the job's
+// output as server-sent events. Like GetJobRoute, the job's token must be in
+// the token query param.
+func (s *Server) StreamJobRoute(w http.ResponseWriter, r *http.Request) {
+	s.StreamJob(w, r, mux.Vars(r)["id"])
+}
+
+// StreamJob sends each line of the job's output as a server-sent event,
+// waiting for more until the job finishes. Then a finished event is sent with
+// the job's status. Each line's id is its number so that browsers that
+// reconnect resume from the line after Last-Event-ID.
+// StreamJobRoute should be called first.
+// This method is split out to make this route testable.
+func (s *Server) StreamJob(w http.ResponseWriter, r *http.Request, id string) {
+	job, ok := s.findJob(w, r, id)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.respond(w, logging.Error, http.StatusInternalServerError, "Streaming isn't supported")
+		return
+	}
+	next, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	for {
+		lines, finished, updated := job.Lines(next)
+		for _, line := range lines {
+			next++
+			// Carriage returns would end the event's data early.
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", next, strings.Replace(line, "\r", "", -1))
+		}
+		if finished {
+			fmt.Fprintf(w, "event: finished\ndata: %s\n\n", job.Info().Status)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// findJob returns the job with id if r has its token in the token query
+// param. If there isn't one, it can't be read or r doesn't have its token, it
+// responds with an error and returns false.
+func (s *Server) findJob(w http.ResponseWriter, r *http.Request, id string) (*jobs.Job, bool) {
+	job, err := s.Jobs.Get(id)
+	if err != nil {
+		s.respond(w, logging.Error, http.StatusInternalServerError, "Failed to get job %s: %s", id, err)
+		return nil, false
+	}
+	if job == nil {
+		s.respond(w, logging.Warn, http.StatusNotFound, "No job found with id %s", id)
+		return nil, false
+	}
+	// Jobs from before tokens were added don't have one and can't be viewed.
+	token := job.Info().Token
+	if token == "" || !hmac.Equal([]byte(r.URL.Query().Get("token")), []byte(token)) {
+		s.respond(w, logging.Warn, http.StatusForbidden, "Missing or invalid token for job %s", id)
+		return nil, false
+	}
+	return job, true
+}
+
 // postEvents handles POST requests to our /events endpoint. These should be
 // VCS webhook requests.
 func (s *Server) postEvents(w http.ResponseWriter, r *http.Request) {
 	s.EventsController.Post(w, r)
 }
 
+// splitAPITokens returns the tokens in the comma-separated list.
+func splitAPITokens(list string) []string {
+	var tokens []string
+	for _, t := range strings.Split(list, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
 // respond is a helper function to respond and log the response. lvl is the log
 // level to log at, code is the HTTP response code.
 func (s *Server) respond(w http.ResponseWriter, lvl logging.LogLevel, code int, format string, args ...interface{}) {
@@ -6,12 +6,15 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/hootsuite/atlantis/server"
+	"github.com/hootsuite/atlantis/server/events"
+	"github.com/hootsuite/atlantis/server/events/jobs"
 	"github.com/hootsuite/atlantis/server/events/locking/mocks"
 	"github.com/hootsuite/atlantis/server/events/models"
 	"github.com/hootsuite/atlantis/server/logging"
@@ -30,6 +33,23 @@ func TestNewServer(t *testing.T) {
 	Ok(t, err)
 }
 
+func TestHealthz(t *testing.T) {
+	t.Log("healthz should return 200 until the server starts draining")
+	s := server.Server{
+		CommandQueue: &events.CommandQueue{},
+	}
+	req, _ := http.NewRequest("GET", "/healthz", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	s.Healthz(w, req)
+	responseContains(t, w, http.StatusOK, `{"status":"ok"}`)
+	Equals(t, "application/json", w.Header().Get("Content-Type"))
+
+	s.CommandQueue.Drain(time.Second)
+	w = httptest.NewRecorder()
+	s.Healthz(w, req)
+	responseContains(t, w, http.StatusServiceUnavailable, `{"status":"draining"}`)
+}
+
 func TestIndex_LockErr(t *testing.T) {
 	t.Log("index should return a 503 if unable to list locks")
 	RegisterMockTestingT(t)
@@ -137,13 +157,14 @@ func TestGetLock_Success(t *testing.T) {
 	l := mocks.NewMockLocker()
 	When(l.GetLock("id")).ThenReturn(&models.ProjectLock{
 		Project:   models.Project{RepoFullName: "owner/repo", Path: "path"},
-		Pull:      models.PullRequest{URL: "url", Author: "lkysow"},
+		Pull:      models.PullRequest{URL: "url", Author: "lkysow", Num: 1},
 		Workspace: "workspace",
 	}, nil)
 	tmpl := sMocks.NewMockTemplateWriter()
 	s := server.Server{
 		Locker:             l,
 		LockDetailTemplate: tmpl,
+		CSRFKey:            []byte("key"),
 	}
 	eventsReq, _ = http.NewRequest("GET", "", bytes.NewBuffer(nil))
 	w := httptest.NewRecorder()
@@ -153,6 +174,8 @@ func TestGetLock_Success(t *testing.T) {
 		LockKey:         "id",
 		RepoOwner:       "owner",
 		RepoName:        "repo",
+		PullNum:         1,
+		CancelToken:     s.CancelToken("owner/repo", 1),
 		PullRequestLink: "url",
 		LockedBy:        "lkysow",
 		Workspace:       "workspace",
@@ -160,6 +183,188 @@ func TestGetLock_Success(t *testing.T) {
 	responseContains(t, w, http.StatusOK, "")
 }
 
+func TestCancel_NothingRunning(t *testing.T) {
+	t.Log("cancelling when nothing is running or queued should return a 404")
+	s := server.Server{
+		CommandQueue: &events.CommandQueue{Logger: logging.NewNoopLogger()},
+		Logger:       logging.NewNoopLogger(),
+	}
+	req, _ := http.NewRequest("POST", "/cancel?repo=owner/repo&pull=1", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	s.Cancel(w, req, "owner/repo", 1, "the Atlantis UI")
+	responseContains(t, w, http.StatusNotFound, "No running or queued commands for owner/repo#1")
+}
+
+func TestCancelRoute_Unauthenticated(t *testing.T) {
+	t.Log("cancelling should require an API token or the pull request's cancel token")
+	s := newRoutedServer()
+	req, _ := http.NewRequest("POST", "/cancel?repo=owner/repo&pull=1", bytes.NewBuffer(nil))
+	req.Header.Set("Authorization", "Bearer wrong")
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	responseContains(t, w, http.StatusUnauthorized, "Missing or invalid API token")
+
+	for _, token := range []string{"", "wrong", s.CancelToken("owner/repo", 2)} {
+		req, _ := http.NewRequest("POST", "/cancel?repo=owner/repo&pull=1", bytes.NewBuffer(nil))
+		if token != "" {
+			req.Header.Set(server.CancelTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, req)
+		responseContains(t, w, http.StatusForbidden, "Missing or invalid cancel token for owner/repo#1")
+	}
+
+	req, _ = http.NewRequest("POST", "/cancel?repo=owner/repo&pull=1", bytes.NewBuffer(nil))
+	req.Header.Set("Authorization", "Bearer token")
+	w = httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	responseContains(t, w, http.StatusNotFound, "No running or queued commands for owner/repo#1")
+}
+
+func TestCancelRoute_CancelToken(t *testing.T) {
+	t.Log("the lock page should be able to cancel with the pull request's cancel token")
+	s := newRoutedServer()
+	req, _ := http.NewRequest("POST", "/cancel?repo=owner/repo&pull=1", bytes.NewBuffer(nil))
+	req.Header.Set(server.CancelTokenHeader, s.CancelToken("owner/repo", 1))
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	responseContains(t, w, http.StatusNotFound, "No running or queued commands for owner/repo#1")
+}
+
+func TestAPILockRoutes_RootProject(t *testing.T) {
+	t.Log("the API lock routes should accept lock ids with /./ in them without redirecting")
+	RegisterMockTestingT(t)
+	s := newRoutedServer()
+	l := mocks.NewMockLocker()
+	s.APIController.Locker = l
+	lock := models.ProjectLock{Project: models.Project{RepoFullName: "owner/repo", Path: "."}, Workspace: "default"}
+	When(l.GetLock("owner/repo/./default")).ThenReturn(&lock, nil)
+	When(l.Unlock("owner/repo/./default")).ThenReturn(&lock, nil)
+
+	for _, method := range []string{"GET", "DELETE"} {
+		req, _ := http.NewRequest(method, "/api/v1/locks/owner/repo/./default", bytes.NewBuffer(nil))
+		req.Header.Set("Authorization", "Bearer token")
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, req)
+		responseContains(t, w, http.StatusOK, `"id":"owner/repo/./default"`)
+	}
+	l.VerifyWasCalledOnce().Unlock("owner/repo/./default")
+}
+
+func TestJobRoutes_Token(t *testing.T) {
+	t.Log("job pages and their output should need the job's token but not an API token so browsers can view them")
+	js, cleanup := newJobServer(t)
+	defer cleanup()
+	s := server.Server{
+		Router:            mux.NewRouter(),
+		Logger:            logging.NewNoopLogger(),
+		Jobs:              js.Jobs,
+		JobDetailTemplate: sMocks.NewMockTemplateWriter(),
+		APIController:     &server.APIController{Logger: logging.NewNoopLogger()},
+	}
+	s.AddRoutes()
+	job, err := s.Jobs.Start(jobs.Info{})
+	Ok(t, err)
+	_, err = job.Write([]byte("one\n"))
+	Ok(t, err)
+	Ok(t, job.Finish("success"))
+	info := job.Info()
+
+	for _, path := range []string{"/jobs/" + info.ID, "/jobs/" + info.ID + "/stream"} {
+		for _, query := range []string{"", "?token=wrong", "?token=" + info.ID} {
+			req, _ := http.NewRequest("GET", path+query, bytes.NewBuffer(nil))
+			w := httptest.NewRecorder()
+			s.Router.ServeHTTP(w, req)
+			responseContains(t, w, http.StatusForbidden, "Missing or invalid token for job "+info.ID)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/jobs/"+info.ID+"?token="+info.Token, bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	Equals(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/jobs/"+info.ID+"/stream?token="+info.Token, bytes.NewBuffer(nil))
+	w = httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	responseContains(t, w, http.StatusOK, "data: one")
+}
+
+func TestGetJob_None(t *testing.T) {
+	t.Log("If there is no job with that ID we get a 404")
+	s, cleanup := newJobServer(t)
+	defer cleanup()
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	s.GetJob(w, req, "0123456789abcdef")
+	responseContains(t, w, http.StatusNotFound, "No job found with id 0123456789abcdef")
+}
+
+func TestGetJob_Success(t *testing.T) {
+	t.Log("Should be able to render a job successfully")
+	s, cleanup := newJobServer(t)
+	defer cleanup()
+	tmpl := sMocks.NewMockTemplateWriter()
+	s.JobDetailTemplate = tmpl
+	job, err := s.Jobs.Start(jobs.Info{RepoFullName: "owner/repo", PullNum: 1, Command: "plan", Workspace: "default"})
+	Ok(t, err)
+	info := job.Info()
+
+	req, _ := http.NewRequest("GET", "/?token="+info.Token, bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	s.GetJob(w, req, info.ID)
+	tmpl.VerifyWasCalledOnce().Execute(w, server.JobDetailData{
+		ID:           info.ID,
+		RepoFullName: "owner/repo",
+		PullNum:      1,
+		Command:      "plan",
+		Workspace:    "default",
+		Status:       jobs.RunningStatus,
+		Started:      info.Started,
+		StreamURL:    "/jobs/" + info.ID + "/stream?token=" + info.Token,
+	})
+	responseContains(t, w, http.StatusOK, "")
+}
+
+func TestStreamJob_Finished(t *testing.T) {
+	t.Log("finished jobs should be replayed from the line after Last-Event-ID")
+	s, cleanup := newJobServer(t)
+	defer cleanup()
+	job, err := s.Jobs.Start(jobs.Info{})
+	Ok(t, err)
+	_, err = job.Write([]byte("one\ntwo\nthree\n"))
+	Ok(t, err)
+	Ok(t, job.Finish("success"))
+
+	req, _ := http.NewRequest("GET", "/?token="+job.Info().Token, bytes.NewBuffer(nil))
+	req.Header.Set("Last-Event-ID", "1")
+	w := httptest.NewRecorder()
+	s.StreamJob(w, req, job.Info().ID)
+	Equals(t, http.StatusOK, w.Code)
+	Equals(t, "text/event-stream", w.Header().Get("Content-Type"))
+	Equals(t, "id: 2\ndata: two\n\nid: 3\ndata: three\n\nevent: finished\ndata: success\n\n", w.Body.String())
+}
+
+func TestStreamJob_Running(t *testing.T) {
+	t.Log("output should be streamed until the job finishes")
+	s, cleanup := newJobServer(t)
+	defer cleanup()
+	job, err := s.Jobs.Start(jobs.Info{})
+	Ok(t, err)
+	_, err = job.Write([]byte("one\n"))
+	Ok(t, err)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		job.Write([]byte("two\r\n")) // nolint: errcheck
+		job.Finish("failed")         // nolint: errcheck
+	}()
+
+	req, _ := http.NewRequest("GET", "/?token="+job.Info().Token, bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	s.StreamJob(w, req, job.Info().ID)
+	Equals(t, "id: 1\ndata: one\n\nid: 2\ndata: two\n\nevent: finished\ndata: failed\n\n", w.Body.String())
+}
+
 func TestDeleteLockRoute_NoLockID(t *testing.T) {
 	t.Log("If there is no lock ID in the request then we should get a 400")
 	eventsReq, _ = http.NewRequest("GET", "", bytes.NewBuffer(nil))
@@ -223,6 +428,32 @@ func TestDeleteLock_Success(t *testing.T) {
 	responseContains(t, w, http.StatusOK, "Deleted lock id id")
 }
 
+// newRoutedServer returns a server with its routes added whose API accepts
+// the token "token".
+func newRoutedServer() server.Server {
+	s := server.Server{
+		Router:       mux.NewRouter(),
+		CommandQueue: &events.CommandQueue{Logger: logging.NewNoopLogger()},
+		Logger:       logging.NewNoopLogger(),
+		CSRFKey:      []byte("key"),
+		APIController: &server.APIController{
+			Logger:    logging.NewNoopLogger(),
+			APITokens: []string{"token"},
+		},
+	}
+	s.AddRoutes()
+	return s
+}
+
+func newJobServer(t *testing.T) (server.Server, func()) {
+	dir, err := ioutil.TempDir("", "atlantis-jobs")
+	Ok(t, err)
+	m, err := jobs.NewManager(dir, 0)
+	Ok(t, err)
+	RegisterMockTestingT(t)
+	return server.Server{Jobs: m, Logger: logging.NewNoopLogger()}, func() { os.RemoveAll(dir) } // nolint: errcheck
+}
+
 func responseContains(t *testing.T, r *httptest.ResponseRecorder, status int, bodySubstr string) {
 	Equals(t, status, r.Result().StatusCode)
 	body, _ := ioutil.ReadAll(r.Result().Body)
@@ -88,6 +88,8 @@ type LockDetailData struct {
 	LockKey         string
 	RepoOwner       string
 	RepoName        string
+	PullNum         int
+	CancelToken     string
 	PullRequestLink string
 	LockedBy        string
 	Workspace       string
@@ -129,6 +131,7 @@ var lockTemplate = template.Must(template.New("lock.html.tmpl").Parse(`
       </div>
       <div class="four columns">
         <a class="button button-default" id="discardPlanUnlock">Discard Plan & Unlock</a>
+        <a class="button button-default" id="cancelCommands" data-repo="{{.RepoOwner}}/{{.RepoName}}" data-pull="{{.PullNum}}" data-token="{{.CancelToken}}">Cancel Running Commands</a>
       </div>
     </section>
   </div>
@@ -182,6 +185,23 @@ var lockTemplate = template.Must(template.New("lock.html.tmpl").Parse(`
     });
   });
 
+  $("#cancelCommands").click(function() {
+    if (!confirm("Are you sure you want to cancel this pull request's running and queued commands?")) {
+      return;
+    }
+    $.ajax({
+        url: '/cancel?repo='+encodeURIComponent($(this).attr('data-repo'))+'&pull='+$(this).attr('data-pull'),
+        type: 'POST',
+        headers: {'X-Atlantis-Cancel-Token': $(this).attr('data-token')},
+        success: function(result) {
+          alert(result);
+        },
+        error: function(xhr) {
+          alert(xhr.responseText);
+        }
+    });
+  });
+
   // When the user clicks anywhere outside of the modal, close it
   window.onclick = function(event) {
       if (event.target == modal) {
@@ -192,3 +212,68 @@ var lockTemplate = template.Must(template.New("lock.html.tmpl").Parse(`
 </body>
 </html>
 `))
+
+// JobDetailData holds the fields needed to display the job detail view.
+type JobDetailData struct {
+	ID           string
+	RepoFullName string
+	PullNum      int
+	Command      string
+	Workspace    string
+	Status       string
+	Started      time.Time
+	StreamURL    string
+}
+
+var jobTemplate = template.Must(template.New("job.html.tmpl").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>atlantis</title>
+  <meta name="description" content="">
+  <meta name="author" content="">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <link rel="stylesheet" href="/static/css/normalize.css">
+  <link rel="stylesheet" href="/static/css/skeleton.css">
+  <link rel="stylesheet" href="/static/css/custom.css">
+  <link rel="icon" type="image/png" href="/static/images/atlantis-icon.png">
+  <script src="/static/js/jquery-3.2.1.min.js"></script>
+</head>
+<body>
+  <div class="container">
+    <section class="header">
+    <a title="atlantis" href="/"><img src="/static/images/atlantis-icon.png"/></a>
+    <p class="title-heading">atlantis</p>
+    <p class="title-heading"><strong>{{.RepoFullName}} #{{.PullNum}}</strong> <code id="jobStatus">{{.Status}}</code></p>
+    </section>
+    <div class="navbar-spacer"></div>
+    <br>
+    <section>
+      <h6><code>Command</code>: <strong>{{.Command}}</strong></h6>
+      <h6><code>Workspace</code>: <strong>{{.Workspace}}</strong></h6>
+      <h6><code>Started</code>: <strong>{{.Started}}</strong></h6>
+      <pre><code id="jobOutput"></code></pre>
+    </section>
+  </div>
+<script>
+  var output = $("#jobOutput");
+  // Lines are sent with their number as their id so if the connection
+  // drops the browser resumes after the last line it got.
+  var source = new EventSource("{{.StreamURL}}");
+  source.onmessage = function(event) {
+    // Only follow the output if they haven't scrolled up to read it.
+    var following = $(window).scrollTop() + $(window).height() >= $(document).height() - 50;
+    output.append(document.createTextNode(event.data + "\n"));
+    if (following) {
+      window.scrollTo(0, document.body.scrollHeight);
+    }
+  };
+  source.addEventListener("finished", function(event) {
+    $("#jobStatus").text(event.data);
+    source.close();
+  });
+</script>
+</body>
+</html>
+`))